@@ -0,0 +1,600 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// apiTokenEnv はAPIサーバーの認証トークンを指定する環境変数の名前
+const apiTokenEnv = "NAROU_DOWNLOAD_API_TOKEN"
+
+// maxQueuedJobs は待機できるダウンロードの最大数
+const maxQueuedJobs = 100
+
+// maxJobEvents はダウンロードごとに保持するイベントの最大数（SSEの接続前に送信したイベントを再送するため）
+const maxJobEvents = 1000
+
+// finishedJobTTL は終了したダウンロードの状態とイベントを保持する時間
+var finishedJobTTL = time.Hour
+
+// maxFinishedJobs は保持する終了したダウンロードの最大数（超えた場合は古いものから削除する）
+const maxFinishedJobs = 100
+
+// jobEvictInterval は保持する時間を過ぎたダウンロードを削除する間隔
+var jobEvictInterval = time.Minute
+
+// sseKeepAlive はSSEの接続を維持するためにコメントを送信する間隔
+var sseKeepAlive = 30 * time.Second
+
+// ダウンロードの状態
+const (
+	jobQueued  = "queued"
+	jobRunning = "running"
+	jobDone    = "done"
+	jobFailed  = "failed"
+)
+
+// APIDownloadRequest はAPIでダウンロードを開始する際の指定を表す構造体
+type APIDownloadRequest struct {
+	URL      string          `json:"url"`
+	SavePath string          `json:"savePath"` // 省略時は既定の保存先
+	Formats  []FormatRequest `json:"formats"`  // 省略時は設定の出力形式
+	Profile  string          `json:"profile"`  // 指定した場合はプロファイルの出力形式と保存先を使う
+}
+
+// APIJob はAPIで開始したダウンロードの状態を表す構造体
+type APIJob struct {
+	ID           string          `json:"id"`
+	URL          string          `json:"url"`
+	SavePath     string          `json:"savePath"`
+	Formats      []FormatRequest `json:"formats"`
+	Profile      string          `json:"profile,omitempty"`
+	State        string          `json:"state"` // queued・running・done・failed
+	Progress     int             `json:"progress"`
+	ProgressText string          `json:"progressText"`
+	Error        string          `json:"error,omitempty"`
+	CreatedAt    time.Time       `json:"createdAt"`
+	StartedAt    time.Time       `json:"startedAt,omitempty"`
+	FinishedAt   time.Time       `json:"finishedAt,omitempty"`
+}
+
+// finished はダウンロードが終了したかどうかを返します
+func (j APIJob) finished() bool {
+	return j.State == jobDone || j.State == jobFailed
+}
+
+// APIEvent はSSEで送信するイベント（フロントエンドが受け取る progress・log・progressText と、状態が変わったときの job）を表す構造体
+type APIEvent struct {
+	ID   int64       `json:"id"`
+	Job  string      `json:"job,omitempty"` // イベントが発生したときに実行中だったダウンロード
+	Name string      `json:"name"`
+	Data interface{} `json:"data"`
+}
+
+// APINovel はAPIで返す小説の情報と目次を表す構造体
+type APINovel struct {
+	NCode    string       `json:"ncode"`
+	Title    string       `json:"title"`
+	Author   string       `json:"author"`
+	URL      string       `json:"url"`
+	Short    bool         `json:"short"`
+	Chapters []APIChapter `json:"chapters"`
+}
+
+// APIChapter はAPIで返す章を表す構造体
+type APIChapter struct {
+	Title    string       `json:"title"`
+	Episodes []APIEpisode `json:"episodes"`
+}
+
+// APIEpisode はAPIで返す目次の1話分を表す構造体
+type APIEpisode struct {
+	Index  int    `json:"index"`
+	Number string `json:"number"`
+	Title  string `json:"title"`
+	URL    string `json:"url"`
+}
+
+// apiServer は他のツールやスクリプトからダウンロードを開始するためのHTTP APIサーバーを表す構造体
+// ダウンロードは1件ずつ順番に実行し、その間に送信されたイベントを実行中のダウンロードのものとして記録します
+type apiServer struct {
+	app   *App
+	token string
+	// download はダウンロードを実行する関数（テストで差し替える）
+	download func(ctx context.Context, job APIJob) error
+
+	mu          sync.Mutex
+	jobs        map[string]*APIJob
+	order       []string
+	jobEvents   map[string][]APIEvent
+	nextID      int
+	seq         int64
+	current     string
+	queue       chan string
+	subscribers map[chan APIEvent]struct{}
+}
+
+// newAPIServer はAPIサーバーを作成し、app のイベントをSSEでも送信するように設定します
+func newAPIServer(app *App, token string) *apiServer {
+	s := &apiServer{
+		app:         app,
+		token:       token,
+		jobs:        make(map[string]*APIJob),
+		jobEvents:   make(map[string][]APIEvent),
+		queue:       make(chan string, maxQueuedJobs),
+		subscribers: make(map[chan APIEvent]struct{}),
+	}
+	s.download = func(ctx context.Context, job APIJob) error {
+		return app.downloadNovel(ctx, job.URL, job.SavePath, job.Formats, job.Profile)
+	}
+
+	emitter := app.emitter
+	app.emitter = func(name string, data ...interface{}) {
+		if emitter != nil {
+			emitter(name, data...)
+		}
+		s.publish(name, data...)
+	}
+	return s
+}
+
+// generateAPIToken は認証トークンを生成します
+func generateAPIToken() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("認証トークンの生成に失敗しました: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// checkLoopbackAddr は待ち受けるアドレスが自分のコンピューターからしか接続できないアドレスかどうかを確認します
+func checkLoopbackAddr(addr string) error {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return fmt.Errorf("待ち受けるアドレスが正しくありません: %w", err)
+	}
+	if host == "localhost" {
+		return nil
+	}
+	if ip := net.ParseIP(host); ip != nil && ip.IsLoopback() {
+		return nil
+	}
+	return fmt.Errorf("localhost（127.0.0.1・::1）でのみ待ち受けられます: %s", addr)
+}
+
+// serve は addr でAPIサーバーを開始し、ctx が終了するまでリクエストを処理します
+func (s *apiServer) serve(ctx context.Context, addr string) error {
+	go s.run(ctx)
+	return serveLocalHTTP(ctx, addr, s.handler(), func(addr net.Addr) {
+		s.app.emit("log", fmt.Sprintf("APIサーバーを開始しました: http://%s/api/", addr))
+	})
+}
+
+// serveLocalHTTP は localhost の addr で handler を公開し、ctx が終了するまでリクエストを処理します
+// 待ち受けを開始すると started を呼び出します
+func serveLocalHTTP(ctx context.Context, addr string, handler http.Handler, started func(addr net.Addr)) error {
+	if err := checkLoopbackAddr(addr); err != nil {
+		return err
+	}
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("サーバーを開始できませんでした: %w", err)
+	}
+
+	// SSEなどの接続も ctx の終了と同時に閉じる
+	server := &http.Server{
+		Handler:     handler,
+		BaseContext: func(net.Listener) context.Context { return ctx },
+	}
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		server.Shutdown(shutdownCtx)
+	}()
+
+	started(listener.Addr())
+	if err := server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return fmt.Errorf("サーバーが終了しました: %w", err)
+	}
+	return nil
+}
+
+// handler はAPIのエンドポイントを返します（すべて認証が必要）
+func (s *apiServer) handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /api/downloads", s.handleStartDownload)
+	mux.HandleFunc("GET /api/jobs", s.handleListJobs)
+	mux.HandleFunc("GET /api/jobs/{id}", s.handleGetJob)
+	mux.HandleFunc("GET /api/jobs/{id}/events", s.handleEvents)
+	mux.HandleFunc("GET /api/events", s.handleEvents)
+	mux.HandleFunc("GET /api/novel", s.handleGetNovel)
+	return s.authorize(mux)
+}
+
+// authorize は認証トークンを確認します
+// トークンは Authorization: Bearer ヘッダーで指定します（EventSource などヘッダーを指定できない場合は token パラメーター）
+func (s *apiServer) authorize(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok {
+			token = r.URL.Query().Get("token")
+		}
+		if token == "" || subtle.ConstantTimeCompare([]byte(token), []byte(s.token)) != 1 {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			writeAPIError(w, http.StatusUnauthorized, "認証トークンが正しくありません")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// writeJSON は値をJSONで返します
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	encoder.Encode(v)
+}
+
+// writeAPIError はエラーをJSONで返します
+func writeAPIError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, map[string]string{"error": message})
+}
+
+// handleStartDownload はダウンロードを順番待ちに追加します
+func (s *apiServer) handleStartDownload(w http.ResponseWriter, r *http.Request) {
+	var request APIDownloadRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		writeAPIError(w, http.StatusBadRequest, fmt.Sprintf("リクエストのJSON解析に失敗しました: %v", err))
+		return
+	}
+	job, err := s.newJob(request)
+	if err != nil {
+		writeAPIError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	job, err = s.enqueue(job)
+	if err != nil {
+		writeAPIError(w, http.StatusServiceUnavailable, err.Error())
+		return
+	}
+	w.Header().Set("Location", "/api/jobs/"+job.ID)
+	writeJSON(w, http.StatusAccepted, job)
+}
+
+// newJob はリクエストの出力形式と保存先を決め、ダウンロードを作成します
+func (s *apiServer) newJob(request APIDownloadRequest) (APIJob, error) {
+	request.URL = strings.TrimSpace(request.URL)
+	if request.URL == "" {
+		return APIJob{}, fmt.Errorf("URLを指定してください")
+	}
+	if !s.app.site.isNovelURL(request.URL) {
+		return APIJob{}, fmt.Errorf("小説家になろうのURLを指定してください: %s", request.URL)
+	}
+
+	job := APIJob{
+		URL:       request.URL,
+		SavePath:  request.SavePath,
+		Formats:   request.Formats,
+		State:     jobQueued,
+		CreatedAt: time.Now(),
+	}
+	if request.Profile != "" {
+		profile, ok := s.app.currentSettings().findProfile(request.Profile)
+		if !ok {
+			return APIJob{}, fmt.Errorf("プロファイルが見つかりません: %s", request.Profile)
+		}
+		job.Profile = profile.Name
+		job.Formats = profile.Formats
+		if profile.SavePath != "" {
+			job.SavePath = profile.SavePath
+		}
+	}
+	if len(job.Formats) == 0 {
+		job.Formats = s.app.currentSettings().Formats
+	}
+	if len(job.Formats) == 0 {
+		job.Formats = defaultFormats()
+	}
+	if _, _, err := buildWriters(job.Formats); err != nil {
+		return APIJob{}, fmt.Errorf("出力形式の設定エラー: %w", err)
+	}
+	return job, nil
+}
+
+// enqueue はダウンロードに番号を付けて順番待ちに追加し、番号を付けたダウンロードを返します
+func (s *apiServer) enqueue(job APIJob) (APIJob, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.queue) == cap(s.queue) {
+		return APIJob{}, fmt.Errorf("順番待ちのダウンロードが多すぎます（最大%d件）", maxQueuedJobs)
+	}
+	s.nextID++
+	job.ID = strconv.Itoa(s.nextID)
+	s.jobs[job.ID] = &job
+	s.order = append(s.order, job.ID)
+	s.queue <- job.ID
+	s.publishLocked("job", job)
+	return job, nil
+}
+
+// run は順番待ちのダウンロードを ctx が終了するまで1件ずつ実行します
+// 終了したダウンロードは finishedJobTTL を過ぎると削除します
+func (s *apiServer) run(ctx context.Context) {
+	evict := time.NewTicker(jobEvictInterval)
+	defer evict.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case id := <-s.queue:
+			s.runJob(ctx, id)
+		case now := <-evict.C:
+			s.mu.Lock()
+			s.evictJobsLocked(now)
+			s.mu.Unlock()
+		}
+	}
+}
+
+// evictJobsLocked は mu を取得した状態で、保持する時間を過ぎたか最大数を超えた終了したダウンロードとそのイベントを削除します
+// 順番待ちと実行中のダウンロードは削除しません
+func (s *apiServer) evictJobsLocked(now time.Time) {
+	finished := 0
+	for _, id := range s.order {
+		if s.jobs[id].finished() {
+			finished++
+		}
+	}
+
+	order := s.order[:0]
+	for _, id := range s.order {
+		job := s.jobs[id]
+		// s.order は追加した順のため、最大数を超えた分は古いものから削除される
+		if job.finished() && (now.Sub(job.FinishedAt) > finishedJobTTL || finished > maxFinishedJobs) {
+			finished--
+			delete(s.jobs, id)
+			delete(s.jobEvents, id)
+			continue
+		}
+		order = append(order, id)
+	}
+	s.order = order
+}
+
+// runJob はダウンロードを1件実行します（ctx が終了した場合は次の話の前で中断する）
+func (s *apiServer) runJob(ctx context.Context, id string) {
+	s.mu.Lock()
+	job := s.jobs[id]
+	job.State = jobRunning
+	job.StartedAt = time.Now()
+	s.current = id
+	s.publishLocked("job", *job)
+	snapshot := *job
+	s.mu.Unlock()
+
+	err := s.download(ctx, snapshot)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	job.State = jobDone
+	if err != nil {
+		job.State = jobFailed
+		job.Error = err.Error()
+	}
+	job.FinishedAt = time.Now()
+	s.publishLocked("job", *job)
+	s.current = ""
+	s.evictJobsLocked(job.FinishedAt)
+}
+
+// publish はイベントを記録し、SSEで接続しているクライアントに送信します
+func (s *apiServer) publish(name string, data ...interface{}) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var value interface{}
+	switch len(data) {
+	case 0:
+	case 1:
+		value = data[0]
+	default:
+		value = data
+	}
+	s.publishLocked(name, value)
+}
+
+// publishLocked は mu を取得した状態でイベントを記録・送信します
+func (s *apiServer) publishLocked(name string, data interface{}) {
+	s.seq++
+	event := APIEvent{ID: s.seq, Job: s.current, Name: name, Data: data}
+	if job, ok := data.(APIJob); ok {
+		event.Job = job.ID
+	}
+
+	if job := s.jobs[event.Job]; job != nil {
+		switch name {
+		case "progress":
+			if progress, ok := data.(int); ok {
+				job.Progress = progress
+			}
+		case "progressText":
+			if text, ok := data.(string); ok {
+				job.ProgressText = text
+			}
+		}
+		events := append(s.jobEvents[event.Job], event)
+		if len(events) > maxJobEvents {
+			events = events[len(events)-maxJobEvents:]
+		}
+		s.jobEvents[event.Job] = events
+	}
+
+	for ch := range s.subscribers {
+		// 受信が追いつかないクライアントのためにダウンロードを止めない
+		select {
+		case ch <- event:
+		default:
+		}
+	}
+}
+
+// handleListJobs はダウンロードの一覧を追加した順に返します
+func (s *apiServer) handleListJobs(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	jobs := make([]APIJob, 0, len(s.order))
+	for _, id := range s.order {
+		jobs = append(jobs, *s.jobs[id])
+	}
+	s.mu.Unlock()
+	writeJSON(w, http.StatusOK, jobs)
+}
+
+// handleGetJob はダウンロードの状態を返します
+func (s *apiServer) handleGetJob(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	job, ok := s.jobs[r.PathValue("id")]
+	var snapshot APIJob
+	if ok {
+		snapshot = *job
+	}
+	s.mu.Unlock()
+	if !ok {
+		writeAPIError(w, http.StatusNotFound, "ダウンロードが見つかりません")
+		return
+	}
+	writeJSON(w, http.StatusOK, snapshot)
+}
+
+// handleEvents はイベントをServer-Sent Eventsで送信します
+// /api/jobs/{id}/events は指定したダウンロードのイベントだけを送信し（接続前のイベントも再送する）、ダウンロードが終わると切断します
+func (s *apiServer) handleEvents(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeAPIError(w, http.StatusInternalServerError, "ストリーミングに対応していません")
+		return
+	}
+
+	id := r.PathValue("id")
+	ch := make(chan APIEvent, 256)
+	s.mu.Lock()
+	var backlog []APIEvent
+	finished := false
+	if id != "" {
+		job, ok := s.jobs[id]
+		if !ok {
+			s.mu.Unlock()
+			writeAPIError(w, http.StatusNotFound, "ダウンロードが見つかりません")
+			return
+		}
+		backlog = append(backlog, s.jobEvents[id]...)
+		finished = job.finished()
+	}
+	s.subscribers[ch] = struct{}{}
+	s.mu.Unlock()
+	defer func() {
+		s.mu.Lock()
+		delete(s.subscribers, ch)
+		s.mu.Unlock()
+	}()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	for _, event := range backlog {
+		writeSSE(w, event)
+	}
+	flusher.Flush()
+	if finished {
+		return
+	}
+
+	keepAlive := time.NewTicker(sseKeepAlive)
+	defer keepAlive.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-keepAlive.C:
+			fmt.Fprint(w, ": keep-alive\n\n")
+			flusher.Flush()
+		case event := <-ch:
+			if id != "" && event.Job != id {
+				continue
+			}
+			writeSSE(w, event)
+			flusher.Flush()
+			if id != "" && event.Name == "job" && event.Data.(APIJob).finished() {
+				return
+			}
+		}
+	}
+}
+
+// writeSSE はイベントを1件、Server-Sent Eventsの形式で書き込みます
+func writeSSE(w http.ResponseWriter, event APIEvent) {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return
+	}
+	fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", event.ID, event.Name, data)
+}
+
+// handleGetNovel は小説の情報と目次を取得して返します（url パラメーターで小説または各話のURLを指定する）
+func (s *apiServer) handleGetNovel(w http.ResponseWriter, r *http.Request) {
+	url := strings.TrimSpace(r.URL.Query().Get("url"))
+	if url == "" {
+		writeAPIError(w, http.StatusBadRequest, "url パラメーターを指定してください")
+		return
+	}
+	if !s.app.site.isNovelURL(url) {
+		writeAPIError(w, http.StatusBadRequest, fmt.Sprintf("小説家になろうのURLを指定してください: %s", url))
+		return
+	}
+	url = s.app.convertToIndexURL(url)
+
+	result := s.app.StartScraping(url)
+	if result.Error != "" {
+		writeAPIError(w, http.StatusBadGateway, fmt.Sprintf("小説の取得に失敗しました: %s", result.Error))
+		return
+	}
+	writeJSON(w, http.StatusOK, newAPINovel(newNovelFromResult(result, url)))
+}
+
+// newAPINovel は文書モデルからAPIで返す小説の情報を作成します（本文は含めない）
+func newAPINovel(novel *Novel) APINovel {
+	info := APINovel{
+		NCode:    novel.NCode,
+		Title:    novel.Title,
+		Author:   novel.Author,
+		URL:      novel.URL,
+		Short:    novel.Short,
+		Chapters: []APIChapter{},
+	}
+	for _, chapter := range novel.Chapters {
+		c := APIChapter{Title: chapter.Title, Episodes: []APIEpisode{}}
+		for _, episode := range chapter.Episodes {
+			c.Episodes = append(c.Episodes, APIEpisode{
+				Index:  episode.Index,
+				Number: episode.Number,
+				Title:  episode.Title,
+				URL:    episode.URL,
+			})
+		}
+		info.Chapters = append(info.Chapters, c)
+	}
+	return info
+}
@@ -0,0 +1,290 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newTestAPIServer はダウンロードを fake に置き換えたAPIサーバーを開始します
+func newTestAPIServer(t *testing.T, fake func(app *App, job APIJob) error) (*httptest.Server, *App) {
+	t.Helper()
+	app := NewApp()
+	app.settings = Settings{Profiles: []Profile{{Name: "保存用", Formats: []FormatRequest{{Name: "epub"}}, SavePath: "/tmp/保存用"}}}
+	s := newAPIServer(app, "secret")
+	s.download = func(ctx context.Context, job APIJob) error { return fake(app, job) }
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go s.run(ctx)
+	server := httptest.NewServer(s.handler())
+	t.Cleanup(func() {
+		server.Close()
+		cancel()
+	})
+	return server, app
+}
+
+// apiRequest は認証トークンを付けてリクエストを送信します
+func apiRequest(t *testing.T, method, url, body string) *http.Response {
+	t.Helper()
+	req, err := http.NewRequest(method, url, strings.NewReader(body))
+	if err != nil {
+		t.Fatalf("NewRequest() error = %v", err)
+	}
+	req.Header.Set("Authorization", "Bearer secret")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("%s %s error = %v", method, url, err)
+	}
+	t.Cleanup(func() { resp.Body.Close() })
+	return resp
+}
+
+func TestAPIServer_Authorize(t *testing.T) {
+	server, _ := newTestAPIServer(t, func(*App, APIJob) error { return nil })
+
+	tests := []struct {
+		name   string
+		path   string
+		header string
+		want   int
+	}{
+		{name: "トークンなし", path: "/api/jobs", want: http.StatusUnauthorized},
+		{name: "誤ったトークン", path: "/api/jobs", header: "Bearer wrong", want: http.StatusUnauthorized},
+		{name: "Authorizationヘッダー", path: "/api/jobs", header: "Bearer secret", want: http.StatusOK},
+		{name: "tokenパラメーター", path: "/api/jobs?token=secret", want: http.StatusOK},
+		{name: "存在しないダウンロード", path: "/api/jobs/99", header: "Bearer secret", want: http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, _ := http.NewRequest(http.MethodGet, server.URL+tt.path, nil)
+			if tt.header != "" {
+				req.Header.Set("Authorization", tt.header)
+			}
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatalf("GET %s error = %v", tt.path, err)
+			}
+			resp.Body.Close()
+			if resp.StatusCode != tt.want {
+				t.Errorf("StatusCode = %d, want %d", resp.StatusCode, tt.want)
+			}
+		})
+	}
+}
+
+func TestAPIServer_StartDownload(t *testing.T) {
+	release := make(chan struct{})
+	server, _ := newTestAPIServer(t, func(*App, APIJob) error {
+		<-release
+		return nil
+	})
+	defer close(release)
+
+	tests := []struct {
+		name         string
+		body         string
+		want         int
+		wantSavePath string
+		wantFormat   string
+	}{
+		{name: "JSONでない", body: "url=", want: http.StatusBadRequest},
+		{name: "URLなし", body: `{}`, want: http.StatusBadRequest},
+		{name: "なろう以外のURL", body: `{"url":"https://example.com/"}`, want: http.StatusBadRequest},
+		{name: "なろうのドメインを含むだけのURL", body: `{"url":"https://example.com/?next=https://ncode.syosetu.com/n1234ab/"}`, want: http.StatusBadRequest},
+		{name: "存在しないプロファイル", body: `{"url":"https://ncode.syosetu.com/n1234ab/","profile":"なし"}`, want: http.StatusBadRequest},
+		{name: "存在しない出力形式", body: `{"url":"https://ncode.syosetu.com/n1234ab/","formats":[{"name":"doc"}]}`, want: http.StatusBadRequest},
+		{name: "出力形式の省略", body: `{"url":"https://ncode.syosetu.com/n1234ab/"}`, want: http.StatusAccepted, wantFormat: "txt"},
+		{name: "プロファイル", body: `{"url":"https://ncode.syosetu.com/n1234ab/","profile":"保存用"}`, want: http.StatusAccepted, wantSavePath: "/tmp/保存用", wantFormat: "epub"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := apiRequest(t, http.MethodPost, server.URL+"/api/downloads", tt.body)
+			if resp.StatusCode != tt.want {
+				t.Fatalf("StatusCode = %d, want %d", resp.StatusCode, tt.want)
+			}
+			if tt.want != http.StatusAccepted {
+				return
+			}
+			var job APIJob
+			if err := json.NewDecoder(resp.Body).Decode(&job); err != nil {
+				t.Fatalf("Decode() error = %v", err)
+			}
+			if job.ID == "" || resp.Header.Get("Location") != "/api/jobs/"+job.ID {
+				t.Errorf("ID = %q, Location = %q", job.ID, resp.Header.Get("Location"))
+			}
+			if job.SavePath != tt.wantSavePath || len(job.Formats) != 1 || job.Formats[0].Name != tt.wantFormat {
+				t.Errorf("SavePath, Formats = %q, %+v, want %q, %s", job.SavePath, job.Formats, tt.wantSavePath, tt.wantFormat)
+			}
+		})
+	}
+}
+
+func TestAPIServer_JobEvents(t *testing.T) {
+	server, _ := newTestAPIServer(t, func(app *App, job APIJob) error {
+		app.emit("progress", 50)
+		app.emit("log", "取得中: "+job.URL)
+		if strings.Contains(job.URL, "n9999zz") {
+			return errors.New("小説が見つかりません")
+		}
+		app.emit("progress", 100)
+		return nil
+	})
+
+	tests := []struct {
+		name         string
+		url          string
+		wantState    string
+		wantProgress int
+		wantEvents   []string
+	}{
+		{
+			name:         "完了",
+			url:          "https://ncode.syosetu.com/n1234ab/",
+			wantState:    jobDone,
+			wantProgress: 100,
+			wantEvents:   []string{"event: progress", `"data":50`, `"data":"取得中: https://ncode.syosetu.com/n1234ab/"`, `"state":"done"`},
+		},
+		{
+			name:         "失敗",
+			url:          "https://ncode.syosetu.com/n9999zz/",
+			wantState:    jobFailed,
+			wantProgress: 50,
+			wantEvents:   []string{"event: log", `"state":"failed"`, `"error":"小説が見つかりません"`},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := apiRequest(t, http.MethodPost, server.URL+"/api/downloads", `{"url":"`+tt.url+`"}`)
+			var job APIJob
+			if err := json.NewDecoder(resp.Body).Decode(&job); err != nil {
+				t.Fatalf("Decode() error = %v", err)
+			}
+
+			// ダウンロードが終わるとイベントの送信も終わる（接続前のイベントも再送される）
+			events := apiRequest(t, http.MethodGet, server.URL+"/api/jobs/"+job.ID+"/events", "")
+			if got := events.Header.Get("Content-Type"); got != "text/event-stream" {
+				t.Errorf("Content-Type = %q, want text/event-stream", got)
+			}
+			body, err := io.ReadAll(events.Body)
+			if err != nil {
+				t.Fatalf("ReadAll() error = %v", err)
+			}
+			for _, want := range tt.wantEvents {
+				if !strings.Contains(string(body), want) {
+					t.Errorf("イベントに %q が含まれていません:\n%s", want, body)
+				}
+			}
+
+			resp = apiRequest(t, http.MethodGet, server.URL+"/api/jobs/"+job.ID, "")
+			if err := json.NewDecoder(resp.Body).Decode(&job); err != nil {
+				t.Fatalf("Decode() error = %v", err)
+			}
+			if job.State != tt.wantState || job.Progress != tt.wantProgress {
+				t.Errorf("State, Progress = %s, %d, want %s, %d", job.State, job.Progress, tt.wantState, tt.wantProgress)
+			}
+		})
+	}
+}
+
+func TestAPIServer_EvictJobs(t *testing.T) {
+	now := time.Now()
+	tests := []struct {
+		name     string
+		jobs     []APIJob
+		wantJobs []string
+	}{
+		{
+			name: "保持する時間を過ぎた終了したダウンロード",
+			jobs: []APIJob{
+				{State: jobDone, FinishedAt: now.Add(-2 * finishedJobTTL)},
+				{State: jobFailed, FinishedAt: now.Add(-time.Minute)},
+				{State: jobRunning},
+				{State: jobQueued},
+			},
+			wantJobs: []string{"2", "3", "4"},
+		},
+		{
+			name: "最大数を超えた終了したダウンロード",
+			jobs: func() []APIJob {
+				jobs := []APIJob{{State: jobRunning}}
+				for range maxFinishedJobs + 1 {
+					jobs = append(jobs, APIJob{State: jobDone, FinishedAt: now})
+				}
+				return jobs
+			}(),
+			wantJobs: func() []string {
+				ids := []string{"1"}
+				for i := range maxFinishedJobs {
+					ids = append(ids, strconv.Itoa(i+3))
+				}
+				return ids
+			}(),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newAPIServer(NewApp(), "secret")
+			for i, job := range tt.jobs {
+				job.ID = strconv.Itoa(i + 1)
+				s.jobs[job.ID] = &job
+				s.jobEvents[job.ID] = []APIEvent{{Job: job.ID, Name: "job", Data: job}}
+				s.order = append(s.order, job.ID)
+			}
+
+			s.evictJobsLocked(now)
+			if !slices.Equal(s.order, tt.wantJobs) {
+				t.Errorf("order = %v, want %v", s.order, tt.wantJobs)
+			}
+			if len(s.jobs) != len(tt.wantJobs) || len(s.jobEvents) != len(tt.wantJobs) {
+				t.Errorf("len(jobs), len(jobEvents) = %d, %d, want %d", len(s.jobs), len(s.jobEvents), len(tt.wantJobs))
+			}
+		})
+	}
+}
+
+func TestCheckLoopbackAddr(t *testing.T) {
+	tests := []struct {
+		addr    string
+		wantErr bool
+	}{
+		{"127.0.0.1:8765", false},
+		{"localhost:8765", false},
+		{"[::1]:8765", false},
+		{"0.0.0.0:8765", true},
+		{":8765", true},
+		{"192.168.1.10:8765", true},
+		{"127.0.0.1", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.addr, func(t *testing.T) {
+			if err := checkLoopbackAddr(tt.addr); (err != nil) != tt.wantErr {
+				t.Errorf("checkLoopbackAddr(%q) error = %v, wantErr %v", tt.addr, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestNewAPINovel(t *testing.T) {
+	info := newAPINovel(newTestNovel())
+	if info.NCode != "N1234AB" || info.Title != "テスト小説" || len(info.Chapters) != 2 {
+		t.Fatalf("newAPINovel() = %+v", info)
+	}
+	episodes := info.Chapters[0].Episodes
+	if len(episodes) != 2 || episodes[1].Number != "2" || episodes[1].Title != "二話" {
+		t.Errorf("Chapters[0].Episodes = %+v", episodes)
+	}
+}
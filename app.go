@@ -1,170 +1,244 @@
 package main
 
 import (
+	"bytes"
 	"context"
-	"encoding/json"
 	"fmt"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"regexp"
 	"strings"
+	"sync"
 	"time"
 
 	goruntime "runtime"
 
 	"github.com/wailsapp/wails/v2/pkg/runtime"
-	"golang.org/x/text/encoding/japanese"
-	"golang.org/x/text/transform"
 )
 
 // App struct
 type App struct {
-	ctx      context.Context
-	settings Settings
+	ctx context.Context
+	// settings は現在の設定（定期確認やAPIサーバーからも読むため settingsMu で保護する）
+	settings   Settings
+	settingsMu sync.RWMutex
+	scheduler  scheduler
+	site       syosetuSite // 取得するなろうのサイトのURL（テストでは偽のサイトに置き換える）
+	// emitter はイベントの送信先（nil の場合はWailsのフロントエンドに送信する）
+	emitter func(name string, data ...interface{})
 }
 
-// Settings はアプリケーションの設定を表す構造体
-type Settings struct {
-	URL            string `json:"url"`
-	SavePath       string `json:"savePath"`
-	Encoding       string `json:"encoding"`
-	LineEnding     string `json:"lineEnding"`
-	CreateHtml     bool   `json:"createHtml"`
-	CreateTxt      bool   `json:"createTxt"`
-	CreateCombined bool   `json:"createCombined"`
-	ShowInFront    bool   `json:"showInFront"`
-}
+// episodeInterval は連載の各話を取得する間隔（サーバーに負荷をかけないため）
+var episodeInterval = 10 * time.Second
 
 // NewApp creates a new App application struct
 func NewApp() *App {
-	return &App{}
+	return &App{site: defaultSite}
 }
 
 // startup is called when the app starts. The context is saved
 // so we can call the runtime methods
 func (a *App) startup(ctx context.Context) {
 	a.ctx = ctx
+
+	// 定期確認が有効な場合は起動と同時に開始する
+	settings, err := a.LoadSettings()
+	if err != nil {
+		a.emit("log", err.Error())
+		return
+	}
+	if err := a.startScheduler(ctx, settings.Schedule); err != nil {
+		a.emit("log", fmt.Sprintf("定期確認を開始できませんでした: %v", err))
+	}
+}
+
+// emit はフロントエンドにイベント（log・progress・progressText）を送信します
+// コマンドラインから実行した場合など、Wailsのコンテキストがない場合は emitter に渡すか破棄します
+func (a *App) emit(name string, data ...interface{}) {
+	if a.emitter != nil {
+		a.emitter(name, data...)
+		return
+	}
+	if a.ctx == nil {
+		return
+	}
+	runtime.EventsEmit(a.ctx, name, data...)
 }
 
 // setupSavePath は保存先のパスを設定します
 func (a *App) setupSavePath(savePath string, title string) (string, error) {
 	if savePath == "" {
 		// 実行ファイルのディレクトリを取得
-		exePath, err := os.Executable()
+		exeDir, err := defaultSaveRoot()
 		if err != nil {
-			runtime.EventsEmit(a.ctx, "log", fmt.Sprintf("実行ファイルのパスを取得できませんでした: %v", err))
-			return "", fmt.Errorf("実行ファイルのパスを取得できませんでした: %w", err)
+			a.emit("log", err.Error())
+			return "", err
 		}
-		exeDir := filepath.Dir(exePath)
 
 		// 小説のタイトルと同じ名前のディレクトリを作成
-		savePath = filepath.Join(exeDir, title)
+		savePath = filepath.Join(exeDir, sanitizeFileName(title))
 	}
 
 	// ディレクトリを作成
 	if err := os.MkdirAll(savePath, 0755); err != nil {
-		runtime.EventsEmit(a.ctx, "log", fmt.Sprintf("保存先ディレクトリの作成に失敗しました: %v", err))
+		a.emit("log", fmt.Sprintf("保存先ディレクトリの作成に失敗しました: %v", err))
 		return "", fmt.Errorf("保存先ディレクトリの作成に失敗しました: %w", err)
 	}
-	runtime.EventsEmit(a.ctx, "log", fmt.Sprintf("保存先ディレクトリを作成しました: %s", savePath))
+	a.emit("log", fmt.Sprintf("保存先ディレクトリを作成しました: %s", savePath))
 
 	return savePath, nil
 }
 
+// defaultSaveRoot は保存先が指定されていない場合の保存先ルート（実行ファイルのディレクトリ）を返します
+func defaultSaveRoot() (string, error) {
+	exePath, err := os.Executable()
+	if err != nil {
+		return "", fmt.Errorf("実行ファイルのパスを取得できませんでした: %w", err)
+	}
+	return filepath.Dir(exePath), nil
+}
+
 // DownloadNovel は小説のダウンロードを開始します
-func (a *App) DownloadNovel(url string, savePath string, options map[string]interface{}) error {
+// フックは画面で選択しているプロファイルの設定に従って実行します
+func (a *App) DownloadNovel(url string, savePath string, formats []FormatRequest) error {
+	return a.downloadNovel(context.Background(), url, savePath, formats, a.currentSettings().ActiveProfile)
+}
+
+// downloadNovel は小説をダウンロードし、完了後にプロファイル profileName の設定に従ってフックの実行と送信先への送信を行います
+// 完了・失敗は設定に従って通知します。ctx が終了した場合は次の話を取得する前に中断します
+func (a *App) downloadNovel(ctx context.Context, url string, savePath string, formats []FormatRequest, profileName string) (err error) {
+	startedAt := time.Now()
+	title := url
+	defer func() {
+		// 定期確認の終了などで中断した場合は失敗として通知しない
+		if err != nil && ctx.Err() == nil {
+			a.notify(Notification{Event: notifyFailed, Title: "ダウンロードに失敗しました", Message: fmt.Sprintf("%s: %v", title, err), URL: url})
+		}
+	}()
+
+	// 出力形式の決定（設定に誤りがある場合は取得を始める前に中止する）
+	episodeWriters, novelWriters, err := buildWriters(formats)
+	if err != nil {
+		a.emit("log", fmt.Sprintf("出力形式の設定エラー: %v", err))
+		return err
+	}
+
 	// 進捗状況を更新
-	runtime.EventsEmit(a.ctx, "progress", 0)
-	runtime.EventsEmit(a.ctx, "log", "HTMLの取得を開始します...")
+	a.emit("progress", 0)
+	a.emit("log", "HTMLの取得を開始します...")
 
 	// 各話URLの場合は小説インデックスURLに変換
 	processedURL := a.convertToIndexURL(url)
 	if processedURL != url {
-		runtime.EventsEmit(a.ctx, "log", fmt.Sprintf("各話URLを検出しました。小説全体をダウンロードします: %s", processedURL))
+		a.emit("log", fmt.Sprintf("各話URLを検出しました。小説全体をダウンロードします: %s", processedURL))
 	}
 
 	// スクレイピングの実行
 	result := a.StartScraping(processedURL)
 	if result.Error != "" {
-		runtime.EventsEmit(a.ctx, "log", fmt.Sprintf("スクレイピングエラー: %s", result.Error))
+		a.emit("log", fmt.Sprintf("スクレイピングエラー: %s", result.Error))
 		return fmt.Errorf("スクレイピングエラー: %s", result.Error)
 	}
 
+	title = result.Title
+
 	// 保存先の設定
-	savePath, err := a.setupSavePath(savePath, result.Title)
+	savePath, err = a.setupSavePath(savePath, result.Title)
 	if err != nil {
 		return err
 	}
 
-	// 設定の取得
-	encoding := options["encoding"].(string)
-	lineEnding := options["lineEnding"].(string)
-	createHtml := options["createHtml"].(bool)
-	createTxt := options["createTxt"].(bool)
-	createCombined := options["createCombined"].(bool)
-
 	// 連載か短編かで処理を分岐
+	var novel *Novel
 	switch result.PageType {
 	case "rensai":
-		return a.downloadRensai(savePath, result, createHtml, createTxt, encoding, lineEnding, createCombined)
+		novel = newNovelFromResult(result, processedURL)
+		err = a.downloadRensai(ctx, savePath, result, novel, formats, episodeWriters, novelWriters)
 	case "short":
-		return a.downloadShort(savePath, result, createHtml, createTxt, encoding, lineEnding, url)
+		novel = newNovelFromResult(result, url)
+		err = a.downloadShort(savePath, novel, formats, episodeWriters, novelWriters)
 	default:
 		return fmt.Errorf("不明なページタイプ: %s", result.PageType)
 	}
+
+	// 途中で失敗しても保存できた話はあるため、新着のフィードは毎回更新する
+	if path, feedErr := a.writeUpdateFeed(updateFeedFolder(savePath)); feedErr != nil {
+		a.emit("log", fmt.Sprintf("新着のフィードを更新できませんでした: %v", feedErr))
+	} else {
+		a.emit("log", fmt.Sprintf("新着のフィードを更新しました: %s", path))
+	}
+
+	// フックや送信先への送信の失敗はダウンロードの失敗にしない（結果はログに表示される）
+	settings := a.currentSettings()
+	if hooks := settings.hooksFor(profileName); err == nil && len(hooks) > 0 {
+		a.runHooks(hooks, savePath, novel.NCode, profileName, startedAt)
+	}
+	if err == nil && len(settings.Deliveries) > 0 {
+		if _, deliverErr := a.DeliverNovel(savePath, novel.NCode, false); deliverErr != nil {
+			a.emit("log", fmt.Sprintf("送信先に送れませんでした: %v", deliverErr))
+		}
+	}
+	if err == nil {
+		a.notifyDownloaded(savePath, novel, startedAt)
+	}
+	return err
 }
 
 // downloadRensai は連載小説のダウンロード処理を行います（リトライ機能付き）
-func (a *App) downloadRensai(savePath string, result ScrapeResult, createHtml, createTxt bool, encoding, lineEnding string, createCombined bool) error {
-	if len(result.Chapters) == 0 {
+func (a *App) downloadRensai(ctx context.Context, savePath string, result ScrapeResult, novel *Novel, formats []FormatRequest, episodeWriters []EpisodeWriter, novelWriters []NovelWriter) error {
+	episodes := novel.Episodes()
+	if len(episodes) == 0 {
 		return fmt.Errorf("エピソードが見つかりませんでした")
 	}
 
-	totalChapters := len(result.Chapters)
-	runtime.EventsEmit(a.ctx, "log", fmt.Sprintf("%d話を取得しました。ダウンロードを開始します...", totalChapters))
-	runtime.EventsEmit(a.ctx, "progressText", fmt.Sprintf("0/%d話", totalChapters))
+	store, manifest, err := a.openNovelStore(savePath, novel, formats)
+	if err != nil {
+		return err
+	}
+	// 保存した話は途中で失敗した場合も含めて最後にまとめて検索用索引に反映する
+	defer a.flushSearchIndex(store, manifest)
+	a.updateNovelInfo(store, manifest)
+
+	// 前回から削除・話数の変更があった話を反映する（削除された話は archive フォルダに保管する）
+	if len(manifest.Episodes) > 0 {
+		if err := a.applyTOCChanges(store, manifest, novel, diffTOC(manifest, novel), episodeWriters); err != nil {
+			a.emit("log", fmt.Sprintf("目次の変更の反映に失敗しました: %v", err))
+			return err
+		}
+	}
 
-	// HTMLファイル用のディレクトリ作成は無効化
-	// var htmlDir string
-	// if createHtml {
-	// 	htmlDir = filepath.Join(savePath, "html")
-	// 	if err := os.MkdirAll(htmlDir, 0755); err != nil {
-	// 		return fmt.Errorf("htmlディレクトリの作成に失敗しました: %w", err)
-	// 	}
-	// }
+	totalChapters := len(episodes)
+	a.emit("log", fmt.Sprintf("%d話を取得しました。ダウンロードを開始します...", totalChapters))
+	a.emit("progressText", fmt.Sprintf("0/%d話", totalChapters))
 
 	// エピソード別コンテンツの取得
-	var allChapterContents []string
-	novelCode := extractNovelCodeFromURL(result.Chapters[0].URL) // 最初のエピソードURLから小説番号を取得
 	var failedChapters int
 	const maxFailures = 3
 
-	for i, chapter := range result.Chapters {
-		runtime.EventsEmit(a.ctx, "progress", int(float64(i)/float64(totalChapters)*80)) // 80%までエピソード取得用
-		runtime.EventsEmit(a.ctx, "progressText", fmt.Sprintf("%d/%d話", i, totalChapters))
-
-		// ファイル名を先に生成してスキップチェック
-		episodeNumber := extractEpisodeNumberFromURL(chapter.URL)
-		// エピソード番号が取得できない場合や、全話が"1"になってしまう場合は、インデックス番号を使用
-		if episodeNumber == "" || (i > 0 && episodeNumber == "1") {
-			episodeNumber = fmt.Sprintf("%d", i+1)
+	for i, episode := range episodes {
+		if err := ctx.Err(); err != nil {
+			a.emit("log", fmt.Sprintf("%d話の前でダウンロードを中断しました", i+1))
+			return fmt.Errorf("ダウンロードを中断しました: %w", err)
 		}
-		chapterFileName := generateFileName(novelCode, episodeNumber)
+		a.emit("progress", int(float64(i)/float64(totalChapters)*80)) // 80%までエピソード取得用
+		a.emit("progressText", fmt.Sprintf("%d/%d話", i, totalChapters))
 
-		// 既に保存済みかチェック
-		if a.shouldSkipEpisode(savePath, chapterFileName, episodeNumber, createHtml, createTxt) {
-			runtime.EventsEmit(a.ctx, "log", fmt.Sprintf("%d話: %s はすでに保存済みです。スキップします。", i+1, chapter.Title))
+		// 既に保存済みかチェック（小説全体を1つのファイルにする形式ではキャッシュから本文を復元する）
+		if a.shouldSkipEpisode(store, manifest, novel, episode, episodeWriters, len(novelWriters) > 0) {
+			a.emit("log", fmt.Sprintf("%d話: %s はすでに保存済みです。スキップします。", i+1, episode.Title))
 			continue
 		}
 
-		runtime.EventsEmit(a.ctx, "log", fmt.Sprintf("%d話: %s を取得中...", i+1, chapter.Title))
+		a.emit("log", fmt.Sprintf("%d話: %s を取得中...", i+1, episode.Title))
 
 		// Chapterの取得（リトライ機能付き）
-		content, rawHTML, fullPageHTML, err := a.ScrapeChapterWithHTML(chapter.URL)
+		// 改稿された話は、本文のキャッシュの有効期限内でもサーバーから取得し直す
+		record := manifest.episode(episode.Number)
+		page, err := a.scrapeEpisode(episode.URL, record != nil && isRevisedSince(record, episode))
 		if err != nil {
 			failedChapters++
-			runtime.EventsEmit(a.ctx, "log", fmt.Sprintf("%d話の取得に失敗しました: %v （失敗回数: %d/%d）", i+1, err, failedChapters, maxFailures))
+			a.emit("log", fmt.Sprintf("%d話の取得に失敗しました: %v （失敗回数: %d/%d）", i+1, err, failedChapters, maxFailures))
 
 			// 失敗回数が上限に達した場合は全体を停止
 			if failedChapters >= maxFailures {
@@ -176,220 +250,218 @@ func (a *App) downloadRensai(savePath string, result ScrapeResult, createHtml, c
 		// 取得に成功した場合は失敗カウンターをリセット
 		failedChapters = 0
 
-		result.Chapters[i].Content = content
-		result.Chapters[i].RawHTML = rawHTML
-		result.Chapters[i].FullPageHTML = fullPageHTML
-
-		// 連結ファイル用に各話のフォーマットされたコンテンツを保存（タイトル・作者名なし）
-		chapterContentForCombined := a.formatChapterContentForCombined(chapter.Title, content)
-		allChapterContents = append(allChapterContents, chapterContentForCombined)
-
-		// 連載の場合、次のエピソードまで10秒間隔を開ける（最後のエピソード以外）
-		if i < len(result.Chapters)-1 {
-			runtime.EventsEmit(a.ctx, "log", fmt.Sprintf("%d話取得完了。10秒待機中...", i+1))
-			time.Sleep(10 * time.Second)
-		}
+		episode.Blocks = page.Blocks
+		result.Chapters[i].Blocks = page.Blocks
+		result.Chapters[i].RawHTML = page.RawHTML
+		result.Chapters[i].FullPageHTML = page.FullPageHTML
 
 		// ファイル保存（リトライ機能付き）
-		if createTxt {
-			// 各話のフォーマット（タイトル、作者名、話タイトル、本文）
-			formattedContent := a.formatChapterContent(result.Title, result.Author, chapter.Title, content)
-			if err := a.saveTextFileWithRetry(savePath, chapterFileName, formattedContent, encoding, lineEnding); err != nil {
-				runtime.EventsEmit(a.ctx, "log", fmt.Sprintf("%d話の保存に失敗しました: %v", i+1, err))
-			}
+		if err := a.saveEpisode(store, manifest, novel, episode, episodeWriters); err != nil {
+			a.emit("log", fmt.Sprintf("%d話の保存に失敗しました: %v", i+1, err))
 		}
 
-		// HTMLファイル保存は無効化
-		// if createHtml {
-		// 	// 元ページ全体のHTMLからiframeを除去してから保存
-		// 	cleanHTML := a.removeIframes(fullPageHTML)
-		// 	episodeFilePath := filepath.Join(htmlDir, fmt.Sprintf("%s.html", episodeNumber))
-		// 	if err := os.WriteFile(episodeFilePath, []byte(cleanHTML), 0644); err != nil {
-		// 		runtime.EventsEmit(a.ctx, "log", fmt.Sprintf("%d話のHTML保存に失敗しました: %v", i+1, err))
-		// 	}
-		// }
+		// 連載の場合、次のエピソードまで間隔を開ける（最後のエピソードとキャッシュから読んだ場合以外）
+		// 待っている間に中断された場合は、次の話の前で止まる
+		if i < len(episodes)-1 && !page.Cached {
+			a.emit("log", fmt.Sprintf("%d話取得完了。%d秒待機中...", i+1, int(episodeInterval.Seconds())))
+			sleepContext(ctx, episodeInterval)
+		}
 	}
 
-	// インデックスページの作成は無効化
-	// if createHtml && len(result.Chapters) > 0 {
-	// 	runtime.EventsEmit(a.ctx, "progress", 85)
-	// 	runtime.EventsEmit(a.ctx, "progressText", "インデックスページ作成中")
-	// 	runtime.EventsEmit(a.ctx, "log", "インデックスページを作成中...")
-
-	// 	if err := a.saveOriginalIndexPages(savePath, result.IndexPagesHTML, result.Chapters); err != nil {
-	// 		runtime.EventsEmit(a.ctx, "log", fmt.Sprintf("インデックスページの作成に失敗しました: %v", err))
-	// 	}
-	// }
-
-	// 連結ファイルの作成
-	if createCombined && len(allChapterContents) > 0 {
-		runtime.EventsEmit(a.ctx, "progress", 90)
-		runtime.EventsEmit(a.ctx, "progressText", "連結ファイル作成中")
-		runtime.EventsEmit(a.ctx, "log", "連結ファイルを作成中...")
-
-		// 冒頭に小説タイトルと作者名を追加（ルビ変換済み）
-		var combinedBuilder strings.Builder
-		combinedBuilder.WriteString(a.convertRubyToAozora(result.Title))
-		combinedBuilder.WriteString("\n")
-		combinedBuilder.WriteString(a.convertRubyToAozora(result.Author))
-		combinedBuilder.WriteString("\n\n\n")
-
-		// 各話を点線区切りで連結
-		combinedBuilder.WriteString(strings.Join(allChapterContents, "\n\n----------------\n\n\n"))
-
-		combinedContent := combinedBuilder.String()
-
-		if createTxt {
-			if err := a.saveTextFileWithRetry(savePath, "all", combinedContent, encoding, lineEnding); err != nil {
-				return fmt.Errorf("連結TXTファイルの保存に失敗しました: %w", err)
+	// 連結ファイルの作成（取得に失敗した話は除いて連結する）
+	fetched := novel.withEpisodes(func(e *Episode) bool { return len(e.Blocks) > 0 })
+	if len(novelWriters) > 0 && len(fetched.Chapters) > 0 {
+		a.emit("progress", 90)
+		a.emit("progressText", "連結ファイル作成中")
+		a.emit("log", "連結ファイルを作成中...")
+
+		for _, w := range novelWriters {
+			if err := a.saveNovelFile(store, manifest, fetched, w); err != nil {
+				return fmt.Errorf("連結ファイルの保存に失敗しました: %w", err)
 			}
 		}
 	}
 
 	// 進捗状況を更新
-	runtime.EventsEmit(a.ctx, "progress", 100)
-	runtime.EventsEmit(a.ctx, "progressText", fmt.Sprintf("完了 (%d/%d話)", totalChapters, totalChapters))
-	runtime.EventsEmit(a.ctx, "log", "ダウンロードが完了しました")
+	a.emit("progress", 100)
+	a.emit("progressText", fmt.Sprintf("完了 (%d/%d話)", totalChapters, totalChapters))
+	a.emit("log", "ダウンロードが完了しました")
 
 	return nil
 }
 
 // downloadShort は短編小説のダウンロード処理を行います
-func (a *App) downloadShort(savePath string, result ScrapeResult, createHtml, createTxt bool, encoding, lineEnding string, originalURL string) error {
-	runtime.EventsEmit(a.ctx, "progressText", "短編小説処理中")
+func (a *App) downloadShort(savePath string, novel *Novel, formats []FormatRequest, episodeWriters []EpisodeWriter, novelWriters []NovelWriter) error {
+	a.emit("progressText", "短編小説処理中")
 
-	// 短編小説のファイル名生成（元のURLから小説番号を取得）
-	novelCode := extractNovelCodeFromURL(originalURL)
-	fileName := generateFileName(novelCode, "1") // 短編は常にエピソード1
+	episode := novel.Episodes()[0]
+
+	store, manifest, err := a.openNovelStore(savePath, novel, formats)
+	if err != nil {
+		return err
+	}
+	// 保存した話は途中で失敗した場合も含めて最後にまとめて検索用索引に反映する
+	defer a.flushSearchIndex(store, manifest)
+	a.updateNovelInfo(store, manifest)
 
 	// 既に保存済みかチェック
-	if a.shouldSkipEpisode(savePath, fileName, "1", createHtml, createTxt) {
-		runtime.EventsEmit(a.ctx, "log", "短編小説はすでに保存済みです。スキップします。")
-		runtime.EventsEmit(a.ctx, "progress", 100)
-		runtime.EventsEmit(a.ctx, "progressText", "完了（スキップ）")
+	if len(novelWriters) == 0 && a.shouldSkipEpisode(store, manifest, novel, episode, episodeWriters, false) {
+		a.emit("log", "短編小説はすでに保存済みです。スキップします。")
+		a.emit("progress", 100)
+		a.emit("progressText", "完了（スキップ）")
 		return nil
 	}
 
-	// HTMLファイルの保存は無効化
-	// if createHtml {
-	// 	if result.FullPageHTML != "" {
-	// 		// 元ページ全体のHTMLからiframeを除去してから保存
-	// 		cleanHTML := a.removeIframes(result.FullPageHTML)
-	// 		htmlFilePath := filepath.Join(savePath, fileName+".html")
-	// 		if err := os.WriteFile(htmlFilePath, []byte(cleanHTML), 0644); err != nil {
-	// 			runtime.EventsEmit(a.ctx, "log", fmt.Sprintf("HTMLファイルの保存に失敗しました: %v", err))
-	// 			return fmt.Errorf("HTMLファイルの保存に失敗しました: %w", err)
-	// 		}
-	// 	} else {
-	// 		// フォールバック：元のHTML生成方法（テキストコンテンツを使用）
-	// 		htmlContent := a.generateShortNovelHTML(result.Title, strings.Join(result.TextContent, "\n"))
-	// 		if err := a.saveHtmlFile(savePath, []string{htmlContent}, fileName); err != nil {
-	// 			return err
-	// 		}
-	// 	}
-	// }
-
-	// テキストファイルの保存
-	if createTxt {
-		content := strings.Join(result.TextContent, "\n")
-		if content == "" {
-			runtime.EventsEmit(a.ctx, "log", "本文を取得できませんでした")
-			return fmt.Errorf("本文を取得できませんでした")
-		}
+	if len(episode.Blocks) == 0 {
+		a.emit("log", "本文を取得できませんでした")
+		return fmt.Errorf("本文を取得できませんでした")
+	}
 
-		// 短編小説のフォーマット（タイトル、作者名、話タイトルなし、本文）
-		formattedContent := a.formatChapterContent(result.Title, result.Author, "", content)
-		if err := a.saveTextFileWithRetry(savePath, fileName, formattedContent, encoding, lineEnding); err != nil {
+	// ファイルの保存
+	if err := a.saveEpisode(store, manifest, novel, episode, episodeWriters); err != nil {
+		return err
+	}
+	for _, w := range novelWriters {
+		if err := a.saveNovelFile(store, manifest, novel, w); err != nil {
 			return err
 		}
 	}
 
 	// 進捗状況を更新
-	runtime.EventsEmit(a.ctx, "progress", 100)
-	runtime.EventsEmit(a.ctx, "progressText", "完了")
-	runtime.EventsEmit(a.ctx, "log", "ファイルの保存が完了しました")
+	a.emit("progress", 100)
+	a.emit("progressText", "完了")
+	a.emit("log", "ファイルの保存が完了しました")
 
 	return nil
 }
 
-// saveHtmlFile はHTMLファイルを保存します
-func (a *App) saveHtmlFile(savePath string, rawHTML []string, fileName string) error {
-	htmlContent := strings.Join(rawHTML, "\n")
-	filePath := filepath.Join(savePath, sanitizeFileName(fileName)+".html")
-	err := os.WriteFile(filePath, []byte(htmlContent), 0644)
+// openNovelStore は小説ごとの作業用ディレクトリを準備し、マニフェストを読み込みます
+func (a *App) openNovelStore(savePath string, novel *Novel, formats []FormatRequest) (*novelStore, *Manifest, error) {
+	store, err := newNovelStore(savePath, novel.NCode)
 	if err != nil {
-		runtime.EventsEmit(a.ctx, "log", fmt.Sprintf("HTMLファイルの保存に失敗しました: %v", err))
-		return fmt.Errorf("HTMLファイルの保存に失敗しました: %w", err)
+		a.emit("log", err.Error())
+		return nil, nil, err
 	}
-	return nil
-}
-
-// saveTextFile はテキストファイルを保存します
-func (a *App) saveTextFile(savePath, title, content, encoding, lineEnding string) error {
-	// 改行コードの変換
-	if lineEnding == "CR+LF" {
-		content = strings.ReplaceAll(content, "\n", "\r\n")
+	manifest, err := store.loadManifest()
+	if err != nil {
+		a.emit("log", err.Error())
+		return nil, nil, err
 	}
-
-	// エンコードの変換
-	var txtData []byte
-	var err error
-	switch encoding {
-	case "UTF-8":
-		txtData = []byte(content)
-	case "UTF-16LE":
-		// UTF-16LEエンコード
-		utf16Data := make([]byte, 0, len(content)*2)
-		for _, r := range content {
-			utf16Data = append(utf16Data, byte(r), byte(r>>8))
+	if len(manifest.Episodes) == 0 {
+		if imported := store.importLegacyEpisodes(manifest, novel); imported > 0 {
+			a.emit("log", fmt.Sprintf("以前のバージョンで保存した%d話分のファイルを引き継ぎました", imported))
 		}
-		txtData = utf16Data
-	case "Shift-JIS":
-		// Shift-JISエンコード
-		encoder := japanese.ShiftJIS.NewEncoder()
-		txtData, _, err = transform.Bytes(encoder, []byte(content))
+	}
+	manifest.Title = novel.Title
+	manifest.Author = novel.Author
+	manifest.URL = novel.URL
+	manifest.Short = novel.Short
+	manifest.recordFormats(formats)
+	return store, manifest, nil
+}
+
+// saveEpisode は1話分をすべての形式で保存し、すべて成功した場合のみマニフェストに記録します
+func (a *App) saveEpisode(store *novelStore, manifest *Manifest, novel *Novel, episode *Episode, episodeWriters []EpisodeWriter) error {
+	record := ManifestEpisode{
+		Index:     episode.Index,
+		Number:    episode.Number,
+		Title:     episode.Title,
+		URL:       episode.URL,
+		Hash:      blocksHash(episode.Blocks),
+		Revised:   episode.Revised,
+		FetchedAt: time.Now(),
+	}
+	if chapter := novel.ChapterOf(episode); chapter != nil {
+		record.Chapter = chapter.Title
+	}
+
+	// 改稿された話は上書きする前の本文を過去の版として残す
+	if old := manifest.episode(episode.Number); old != nil && old.Hash != "" && old.Hash != record.Hash {
+		if err := store.saveHistory(*old); err != nil {
+			a.emit("log", fmt.Sprintf("%s話の過去の版を保存できませんでした: %v", episode.Number, err))
+		}
+	}
+
+	for _, w := range episodeWriters {
+		file, err := a.saveEpisodeFile(store, novel, episode, w)
 		if err != nil {
-			runtime.EventsEmit(a.ctx, "log", fmt.Sprintf("Shift-JISエンコードエラー: %v", err))
-			return fmt.Errorf("Shift-JISエンコードエラー: %w", err)
+			return err
 		}
+		record.Files = append(record.Files, file)
 	}
 
-	// ファイルの保存
-	baseFileName := filepath.Join(savePath, title)
-	err = os.WriteFile(baseFileName+".txt", txtData, 0644)
-	if err != nil {
-		runtime.EventsEmit(a.ctx, "log", fmt.Sprintf("TXTファイルの保存に失敗しました: %v", err))
-		return fmt.Errorf("TXTファイルの保存に失敗しました: %w", err)
+	// 小説全体のファイルを後から作り直せるように本文を残しておく
+	if err := store.saveBlocks(episode.Number, episode.Blocks); err != nil {
+		return fmt.Errorf("本文のキャッシュの保存に失敗しました: %w", err)
 	}
 
+	manifest.recordEpisode(record)
+	if err := store.saveManifest(manifest); err != nil {
+		return err
+	}
 	return nil
 }
 
-// saveTextFileWithRetry はテキストファイルの保存をリトライ機能付きで実行します
-func (a *App) saveTextFileWithRetry(savePath, title, content, encoding, lineEnding string) error {
+// saveEpisodeFile は1話分を指定の形式で保存します
+func (a *App) saveEpisodeFile(store *novelStore, novel *Novel, episode *Episode, w EpisodeWriter) (ManifestFile, error) {
+	var buf bytes.Buffer
+	if err := w.WriteEpisode(&buf, novel, episode); err != nil {
+		a.emit("log", fmt.Sprintf("%sファイルの生成に失敗しました: %v", strings.ToUpper(w.Ext()), err))
+		return ManifestFile{}, fmt.Errorf("%sファイルの生成に失敗しました: %w", strings.ToUpper(w.Ext()), err)
+	}
+	return a.saveFileWithRetry(store, episodeFileName(novel, episode, w), buf.Bytes(), w)
+}
+
+// saveNovelFile は小説全体を指定の形式で1つのファイルに保存し、マニフェストに記録します
+func (a *App) saveNovelFile(store *novelStore, manifest *Manifest, novel *Novel, w NovelWriter) error {
+	var buf bytes.Buffer
+	if err := w.WriteNovel(&buf, novel); err != nil {
+		a.emit("log", fmt.Sprintf("%sファイルの生成に失敗しました: %v", strings.ToUpper(w.Ext()), err))
+		return fmt.Errorf("%sファイルの生成に失敗しました: %w", strings.ToUpper(w.Ext()), err)
+	}
+	file, err := a.saveFileWithRetry(store, w.NovelFileName(novel), buf.Bytes(), w)
+	if err != nil {
+		return err
+	}
+	manifest.recordFile(file)
+	return store.saveManifest(manifest)
+}
+
+// saveFile はファイルを一時ファイル経由で保存します
+func (a *App) saveFile(store *novelStore, fileName string, data []byte, w Writer) (ManifestFile, error) {
+	file, err := store.writeFile(fileName, data, w.Ext(), writerEncoding(w))
+	if err != nil {
+		a.emit("log", fmt.Sprintf("ファイルの保存に失敗しました: %v", err))
+		return ManifestFile{}, fmt.Errorf("ファイルの保存に失敗しました: %w", err)
+	}
+	return file, nil
+}
+
+// saveFileWithRetry はファイルの保存をリトライ機能付きで実行します
+func (a *App) saveFileWithRetry(store *novelStore, fileName string, data []byte, w Writer) (ManifestFile, error) {
 	const maxRetries = 3
 	var lastErr error
 
 	for retry := 0; retry < maxRetries; retry++ {
 		if retry > 0 {
-			runtime.EventsEmit(a.ctx, "log", fmt.Sprintf("ファイル保存をリトライします（%d/%d回目）: %s", retry+1, maxRetries, title))
+			a.emit("log", fmt.Sprintf("ファイル保存をリトライします（%d/%d回目）: %s", retry+1, maxRetries, fileName))
 			// リトライ前に少し待機
 			time.Sleep(2 * time.Second)
 		}
 
-		err := a.saveTextFile(savePath, title, content, encoding, lineEnding)
+		file, err := a.saveFile(store, fileName, data, w)
 		if err == nil {
 			if retry > 0 {
-				runtime.EventsEmit(a.ctx, "log", fmt.Sprintf("ファイル保存に成功しました（%d回目で成功）: %s", retry+1, title))
+				a.emit("log", fmt.Sprintf("ファイル保存に成功しました（%d回目で成功）: %s", retry+1, fileName))
 			}
-			return nil
+			return file, nil
 		}
 
 		lastErr = err
-		runtime.EventsEmit(a.ctx, "log", fmt.Sprintf("ファイル保存に失敗しました（%d/%d回目）: %s - エラー: %v", retry+1, maxRetries, title, err))
+		a.emit("log", fmt.Sprintf("ファイル保存に失敗しました（%d/%d回目）: %s - エラー: %v", retry+1, maxRetries, fileName, err))
 	}
 
-	return fmt.Errorf("ファイル保存に%d回失敗しました: %s - 最後のエラー: %w", maxRetries, title, lastErr)
+	return ManifestFile{}, fmt.Errorf("ファイル保存に%d回失敗しました: %s - 最後のエラー: %w", maxRetries, fileName, lastErr)
 }
 
 // SelectFolder はフォルダ選択ダイアログを表示します
@@ -435,89 +507,13 @@ func (a *App) SetAlwaysOnTop(enable bool) {
 	runtime.WindowSetAlwaysOnTop(a.ctx, enable)
 }
 
-// SaveSettings は設定をJSONファイルに保存します
-func (a *App) SaveSettings(settings Settings) error {
-	// 実行ファイルと同じディレクトリに設定ファイルを保存
-	a.settings = settings
-	exePath, err := os.Executable()
-	if err != nil {
-		return fmt.Errorf("実行ファイルのパスを取得できませんでした: %w", err)
-	}
-
-	configPath := filepath.Join(filepath.Dir(exePath), "settings.json")
-	data, err := json.MarshalIndent(settings, "", "  ")
-	if err != nil {
-		return fmt.Errorf("設定のJSON変換に失敗しました: %w", err)
-	}
-
-	err = os.WriteFile(configPath, data, 0644)
-	if err != nil {
-		return fmt.Errorf("設定の保存に失敗しました: %w", err)
-	}
-
-	return nil
-}
-
-// LoadSettings はJSONファイルから設定を読み込みます
-func (a *App) LoadSettings() (Settings, error) {
-	var settings Settings
-
-	// 実行ファイルと同じディレクトリから設定ファイルを読み込み
-	exePath, err := os.Executable()
-	if err != nil {
-		return settings, fmt.Errorf("実行ファイルのパスを取得できませんでした: %w", err)
-	}
-
-	configPath := filepath.Join(filepath.Dir(exePath), "settings.json")
-	data, err := os.ReadFile(configPath)
-	if err != nil {
-		if os.IsNotExist(err) {
-			// 設定ファイルが存在しない場合はデフォルト値を返す
-			return Settings{
-				Encoding:   "UTF-8",
-				LineEnding: "CR+LF",
-				CreateHtml: true,
-				CreateTxt:  true,
-			}, nil
-		}
-		return settings, fmt.Errorf("設定の読み込みに失敗しました: %w", err)
-	}
-
-	err = json.Unmarshal(data, &settings)
-	if err != nil {
-		return settings, fmt.Errorf("設定のJSON解析に失敗しました: %w", err)
-	}
-
-	return settings, nil
-}
-
 func (a *App) Quit() {
 	runtime.Quit(a.ctx)
 }
 
 func (a *App) shutdown(ctx context.Context) error {
-	return a.SaveSettings(a.settings)
-}
-
-// sanitizeFileName はファイル名に使用できない文字を安全な文字に置換します
-func sanitizeFileName(fileName string) string {
-	// ファイル名に使用できない文字を置換
-	fileName = strings.ReplaceAll(fileName, "/", "_")
-	fileName = strings.ReplaceAll(fileName, "\\", "_")
-	fileName = strings.ReplaceAll(fileName, ":", "_")
-	fileName = strings.ReplaceAll(fileName, "*", "_")
-	fileName = strings.ReplaceAll(fileName, "?", "_")
-	fileName = strings.ReplaceAll(fileName, "\"", "_")
-	fileName = strings.ReplaceAll(fileName, "<", "_")
-	fileName = strings.ReplaceAll(fileName, ">", "_")
-	fileName = strings.ReplaceAll(fileName, "|", "_")
-
-	// 長すぎる場合は切り詰める
-	if len(fileName) > 100 {
-		fileName = fileName[:100]
-	}
-
-	return fileName
+	a.stopScheduler()
+	return a.SaveSettings(a.currentSettings())
 }
 
 // extractNovelCodeFromURL はURLから小説番号を抽出します
@@ -592,39 +588,39 @@ func generateFileName(novelCode, episodeNumber string) string {
 	return fmt.Sprintf("%s-%s", novelCode, episodeNumber)
 }
 
-// isFileAlreadySaved は指定されたファイルが既に保存されているかチェックします
-func (a *App) isFileAlreadySaved(savePath, fileName, episodeNumber string, createHtml, createTxt bool) (bool, bool) {
-	var htmlExists, txtExists bool
-
-	// HTMLファイルの存在チェック（エピソード番号のみをファイル名に使用）
-	if createHtml {
-		htmlPath := filepath.Join(savePath, "html", episodeNumber+".html")
-		if _, err := os.Stat(htmlPath); err == nil {
-			htmlExists = true
+// shouldSkipEpisode はエピソードをスキップするかどうかを判定します
+// マニフェストに記録されたファイルがすべて記録どおりの大きさで存在する場合のみスキップし、
+// needBlocks が true の場合は小説全体のファイルのためにキャッシュから本文を復元します
+func (a *App) shouldSkipEpisode(store *novelStore, manifest *Manifest, novel *Novel, episode *Episode, episodeWriters []EpisodeWriter, needBlocks bool) bool {
+	record := manifest.episode(episode.Number)
+	if record == nil {
+		return false
+	}
+	// 改稿された話は取得し直す（上書きする前の本文は過去の版として残る）
+	if isRevisedSince(record, episode) {
+		a.emit("log", fmt.Sprintf("%s話: %s は%sに改稿されています。取得し直します。", episode.Number, episode.Title, episode.Revised))
+		return false
+	}
+
+	saved := make(map[string]ManifestFile, len(record.Files))
+	for _, file := range record.Files {
+		saved[file.Path] = file
+	}
+	for _, w := range episodeWriters {
+		file, ok := saved[episodeFileName(novel, episode, w)]
+		if !ok || !store.isComplete(file) {
+			return false
 		}
-	} else {
-		htmlExists = true // HTMLを作成しない場合は常にtrue
 	}
 
-	// TXTファイルの存在チェック（従来通り小説番号-エピソード番号）
-	if createTxt {
-		txtPath := filepath.Join(savePath, fileName+".txt")
-		if _, err := os.Stat(txtPath); err == nil {
-			txtExists = true
+	if needBlocks {
+		blocks, err := store.loadBlocks(episode.Number)
+		if err != nil {
+			return false
 		}
-	} else {
-		txtExists = true // TXTを作成しない場合は常にtrue
+		episode.Blocks = blocks
 	}
-
-	return htmlExists, txtExists
-}
-
-// shouldSkipEpisode はエピソードをスキップするかどうかを判定します
-func (a *App) shouldSkipEpisode(savePath, fileName, episodeNumber string, createHtml, createTxt bool) bool {
-	htmlExists, txtExists := a.isFileAlreadySaved(savePath, fileName, episodeNumber, createHtml, createTxt)
-
-	// 必要なファイルがすべて存在する場合はスキップ
-	return htmlExists && txtExists
+	return true
 }
 
 // GetTitle は小説のタイトルを取得します（フロントエンド用）
@@ -639,566 +635,7 @@ func (a *App) GetTitle(url string) (string, error) {
 	return result.Title, nil
 }
 
-// generateEpisodeHTML はエピソード用HTMLを生成します
-func (a *App) generateEpisodeHTML(episodeTitle, content, novelTitle string, episodeNum, totalEpisodes int) string {
-	// 改行をHTMLの<br>タグに変換
-	htmlContent := strings.ReplaceAll(content, "\n", "<br>")
-
-	// ナビゲーションリンクの生成
-	var prevLink, nextLink string
-	if episodeNum > 1 {
-		prevLink = fmt.Sprintf(`<a href="%d.html">← 前のエピソード</a>`, episodeNum-1)
-	}
-	if episodeNum < totalEpisodes {
-		nextLink = fmt.Sprintf(`<a href="%d.html">次のエピソード →</a>`, episodeNum+1)
-	}
-
-	html := fmt.Sprintf(`<!DOCTYPE html>
-<html lang="ja">
-<head>
-    <meta charset="UTF-8">
-    <meta name="viewport" content="width=device-width, initial-scale=1.0">
-    <title>%s - %s</title>
-    <style>
-        body { font-family: 'Hiragino Kaku Gothic Pro', 'ヒラギノ角ゴ Pro W3', Meiryo, メイリオ, Osaka, 'MS PGothic', arial, helvetica, sans-serif; line-height: 1.6; margin: 40px; max-width: 800px; margin: 0 auto; padding: 20px; }
-        h1 { color: #333; border-bottom: 2px solid #333; padding-bottom: 10px; }
-        .nav { margin: 20px 0; text-align: center; }
-        .nav a { display: inline-block; margin: 0 10px; padding: 8px 16px; background: #f0f0f0; text-decoration: none; color: #333; border-radius: 4px; }
-        .nav a:hover { background: #e0e0e0; }
-        .content { margin: 20px 0; }
-        .back-to-index { text-align: center; margin: 30px 0; }
-        .back-to-index a { padding: 10px 20px; background: #007bff; color: white; text-decoration: none; border-radius: 4px; }
-        .back-to-index a:hover { background: #0056b3; }
-    </style>
-</head>
-<body>
-    <h1>%s</h1>
-    
-    <div class="nav">
-        %s
-        %s
-    </div>
-    
-    <div class="content">
-        %s
-    </div>
-    
-    <div class="nav">
-        %s
-        %s
-    </div>
-    
-    <div class="back-to-index">
-        <a href="../index-1.html">← エピソード一覧に戻る</a>
-    </div>
-</body>
-</html>`, episodeTitle, novelTitle, episodeTitle, prevLink, nextLink, htmlContent, prevLink, nextLink)
-
-	return html
-}
-
-// formatChapterContent は各話のテキストコンテンツをフォーマットします
-func (a *App) formatChapterContent(novelTitle, author, chapterTitle, content string) string {
-	var formatted strings.Builder
-
-	// ルビを青空文庫形式に変換
-	content = a.convertRubyToAozora(content)
-
-	// 各話のタイトル（短編の場合でもタイトルを表示）
-	if chapterTitle != "" {
-		// タイトルのルビも変換
-		convertedTitle := a.convertRubyToAozora(chapterTitle)
-		formatted.WriteString(convertedTitle)
-		formatted.WriteString("\n\n")
-	} else {
-		// 短編の場合は小説タイトルを使用
-		convertedNovelTitle := a.convertRubyToAozora(novelTitle)
-		formatted.WriteString(convertedNovelTitle)
-		formatted.WriteString("\n\n")
-	}
-
-	// 本文（各行にインデントを追加）
-	lines := strings.Split(content, "\n")
-	for _, line := range lines {
-		if strings.TrimSpace(line) != "" {
-			formatted.WriteString(line)
-		}
-		formatted.WriteString("\n")
-	}
-
-	return formatted.String()
-}
-
-// convertRubyToAozora はHTMLのrubyタグを青空文庫形式に変換します
-func (a *App) convertRubyToAozora(content string) string {
-	rubyPattern := regexp.MustCompile(`<ruby>(.*?)<rp>.*?</rp><rt>(.*?)</rt><rp>.*?</rp></ruby>`)
-	content = rubyPattern.ReplaceAllStringFunc(content, func(match string) string {
-		submatch := rubyPattern.FindStringSubmatch(match)
-		if len(submatch) >= 3 {
-			baseText := submatch[1]
-			ruby := submatch[2]
-			return a.formatAozoraRuby(baseText, ruby, match)
-		}
-		return match
-	})
-
-	simpleRubyPattern := regexp.MustCompile(`<ruby>(.*?)<rt>(.*?)</rt></ruby>`)
-	content = simpleRubyPattern.ReplaceAllStringFunc(content, func(match string) string {
-		submatch := simpleRubyPattern.FindStringSubmatch(match)
-		if len(submatch) >= 3 {
-			baseText := submatch[1]
-			ruby := submatch[2]
-			return a.formatAozoraRuby(baseText, ruby, match)
-		}
-		return match
-	})
-
-	return content
-}
-
-// formatAozoraRuby は青空文庫形式のルビを正しくフォーマットします
-func (a *App) formatAozoraRuby(baseText, ruby, originalMatch string) string {
-	// ルビのかかる文字列が漢字のみか確認
-	isKanjiOnly := regexp.MustCompile(`^[\p{Han}々仝〆〇ヶ]+$`).MatchString(baseText)
-
-	if isKanjiOnly {
-		// 漢字のみの場合、｜は不要
-		return baseText + "《" + ruby + "》"
-	} else {
-		// 漢字以外が含まれる場合、｜を付ける
-		return "｜" + baseText + "《" + ruby + "》"
-	}
-}
-
-// formatChapterContentForCombined は連結ファイル用に各話のテキストコンテンツをフォーマットします（タイトル・作者名なし）
-func (a *App) formatChapterContentForCombined(chapterTitle, content string) string {
-	var formatted strings.Builder
-
-	// ルビを青空文庫形式に変換
-	content = a.convertRubyToAozora(content)
-
-	// 各話のタイトル（ルビ変換済み）
-	convertedTitle := a.convertRubyToAozora(chapterTitle)
-	formatted.WriteString(convertedTitle)
-	formatted.WriteString("\n\n")
-
-	// 本文（各行にインデントを追加）
-	lines := strings.Split(content, "\n")
-	for _, line := range lines {
-		if strings.TrimSpace(line) != "" {
-			formatted.WriteString(line)
-		}
-		formatted.WriteString("\n")
-	}
-
-	return formatted.String()
-}
-
 // convertToIndexURL は各話URLを小説インデックスURLに変換します
 func (a *App) convertToIndexURL(url string) string {
-	// ncode.syosetu.com用の正規表現
-	ncodePattern := regexp.MustCompile(`^(https://ncode\.syosetu\.com/n[0-9]+[a-z]+)/([0-9]+)/?$`)
-	matches := ncodePattern.FindStringSubmatch(url)
-
-	if len(matches) >= 2 {
-		// 各話URLの場合、インデックスURLに変換
-		indexURL := matches[1] + "/"
-		return indexURL
-	}
-
-	// novel18.syosetu.com用の正規表現
-	novel18Pattern := regexp.MustCompile(`^(https://novel18\.syosetu\.com/n[0-9]+[a-z]+)/([0-9]+)/?$`)
-	matches = novel18Pattern.FindStringSubmatch(url)
-
-	if len(matches) >= 2 {
-		// 各話URLの場合、インデックスURLに変換
-		indexURL := matches[1] + "/"
-		return indexURL
-	}
-
-	// 各話URLでない場合はそのまま返す
-	return url
-}
-
-// generateEpisodeHTMLWithOriginalStructure は元のHTML構造を保った上でエピソード用HTMLを生成します
-func (a *App) generateEpisodeHTMLWithOriginalStructure(episodeTitle, rawHTML, novelTitle string, episodeNum, totalEpisodes int) string {
-	// ナビゲーションリンクの生成
-	var prevLink, nextLink string
-	if episodeNum > 1 {
-		prevLink = fmt.Sprintf(`<a href="%d.html">← 前のエピソード</a>`, episodeNum-1)
-	}
-	if episodeNum < totalEpisodes {
-		nextLink = fmt.Sprintf(`<a href="%d.html">次のエピソード →</a>`, episodeNum+1)
-	}
-
-	html := fmt.Sprintf(`<!DOCTYPE html>
-<html lang="ja">
-<head>
-    <meta charset="UTF-8">
-    <meta name="viewport" content="width=device-width, initial-scale=1.0">
-    <title>%s - %s</title>
-    <style>
-        /* 元サイトのスタイルを模擬 */
-        body { 
-            font-family: 'Hiragino Kaku Gothic Pro', 'ヒラギノ角ゴ Pro W3', Meiryo, メイリオ, Osaka, 'MS PGothic', arial, helvetica, sans-serif; 
-            line-height: 1.7; 
-            color: #333; 
-            background-color: #fff;
-            max-width: 800px; 
-            margin: 0 auto; 
-            padding: 20px; 
-        }
-        
-        /* ナビゲーション */
-        .nav { 
-            margin: 20px 0; 
-            text-align: center;
-            padding: 15px 0;
-            border-top: 1px solid #ddd;
-            border-bottom: 1px solid #ddd;
-        }
-        .nav a { 
-            display: inline-block; 
-            margin: 0 15px; 
-            padding: 10px 20px; 
-            background: #f8f9fa; 
-            text-decoration: none; 
-            color: #495057; 
-            border-radius: 6px;
-            border: 1px solid #dee2e6;
-            transition: all 0.2s;
-        }
-        .nav a:hover { 
-            background: #e9ecef; 
-            color: #212529;
-        }
-        
-        /* 小説本文エリア */
-        .p-novel__body {
-            margin: 30px 0;
-            line-height: 1.8;
-        }
-        
-        /* 本文テキスト */
-        .p-novel__text {
-            margin: 1.5em 0;
-            text-align: left;
-        }
-        
-        /* 改ページ */
-        .js-novel-text-br {
-            height: 1em;
-        }
-        
-        /* ルビ */
-        ruby {
-            ruby-align: center;
-        }
-        
-        rt {
-            font-size: 0.7em;
-        }
-        
-        /* 傍点 */
-        .emphasis {
-            text-emphasis: filled circle;
-            -webkit-text-emphasis: filled circle;
-        }
-        
-        h1 { 
-            color: #333; 
-            border-bottom: 2px solid #007bff; 
-            padding-bottom: 10px;
-            margin-bottom: 30px;
-        }
-        
-        .back-to-index { 
-            text-align: center; 
-            margin: 40px 0; 
-        }
-        .back-to-index a { 
-            padding: 12px 24px; 
-            background: #007bff; 
-            color: white; 
-            text-decoration: none; 
-            border-radius: 6px;
-            transition: background-color 0.2s;
-        }
-        .back-to-index a:hover { 
-            background: #0056b3; 
-        }
-    </style>
-</head>
-<body>
-    <h1>%s</h1>
-    
-    <div class="nav">
-        %s
-        %s
-    </div>
-    
-    <div class="p-novel__body">
-        %s
-    </div>
-    
-    <div class="nav">
-        %s
-        %s
-    </div>
-    
-    <div class="back-to-index">
-        <a href="../index-1.html">← エピソード一覧に戻る</a>
-    </div>
-</body>
-</html>`, episodeTitle, novelTitle, episodeTitle, prevLink, nextLink, rawHTML, prevLink, nextLink)
-
-	return html
-}
-
-// createIndexPages はインデックスページを作成します（ページング対応）
-func (a *App) createIndexPages(savePath, novelTitle string, chapters []ChapterInfo) error {
-	const episodesPerPage = 50 // 1ページあたりのエピソード数
-	totalPages := (len(chapters) + episodesPerPage - 1) / episodesPerPage
-
-	for page := 1; page <= totalPages; page++ {
-		startIdx := (page - 1) * episodesPerPage
-		endIdx := startIdx + episodesPerPage
-		if endIdx > len(chapters) {
-			endIdx = len(chapters)
-		}
-
-		pageChapters := chapters[startIdx:endIdx]
-
-		// インデックスページのHTML生成
-		indexHTML := a.generateIndexHTML(novelTitle, pageChapters, page, totalPages, startIdx)
-
-		// ファイル保存
-		fileName := fmt.Sprintf("index-%d.html", page)
-		filePath := filepath.Join(savePath, fileName)
-		if err := os.WriteFile(filePath, []byte(indexHTML), 0644); err != nil {
-			return fmt.Errorf("インデックスページ%dの保存に失敗しました: %w", page, err)
-		}
-	}
-
-	return nil
-}
-
-// generateIndexHTML はインデックスページ用HTMLを生成します
-func (a *App) generateIndexHTML(novelTitle string, chapters []ChapterInfo, currentPage, totalPages, startIdx int) string {
-	var episodeList strings.Builder
-
-	for i, chapter := range chapters {
-		episodeNum := startIdx + i + 1
-		// URLからエピソード番号を抽出（HTMLファイル名として使用）
-		episodeNumber := extractEpisodeNumberFromURL(chapter.URL)
-		if episodeNumber == "" || (i > 0 && episodeNumber == "1") {
-			episodeNumber = fmt.Sprintf("%d", episodeNum)
-		}
-		episodeList.WriteString(fmt.Sprintf(`        <li><a href="html/%s.html">第%d話 %s</a></li>
-`, episodeNumber, episodeNum, chapter.Title))
-	}
-
-	// ページネーション
-	var pagination strings.Builder
-	if currentPage > 1 {
-		pagination.WriteString(fmt.Sprintf(`        <a href="index-%d.html">← 前のページ</a>
-`, currentPage-1))
-	}
-	if currentPage < totalPages {
-		pagination.WriteString(fmt.Sprintf(`        <a href="index-%d.html">次のページ →</a>
-`, currentPage+1))
-	}
-
-	// ページリンク
-	var pageLinks strings.Builder
-	for i := 1; i <= totalPages; i++ {
-		if i == currentPage {
-			pageLinks.WriteString(fmt.Sprintf(`        <span class="current-page">%d</span>
-`, i))
-		} else {
-			pageLinks.WriteString(fmt.Sprintf(`        <a href="index-%d.html">%d</a>
-`, i, i))
-		}
-	}
-
-	html := fmt.Sprintf(`<!DOCTYPE html>
-<html lang="ja">
-<head>
-    <meta charset="UTF-8">
-    <meta name="viewport" content="width=device-width, initial-scale=1.0">
-    <title>%s - エピソード一覧 (ページ%d)</title>
-    <style>
-        body { font-family: 'Hiragino Kaku Gothic Pro', 'ヒラギノ角ゴ Pro W3', Meiryo, メイリオ, Osaka, 'MS PGothic', arial, helvetica, sans-serif; line-height: 1.6; margin: 40px; max-width: 800px; margin: 0 auto; padding: 20px; }
-        h1 { color: #333; border-bottom: 2px solid #333; padding-bottom: 10px; }
-        .page-info { text-align: center; margin: 20px 0; color: #666; }
-        ul { list-style-type: none; padding: 0; }
-        li { margin: 8px 0; padding: 8px; border: 1px solid #ddd; border-radius: 4px; }
-        li:hover { background-color: #f9f9f9; }
-        a { text-decoration: none; color: #007bff; }
-        a:hover { text-decoration: underline; }
-        .pagination { text-align: center; margin: 30px 0; }
-        .pagination a, .pagination span { display: inline-block; margin: 0 5px; padding: 8px 12px; border: 1px solid #ddd; text-decoration: none; color: #007bff; border-radius: 4px; }
-        .pagination a:hover { background-color: #e9ecef; }
-        .pagination .current-page { background-color: #007bff; color: white; border-color: #007bff; }
-        .page-links { text-align: center; margin: 20px 0; }
-        .page-links a, .page-links span { margin: 0 2px; }
-    </style>
-</head>
-<body>
-    <h1>%s</h1>
-    <div class="page-info">ページ %d / %d</div>
-    
-    <ul>
-%s    </ul>
-    
-    <div class="pagination">
-%s    </div>
-    
-    <div class="page-links">
-%s    </div>
-</body>
-</html>`, novelTitle, currentPage, novelTitle, currentPage, totalPages, episodeList.String(), pagination.String(), pageLinks.String())
-
-	return html
-}
-
-// saveOriginalIndexPages は元のHTMLを使用してインデックスページを保存します
-func (a *App) saveOriginalIndexPages(savePath string, indexPagesHTML []string, chapters []ChapterInfo) error {
-	for i, pageHTML := range indexPagesHTML {
-		// iframeタグを除去
-		cleanHTML := a.removeIframes(pageHTML)
-
-		// エピソードリンクをローカルファイルリンクに変換
-		modifiedHTML := a.convertEpisodeLinksToLocal(cleanHTML, chapters)
-
-		fileName := fmt.Sprintf("index-%d.html", i+1)
-		filePath := filepath.Join(savePath, fileName)
-		if err := os.WriteFile(filePath, []byte(modifiedHTML), 0644); err != nil {
-			return fmt.Errorf("インデックスページ%dの保存に失敗しました: %w", i+1, err)
-		}
-	}
-	return nil
-}
-
-// convertEpisodeLinksToLocal はエピソードリンクをローカルファイルリンクに変換します
-func (a *App) convertEpisodeLinksToLocal(html string, chapters []ChapterInfo) string {
-	// エピソードURLとローカルファイル名のマッピングを作成
-	for _, chapter := range chapters {
-		// 元のエピソードURL
-		originalURL := chapter.URL
-
-		// ローカルファイル名を生成
-		episodeNumber := extractEpisodeNumberFromURL(originalURL)
-		if episodeNumber == "" {
-			continue
-		}
-
-		// 相対パス形式のローカルリンク
-		localLink := fmt.Sprintf("html/%s.html", episodeNumber)
-
-		// 正規表現でより柔軟なリンク置換
-		// 完全URL形式のパターン
-		fullURLPattern := regexp.MustCompile(regexp.QuoteMeta(originalURL))
-		html = fullURLPattern.ReplaceAllString(html, localLink)
-
-		// 相対パス形式のパターン（例：/n2532kp/1/）
-		if strings.Contains(originalURL, "ncode.syosetu.com") {
-			// URLから相対パス部分を抽出
-			re := regexp.MustCompile(`https://ncode\.syosetu\.com(/n[0-9]+[a-z]+/[0-9]+/?)`)
-			matches := re.FindStringSubmatch(originalURL)
-			if len(matches) > 1 {
-				relativePath := matches[1]
-				// 相対パスのリンクも置換
-				relativePattern := regexp.MustCompile(fmt.Sprintf(`href="(%s)"`, regexp.QuoteMeta(relativePath)))
-				html = relativePattern.ReplaceAllString(html, fmt.Sprintf(`href="%s"`, localLink))
-			}
-		}
-	}
-
-	return html
-}
-
-// removeIframes はHTMLからすべてのiframeタグを除去します
-func (a *App) removeIframes(html string) string {
-	// iframeタグ（開始タグから終了タグまで）を正規表現で除去
-	iframePattern := regexp.MustCompile(`(?i)<iframe[^>]*>.*?</iframe>`)
-	html = iframePattern.ReplaceAllString(html, "")
-
-	// 自己完結型のiframeタグも除去
-	selfClosingIframePattern := regexp.MustCompile(`(?i)<iframe[^>]*/>`)
-	html = selfClosingIframePattern.ReplaceAllString(html, "")
-
-	return html
-}
-
-// generateShortNovelHTML は短編小説用のHTMLを生成します
-func (a *App) generateShortNovelHTML(title, rawHTML string) string {
-	html := fmt.Sprintf(`<\!DOCTYPE html>
-<html lang="ja">
-<head>
-    <meta charset="UTF-8">
-    <meta name="viewport" content="width=device-width, initial-scale=1.0">
-    <title>%s</title>
-    <style>
-        /* 元サイトのスタイルを模擬 */
-        body { 
-            font-family: "Hiragino Kaku Gothic Pro", "ヒラギノ角ゴ Pro W3", Meiryo, メイリオ, Osaka, "MS PGothic", arial, helvetica, sans-serif; 
-            line-height: 1.7; 
-            color: #333; 
-            background-color: #fff;
-            max-width: 800px; 
-            margin: 0 auto; 
-            padding: 20px; 
-        }
-        
-        /* 小説本文エリア */
-        .p-novel__body {
-            margin: 30px 0;
-            line-height: 1.8;
-        }
-        
-        /* 本文テキスト */
-        .p-novel__text {
-            margin: 1.5em 0;
-            text-align: left;
-        }
-        
-        /* 改ページ */
-        .js-novel-text-br {
-            height: 1em;
-        }
-        
-        /* ルビ */
-        ruby {
-            ruby-align: center;
-        }
-        
-        rt {
-            font-size: 0.7em;
-        }
-        
-        /* 傍点 */
-        .emphasis {
-            text-emphasis: filled circle;
-            -webkit-text-emphasis: filled circle;
-        }
-        
-        h1 { 
-            color: #333; 
-            border-bottom: 2px solid #007bff; 
-            padding-bottom: 10px;
-            margin-bottom: 30px;
-        }
-    </style>
-</head>
-<body>
-    <h1>%s</h1>
-    
-    <div class="p-novel__body">
-        %s
-    </div>
-</body>
-</html>`, title, title, rawHTML)
-
-	return html
+	return a.site.indexURL(url)
 }
@@ -0,0 +1,251 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDownloadNovel_FakeSite(t *testing.T) {
+	fake := newFakeSyosetu(t)
+	app := fake.app()
+	savePath := t.TempDir()
+	formats := []FormatRequest{{Name: "txt"}, {Name: "epub"}}
+
+	// 各話のURLを指定しても小説全体をダウンロードする
+	if err := app.downloadNovel(context.Background(), fake.site.Novel+"/n1111aa/2/", savePath, formats, ""); err != nil {
+		t.Fatalf("downloadNovel() error = %v", err)
+	}
+	store, manifest, err := openNovelStoreByNCode(savePath, "N1111AA")
+	if err != nil {
+		t.Fatalf("openNovelStoreByNCode() error = %v", err)
+	}
+	if len(manifest.Episodes) != 3 || len(manifest.Files) != 1 {
+		t.Fatalf("Episodes = %d話, Files = %d件, want 3話, 1件", len(manifest.Episodes), len(manifest.Files))
+	}
+	for _, episode := range manifest.Episodes {
+		for _, file := range episode.Files {
+			if !store.isComplete(file) {
+				t.Errorf("%s話のファイル %s が保存されていません", episode.Number, file.Path)
+			}
+		}
+	}
+	// 保存した話はダウンロードの最後にまとめて検索用索引に登録する
+	index, err := store.loadSearchIndex()
+	if err != nil {
+		t.Fatalf("loadSearchIndex() error = %v", err)
+	}
+	if len(index.Docs) != 3 {
+		t.Errorf("検索用索引に登録した話 = %d話, want 3話", len(index.Docs))
+	}
+	if manifest.Genre != "ハイファンタジー〔ファンタジー〕" || strings.Join(manifest.Keywords, " ") != "冒険 旅 少年" {
+		t.Errorf("Genre, Keywords = %q, %v", manifest.Genre, manifest.Keywords)
+	}
+
+	// 2回目は目次だけを取得し、保存済みの話は取得しない
+	if err := app.downloadNovel(context.Background(), fake.site.Novel+"/n1111aa/", savePath, formats, ""); err != nil {
+		t.Fatalf("2回目の downloadNovel() error = %v", err)
+	}
+	for path, want := range map[string]int{"/n1111aa/": 2, "/n1111aa/?p=2": 2, "/n1111aa/1/": 1, "/n1111aa/3/": 1} {
+		if got := fake.count(path); got != want {
+			t.Errorf("%s へのリクエスト = %d回, want %d回", path, got, want)
+		}
+	}
+}
+
+func TestDownloadNovel_Canceled(t *testing.T) {
+	fake := newFakeSyosetu(t)
+	episodeInterval = time.Hour
+	app := fake.app()
+	savePath := t.TempDir()
+
+	// 1話を保存して次の話を待っている間に中断する
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	app.emitter = func(name string, data ...interface{}) {
+		if name == "log" && strings.Contains(fmt.Sprint(data...), "1話取得完了") {
+			cancel()
+		}
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- app.downloadNovel(ctx, fake.site.Novel+"/n1111aa/", savePath, []FormatRequest{{Name: "txt"}}, "")
+	}()
+	select {
+	case err := <-done:
+		if !errors.Is(err, context.Canceled) {
+			t.Fatalf("downloadNovel() error = %v, want context.Canceled", err)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("中断しても downloadNovel() が終わりません")
+	}
+
+	_, manifest, err := openNovelStoreByNCode(savePath, "N1111AA")
+	if err != nil {
+		t.Fatalf("openNovelStoreByNCode() error = %v", err)
+	}
+	if len(manifest.Episodes) != 1 || fake.count("/n1111aa/2/") != 0 {
+		t.Errorf("Episodes = %d話, 2話へのリクエスト = %d回, want 1話, 0回", len(manifest.Episodes), fake.count("/n1111aa/2/"))
+	}
+}
+
+func TestDownloadNovel_Revised(t *testing.T) {
+	// 本文のキャッシュの有効期限内でも、改稿された話はサーバーから取得し直す
+	useTempConfigDir(t)
+	responseCache.configure(defaultCacheSettings())
+	fake := newFakeSyosetu(t)
+	app := fake.app()
+	savePath := t.TempDir()
+	formats := []FormatRequest{{Name: "txt"}}
+
+	if err := app.downloadNovel(context.Background(), fake.site.Novel+"/n1111aa/", savePath, formats, ""); err != nil {
+		t.Fatalf("downloadNovel() error = %v", err)
+	}
+
+	// 作者が2話を改稿し、目次に改稿日時が表示された
+	fake.edit(t, "ncode/n1111aa/index.html",
+		`<div class="p-eplist__update">2024/01/02 12:00</div>`,
+		`<div class="p-eplist__update">2024/01/02 12:00<span title="2099/02/01 09:30 改稿">（<u>改</u>）</span></div>`)
+	fake.edit(t, "ncode/n1111aa/2.html", "少年は村を出た。", "少年は夜明けに村を出た。")
+
+	if err := app.downloadNovel(context.Background(), fake.site.Novel+"/n1111aa/", savePath, formats, ""); err != nil {
+		t.Fatalf("2回目の downloadNovel() error = %v", err)
+	}
+	for path, want := range map[string]int{"/n1111aa/1/": 1, "/n1111aa/2/": 2, "/n1111aa/3/": 1} {
+		if got := fake.count(path); got != want {
+			t.Errorf("%s へのリクエスト = %d回, want %d回", path, got, want)
+		}
+	}
+
+	store, manifest, err := openNovelStoreByNCode(savePath, "N1111AA")
+	if err != nil {
+		t.Fatalf("openNovelStoreByNCode() error = %v", err)
+	}
+	record := manifest.episode("2")
+	if record.Revised != "2099/02/01 09:30" {
+		t.Errorf("Revised = %q, want 2099/02/01 09:30", record.Revised)
+	}
+	versions, err := store.episodeVersions(*record)
+	if err != nil {
+		t.Fatalf("episodeVersions() error = %v", err)
+	}
+	if len(versions) != 2 {
+		t.Fatalf("版 = %d件, want 改稿前と最新の2件", len(versions))
+	}
+	old, err := store.loadVersion("2", versions[0].ID)
+	if err != nil {
+		t.Fatalf("loadVersion() error = %v", err)
+	}
+	if text := renderAozora(old); !strings.Contains(text, "少年は村を出た。") {
+		t.Errorf("改稿前の版 = %q", text)
+	}
+
+	// 改稿日時が変わらなければ取得し直さない
+	if err := app.downloadNovel(context.Background(), fake.site.Novel+"/n1111aa/", savePath, formats, ""); err != nil {
+		t.Fatalf("3回目の downloadNovel() error = %v", err)
+	}
+	if got := fake.count("/n1111aa/2/"); got != 2 {
+		t.Errorf("/n1111aa/2/ へのリクエスト = %d回, want 2回", got)
+	}
+}
+
+func TestDownloadNovel_LegacyFiles(t *testing.T) {
+	fake := newFakeSyosetu(t)
+	savePath := t.TempDir()
+
+	// マニフェストを作成する前のバージョンで1話と2話を保存したフォルダ（99話は目次にない）
+	for _, name := range []string{"N1111AA-1.txt", "N1111AA-2.txt", "N1111AA-99.txt", "メモ.txt"} {
+		if err := os.WriteFile(filepath.Join(savePath, name), []byte("以前の本文"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := fake.app().downloadNovel(context.Background(), fake.site.Novel+"/n1111aa/", savePath, []FormatRequest{{Name: "txt"}}, ""); err != nil {
+		t.Fatalf("downloadNovel() error = %v", err)
+	}
+	for path, want := range map[string]int{"/n1111aa/1/": 0, "/n1111aa/2/": 0, "/n1111aa/3/": 1} {
+		if got := fake.count(path); got != want {
+			t.Errorf("%s へのリクエスト = %d回, want %d回", path, got, want)
+		}
+	}
+
+	_, manifest, err := openNovelStoreByNCode(savePath, "N1111AA")
+	if err != nil {
+		t.Fatalf("openNovelStoreByNCode() error = %v", err)
+	}
+	if len(manifest.Episodes) != 3 || len(manifest.Archived) != 0 {
+		t.Fatalf("Episodes = %d話, Archived = %d話, want 3話, 0話", len(manifest.Episodes), len(manifest.Archived))
+	}
+	if record := manifest.episode("2"); record.Title != "二話　旅立ち" || record.Chapter != "第一章　はじまり" {
+		t.Errorf("引き継いだ2話の記録 = %+v", record)
+	}
+	if data, _ := os.ReadFile(filepath.Join(savePath, "N1111AA-1.txt")); string(data) != "以前の本文" {
+		t.Errorf("以前のファイルを上書きしました: %q", data)
+	}
+}
+
+func TestDownloadNovel_Results(t *testing.T) {
+	tests := []struct {
+		name    string
+		url     func(site syosetuSite) string
+		wantErr string
+	}{
+		{
+			name:    "短編",
+			url:     func(site syosetuSite) string { return site.Novel + "/n2222bb/" },
+			wantErr: "",
+		},
+		{
+			name:    "R18の小説",
+			url:     func(site syosetuSite) string { return site.Novel18 + "/n3333cc/" },
+			wantErr: "",
+		},
+		{
+			name:    "削除された小説",
+			url:     func(site syosetuSite) string { return site.Novel + "/n9999zz/" },
+			wantErr: "404 Not Found",
+		},
+		{
+			name:    "取得できない話が続く",
+			url:     func(site syosetuSite) string { return site.Novel + "/n4444dd/" },
+			wantErr: "Chapterの取得に3回失敗したため、ダウンロードを停止します",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := newFakeSyosetu(t)
+			var notified []string
+			app := fake.app()
+			app.settings.Notifications = NotificationSettings{Desktop: true}
+			app.emitter = func(name string, data ...interface{}) {
+				if name == "notification" {
+					notified = append(notified, data[0].(Notification).Event)
+				}
+			}
+
+			err := app.downloadNovel(context.Background(), tt.url(fake.site), t.TempDir(), []FormatRequest{{Name: "txt"}}, "")
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("downloadNovel() error = %v", err)
+				}
+				if strings.Join(notified, ",") != notifyCompleted {
+					t.Errorf("通知 = %v, want [%s]", notified, notifyCompleted)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("downloadNovel() error = %v, want %q を含む", err, tt.wantErr)
+			}
+			if strings.Join(notified, ",") != notifyFailed {
+				t.Errorf("通知 = %v, want [%s]", notified, notifyFailed)
+			}
+		})
+	}
+}
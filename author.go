@@ -0,0 +1,222 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// AuthorWork は作者の作品1件分の情報を表す構造体
+type AuthorWork struct {
+	NCode     string `json:"ncode"`
+	Title     string `json:"title"`
+	URL       string `json:"url"`
+	PageType  string `json:"page_type"`
+	Episodes  int    `json:"episodes"`
+	UpdatedAt string `json:"updated_at"`
+}
+
+// AuthorWorks は作者と作品一覧を表す構造体
+type AuthorWorks struct {
+	UserID string       `json:"user_id"`
+	Author string       `json:"author"`
+	Works  []AuthorWork `json:"works"`
+}
+
+// narouAPIWork はなろう小説APIのレスポンス1件分です
+type narouAPIWork struct {
+	Title         string `json:"title"`
+	NCode         string `json:"ncode"`
+	Writer        string `json:"writer"`
+	NovelType     int    `json:"novel_type"`
+	GeneralAllNo  int    `json:"general_all_no"`
+	GeneralLastup string `json:"general_lastup"`
+}
+
+// parseAuthorID はマイページURLまたはユーザIDから作者のIDを取り出します
+func parseAuthorID(input string) (string, error) {
+	input = strings.TrimSpace(input)
+
+	// URL例: https://mypage.syosetu.com/123456/ または https://xmypage.syosetu.com/x1234ab/
+	patterns := []*regexp.Regexp{
+		regexp.MustCompile(`mypage\.syosetu\.com/mypage/[a-z]+/(?:userid|xid)/([0-9]+|x[0-9a-z]+)`),
+		regexp.MustCompile(`mypage\.syosetu\.com/([0-9]+|x[0-9a-z]+)/?`),
+	}
+	for _, re := range patterns {
+		if matches := re.FindStringSubmatch(input); len(matches) >= 2 {
+			return matches[1], nil
+		}
+	}
+
+	// ユーザIDのみが入力された場合
+	if regexp.MustCompile(`^([0-9]+|x[0-9a-z]+)$`).MatchString(input) {
+		return input, nil
+	}
+
+	return "", fmt.Errorf("作者のURLまたはユーザIDではありません: %s", input)
+}
+
+// GetAuthorWorks は作者の作品一覧を取得します（フロントエンド用）
+func (a *App) GetAuthorWorks(input string) (AuthorWorks, error) {
+	works := AuthorWorks{}
+
+	userID, err := parseAuthorID(input)
+	if err != nil {
+		return works, err
+	}
+	works.UserID = userID
+
+	apiWorks, err := a.fetchAuthorWorks(userID)
+	if err != nil {
+		return works, err
+	}
+	if len(apiWorks) == 0 {
+		return works, fmt.Errorf("作品が見つかりませんでした: %s", userID)
+	}
+
+	// ノクターンノベルズの作者はxから始まるIDを持つ
+	host := a.site.Novel
+	if strings.HasPrefix(userID, "x") {
+		host = a.site.Novel18
+	}
+
+	for _, w := range apiWorks {
+		pageType := "rensai"
+		if w.NovelType == 2 {
+			pageType = "short"
+		}
+		ncode := strings.ToLower(w.NCode)
+		works.Works = append(works.Works, AuthorWork{
+			NCode:     w.NCode,
+			Title:     w.Title,
+			URL:       fmt.Sprintf("%s/%s/", host, ncode),
+			PageType:  pageType,
+			Episodes:  w.GeneralAllNo,
+			UpdatedAt: w.GeneralLastup,
+		})
+	}
+	works.Author = apiWorks[0].Writer
+
+	return works, nil
+}
+
+// authorWorksPageSize はなろう小説APIから作品一覧を1回に取得する件数（APIの上限は500件）
+var authorWorksPageSize = 500
+
+// fetchAuthorWorks はなろう小説APIのユーザID指定で作品一覧を取得します
+// 1回で取得できない場合は取得開始位置（st）をずらし、件数（allcount）に達するまで続けて取得します
+func (a *App) fetchAuthorWorks(userID string) ([]narouAPIWork, error) {
+	var works []narouAPIWork
+	for {
+		page, allCount, err := a.fetchAuthorWorksPage(userID, len(works)+1)
+		if err != nil {
+			return nil, err
+		}
+		works = append(works, page...)
+		if len(works) >= allCount {
+			return works, nil
+		}
+		if len(page) == 0 {
+			return nil, fmt.Errorf("作品一覧を最後まで取得できませんでした（%d件中%d件）", allCount, len(works))
+		}
+	}
+}
+
+// fetchAuthorWorksPage は作品一覧を start 件目から authorWorksPageSize 件取得し、作品の総数（allcount）とともに返します
+func (a *App) fetchAuthorWorksPage(userID string, start int) ([]narouAPIWork, int, error) {
+	params := url.Values{}
+	params.Set("out", "json")
+	params.Set("of", "t-n-w-nt-ga-gl")
+	params.Set("lim", strconv.Itoa(authorWorksPageSize))
+	params.Set("st", strconv.Itoa(start))
+	params.Set("order", "old")
+
+	apiURL := a.site.novelAPI(false)
+	if strings.HasPrefix(userID, "x") {
+		// ノクターンノベルズはR18小説APIのxID指定を使う
+		apiURL = a.site.novelAPI(true)
+		params.Set("xid", userID)
+	} else {
+		params.Set("userid", userID)
+	}
+
+	req, err := http.NewRequest("GET", apiURL+"?"+params.Encode(), nil)
+	if err != nil {
+		return nil, 0, err
+	}
+	req.Header.Set("User-Agent", "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/136.0.0.0 Safari/537.36")
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, 0, fmt.Errorf("作品一覧の取得に失敗しました: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, 0, fmt.Errorf("作品一覧の取得に失敗しました: %s", resp.Status)
+	}
+
+	// 先頭の要素は件数（allcount）
+	var items []json.RawMessage
+	if err := json.NewDecoder(resp.Body).Decode(&items); err != nil {
+		return nil, 0, fmt.Errorf("作品一覧のJSON解析に失敗しました: %w", err)
+	}
+	if len(items) == 0 {
+		return nil, 0, fmt.Errorf("作品一覧のJSON解析に失敗しました: 件数がありません")
+	}
+	var count struct {
+		AllCount int `json:"allcount"`
+	}
+	if err := json.Unmarshal(items[0], &count); err != nil {
+		return nil, 0, fmt.Errorf("作品一覧のJSON解析に失敗しました: %w", err)
+	}
+
+	var works []narouAPIWork
+	for _, item := range items[1:] {
+		var w narouAPIWork
+		if err := json.Unmarshal(item, &w); err != nil {
+			return nil, 0, fmt.Errorf("作品一覧のJSON解析に失敗しました: %w", err)
+		}
+		works = append(works, w)
+	}
+
+	return works, count.AllCount, nil
+}
+
+// DownloadAuthorWorks は選択された作者の作品を作者フォルダ以下にまとめてダウンロードします
+func (a *App) DownloadAuthorWorks(works AuthorWorks, ncodes []string, savePath string, formats []FormatRequest) error {
+	if savePath == "" {
+		root, err := defaultSaveRoot()
+		if err != nil {
+			return err
+		}
+		savePath = root
+	}
+	authorDir := filepath.Join(savePath, sanitizeFileName(works.Author))
+
+	selected := make(map[string]bool, len(ncodes))
+	for _, ncode := range ncodes {
+		selected[strings.ToUpper(ncode)] = true
+	}
+
+	var items []QueueItem
+	for _, work := range works.Works {
+		if !selected[strings.ToUpper(work.NCode)] {
+			continue
+		}
+		items = append(items, QueueItem{
+			URL:      work.URL,
+			Title:    work.Title,
+			SavePath: filepath.Join(authorDir, sanitizeFileName(work.Title)),
+		})
+	}
+
+	a.emit("log", fmt.Sprintf("%s の作品%d件をダウンロードします", works.Author, len(items)))
+
+	return a.DownloadQueue(items, formats)
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParseAuthorID(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    string
+		wantErr bool
+	}{
+		{name: "マイページのURL", input: "https://mypage.syosetu.com/123456/", want: "123456"},
+		{name: "末尾の/なし", input: "https://mypage.syosetu.com/123456", want: "123456"},
+		{name: "作品一覧のURL", input: "https://mypage.syosetu.com/mypage/novellist/userid/123456/", want: "123456"},
+		{name: "Xマイページ", input: "https://xmypage.syosetu.com/x1234ab/", want: "x1234ab"},
+		{name: "Xマイページの作品一覧", input: "https://xmypage.syosetu.com/mypage/novellist/xid/x1234ab/", want: "x1234ab"},
+		{name: "ユーザID", input: " 123456 ", want: "123456"},
+		{name: "xID", input: "x1234ab", want: "x1234ab"},
+		{name: "小説のURL", input: "https://ncode.syosetu.com/n1234ab/", wantErr: true},
+		{name: "空", input: "", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseAuthorID(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseAuthorID(%q) error = %v, wantErr %v", tt.input, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("parseAuthorID(%q) = %q, want %q", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetAuthorWorks(t *testing.T) {
+	fake := newFakeSyosetu(t)
+
+	tests := []struct {
+		name       string
+		input      string
+		pageSize   int // 0 の場合は authorWorksPageSize のまま
+		wantAuthor string
+		wantWorks  []string // NCode/種類/URL
+		wantErr    bool
+	}{
+		{
+			name:       "小説家になろうの作者",
+			input:      "https://mypage.syosetu.com/123456/",
+			wantAuthor: "テスト作者",
+			wantWorks: []string{
+				"N1111AA/rensai/" + fake.site.Novel + "/n1111aa/",
+				"N2222BB/short/" + fake.site.Novel + "/n2222bb/",
+				"N4444DD/rensai/" + fake.site.Novel + "/n4444dd/",
+			},
+		},
+		{
+			name:       "1回で取得できない作品数の作者",
+			input:      "123456",
+			pageSize:   2,
+			wantAuthor: "テスト作者",
+			wantWorks: []string{
+				"N1111AA/rensai/" + fake.site.Novel + "/n1111aa/",
+				"N2222BB/short/" + fake.site.Novel + "/n2222bb/",
+				"N4444DD/rensai/" + fake.site.Novel + "/n4444dd/",
+			},
+		},
+		{
+			name:       "ノクターンノベルズの作者",
+			input:      "x1234ab",
+			wantAuthor: "R18作者",
+			wantWorks:  []string{"N3333CC/rensai/" + fake.site.Novel18 + "/n3333cc/"},
+		},
+		{
+			name:    "作品のない作者",
+			input:   "999999",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.pageSize > 0 {
+				saved := authorWorksPageSize
+				authorWorksPageSize = tt.pageSize
+				defer func() { authorWorksPageSize = saved }()
+			}
+			works, err := fake.app().GetAuthorWorks(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("GetAuthorWorks() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			var got []string
+			for _, w := range works.Works {
+				got = append(got, w.NCode+"/"+w.PageType+"/"+w.URL)
+			}
+			if works.Author != tt.wantAuthor || strings.Join(got, ",") != strings.Join(tt.wantWorks, ",") {
+				t.Errorf("GetAuthorWorks() = %s %v, want %s %v", works.Author, got, tt.wantAuthor, tt.wantWorks)
+			}
+		})
+	}
+}
+
+func TestDownloadAuthorWorks(t *testing.T) {
+	fake := newFakeSyosetu(t)
+	app := fake.app()
+	works, err := app.GetAuthorWorks("123456")
+	if err != nil {
+		t.Fatalf("GetAuthorWorks() error = %v", err)
+	}
+
+	// 選択した作品だけを作者のフォルダの下の作品ごとのフォルダに保存する
+	savePath := t.TempDir()
+	if err := app.DownloadAuthorWorks(works, []string{"n1111aa", "N2222BB"}, savePath, []FormatRequest{{Name: "txt"}}); err != nil {
+		t.Fatalf("DownloadAuthorWorks() error = %v", err)
+	}
+	for _, title := range []string{"テスト連載", "テスト短編"} {
+		if _, err := os.Stat(filepath.Join(savePath, "テスト作者", title)); err != nil {
+			t.Errorf("%s が保存されていません: %v", title, err)
+		}
+	}
+	if fake.count("/n4444dd/") != 0 {
+		t.Error("選択していない作品をダウンロードしました")
+	}
+}
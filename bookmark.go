@@ -0,0 +1,288 @@
+package main
+
+import (
+	"fmt"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/wailsapp/wails/v2/pkg/runtime"
+)
+
+// BookmarkEntry はブックマークから読み込んだ小説1件分の情報を表す構造体
+type BookmarkEntry struct {
+	NCode      string `json:"ncode"`
+	Title      string `json:"title"`
+	URL        string `json:"url"`
+	Downloaded bool   `json:"downloaded"`
+}
+
+// BookmarkImport はブックマークの読み込み結果を表す構造体
+type BookmarkImport struct {
+	Entries    []BookmarkEntry `json:"entries"`
+	Queue      []QueueItem     `json:"queue"`
+	Duplicates int             `json:"duplicates"`
+}
+
+var (
+	// bookmarkLinkPattern はブックマーク一覧中の小説へのリンクにマッチします（大文字のncodeも含む）
+	bookmarkLinkPattern = regexp.MustCompile(`(?i)https?://(ncode|novel18)\.syosetu\.com/(n[0-9]+[a-z]+)`)
+	// savedFilePattern は保存済みのテキストファイル名（N1234AB-1.txt）にマッチします
+	savedFilePattern = regexp.MustCompile(`^(N[0-9]+[A-Z]+)-[0-9]+\.txt$`)
+)
+
+// SelectBookmarkFile はブックマーク一覧のHTMLファイル選択ダイアログを表示します
+func (a *App) SelectBookmarkFile() (string, error) {
+	selectedPath, err := runtime.OpenFileDialog(a.ctx, runtime.OpenDialogOptions{
+		Title: "ブックマーク一覧のファイルを選択",
+		Filters: []runtime.FileFilter{
+			{DisplayName: "HTML / テキスト", Pattern: "*.html;*.htm;*.txt"},
+		},
+	})
+	if err != nil {
+		return "", fmt.Errorf("エラーが発生しました: %w", err)
+	}
+	if selectedPath == "" {
+		return "", fmt.Errorf("ファイルが選択されませんでした")
+	}
+	return selectedPath, nil
+}
+
+// ImportBookmarks はブックマーク一覧を読み込んでダウンロードキューを作成します
+// source には保存したブックマーク一覧のファイルパス、またはブックマーク一覧のURLを指定します
+// URLの場合はログイン済みブラウザからコピーしたCookieを cookie に指定します
+func (a *App) ImportBookmarks(source string, cookie string, savePath string) (BookmarkImport, error) {
+	result := BookmarkImport{}
+
+	source = strings.TrimSpace(source)
+	if source == "" {
+		return result, fmt.Errorf("ブックマーク一覧のファイルまたはURLが指定されていません")
+	}
+
+	var pages []string
+	if strings.HasPrefix(source, "http://") || strings.HasPrefix(source, "https://") {
+		fetched, err := a.fetchBookmarkPages(source, cookie)
+		if err != nil {
+			return result, err
+		}
+		pages = fetched
+	} else {
+		data, err := os.ReadFile(source)
+		if err != nil {
+			return result, fmt.Errorf("ブックマーク一覧の読み込みに失敗しました: %w", err)
+		}
+		pages = append(pages, string(data))
+	}
+
+	// ncodeで重複を除きながらエントリを集める
+	seen := make(map[string]int)
+	for _, page := range pages {
+		for _, entry := range a.parseBookmarkEntries(page) {
+			if idx, ok := seen[entry.NCode]; ok {
+				// 後から見つかったタイトルで空のタイトルを補完する
+				if result.Entries[idx].Title == "" {
+					result.Entries[idx].Title = entry.Title
+				}
+				result.Duplicates++
+				continue
+			}
+			seen[entry.NCode] = len(result.Entries)
+			result.Entries = append(result.Entries, entry)
+		}
+	}
+
+	if len(result.Entries) == 0 {
+		return result, fmt.Errorf("ブックマークから小説が見つかりませんでした")
+	}
+
+	// 保存済みの小説を調べる
+	root := savePath
+	if root == "" {
+		defaultRoot, err := defaultSaveRoot()
+		if err != nil {
+			return result, err
+		}
+		root = defaultRoot
+	}
+	downloaded := findDownloadedNovels(root)
+
+	for i, entry := range result.Entries {
+		if downloaded[entry.NCode] {
+			result.Entries[i].Downloaded = true
+			continue
+		}
+
+		title := entry.Title
+		if title == "" {
+			title = entry.NCode
+		}
+		item := QueueItem{URL: entry.URL, Title: title}
+		if savePath != "" {
+			item.SavePath = filepath.Join(savePath, sanitizeFileName(title))
+		}
+		result.Queue = append(result.Queue, item)
+	}
+
+	a.emit("log", fmt.Sprintf("ブックマークから%d件の小説を読み込みました（保存済み%d件、重複%d件）",
+		len(result.Entries), len(result.Entries)-len(result.Queue), result.Duplicates))
+
+	return result, nil
+}
+
+// fetchBookmarkPages はCookieを付けてブックマーク一覧の全ページを取得します
+// ログイン用のCookieをほかのサイトに送らないよう、listURL と同じホストのページだけをたどります
+func (a *App) fetchBookmarkPages(listURL string, cookie string) ([]string, error) {
+	base, err := url.Parse(listURL)
+	if err != nil {
+		return nil, fmt.Errorf("ブックマーク一覧のURLが正しくありません: %w", err)
+	}
+
+	var pages []string
+	visited := make(map[string]bool)
+	pageURL := base.String()
+	for pageURL != "" && !visited[pageURL] {
+		visited[pageURL] = true
+
+		req, err := http.NewRequest("GET", pageURL, nil)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("User-Agent", "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/136.0.0.0 Safari/537.36")
+		// ブックマークは追加・削除されるため、キャッシュの有効期限内でもサーバーに確認する
+		req.Header.Set("Cache-Control", "no-cache")
+		if cookie != "" {
+			req.Header.Set("Cookie", cookie)
+		}
+
+		resp, err := httpClient.Do(req)
+		if err != nil {
+			return nil, fmt.Errorf("ブックマーク一覧の取得に失敗しました: %w", err)
+		}
+
+		doc, err := goquery.NewDocumentFromReader(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		if resp.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("ブックマーク一覧の取得に失敗しました: %s", resp.Status)
+		}
+
+		html, err := doc.Html()
+		if err != nil {
+			return nil, err
+		}
+		pages = append(pages, html)
+
+		// 「次へ」ボタンがあれば次のページも取得する（ほかのホストへのリンクはたどらない）
+		pageURL = ""
+		if next, exists := doc.Find(".c-pager__item--next").Attr("href"); exists && next != "" {
+			nextURL, err := base.Parse(next)
+			if err != nil {
+				continue
+			}
+			if nextURL.Host != base.Host {
+				a.emit("log", fmt.Sprintf("ブックマーク一覧の次のページがほかのサイトにあるため取得しません: %s", nextURL))
+				continue
+			}
+			pageURL = nextURL.String()
+		}
+	}
+
+	if len(pages) > 0 && len(a.parseBookmarkEntries(pages[0])) == 0 {
+		return nil, fmt.Errorf("ブックマーク一覧を取得できませんでした。Cookieが正しいか確認してください")
+	}
+
+	return pages, nil
+}
+
+// parseBookmarkEntries はブックマーク一覧のHTMLまたはテキストから小説を取り出します
+// 同じ小説への複数のリンク（小説トップと栞など）は1件にまとめます
+func (a *App) parseBookmarkEntries(content string) []BookmarkEntry {
+	var entries []BookmarkEntry
+	index := make(map[string]int)
+	add := func(entry BookmarkEntry) {
+		if i, ok := index[entry.NCode]; ok {
+			if entries[i].Title == "" {
+				entries[i].Title = entry.Title
+			}
+			return
+		}
+		index[entry.NCode] = len(entries)
+		entries = append(entries, entry)
+	}
+
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(content))
+	if err == nil {
+		doc.Find("a[href]").Each(func(i int, s *goquery.Selection) {
+			href, _ := s.Attr("href")
+			matches := bookmarkLinkPattern.FindStringSubmatch(href)
+			if len(matches) < 3 {
+				return
+			}
+
+			// 小説トップへのリンクの文字列をタイトルとして使う（栞へのリンクなどはタイトルにしない）
+			title := ""
+			if strings.TrimRight(strings.TrimPrefix(href, matches[0]), "/") == "" {
+				title = strings.TrimSpace(s.Text())
+			}
+			add(a.site.bookmarkEntry(matches[1], matches[2], title))
+		})
+	}
+
+	// リンクが見つからない場合はURLを列挙したテキストとして扱う
+	if len(entries) == 0 {
+		for _, matches := range bookmarkLinkPattern.FindAllStringSubmatch(content, -1) {
+			add(a.site.bookmarkEntry(matches[1], matches[2], ""))
+		}
+	}
+
+	return entries
+}
+
+// bookmarkEntry はリンクのサブドメイン（ncode・novel18）とncodeからエントリを作成します（URLは小文字にそろえる）
+// URLは取得するサイトのものにするため、ブックマークのリンクのホストは使いません
+func (s syosetuSite) bookmarkEntry(subdomain, ncode, title string) BookmarkEntry {
+	base := s.Novel
+	if strings.EqualFold(subdomain, "novel18") {
+		base = s.Novel18
+	}
+	return BookmarkEntry{
+		NCode: strings.ToUpper(ncode),
+		Title: title,
+		URL:   fmt.Sprintf("%s/%s/", base, strings.ToLower(ncode)),
+	}
+}
+
+// findDownloadedNovels は保存先以下を探索して保存済みの小説番号を返します
+func findDownloadedNovels(root string) map[string]bool {
+	downloaded := make(map[string]bool)
+
+	filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			// 読めないディレクトリは無視して探索を続ける
+			if d != nil && d.IsDir() {
+				return fs.SkipDir
+			}
+			return nil
+		}
+		if d.IsDir() {
+			return nil
+		}
+		if matches := savedFilePattern.FindStringSubmatch(d.Name()); len(matches) >= 2 {
+			downloaded[matches[1]] = true
+		}
+		// ファイル名のテンプレートを変更している場合もマニフェストから判定する（.narou/<NCODE>/manifest.json）
+		if d.Name() == "manifest.json" && filepath.Base(filepath.Dir(filepath.Dir(path))) == workDirName {
+			downloaded[filepath.Base(filepath.Dir(path))] = true
+		}
+		return nil
+	})
+
+	return downloaded
+}
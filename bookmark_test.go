@@ -0,0 +1,138 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestParseBookmarkEntries(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    []string // NCode|タイトル|URL
+	}{
+		{
+			name: "ブックマーク一覧のHTML",
+			content: `<ul>
+<li><a href="https://ncode.syosetu.com/n1234ab/">テスト連載</a> <a href="https://ncode.syosetu.com/n1234ab/12/">12部分</a></li>
+<li><a href="https://novel18.syosetu.com/n5678cd/">テストR18連載</a></li>
+<li><a href="https://syosetu.com/">小説家になろう</a></li>
+</ul>`,
+			want: []string{
+				"N1234AB|テスト連載|https://ncode.syosetu.com/n1234ab/",
+				"N5678CD|テストR18連載|https://novel18.syosetu.com/n5678cd/",
+			},
+		},
+		{
+			name:    "栞へのリンクが先にある場合",
+			content: `<a href="https://ncode.syosetu.com/n1234ab/3/">3部分</a><a href="https://ncode.syosetu.com/n1234ab/">テスト連載</a>`,
+			want:    []string{"N1234AB|テスト連載|https://ncode.syosetu.com/n1234ab/"},
+		},
+		{
+			name:    "大文字のncode",
+			content: `<a href="https://NCODE.syosetu.com/N1234AB/">テスト連載</a>`,
+			want:    []string{"N1234AB|テスト連載|https://ncode.syosetu.com/n1234ab/"},
+		},
+		{
+			name:    "URLを列挙したテキスト",
+			content: "https://ncode.syosetu.com/n1234ab/\nhttps://ncode.syosetu.com/N1234AB/5/\nhttps://novel18.syosetu.com/n5678cd/\n",
+			want: []string{
+				"N1234AB||https://ncode.syosetu.com/n1234ab/",
+				"N5678CD||https://novel18.syosetu.com/n5678cd/",
+			},
+		},
+		{
+			name:    "小説がない",
+			content: `<a href="https://example.com/n1234ab/">別のサイト</a>`,
+			want:    nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, entry := range NewApp().parseBookmarkEntries(tt.content) {
+				got = append(got, fmt.Sprintf("%s|%s|%s", entry.NCode, entry.Title, entry.URL))
+			}
+			if strings.Join(got, "\n") != strings.Join(tt.want, "\n") {
+				t.Errorf("parseBookmarkEntries() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFindDownloadedNovels(t *testing.T) {
+	root := t.TempDir()
+	files := []string{
+		"テスト連載/N1234AB-1.txt",                             // 以前の形式のファイル名
+		"テスト短編/" + workDirName + "/N5678CD/manifest.json", // ファイル名のテンプレートを変更した小説
+		"メモ/N9999ZZ.txt",                                  // 保存した話のファイル名ではない
+		"メモ/manifest.json",                                // 作業ディレクトリの外のマニフェスト
+	}
+	for _, file := range files {
+		path := filepath.Join(root, filepath.FromSlash(file))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte("{}"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var got []string
+	for ncode := range findDownloadedNovels(root) {
+		got = append(got, ncode)
+	}
+	sort.Strings(got)
+	if strings.Join(got, ",") != "N1234AB,N5678CD" {
+		t.Errorf("findDownloadedNovels() = %v, want [N1234AB N5678CD]", got)
+	}
+}
+
+func TestImportBookmarks_URL(t *testing.T) {
+	fake := newFakeSyosetu(t)
+	app := fake.app()
+
+	// ほかのサイトにはCookieを送らず、ページもたどらない
+	otherRequests := 0
+	other := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		otherRequests++
+	}))
+	defer other.Close()
+
+	var cookies []string
+	bookmarks := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		cookies = append(cookies, r.Header.Get("Cookie"))
+		switch r.URL.Query().Get("p") {
+		case "":
+			fmt.Fprint(w, `<a href="https://ncode.syosetu.com/n1111aa/">テスト連載</a><a class="c-pager__item--next" href="/list?p=2">次へ</a>`)
+		case "2":
+			fmt.Fprintf(w, `<a href="https://novel18.syosetu.com/n3333cc/">テストR18連載</a><a class="c-pager__item--next" href="%s/list?p=3">次へ</a>`, other.URL)
+		}
+	}))
+	defer bookmarks.Close()
+
+	result, err := app.ImportBookmarks(bookmarks.URL+"/list", "ses=secret", t.TempDir())
+	if err != nil {
+		t.Fatalf("ImportBookmarks() error = %v", err)
+	}
+	if strings.Join(cookies, ",") != "ses=secret,ses=secret" || otherRequests != 0 {
+		t.Errorf("Cookie = %v, ほかのサイトへのリクエスト = %d回, want 同じサイトの2ページだけ", cookies, otherRequests)
+	}
+
+	// 取り込んだ小説のURLは取得するサイトのものにする
+	var got []string
+	for _, item := range result.Queue {
+		got = append(got, item.URL)
+	}
+	want := []string{fake.site.Novel + "/n1111aa/", fake.site.Novel18 + "/n3333cc/"}
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("Queue = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,596 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+)
+
+// cliCommands はコマンドラインから実行できるサブコマンドの一覧（引数なしで起動した場合はGUIを表示する）
+var cliCommands = map[string]func(app *App, args []string, stdout, stderr io.Writer) int{
+	"verify":  runVerifyCommand,
+	"diff":    runDiffCommand,
+	"search":  runSearchCommand,
+	"unread":  runUnreadCommand,
+	"daemon":  runDaemonCommand,
+	"serve":   runServeCommand,
+	"opds":    runOPDSCommand,
+	"deliver": runDeliverCommand,
+}
+
+// runCLI は引数がサブコマンドの場合に実行し、終了コードを返します（サブコマンドでない場合は ok が false）
+func runCLI(args []string, stdout, stderr io.Writer) (code int, ok bool) {
+	if len(args) == 0 {
+		return 0, false
+	}
+	command, ok := cliCommands[args[0]]
+	if !ok {
+		return 0, false
+	}
+
+	// ログは標準エラー出力に表示し、結果は標準出力に出力する
+	app := NewApp()
+	app.emitter = func(name string, data ...interface{}) {
+		if name == "log" {
+			fmt.Fprintln(stderr, data...)
+		}
+	}
+	return command(app, args[1:], stdout, stderr), true
+}
+
+// verifyUsage は verify サブコマンドの使い方
+const verifyUsage = "verify [-url URL] [-online] [-deep] [-repair] [-json] <保存先フォルダ>"
+
+// runVerifyCommand は保存済みの小説を検証・修復します
+// 問題が残っている場合は終了コード1、引数の誤りやエラーの場合は2を返します
+func runVerifyCommand(app *App, args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("verify", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.Usage = func() {
+		fmt.Fprintln(stderr, "使い方: narou_download "+verifyUsage)
+		flags.PrintDefaults()
+	}
+
+	var options VerifyOptions
+	var asJSON bool
+	flags.StringVar(&options.URL, "url", "", "目次のURL（省略時はマニフェストに記録されたURL）")
+	flags.BoolVar(&options.Online, "online", false, "最新の目次と比較して未取得の話を探す")
+	flags.BoolVar(&options.Deep, "deep", false, "各話の本文を取得して更新を確認する（時間がかかります）")
+	flags.BoolVar(&options.Repair, "repair", false, "問題のある話・ファイルだけを取得し直して修復する")
+	flags.BoolVar(&asJSON, "json", false, "結果をJSONで出力する")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return 2
+	}
+
+	reports, err := app.VerifyNovel(flags.Arg(0), options)
+	if err != nil {
+		fmt.Fprintln(stderr, "エラー:", err)
+		if len(reports) == 0 {
+			return 2
+		}
+	}
+
+	if asJSON {
+		encoder := json.NewEncoder(stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(reports); err != nil {
+			fmt.Fprintln(stderr, "エラー:", err)
+			return 2
+		}
+	} else {
+		writeVerifyReports(stdout, reports)
+	}
+
+	// 修復しなかった、または修復しきれなかった問題がある場合は失敗とする
+	for _, report := range reports {
+		if len(report.Issues) > 0 && (!options.Repair || report.Repaired == 0) {
+			return 1
+		}
+	}
+	if err != nil {
+		return 2
+	}
+	return 0
+}
+
+// writeVerifyReports は検証結果を人が読める形式で出力します
+func writeVerifyReports(w io.Writer, reports []VerifyReport) {
+	for _, report := range reports {
+		fmt.Fprintf(w, "%s %s（%s）: %d件のファイルを確認、問題 %d件", report.NCode, report.Title, report.Folder, report.Checked, len(report.Issues))
+		if report.Repaired > 0 {
+			fmt.Fprintf(w, "、修復 %d件", report.Repaired)
+		}
+		fmt.Fprintln(w)
+
+		for _, issue := range report.Issues {
+			target := issue.Path
+			if issue.Number != "" {
+				target = fmt.Sprintf("%s話 %s", issue.Number, issue.Title)
+				if issue.Path != "" {
+					target += "（" + issue.Path + "）"
+				}
+			}
+			fmt.Fprintf(w, "  [%s] %s: %s\n", issue.Kind, target, issue.Detail)
+		}
+	}
+}
+
+// diffUsage は diff サブコマンドの使い方
+const diffUsage = "diff [-ncode NCODE] [-from 版] [-to 版] [-char] [-list] [-json] <保存先フォルダ> <話数>"
+
+// runDiffCommand は保存済みの話の過去の版との差分を出力します
+// 差分がある場合は終了コード1、引数の誤りやエラーの場合は2を返します
+func runDiffCommand(app *App, args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("diff", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.Usage = func() {
+		fmt.Fprintln(stderr, "使い方: narou_download "+diffUsage)
+		flags.PrintDefaults()
+	}
+
+	var request DiffRequest
+	var char, list, asJSON bool
+	flags.StringVar(&request.NCode, "ncode", "", "小説番号（保存先に小説が1つしかない場合は省略可）")
+	flags.StringVar(&request.From, "from", "", "比較元の版（省略時は最新の1つ前の版）")
+	flags.StringVar(&request.To, "to", "", "比較先の版（省略時は最新の版）")
+	flags.BoolVar(&char, "char", false, "文字単位で比較する（省略時は段落単位）")
+	flags.BoolVar(&list, "list", false, "版の一覧を表示する")
+	flags.BoolVar(&asJSON, "json", false, "結果をJSONで出力する")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if flags.NArg() != 2 {
+		flags.Usage()
+		return 2
+	}
+	request.Folder, request.Number = flags.Arg(0), flags.Arg(1)
+	if char {
+		request.Mode = "char"
+	}
+
+	var result interface{}
+	changed := false
+	if list {
+		versions, err := app.ListEpisodeVersions(request.Folder, request.NCode, request.Number)
+		if err != nil {
+			fmt.Fprintln(stderr, "エラー:", err)
+			return 2
+		}
+		result = versions
+		if !asJSON {
+			for _, version := range versions {
+				fmt.Fprintf(stdout, "%s\t%s\n", version.ID, version.FetchedAt.Format("2006-01-02 15:04:05"))
+			}
+			return 0
+		}
+	} else {
+		diff, err := app.DiffEpisode(request)
+		if err != nil {
+			fmt.Fprintln(stderr, "エラー:", err)
+			return 2
+		}
+		result = diff
+		if !asJSON {
+			fmt.Fprintf(stdout, "--- %s話 %s（%s）\n+++ %s話 %s（%s）\n", diff.Number, diff.Title, diff.From, diff.Number, diff.Title, diff.To)
+			fmt.Fprint(stdout, formatDiff(diff))
+		}
+		for _, op := range diff.Ops {
+			changed = changed || op.Kind != "equal"
+		}
+	}
+
+	if asJSON {
+		encoder := json.NewEncoder(stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(result); err != nil {
+			fmt.Fprintln(stderr, "エラー:", err)
+			return 2
+		}
+	}
+	if changed {
+		return 1
+	}
+	return 0
+}
+
+// searchUsage は search サブコマンドの使い方
+const searchUsage = "search [-ncode NCODE] [-limit 件数] [-json] <保存先フォルダ> <検索語>"
+
+// runSearchCommand は保存済みの小説の本文を検索します
+// 一致しなかった場合は終了コード1、引数の誤りやエラーの場合は2を返します
+func runSearchCommand(app *App, args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("search", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.Usage = func() {
+		fmt.Fprintln(stderr, "使い方: narou_download "+searchUsage)
+		flags.PrintDefaults()
+	}
+
+	var request SearchRequest
+	var asJSON bool
+	flags.StringVar(&request.NCode, "ncode", "", "検索する小説の小説番号（省略時はすべての小説）")
+	flags.IntVar(&request.Limit, "limit", defaultSearchLimit, "表示する件数の上限")
+	flags.BoolVar(&asJSON, "json", false, "結果をJSONで出力する")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if flags.NArg() < 2 {
+		flags.Usage()
+		return 2
+	}
+	request.Folder = flags.Arg(0)
+	request.Query = strings.Join(flags.Args()[1:], " ")
+
+	result, err := app.SearchLibrary(request)
+	if err != nil {
+		fmt.Fprintln(stderr, "エラー:", err)
+		return 2
+	}
+
+	if asJSON {
+		encoder := json.NewEncoder(stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(result); err != nil {
+			fmt.Fprintln(stderr, "エラー:", err)
+			return 2
+		}
+	} else {
+		for _, hit := range result.Hits {
+			fmt.Fprintf(stdout, "%s %s %s話 %s（%d行目）: %s\n", hit.NCode, hit.Title, hit.Number, hit.EpisodeTitle, hit.Paragraph+1, highlightSnippet(hit))
+		}
+		if result.Truncated {
+			fmt.Fprintf(stdout, "（%d件を超えたため以降は省略しました）\n", len(result.Hits))
+		}
+	}
+
+	if len(result.Hits) == 0 {
+		return 1
+	}
+	return 0
+}
+
+// unreadUsage は unread サブコマンドの使い方
+const unreadUsage = "unread [-ncode NCODE] [-export 形式] [-output フォルダ] [-mark-read] [-json] <保存先フォルダ>"
+
+// runUnreadCommand は小説ごとの未読の話数を表示し、指定された場合は未読の話だけをまとめて出力します
+// 引数の誤りやエラーの場合は終了コード2を返します
+func runUnreadCommand(app *App, args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("unread", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.Usage = func() {
+		fmt.Fprintln(stderr, "使い方: narou_download "+unreadUsage)
+		flags.PrintDefaults()
+	}
+
+	var request UnreadExportRequest
+	var asJSON bool
+	flags.StringVar(&request.NCode, "ncode", "", "対象の小説番号（省略時はすべての小説）")
+	flags.StringVar(&request.Format.Name, "export", "", "未読の話だけをまとめて出力する形式（epub・pdf・txt など）")
+	flags.StringVar(&request.Output, "output", "", "まとめたファイルの出力先フォルダ（省略時は保存先フォルダ）")
+	flags.BoolVar(&request.MarkRead, "mark-read", false, "出力した話を既読にする")
+	flags.BoolVar(&asJSON, "json", false, "結果をJSONで出力する")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return 2
+	}
+	request.Folder = flags.Arg(0)
+
+	novels, err := app.ListLibrary(request.Folder)
+	if err != nil {
+		fmt.Fprintln(stderr, "エラー:", err)
+		return 2
+	}
+
+	code := 0
+	progresses := []ReadingProgress{}
+	for _, novel := range novels {
+		if request.NCode != "" && !strings.EqualFold(novel.NCode, request.NCode) {
+			continue
+		}
+		progress, err := app.GetReadingProgress(novel.Folder, novel.NCode)
+		if err != nil {
+			fmt.Fprintln(stderr, "エラー:", err)
+			code = 2
+			continue
+		}
+		progresses = append(progresses, progress)
+		if !asJSON {
+			fmt.Fprintf(stdout, "%s %s: 未読 %d話 / 全%d話\n", progress.NCode, progress.Title, progress.Unread, len(progress.Episodes))
+		}
+
+		if request.Format.Name != "" && progress.Unread > 0 {
+			export := request
+			export.Folder, export.NCode = novel.Folder, novel.NCode
+			path, err := app.ExportUnread(export)
+			if err != nil {
+				fmt.Fprintln(stderr, "エラー:", err)
+				code = 2
+				continue
+			}
+			if !asJSON {
+				fmt.Fprintln(stdout, "  出力:", path)
+			}
+		}
+	}
+
+	if asJSON {
+		encoder := json.NewEncoder(stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(progresses); err != nil {
+			fmt.Fprintln(stderr, "エラー:", err)
+			return 2
+		}
+	}
+	return code
+}
+
+// daemonUsage は daemon サブコマンドの使い方
+const daemonUsage = "daemon [-interval 間隔] [-cron 予定] [-auto-download] [-once] [-json] <本棚のフォルダ>"
+
+// runDaemonCommand は画面を表示せずに新しい話の定期確認を続けます（-once の場合は1回だけ確認する）
+// 中断（Ctrl+C）されると終了コード0、引数の誤りやエラーの場合は2を返します
+func runDaemonCommand(app *App, args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("daemon", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.Usage = func() {
+		fmt.Fprintln(stderr, "使い方: narou_download "+daemonUsage)
+		flags.PrintDefaults()
+	}
+
+	settings := defaultScheduleSettings()
+	settings.Enabled = true
+	var once, asJSON bool
+	flags.StringVar(&settings.Interval, "interval", settings.Interval, "確認する間隔（例: 6h、30m）")
+	flags.StringVar(&settings.Cron, "cron", "", "cron形式の予定（例: \"0 */3 * * *\"）。指定した場合は -interval より優先する")
+	flags.BoolVar(&settings.AutoDownload, "auto-download", false, "新しい話があれば自動でダウンロードする")
+	flags.BoolVar(&once, "once", false, "1回だけ確認して終了する")
+	flags.BoolVar(&asJSON, "json", false, "確認結果をJSONで出力する（-once の場合のみ）")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return 2
+	}
+	settings.Folder = flags.Arg(0)
+
+	// 通知・フック・送信先の設定はGUIと共通
+	if _, err := app.LoadSettings(); err != nil {
+		fmt.Fprintln(stderr, "エラー:", err)
+		return 2
+	}
+
+	if once {
+		checks, err := app.CheckUpdates(settings.Folder, settings.AutoDownload)
+		if err != nil {
+			fmt.Fprintln(stderr, "エラー:", err)
+			return 2
+		}
+		if asJSON {
+			encoder := json.NewEncoder(stdout)
+			encoder.SetIndent("", "  ")
+			if err := encoder.Encode(checks); err != nil {
+				fmt.Fprintln(stderr, "エラー:", err)
+				return 2
+			}
+			return 0
+		}
+		writeUpdateChecks(stdout, checks)
+		return 0
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	if err := app.startScheduler(ctx, settings); err != nil {
+		fmt.Fprintln(stderr, "エラー:", err)
+		return 2
+	}
+	fmt.Fprintf(stderr, "定期確認を開始しました（次回: %s）\n", app.GetScheduleStatus().NextRun.Format("2006-01-02 15:04"))
+	<-ctx.Done()
+	app.stopScheduler()
+	return 0
+}
+
+// writeUpdateChecks は定期確認の結果を人が読める形式で出力します
+func writeUpdateChecks(w io.Writer, checks []UpdateCheck) {
+	for _, check := range checks {
+		switch {
+		case check.Error != "":
+			fmt.Fprintf(w, "%s %s: %s\n", check.NCode, check.Title, check.Error)
+		case len(check.NewEpisodes) == 0:
+			fmt.Fprintf(w, "%s %s: 新しい話はありません\n", check.NCode, check.Title)
+		default:
+			fmt.Fprintf(w, "%s %s: 新しい話 %d話\n", check.NCode, check.Title, len(check.NewEpisodes))
+			for _, episode := range check.NewEpisodes {
+				fmt.Fprintf(w, "  %s話 %s\n", episode.Number, episode.Title)
+			}
+		}
+	}
+}
+
+// serveUsage は serve サブコマンドの使い方
+const serveUsage = "serve [-addr アドレス] [-token トークン]"
+
+// runServeCommand は画面を表示せずにHTTP APIサーバーを実行します
+// トークンを指定しない場合は環境変数 NAROU_DOWNLOAD_API_TOKEN を使い、それもなければ生成して表示します
+// 中断（Ctrl+C）されると終了コード0、引数の誤りやエラーの場合は2を返します
+func runServeCommand(app *App, args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("serve", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.Usage = func() {
+		fmt.Fprintln(stderr, "使い方: narou_download "+serveUsage)
+		flags.PrintDefaults()
+	}
+
+	var addr, token string
+	flags.StringVar(&addr, "addr", "127.0.0.1:8765", "待ち受けるアドレス（localhost のみ）")
+	flags.StringVar(&token, "token", "", "認証トークン（省略時は環境変数 "+apiTokenEnv+"、それもなければ生成する）")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if flags.NArg() != 0 {
+		flags.Usage()
+		return 2
+	}
+	if err := checkLoopbackAddr(addr); err != nil {
+		fmt.Fprintln(stderr, "エラー:", err)
+		return 2
+	}
+
+	if token == "" {
+		token = os.Getenv(apiTokenEnv)
+	}
+	if token == "" {
+		var err error
+		if token, err = generateAPIToken(); err != nil {
+			fmt.Fprintln(stderr, "エラー:", err)
+			return 2
+		}
+		fmt.Fprintln(stdout, "認証トークン:", token)
+	}
+
+	// プロファイルと出力形式の設定はGUIと共通
+	if _, err := app.LoadSettings(); err != nil {
+		fmt.Fprintln(stderr, "エラー:", err)
+		return 2
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	if err := newAPIServer(app, token).serve(ctx, addr); err != nil {
+		fmt.Fprintln(stderr, "エラー:", err)
+		return 2
+	}
+	return 0
+}
+
+// opdsUsage は opds サブコマンドの使い方
+const opdsUsage = "opds [-addr アドレス] [本棚のフォルダ]"
+
+// runOPDSCommand は画面を表示せずに本棚のOPDSカタログを公開します（フォルダを省略した場合は既定の保存先）
+// 中断（Ctrl+C）されると終了コード0、引数の誤りやエラーの場合は2を返します
+func runOPDSCommand(app *App, args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("opds", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.Usage = func() {
+		fmt.Fprintln(stderr, "使い方: narou_download "+opdsUsage)
+		flags.PrintDefaults()
+	}
+
+	var addr string
+	flags.StringVar(&addr, "addr", "127.0.0.1:8766", "待ち受けるアドレス（localhost のみ）")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if flags.NArg() > 1 {
+		flags.Usage()
+		return 2
+	}
+
+	server := &opdsServer{app: app, folder: flags.Arg(0)}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	err := serveLocalHTTP(ctx, addr, server.handler(), func(addr net.Addr) {
+		fmt.Fprintf(stdout, "OPDSカタログ: http://%s/opds/ （OPDS 2.0: http://%s/opds/v2/）\n", addr, addr)
+	})
+	if err != nil {
+		fmt.Fprintln(stderr, "エラー:", err)
+		return 2
+	}
+	return 0
+}
+
+// deliverUsage は deliver サブコマンドの使い方
+const deliverUsage = "deliver [-ncode NCODE] [-force] [-json] <保存先フォルダ>"
+
+// runDeliverCommand は保存済みの小説のファイルを設定した送信先に送ります
+// 送れなかった送信先がある場合は終了コード1、引数の誤りやエラーの場合は2を返します
+func runDeliverCommand(app *App, args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("deliver", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.Usage = func() {
+		fmt.Fprintln(stderr, "使い方: narou_download "+deliverUsage)
+		flags.PrintDefaults()
+	}
+
+	var ncode string
+	var force, asJSON bool
+	flags.StringVar(&ncode, "ncode", "", "対象の小説番号（省略時はすべての小説）")
+	flags.BoolVar(&force, "force", false, "前回から変わっていないファイルも送る")
+	flags.BoolVar(&asJSON, "json", false, "結果をJSONで出力する")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return 2
+	}
+
+	// 送信先の設定はGUIと共通
+	settings, err := app.LoadSettings()
+	if err != nil {
+		fmt.Fprintln(stderr, "エラー:", err)
+		return 2
+	}
+	if len(settings.Deliveries) == 0 {
+		fmt.Fprintln(stderr, "エラー: 送信先が設定されていません")
+		return 2
+	}
+	novels, err := app.ListLibrary(flags.Arg(0))
+	if err != nil {
+		fmt.Fprintln(stderr, "エラー:", err)
+		return 2
+	}
+
+	code := 0
+	results := map[string][]DeliveryResult{}
+	for _, novel := range novels {
+		if ncode != "" && !strings.EqualFold(novel.NCode, ncode) {
+			continue
+		}
+		delivered, err := app.DeliverNovel(novel.Folder, novel.NCode, force)
+		if err != nil {
+			fmt.Fprintln(stderr, "エラー:", err)
+			code = 2
+			continue
+		}
+		results[novel.NCode] = delivered
+		for _, result := range delivered {
+			if result.Error != "" && code == 0 {
+				code = 1
+			}
+			if asJSON {
+				continue
+			}
+			if result.Error != "" {
+				fmt.Fprintf(stdout, "%s %s → %s: 失敗（%s）\n", novel.NCode, novel.Title, result.Target, result.Error)
+			} else {
+				fmt.Fprintf(stdout, "%s %s → %s: %d件\n", novel.NCode, novel.Title, result.Target, len(result.Files))
+			}
+		}
+	}
+
+	if asJSON {
+		encoder := json.NewEncoder(stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(results); err != nil {
+			fmt.Fprintln(stderr, "エラー:", err)
+			return 2
+		}
+	}
+	return code
+}
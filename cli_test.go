@@ -0,0 +1,85 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestRunCLI(t *testing.T) {
+	_, store, _ := newVerifiedNovelStore(t)
+	_, brokenStore, _ := newVerifiedNovelStore(t)
+	os.Remove(brokenStore.path("N1234AB-1.txt"))
+
+	tests := []struct {
+		name     string
+		args     []string
+		ok       bool
+		code     int
+		contains string
+	}{
+		{name: "引数なし", args: nil, ok: false},
+		{name: "サブコマンド以外", args: []string{"-psn_0_12345"}, ok: false},
+		{name: "フォルダの指定なし", args: []string{"verify"}, ok: true, code: 2},
+		{name: "問題なし", args: []string{"verify", store.root}, ok: true, code: 0, contains: "問題 0件"},
+		{name: "問題あり", args: []string{"verify", brokenStore.root}, ok: true, code: 1, contains: "[missing-file] 1話"},
+		{name: "JSON出力", args: []string{"verify", "-json", store.root}, ok: true, code: 0, contains: `"ncode": "N1234AB"`},
+		{name: "検索", args: []string{"search", store.root, "本文二"}, ok: true, code: 0, contains: "N1234AB テスト小説 2話 二話（1行目）: 【本文二】"},
+		{name: "未読の話数", args: []string{"unread", store.root}, ok: true, code: 0, contains: "N1234AB テスト小説: 未読 3話 / 全3話"},
+		{name: "検索に一致しない", args: []string{"search", store.root, "存在しない"}, ok: true, code: 1},
+		{name: "定期確認の間隔が短すぎる", args: []string{"daemon", "-interval", "1m", store.root}, ok: true, code: 2},
+		{name: "APIサーバーをlocalhost以外で待ち受ける", args: []string{"serve", "-addr", "0.0.0.0:8765"}, ok: true, code: 2},
+		{name: "OPDSカタログをlocalhost以外で待ち受ける", args: []string{"opds", "-addr", "0.0.0.0:8766"}, ok: true, code: 2},
+		{name: "送る小説のフォルダの指定なし", args: []string{"deliver", "-force"}, ok: true, code: 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+			code, ok := runCLI(tt.args, &stdout, &stderr)
+			if ok != tt.ok || code != tt.code {
+				t.Fatalf("runCLI() = %d, %v, want %d, %v (stderr: %s)", code, ok, tt.code, tt.ok, stderr.String())
+			}
+			if !strings.Contains(stdout.String(), tt.contains) {
+				t.Errorf("runCLI() の出力に %q が含まれていません: %s", tt.contains, stdout.String())
+			}
+		})
+	}
+}
+
+func TestRunCLI_Diff(t *testing.T) {
+	app, store, novel := newVerifiedNovelStore(t)
+	manifest, _ := store.loadManifest()
+	episode := novel.Episodes()[0]
+	episode.Blocks = []Block{{Kind: BlockParagraph, Inlines: []Inline{{Kind: InlineText, Text: "本文一を直した"}}}}
+	if err := app.saveEpisode(store, manifest, novel, episode, nil); err != nil {
+		t.Fatalf("saveEpisode() error = %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		args     []string
+		code     int
+		contains string
+	}{
+		{name: "話数の指定なし", args: []string{"diff", store.root}, code: 2},
+		{name: "段落単位", args: []string{"diff", store.root, "1"}, code: 1, contains: "-本文一\n+本文一を直した\n"},
+		{name: "文字単位", args: []string{"diff", "-char", store.root, "1"}, code: 1, contains: "本文一{+を直した+}"},
+		{name: "版の一覧", args: []string{"diff", "-list", store.root, "1"}, code: 0, contains: "current"},
+		{name: "過去の版なし", args: []string{"diff", store.root, "2"}, code: 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+			code, ok := runCLI(tt.args, &stdout, &stderr)
+			if !ok || code != tt.code {
+				t.Fatalf("runCLI() = %d, %v, want %d, true (stderr: %s)", code, ok, tt.code, stderr.String())
+			}
+			if !strings.Contains(stdout.String(), tt.contains) {
+				t.Errorf("runCLI() の出力に %q が含まれていません: %s", tt.contains, stdout.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,354 @@
+package main
+
+import (
+	"fmt"
+	"html"
+	"net/url"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/PuerkitoBio/goquery"
+	nethtml "golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// BlockKind は本文ブロックの種類を表します
+type BlockKind string
+
+const (
+	BlockParagraph    BlockKind = "paragraph"    // 段落（1行）
+	BlockBlank        BlockKind = "blank"        // 空行
+	BlockIllustration BlockKind = "illustration" // 挿絵
+	BlockSeparator    BlockKind = "separator"    // 前書き・本文・後書きの区切り
+)
+
+// InlineKind は段落内の要素の種類を表します
+type InlineKind string
+
+const (
+	InlineText     InlineKind = "text"     // 通常の文字列
+	InlineRuby     InlineKind = "ruby"     // ルビ付きの文字列
+	InlineEmphasis InlineKind = "emphasis" // 傍点付きの文字列
+)
+
+// Inline は段落内の文字列要素を表す構造体
+type Inline struct {
+	Kind InlineKind `json:"kind"`
+	Text string     `json:"text"`
+	Ruby string     `json:"ruby,omitempty"`
+}
+
+// Block は本文の1ブロックを表す構造体
+type Block struct {
+	Kind    BlockKind `json:"kind"`
+	Inlines []Inline  `json:"inlines,omitempty"`
+	Src     string    `json:"src,omitempty"`
+}
+
+// PlainText はルビや傍点を除いた段落の文字列を返します
+func (b Block) PlainText() string {
+	var text strings.Builder
+	for _, inline := range b.Inlines {
+		text.WriteString(inline.Text)
+	}
+	return text.String()
+}
+
+// blockBuilder はノードを走査しながらブロックを組み立てます
+type blockBuilder struct {
+	baseURL  string
+	blocks   []Block
+	inlines  []Inline
+	emphasis bool
+}
+
+// extractBlocks はHTMLドキュメントから本文をブロックの列として抽出します
+func extractBlocks(doc *goquery.Document, pageURL string) ([]Block, error) {
+	var blocks []Block
+
+	// p-novel__body 内の p-novel__text（前書き・本文・後書き）をそれぞれ走査する
+	doc.Find(".p-novel__body .p-novel__text").Each(func(i int, s *goquery.Selection) {
+		section := parseSectionBlocks(s, pageURL)
+		if len(section) == 0 {
+			return
+		}
+		if len(blocks) > 0 {
+			blocks = append(blocks, Block{Kind: BlockSeparator})
+		}
+		blocks = append(blocks, section...)
+	})
+
+	if len(blocks) == 0 {
+		return nil, fmt.Errorf("本文を取得できませんでした")
+	}
+
+	return blocks, nil
+}
+
+// parseSectionBlocks は本文の1セクションをブロックの列に変換します
+func parseSectionBlocks(s *goquery.Selection, pageURL string) []Block {
+	b := &blockBuilder{baseURL: pageURL}
+	for _, node := range s.Nodes {
+		for child := node.FirstChild; child != nil; child = child.NextSibling {
+			b.walk(child)
+		}
+	}
+	b.flush()
+
+	// セクション前後の空行は取り除く
+	blocks := b.blocks
+	for len(blocks) > 0 && blocks[0].Kind == BlockBlank {
+		blocks = blocks[1:]
+	}
+	for len(blocks) > 0 && blocks[len(blocks)-1].Kind == BlockBlank {
+		blocks = blocks[:len(blocks)-1]
+	}
+
+	return blocks
+}
+
+// walk はノードを再帰的に走査します
+func (b *blockBuilder) walk(n *nethtml.Node) {
+	switch n.Type {
+	case nethtml.TextNode:
+		// ソース上の改行は書式のためのものなので取り除く
+		text := strings.NewReplacer("\r", "", "\n", "").Replace(n.Data)
+		if text == "" {
+			return
+		}
+		kind := InlineText
+		if b.emphasis {
+			kind = InlineEmphasis
+		}
+		b.appendInline(Inline{Kind: kind, Text: text})
+		return
+	case nethtml.ElementNode:
+	default:
+		return
+	}
+
+	switch n.DataAtom {
+	case atom.Script, atom.Style, atom.Rp, atom.Rt:
+		return
+	case atom.Br:
+		b.flush()
+		return
+	case atom.Img:
+		b.appendIllustration(n)
+		return
+	case atom.Ruby:
+		b.appendRuby(n)
+		return
+	case atom.Em:
+		prev := b.emphasis
+		b.emphasis = true
+		b.walkChildren(n)
+		b.emphasis = prev
+		return
+	case atom.P, atom.Div:
+		// 段落の途中で始まった場合はそれまでの内容を1行として確定する
+		if b.hasText() {
+			b.flush()
+		}
+		b.inlines = nil
+		count := len(b.blocks)
+		b.walkChildren(n)
+		// 内容のない段落（<p><br></p> など）は空行として扱う
+		if len(b.inlines) > 0 || len(b.blocks) == count {
+			b.flush()
+		}
+		return
+	}
+
+	b.walkChildren(n)
+}
+
+// walkChildren は子ノードを順に走査します
+func (b *blockBuilder) walkChildren(n *nethtml.Node) {
+	for child := n.FirstChild; child != nil; child = child.NextSibling {
+		b.walk(child)
+	}
+}
+
+// appendInline は段落に文字列要素を追加します（同じ種類の文字列は連結する）
+func (b *blockBuilder) appendInline(inline Inline) {
+	if inline.Kind != InlineRuby && len(b.inlines) > 0 {
+		last := &b.inlines[len(b.inlines)-1]
+		if last.Kind == inline.Kind {
+			last.Text += inline.Text
+			return
+		}
+	}
+	b.inlines = append(b.inlines, inline)
+}
+
+// appendRuby は ruby 要素をルビ付きの文字列要素として追加します
+func (b *blockBuilder) appendRuby(n *nethtml.Node) {
+	var base, ruby strings.Builder
+	var collect func(n *nethtml.Node, inRt bool)
+	collect = func(n *nethtml.Node, inRt bool) {
+		for child := n.FirstChild; child != nil; child = child.NextSibling {
+			switch {
+			case child.Type == nethtml.TextNode && inRt:
+				ruby.WriteString(child.Data)
+			case child.Type == nethtml.TextNode:
+				base.WriteString(child.Data)
+			case child.Type != nethtml.ElementNode || child.DataAtom == atom.Rp:
+				// 括弧（rp）はルビに含めない
+			case child.DataAtom == atom.Rt:
+				collect(child, true)
+			default:
+				// rb や span などはルビベースとして扱う
+				collect(child, inRt)
+			}
+		}
+	}
+	collect(n, false)
+
+	baseText := strings.TrimSpace(base.String())
+	rubyText := strings.TrimSpace(ruby.String())
+	if baseText == "" {
+		return
+	}
+	if rubyText == "" {
+		b.appendInline(Inline{Kind: InlineText, Text: baseText})
+		return
+	}
+	b.appendInline(Inline{Kind: InlineRuby, Text: baseText, Ruby: rubyText})
+}
+
+// appendIllustration は img 要素を挿絵ブロックとして追加します
+func (b *blockBuilder) appendIllustration(n *nethtml.Node) {
+	var src string
+	for _, attr := range n.Attr {
+		if attr.Key == "src" {
+			src = attr.Val
+		}
+	}
+	if src == "" {
+		return
+	}
+
+	// 相対URL（//xxx.mitemin.net/... など）は絶対URLに変換する
+	if base, err := url.Parse(b.baseURL); err == nil && b.baseURL != "" {
+		if imgURL, err := base.Parse(src); err == nil {
+			src = imgURL.String()
+		}
+	} else if strings.HasPrefix(src, "//") {
+		src = "https:" + src
+	}
+
+	if b.hasText() {
+		b.flush()
+	}
+	b.inlines = nil
+	b.blocks = append(b.blocks, Block{Kind: BlockIllustration, Src: src})
+}
+
+// hasText は確定していない段落に文字列があるかどうかを返します
+func (b *blockBuilder) hasText() bool {
+	return !isBlankInlines(b.inlines)
+}
+
+// flush は確定していない段落を1行として追加します
+func (b *blockBuilder) flush() {
+	if isBlankInlines(b.inlines) {
+		b.blocks = append(b.blocks, Block{Kind: BlockBlank})
+	} else {
+		b.blocks = append(b.blocks, Block{Kind: BlockParagraph, Inlines: b.inlines})
+	}
+	b.inlines = nil
+}
+
+// isBlankInlines は段落が半角空白・ノーブレークスペース（&nbsp;）のみかどうかを返します（全角空白の字下げは内容として扱う）
+func isBlankInlines(inlines []Inline) bool {
+	for _, inline := range inlines {
+		if strings.Trim(inline.Text, " \t\u00a0") != "" {
+			return false
+		}
+	}
+	return true
+}
+
+// kanjiPattern はルビの区切り記号（｜）を省略できる文字にマッチします
+var kanjiPattern = regexp.MustCompile(`^[\p{Han}々仝〆〇ヶ]+$`)
+
+// escapeAozora は青空文庫形式で特別な意味を持つ文字を置き換えます
+func escapeAozora(text string) string {
+	return strings.NewReplacer("《", "≪", "》", "≫").Replace(text)
+}
+
+// renderAozoraInlines は段落内の要素を青空文庫形式の文字列に変換します
+func renderAozoraInlines(inlines []Inline) string {
+	var text strings.Builder
+	for _, inline := range inlines {
+		switch inline.Kind {
+		case InlineRuby:
+			// ルビのかかる文字列が漢字のみで、直前が漢字でない場合は｜を省略する
+			prev, _ := utf8.DecodeLastRuneInString(text.String())
+			if kanjiPattern.MatchString(inline.Text) && (text.Len() == 0 || !kanjiPattern.MatchString(string(prev))) {
+				text.WriteString(escapeAozora(inline.Text) + "《" + escapeAozora(inline.Ruby) + "》")
+			} else {
+				text.WriteString("｜" + escapeAozora(inline.Text) + "《" + escapeAozora(inline.Ruby) + "》")
+			}
+		case InlineEmphasis:
+			text.WriteString("［＃傍点］" + escapeAozora(inline.Text) + "［＃傍点終わり］")
+		default:
+			text.WriteString(escapeAozora(inline.Text))
+		}
+	}
+	return text.String()
+}
+
+// renderAozora はブロックの列を青空文庫形式のテキストに変換します
+func renderAozora(blocks []Block) string {
+	lines := make([]string, 0, len(blocks))
+	for _, block := range blocks {
+		switch block.Kind {
+		case BlockParagraph:
+			lines = append(lines, renderAozoraInlines(block.Inlines))
+		case BlockBlank:
+			lines = append(lines, "")
+		case BlockIllustration:
+			lines = append(lines, fmt.Sprintf("［＃挿絵（%s）入る］", block.Src))
+		case BlockSeparator:
+			lines = append(lines, "************************************************")
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// renderHTMLInlines は段落内の要素をHTMLに変換します
+func renderHTMLInlines(inlines []Inline) string {
+	var text strings.Builder
+	for _, inline := range inlines {
+		switch inline.Kind {
+		case InlineRuby:
+			fmt.Fprintf(&text, "<ruby>%s<rp>(</rp><rt>%s</rt><rp>)</rp></ruby>", html.EscapeString(inline.Text), html.EscapeString(inline.Ruby))
+		case InlineEmphasis:
+			fmt.Fprintf(&text, `<em class="emphasis">%s</em>`, html.EscapeString(inline.Text))
+		default:
+			text.WriteString(html.EscapeString(inline.Text))
+		}
+	}
+	return text.String()
+}
+
+// renderHTML はブロックの列をHTMLの断片に変換します
+func renderHTML(blocks []Block) string {
+	var text strings.Builder
+	for _, block := range blocks {
+		switch block.Kind {
+		case BlockParagraph:
+			text.WriteString("<p>" + renderHTMLInlines(block.Inlines) + "</p>\n")
+		case BlockBlank:
+			text.WriteString("<p><br></p>\n")
+		case BlockIllustration:
+			fmt.Fprintf(&text, "<p class=\"illustration\"><img src=\"%s\" alt=\"挿絵\"></p>\n", html.EscapeString(block.Src))
+		case BlockSeparator:
+			text.WriteString("<hr>\n")
+		}
+	}
+	return text.String()
+}
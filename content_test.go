@@ -0,0 +1,190 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+func newEpisodeDocument(t *testing.T, sections ...string) *goquery.Document {
+	t.Helper()
+
+	var body strings.Builder
+	body.WriteString(`<html><body><div class="p-novel__body">`)
+	for _, section := range sections {
+		body.WriteString(`<div class="js-novel-text p-novel__text">` + section + `</div>`)
+	}
+	body.WriteString(`</div></body></html>`)
+
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(body.String()))
+	if err != nil {
+		t.Fatalf("HTMLの解析に失敗しました: %v", err)
+	}
+	return doc
+}
+
+func TestExtractBlocks(t *testing.T) {
+	tests := []struct {
+		name     string
+		sections []string
+		expected []Block
+	}{
+		{
+			name:     "段落と空行",
+			sections: []string{"<p id=\"L1\">　一行目</p>\n<p id=\"L2\"><br /></p>\n<p id=\"L3\">　三行目</p>"},
+			expected: []Block{
+				{Kind: BlockParagraph, Inlines: []Inline{{Kind: InlineText, Text: "　一行目"}}},
+				{Kind: BlockBlank},
+				{Kind: BlockParagraph, Inlines: []Inline{{Kind: InlineText, Text: "　三行目"}}},
+			},
+		},
+		{
+			name:     "rb要素とrp要素を含むルビ",
+			sections: []string{`<p><ruby><rb>漢字</rb><rp>（</rp><rt>かんじ</rt><rp>）</rp></ruby>です</p>`},
+			expected: []Block{
+				{Kind: BlockParagraph, Inlines: []Inline{
+					{Kind: InlineRuby, Text: "漢字", Ruby: "かんじ"},
+					{Kind: InlineText, Text: "です"},
+				}},
+			},
+		},
+		{
+			name:     "属性に>を含むタグと入れ子のspan",
+			sections: []string{`<p data-note="a>b"><span><span>入れ子</span>の文字列</span></p>`},
+			expected: []Block{
+				{Kind: BlockParagraph, Inlines: []Inline{{Kind: InlineText, Text: "入れ子の文字列"}}},
+			},
+		},
+		{
+			name:     "&nbsp;だけの段落",
+			sections: []string{`<p>　一行目</p><p>&nbsp;</p><p> &nbsp; </p><p>　四行目</p>`},
+			expected: []Block{
+				{Kind: BlockParagraph, Inlines: []Inline{{Kind: InlineText, Text: "　一行目"}}},
+				{Kind: BlockBlank},
+				{Kind: BlockBlank},
+				{Kind: BlockParagraph, Inlines: []Inline{{Kind: InlineText, Text: "　四行目"}}},
+			},
+		},
+		{
+			name:     "文字参照",
+			sections: []string{`<p>&#x3042;&#12356;&hellip;&lt;&amp;&gt;</p>`},
+			expected: []Block{
+				{Kind: BlockParagraph, Inlines: []Inline{{Kind: InlineText, Text: "あい…<&>"}}},
+			},
+		},
+		{
+			name:     "傍点",
+			sections: []string{`<p>これは<em class="emphasisDots"><span>傍</span><span>点</span></em>です</p>`},
+			expected: []Block{
+				{Kind: BlockParagraph, Inlines: []Inline{
+					{Kind: InlineText, Text: "これは"},
+					{Kind: InlineEmphasis, Text: "傍点"},
+					{Kind: InlineText, Text: "です"},
+				}},
+			},
+		},
+		{
+			name:     "挿絵",
+			sections: []string{`<p><a href="//12345.mitemin.net/i67890/"><img src="//12345.mitemin.net/userpageimage/viewimagebig/icode/i67890/" alt="挿絵(By みてみん)" border="0" /></a></p>`},
+			expected: []Block{
+				{Kind: BlockIllustration, Src: "https://12345.mitemin.net/userpageimage/viewimagebig/icode/i67890/"},
+			},
+		},
+		{
+			name:     "前書きと本文の区切り",
+			sections: []string{`<p>前書き</p>`, `<p><br></p><p>本文</p>`},
+			expected: []Block{
+				{Kind: BlockParagraph, Inlines: []Inline{{Kind: InlineText, Text: "前書き"}}},
+				{Kind: BlockSeparator},
+				{Kind: BlockParagraph, Inlines: []Inline{{Kind: InlineText, Text: "本文"}}},
+			},
+		},
+		{
+			name:     "br区切りの旧形式",
+			sections: []string{"一行目<br />\n<br />\n三行目"},
+			expected: []Block{
+				{Kind: BlockParagraph, Inlines: []Inline{{Kind: InlineText, Text: "一行目"}}},
+				{Kind: BlockBlank},
+				{Kind: BlockParagraph, Inlines: []Inline{{Kind: InlineText, Text: "三行目"}}},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc := newEpisodeDocument(t, tt.sections...)
+			blocks, err := extractBlocks(doc, "https://ncode.syosetu.com/n1234ab/1/")
+			if err != nil {
+				t.Fatalf("extractBlocks() error = %v", err)
+			}
+			if !reflect.DeepEqual(blocks, tt.expected) {
+				t.Errorf("extractBlocks() = %#v, want %#v", blocks, tt.expected)
+			}
+		})
+	}
+}
+
+func TestExtractBlocks_Empty(t *testing.T) {
+	doc := newEpisodeDocument(t)
+	if _, err := extractBlocks(doc, ""); err == nil {
+		t.Error("extractBlocks() error = nil, want error")
+	}
+}
+
+func TestRenderAozora(t *testing.T) {
+	tests := []struct {
+		name     string
+		blocks   []Block
+		expected string
+	}{
+		{
+			name: "漢字のみのルビ",
+			blocks: []Block{
+				{Kind: BlockParagraph, Inlines: []Inline{
+					{Kind: InlineText, Text: "これは"},
+					{Kind: InlineRuby, Text: "漢字", Ruby: "かんじ"},
+				}},
+			},
+			expected: "これは漢字《かんじ》",
+		},
+		{
+			name: "直前が漢字のルビ",
+			blocks: []Block{
+				{Kind: BlockParagraph, Inlines: []Inline{
+					{Kind: InlineText, Text: "日本"},
+					{Kind: InlineRuby, Text: "漢字", Ruby: "かんじ"},
+				}},
+			},
+			expected: "日本｜漢字《かんじ》",
+		},
+		{
+			name: "漢字以外を含むルビ",
+			blocks: []Block{
+				{Kind: BlockParagraph, Inlines: []Inline{{Kind: InlineRuby, Text: "ほんと", Ruby: "マジ"}}},
+			},
+			expected: "｜ほんと《マジ》",
+		},
+		{
+			name: "傍点・挿絵・区切り・空行",
+			blocks: []Block{
+				{Kind: BlockParagraph, Inlines: []Inline{{Kind: InlineEmphasis, Text: "傍点"}}},
+				{Kind: BlockBlank},
+				{Kind: BlockIllustration, Src: "https://example.com/a.jpg"},
+				{Kind: BlockSeparator},
+				{Kind: BlockParagraph, Inlines: []Inline{{Kind: InlineText, Text: "《括弧》"}}},
+			},
+			expected: "［＃傍点］傍点［＃傍点終わり］\n\n［＃挿絵（https://example.com/a.jpg）入る］\n************************************************\n≪括弧≫",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := renderAozora(tt.blocks)
+			if result != tt.expected {
+				t.Errorf("renderAozora() = %q, want %q", result, tt.expected)
+			}
+		})
+	}
+}
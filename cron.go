@@ -0,0 +1,176 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// schedule は次に実行する日時を決める定期実行の予定が実装するインターフェース
+type schedule interface {
+	// next は from より後で最初に実行する日時を返します
+	next(from time.Time) time.Time
+}
+
+// intervalSchedule は一定の間隔で実行する予定を表す構造体
+type intervalSchedule struct {
+	interval time.Duration
+}
+
+func (s intervalSchedule) next(from time.Time) time.Time {
+	return from.Add(s.interval)
+}
+
+// cronSchedule はcron形式（分 時 日 月 曜日）で指定した予定を表す構造体
+// 各フィールドは実行する値の集合をビットで表す
+type cronSchedule struct {
+	minute, hour, day, month, weekday uint64
+	// dayAny・weekdayAny は日・曜日が * の場合に true（両方指定された場合はどちらかに一致すれば実行する）
+	dayAny, weekdayAny bool
+}
+
+// cronField はcron形式の各フィールドの範囲を表す構造体
+type cronField struct {
+	name     string
+	min, max int
+}
+
+var cronFields = []cronField{
+	{"分", 0, 59},
+	{"時", 0, 23},
+	{"日", 1, 31},
+	{"月", 1, 12},
+	{"曜日", 0, 7}, // 0 と 7 はどちらも日曜日
+}
+
+// parseCron はcron形式の文字列（例: "0 */3 * * *"）を解析します
+// 各フィールドでは *・数値・範囲（1-5）・間隔（*/15・1-30/5）・カンマ区切りの列挙が使えます
+func parseCron(expr string) (cronSchedule, error) {
+	fields := strings.Fields(expr)
+	if len(fields) != len(cronFields) {
+		return cronSchedule{}, fmt.Errorf("cron形式は「分 時 日 月 曜日」の5つのフィールドで指定してください: %q", expr)
+	}
+
+	var bits [5]uint64
+	for i, field := range fields {
+		b, err := parseCronField(field, cronFields[i])
+		if err != nil {
+			return cronSchedule{}, err
+		}
+		bits[i] = b
+	}
+
+	// 7 は日曜日として扱う
+	weekday := bits[4]
+	if weekday&(1<<7) != 0 {
+		weekday = weekday&^(1<<7) | 1
+	}
+	return cronSchedule{
+		minute:     bits[0],
+		hour:       bits[1],
+		day:        bits[2],
+		month:      bits[3],
+		weekday:    weekday,
+		dayAny:     fields[2] == "*",
+		weekdayAny: fields[4] == "*",
+	}, nil
+}
+
+// parseCronField はcron形式の1つのフィールドを解析し、実行する値の集合を返します
+func parseCronField(field string, spec cronField) (uint64, error) {
+	var bits uint64
+	for _, part := range strings.Split(field, ",") {
+		rangePart, stepPart, hasStep := strings.Cut(part, "/")
+		step := 1
+		if hasStep {
+			n, err := strconv.Atoi(stepPart)
+			if err != nil || n <= 0 {
+				return 0, fmt.Errorf("%sの間隔が正しくありません: %q", spec.name, part)
+			}
+			step = n
+		}
+
+		low, high := spec.min, spec.max
+		if rangePart != "*" {
+			lowPart, highPart, isRange := strings.Cut(rangePart, "-")
+			var err error
+			if low, err = strconv.Atoi(lowPart); err != nil {
+				return 0, fmt.Errorf("%sの値が正しくありません: %q", spec.name, part)
+			}
+			high = low
+			if isRange {
+				if high, err = strconv.Atoi(highPart); err != nil {
+					return 0, fmt.Errorf("%sの値が正しくありません: %q", spec.name, part)
+				}
+			} else if hasStep {
+				// 「5/15」は5から最大値まで15ごと
+				high = spec.max
+			}
+		}
+		if low < spec.min || high > spec.max || low > high {
+			return 0, fmt.Errorf("%sは%dから%dの間で指定してください: %q", spec.name, spec.min, spec.max, part)
+		}
+
+		for v := low; v <= high; v += step {
+			bits |= 1 << v
+		}
+	}
+	return bits, nil
+}
+
+// matchDay は日付が日・曜日の指定に一致するかどうかを返します
+func (s cronSchedule) matchDay(t time.Time) bool {
+	dayMatch := s.day&(1<<t.Day()) != 0
+	weekdayMatch := s.weekday&(1<<int(t.Weekday())) != 0
+	switch {
+	case s.dayAny && s.weekdayAny:
+		return true
+	case s.dayAny:
+		return weekdayMatch
+	case s.weekdayAny:
+		return dayMatch
+	default:
+		return dayMatch || weekdayMatch
+	}
+}
+
+// hasIntervalShorterThan は1年分の実行日時を調べ、連続する実行の間隔が d より短い箇所があるかどうかを返します
+func (s cronSchedule) hasIntervalShorterThan(d time.Duration) bool {
+	// 閏日を含む年を夏時間の影響を受けない UTC で調べる
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	end := start.AddDate(1, 0, 0)
+	t := s.next(start.Add(-time.Minute))
+	for !t.IsZero() && t.Before(end) {
+		n := s.next(t)
+		if n.IsZero() {
+			return false
+		}
+		if n.Sub(t) < d {
+			return true
+		}
+		t = n
+	}
+	return false
+}
+
+func (s cronSchedule) next(from time.Time) time.Time {
+	t := from.Truncate(time.Minute).Add(time.Minute)
+	// 一致しない月・日・時は丸ごと飛ばす（存在しない日付の指定でも終わるように上限を設ける）
+	limit := t.AddDate(5, 0, 0)
+	for t.Before(limit) {
+		switch {
+		case s.month&(1<<int(t.Month())) == 0:
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, t.Location())
+		case !s.matchDay(t):
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, t.Location())
+		case s.hour&(1<<t.Hour()) == 0:
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, t.Location())
+		case s.minute&(1<<t.Minute()) == 0:
+			t = t.Add(time.Minute)
+		default:
+			return t
+		}
+	}
+	return time.Time{}
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseCron_Next(t *testing.T) {
+	// 2024-01-01 は月曜日
+	from := time.Date(2024, 1, 1, 10, 7, 30, 0, time.UTC)
+	tests := []struct {
+		name string
+		expr string
+		want time.Time
+	}{
+		{"毎分", "* * * * *", time.Date(2024, 1, 1, 10, 8, 0, 0, time.UTC)},
+		{"3時間ごと", "0 */3 * * *", time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)},
+		{"分の列挙", "5,30 * * * *", time.Date(2024, 1, 1, 10, 30, 0, 0, time.UTC)},
+		{"範囲と間隔", "0 9-17/4 * * *", time.Date(2024, 1, 1, 13, 0, 0, 0, time.UTC)},
+		{"翌日", "0 6 * * *", time.Date(2024, 1, 2, 6, 0, 0, 0, time.UTC)},
+		{"曜日", "0 0 * * 5", time.Date(2024, 1, 5, 0, 0, 0, 0, time.UTC)},
+		{"7は日曜日", "0 0 * * 7", time.Date(2024, 1, 7, 0, 0, 0, 0, time.UTC)},
+		{"日と曜日はどちらかに一致", "0 0 15 * 3", time.Date(2024, 1, 3, 0, 0, 0, 0, time.UTC)},
+		{"月をまたぐ", "0 0 1 3 *", time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)},
+		{"うるう日", "0 0 29 2 *", time.Date(2024, 2, 29, 0, 0, 0, 0, time.UTC)},
+		{"存在しない日付", "0 0 31 2 *", time.Time{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sched, err := parseCron(tt.expr)
+			if err != nil {
+				t.Fatalf("parseCron(%q) error = %v", tt.expr, err)
+			}
+			if got := sched.next(from); !got.Equal(tt.want) {
+				t.Errorf("next() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseCron_Invalid(t *testing.T) {
+	tests := []struct {
+		name string
+		expr string
+	}{
+		{"フィールドが足りない", "0 * * *"},
+		{"範囲外の分", "60 * * * *"},
+		{"範囲外の日", "0 0 0 * *"},
+		{"逆順の範囲", "0 10-5 * * *"},
+		{"間隔が0", "*/0 * * * *"},
+		{"数値でない", "a * * * *"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := parseCron(tt.expr); err == nil {
+				t.Errorf("parseCron(%q) error = nil, want error", tt.expr)
+			}
+		})
+	}
+}
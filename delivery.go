@@ -0,0 +1,595 @@
+package main
+
+import (
+	"bytes"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 送信先の種類
+const (
+	deliveryEmail  = "email"  // SMTPでメールに添付して送る（Kindleのメール送信など）
+	deliveryFolder = "folder" // 接続した電子書籍リーダーや同期フォルダにコピーする
+)
+
+// defaultMailMaxSize は1通のメールの大きさの既定の上限（多くのメールサービスは10〜25MBまで）
+const defaultMailMaxSize = 10 << 20
+
+// mailOverhead はメールのヘッダーと本文の大きさの見積もり、attachmentOverhead は添付ファイル1件ごとのヘッダーの大きさの見積もり
+const (
+	mailOverhead       = 4096
+	attachmentOverhead = 1024
+)
+
+// smtpTimeout はSMTPサーバーとの1回のやり取り全体（接続から送信の完了まで）の制限時間
+const smtpTimeout = 5 * time.Minute
+
+// defaultDeliveryFormats は送る形式を指定しない場合に送る形式
+var defaultDeliveryFormats = []string{"epub", "txt"}
+
+// DeliveryTarget はダウンロードした小説のファイルを送る先を表す構造体
+type DeliveryTarget struct {
+	Name    string         `json:"name"`
+	Kind    string         `json:"kind"` // "email" または "folder"
+	Enabled bool           `json:"enabled"`
+	Formats []string       `json:"formats"` // 送る形式（空の場合は epub と txt）
+	Email   EmailDelivery  `json:"email"`
+	Folder  FolderDelivery `json:"folder"`
+}
+
+// EmailDelivery はメールで送る場合の設定を表す構造体（パスワードは設定ファイルにそのまま保存される）
+type EmailDelivery struct {
+	Host     string   `json:"host"`
+	Port     int      `json:"port"` // 0 の場合は TLS なら465、そうでなければ587
+	TLS      bool     `json:"tls"`  // 接続時からTLSを使う（false の場合はサーバーが対応していればSTARTTLSを使う）
+	Username string   `json:"username"`
+	Password string   `json:"password"`
+	From     string   `json:"from"`
+	To       []string `json:"to"`
+	MaxSize  int64    `json:"maxSize"` // 1通のメールの大きさの上限（バイト。0 の場合は10MB）。超える場合は複数のメールに分けて送る
+}
+
+// FolderDelivery はフォルダにコピーする場合の設定を表す構造体
+type FolderDelivery struct {
+	Path   string `json:"path"`   // コピー先のフォルダ（電子書籍リーダーのマウント先や同期フォルダ）
+	Layout string `json:"layout"` // コピー先でのファイル名のテンプレート（例: "{author}/{title}"。空の場合は小説のタイトル）
+}
+
+// connected はコピー先のフォルダがあるかどうか（電子書籍リーダーなどの機器が接続されているか）を返します
+func (c FolderDelivery) connected() bool {
+	info, err := os.Stat(c.Path)
+	return err == nil && info.IsDir()
+}
+
+// DeliveryResult は送信先1件分の送信結果を表す構造体
+type DeliveryResult struct {
+	Target string   `json:"target"`
+	Files  []string `json:"files"` // 送ったファイル（保存先フォルダからの相対パス）
+	Error  string   `json:"error,omitempty"`
+}
+
+// deliveryState は送信先ごとに送ったファイルのハッシュの記録（送信先の名前 → 保存先フォルダからの相対パス → SHA-256）
+type deliveryState map[string]map[string]string
+
+// mailAttachment はメールに添付するファイル（分割した場合はその一部）を表す構造体
+type mailAttachment struct {
+	file ManifestFile
+	name string
+	data []byte
+}
+
+// port は接続するSMTPサーバーのポート番号を返します
+func (e EmailDelivery) port() int {
+	if e.Port != 0 {
+		return e.Port
+	}
+	if e.TLS {
+		return 465
+	}
+	return 587
+}
+
+// maxSize は1通のメールの大きさの上限を返します
+func (e EmailDelivery) maxSize() int64 {
+	if e.MaxSize > 0 {
+		return e.MaxSize
+	}
+	return defaultMailMaxSize
+}
+
+// formats は送る形式を返します
+func (t DeliveryTarget) formats() []string {
+	if len(t.Formats) == 0 {
+		return defaultDeliveryFormats
+	}
+	return t.Formats
+}
+
+// validate は送信先の設定に誤りがないか確認します
+func (t DeliveryTarget) validate() error {
+	if t.Name == "" {
+		return fmt.Errorf("送信先の名前を入力してください")
+	}
+	for _, format := range t.Formats {
+		if opdsFileTypes[format] == "" {
+			return fmt.Errorf("送ることのできない形式です: %s", format)
+		}
+	}
+
+	switch t.Kind {
+	case deliveryEmail:
+		if t.Email.Host == "" {
+			return fmt.Errorf("SMTPサーバーを入力してください")
+		}
+		if _, err := mail.ParseAddress(t.Email.From); err != nil {
+			return fmt.Errorf("送信元のメールアドレスが正しくありません: %q", t.Email.From)
+		}
+		if len(t.Email.To) == 0 {
+			return fmt.Errorf("宛先のメールアドレスを入力してください")
+		}
+		for _, to := range t.Email.To {
+			if _, err := mail.ParseAddress(to); err != nil {
+				return fmt.Errorf("宛先のメールアドレスが正しくありません: %q", to)
+			}
+		}
+		if t.Email.MaxSize < 0 || (t.Email.MaxSize > 0 && maxAttachmentData(t.Email.MaxSize) < 64<<10) {
+			return fmt.Errorf("1通のメールの大きさの上限が小さすぎます: %d", t.Email.MaxSize)
+		}
+	case deliveryFolder:
+		if !filepath.IsAbs(t.Folder.Path) {
+			return fmt.Errorf("コピー先のフォルダは絶対パスで指定してください: %q", t.Folder.Path)
+		}
+		if t.Folder.Layout != "" {
+			if err := namingTemplate(t.Folder.Layout).validate(false); err != nil {
+				return err
+			}
+		}
+	default:
+		return fmt.Errorf("不明な送信先の種類です: %q", t.Kind)
+	}
+	return nil
+}
+
+// pendingFiles は送信先にまだ送っていない（または前回送ってから変わった）小説全体のファイルを返します
+func (t DeliveryTarget) pendingFiles(manifest *Manifest, sent map[string]string, force bool) []ManifestFile {
+	formats := t.formats()
+	var files []ManifestFile
+	for _, file := range manifest.bookFiles() {
+		if !slices.Contains(formats, file.Format) {
+			continue
+		}
+		if !force && sent[file.Path] == file.SHA256 {
+			continue
+		}
+		files = append(files, file)
+	}
+	return files
+}
+
+// deliveryPath は送ったファイルの記録を保存するファイルのパスを返します
+func (s *novelStore) deliveryPath() string {
+	return filepath.Join(s.dir(), "delivery.json")
+}
+
+// loadDeliveryState は送ったファイルの記録を読み込みます（まだ送っていない場合は空の記録）
+func (s *novelStore) loadDeliveryState() (deliveryState, error) {
+	data, err := os.ReadFile(s.deliveryPath())
+	if errors.Is(err, os.ErrNotExist) {
+		return deliveryState{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("送信の記録の読み込みに失敗しました: %w", err)
+	}
+	state := deliveryState{}
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, fmt.Errorf("送信の記録の解析に失敗しました: %w", err)
+	}
+	return state, nil
+}
+
+// saveDeliveryState は送ったファイルの記録を保存します
+func (s *novelStore) saveDeliveryState(state deliveryState) error {
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return fmt.Errorf("送信の記録のJSON変換に失敗しました: %w", err)
+	}
+	return writeFileAtomic(s.deliveryPath(), data, s.tmpDir())
+}
+
+// DeliverNovel は保存済みの小説のファイルを有効な送信先に送ります（フロントエンド・CLI用）
+// force でない場合は、前回送ってから変わったファイルだけを送ります
+func (a *App) DeliverNovel(folder, ncode string, force bool) ([]DeliveryResult, error) {
+	store, manifest, err := openNovelStoreByNCode(folder, ncode)
+	if err != nil {
+		return nil, err
+	}
+	return a.deliverNovel(store, manifest, a.currentSettings().Deliveries, force)
+}
+
+// deliverNovel は送信先ごとにファイルを送り、送れたファイルを記録します
+// 送信先ごとの失敗は結果に記録し、ほかの送信先への送信は続けます
+func (a *App) deliverNovel(store *novelStore, manifest *Manifest, targets []DeliveryTarget, force bool) ([]DeliveryResult, error) {
+	state, err := store.loadDeliveryState()
+	if err != nil {
+		return nil, err
+	}
+
+	results := []DeliveryResult{}
+	for _, target := range targets {
+		if !target.Enabled {
+			continue
+		}
+		files := target.pendingFiles(manifest, state[target.Name], force)
+		if len(files) == 0 {
+			continue
+		}
+		// 機器が接続されていない（コピー先のフォルダがない）場合は何もせず、接続されているときに送る
+		if target.Kind == deliveryFolder && !target.Folder.connected() {
+			continue
+		}
+
+		a.emit("log", fmt.Sprintf("%s を %s に送っています...", manifest.Title, target.Name))
+		var sent []ManifestFile
+		var sendErr error
+		switch target.Kind {
+		case deliveryEmail:
+			sent, sendErr = a.deliverByEmail(target.Email, store, manifest, files)
+		case deliveryFolder:
+			sent, sendErr = deliverToFolder(target.Folder, store, manifest, files)
+		default:
+			sendErr = fmt.Errorf("不明な送信先の種類です: %q", target.Kind)
+		}
+
+		result := DeliveryResult{Target: target.Name, Files: []string{}}
+		if len(sent) > 0 && state[target.Name] == nil {
+			state[target.Name] = map[string]string{}
+		}
+		for _, file := range sent {
+			state[target.Name][file.Path] = file.SHA256
+			result.Files = append(result.Files, file.Path)
+		}
+		if sendErr != nil {
+			result.Error = sendErr.Error()
+			a.emit("log", fmt.Sprintf("%s を %s に送れませんでした: %v", manifest.Title, target.Name, sendErr))
+		} else {
+			a.emit("log", fmt.Sprintf("%s を %s に送りました（%d件）", manifest.Title, target.Name, len(sent)))
+		}
+		results = append(results, result)
+	}
+
+	if len(results) > 0 {
+		if err := store.saveDeliveryState(state); err != nil {
+			return results, err
+		}
+	}
+	return results, nil
+}
+
+// defaultDeliveryLayout は送信先でのファイル名の既定のテンプレート
+// 保存先のファイル名（all.txt など）は小説ごとに区別できないため、小説のタイトルを使う
+const defaultDeliveryLayout = "{title}"
+
+// deliveryFileName は送信先でのファイル名をテンプレートから作成します（空の場合は既定のテンプレートを使う）
+func deliveryFileName(layout string, novel *Novel, file ManifestFile) string {
+	if layout == "" {
+		layout = defaultDeliveryLayout
+	}
+	return namingTemplate(layout).render(novel, nil, strings.TrimPrefix(path.Ext(file.Path), "."))
+}
+
+// deliverToFolder はファイルをフォルダにコピーし、コピーできたファイルを返します
+// コピー先のフォルダがあること（機器が接続されていること）は呼び出し側で確認します
+func deliverToFolder(config FolderDelivery, store *novelStore, manifest *Manifest, files []ManifestFile) ([]ManifestFile, error) {
+	novel := manifest.novel()
+	sent := []ManifestFile{}
+	for _, file := range files {
+		name := deliveryFileName(config.Layout, novel, file)
+		data, err := os.ReadFile(store.path(file.Path))
+		if err != nil {
+			return sent, fmt.Errorf("ファイルの読み込みに失敗しました: %w", err)
+		}
+		dest := filepath.Join(config.Path, filepath.FromSlash(name))
+		if err := writeFileAtomic(dest, data, ""); err != nil {
+			return sent, fmt.Errorf("%s へのコピーに失敗しました: %w", dest, err)
+		}
+		sent = append(sent, file)
+	}
+	return sent, nil
+}
+
+// deliverByEmail はファイルをメールに添付して送り、送れたファイルを返します
+// 1通に収まらない場合は複数のメールに分け、1つで上限を超えるテキストは改行の位置で分割します
+func (a *App) deliverByEmail(config EmailDelivery, store *novelStore, manifest *Manifest, files []ManifestFile) ([]ManifestFile, error) {
+	maxSize := config.maxSize()
+	novel := manifest.novel()
+	var errs []error
+	var attachments []mailAttachment
+	for _, file := range files {
+		data, err := os.ReadFile(store.path(file.Path))
+		if err != nil {
+			errs = append(errs, fmt.Errorf("ファイルの読み込みに失敗しました: %w", err))
+			continue
+		}
+		parts, err := splitAttachment(mailAttachment{file: file, name: deliveryFileName("", novel, file), data: data}, maxSize)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		attachments = append(attachments, parts...)
+	}
+
+	// 分割したファイルはすべての部分を送れた場合だけ送ったものとする
+	remaining := map[string]int{}
+	for _, attachment := range attachments {
+		remaining[attachment.file.Path]++
+	}
+
+	groups := groupAttachments(attachments, maxSize)
+	sent := []ManifestFile{}
+	for i, group := range groups {
+		subject := manifest.Title
+		if len(groups) > 1 {
+			subject = fmt.Sprintf("%s（%d/%d）", manifest.Title, i+1, len(groups))
+		}
+		message, err := buildMail(config.From, config.To, subject, mailBody(manifest, group), group, time.Now())
+		if err == nil {
+			err = sendMail(config, message)
+		}
+		if err != nil {
+			return sent, errors.Join(append(errs, err)...)
+		}
+
+		for _, attachment := range group {
+			remaining[attachment.file.Path]--
+			if remaining[attachment.file.Path] == 0 {
+				sent = append(sent, attachment.file)
+			}
+		}
+	}
+	return sent, errors.Join(errs...)
+}
+
+// encodedSize は data バイトをBase64で76文字ごとに改行して添付した場合の大きさを返します（57バイトが1行になる）
+func encodedSize(n int) int64 {
+	return int64((n+56)/57) * 78
+}
+
+// maxAttachmentData は1通のメールに添付できるファイル1件の大きさ（エンコード前）を返します
+func maxAttachmentData(maxSize int64) int {
+	return int((maxSize - mailOverhead - attachmentOverhead) / 78 * 57)
+}
+
+// splitAttachment は1通のメールに収まらない添付ファイルを分割します
+// 分割できるのはテキストだけで、ほかの形式は収まらない場合にエラーを返します
+func splitAttachment(attachment mailAttachment, maxSize int64) ([]mailAttachment, error) {
+	limit := maxAttachmentData(maxSize)
+	if len(attachment.data) <= limit {
+		return []mailAttachment{attachment}, nil
+	}
+	if attachment.file.Format != "txt" {
+		return nil, fmt.Errorf("%s は1通のメールに収まりません（%dバイト）", attachment.name, len(attachment.data))
+	}
+
+	chunks, err := splitText(attachment.data, limit, attachment.file.Encoding)
+	if err != nil {
+		return nil, fmt.Errorf("%s を分割できませんでした: %w", attachment.name, err)
+	}
+	ext := path.Ext(attachment.name)
+	base := strings.TrimSuffix(attachment.name, ext)
+	parts := make([]mailAttachment, len(chunks))
+	for i, chunk := range chunks {
+		parts[i] = mailAttachment{file: attachment.file, name: fmt.Sprintf("%s_%d%s", base, i+1, ext), data: chunk}
+	}
+	return parts, nil
+}
+
+// splitText はテキストを改行の直後で limit バイト以下に分割します
+// Shift-JIS の2バイト目に改行のバイトは現れないため、UTF-16LE 以外は1バイト単位で改行を探せます
+func splitText(data []byte, limit int, encoding string) ([][]byte, error) {
+	newline, unit := []byte("\n"), 1
+	if strings.EqualFold(encoding, "UTF-16LE") {
+		newline, unit = []byte("\n\x00"), 2
+	}
+
+	var chunks [][]byte
+	for len(data) > limit {
+		cut := -1
+		window := data[:limit]
+		for {
+			i := bytes.LastIndex(window, newline)
+			if i < 0 {
+				break
+			}
+			if i%unit == 0 {
+				cut = i + len(newline)
+				break
+			}
+			window = window[:i+len(newline)-1]
+		}
+		if cut <= 0 {
+			return nil, fmt.Errorf("改行のない部分が長すぎます")
+		}
+		chunks = append(chunks, data[:cut])
+		data = data[cut:]
+	}
+	return append(chunks, data), nil
+}
+
+// groupAttachments は添付ファイルを順に、1通の大きさの上限を超えないようにメールごとにまとめます
+func groupAttachments(attachments []mailAttachment, maxSize int64) [][]mailAttachment {
+	var groups [][]mailAttachment
+	var size int64
+	for _, attachment := range attachments {
+		n := attachmentOverhead + encodedSize(len(attachment.data))
+		if len(groups) == 0 || size+n > maxSize {
+			groups = append(groups, nil)
+			size = mailOverhead
+		}
+		groups[len(groups)-1] = append(groups[len(groups)-1], attachment)
+		size += n
+	}
+	return groups
+}
+
+// mailBody はメールの本文（小説の情報と添付ファイルの一覧）を作成します
+func mailBody(manifest *Manifest, attachments []mailAttachment) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s（%s）\n%s\n\n", manifest.Title, manifest.Author, manifest.URL)
+	for _, attachment := range attachments {
+		fmt.Fprintf(&b, "- %s\n", attachment.name)
+	}
+	return b.String()
+}
+
+// buildMail は添付ファイル付きのメール（multipart/mixed）を作成します
+// 件名と日本語のファイル名はRFC 2047・RFC 2231の形式でエンコードします
+func buildMail(from string, to []string, subject, body string, attachments []mailAttachment, date time.Time) ([]byte, error) {
+	fromAddr, err := mail.ParseAddress(from)
+	if err != nil {
+		return nil, fmt.Errorf("送信元のメールアドレスが正しくありません: %w", err)
+	}
+	var toAddrs []string
+	for _, addr := range to {
+		toAddr, err := mail.ParseAddress(addr)
+		if err != nil {
+			return nil, fmt.Errorf("宛先のメールアドレスが正しくありません: %w", err)
+		}
+		toAddrs = append(toAddrs, toAddr.String())
+	}
+
+	boundary := fmt.Sprintf("narou-download-%d", date.UnixNano())
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "From: %s\r\n", fromAddr)
+	fmt.Fprintf(&b, "To: %s\r\n", strings.Join(toAddrs, ", "))
+	fmt.Fprintf(&b, "Subject: %s\r\n", mime.BEncoding.Encode("UTF-8", subject))
+	fmt.Fprintf(&b, "Date: %s\r\n", date.Format(time.RFC1123Z))
+	b.WriteString("MIME-Version: 1.0\r\n")
+	fmt.Fprintf(&b, "Content-Type: %s\r\n\r\n", mime.FormatMediaType("multipart/mixed", map[string]string{"boundary": boundary}))
+
+	fmt.Fprintf(&b, "--%s\r\n", boundary)
+	b.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+	b.WriteString("Content-Transfer-Encoding: base64\r\n\r\n")
+	writeBase64(&b, []byte(body))
+
+	for _, attachment := range attachments {
+		fmt.Fprintf(&b, "--%s\r\n", boundary)
+		// 古いメールソフト向けに name にもRFC 2047の形式でファイル名を入れる
+		fmt.Fprintf(&b, "Content-Type: %s; name=\"%s\"\r\n", opdsFileType(attachment.file), mime.BEncoding.Encode("UTF-8", attachment.name))
+		fmt.Fprintf(&b, "Content-Disposition: %s\r\n", mime.FormatMediaType("attachment", map[string]string{"filename": attachment.name}))
+		b.WriteString("Content-Transfer-Encoding: base64\r\n\r\n")
+		writeBase64(&b, attachment.data)
+	}
+	fmt.Fprintf(&b, "--%s--\r\n", boundary)
+	return b.Bytes(), nil
+}
+
+// writeBase64 は data をBase64で76文字ごとに改行して書き込みます
+func writeBase64(b *bytes.Buffer, data []byte) {
+	encoded := base64.StdEncoding.EncodeToString(data)
+	for len(encoded) > 76 {
+		b.WriteString(encoded[:76])
+		b.WriteString("\r\n")
+		encoded = encoded[76:]
+	}
+	b.WriteString(encoded)
+	b.WriteString("\r\n")
+}
+
+// sendMail はSMTPサーバーにメールを送信します
+// TLS でない場合もサーバーが対応していればSTARTTLSで暗号化し、ユーザー名がある場合はPLAIN認証を使います
+func sendMail(config EmailDelivery, message []byte) error {
+	addr := net.JoinHostPort(config.Host, strconv.Itoa(config.port()))
+	dialer := &net.Dialer{Timeout: 30 * time.Second}
+	var conn net.Conn
+	var err error
+	if config.TLS {
+		conn, err = tls.DialWithDialer(dialer, "tcp", addr, &tls.Config{ServerName: config.Host})
+	} else {
+		conn, err = dialer.Dial("tcp", addr)
+	}
+	if err != nil {
+		return fmt.Errorf("SMTPサーバーに接続できませんでした: %w", err)
+	}
+	conn.SetDeadline(time.Now().Add(smtpTimeout))
+
+	client, err := smtp.NewClient(conn, config.Host)
+	if err != nil {
+		conn.Close()
+		return fmt.Errorf("SMTPサーバーに接続できませんでした: %w", err)
+	}
+	defer client.Close()
+
+	if !config.TLS {
+		if ok, _ := client.Extension("STARTTLS"); ok {
+			if err := client.StartTLS(&tls.Config{ServerName: config.Host}); err != nil {
+				return fmt.Errorf("STARTTLSに失敗しました: %w", err)
+			}
+		}
+	}
+	if config.Username != "" {
+		// 暗号化されていない接続では localhost 以外への認証を net/smtp が拒否する
+		if err := client.Auth(smtp.PlainAuth("", config.Username, config.Password, config.Host)); err != nil {
+			return fmt.Errorf("SMTPの認証に失敗しました: %w", err)
+		}
+	}
+
+	from, _ := mail.ParseAddress(config.From)
+	if err := client.Mail(from.Address); err != nil {
+		return fmt.Errorf("送信元が受け付けられませんでした: %w", err)
+	}
+	for _, to := range config.To {
+		addr, _ := mail.ParseAddress(to)
+		if err := client.Rcpt(addr.Address); err != nil {
+			return fmt.Errorf("宛先 %s が受け付けられませんでした: %w", addr.Address, err)
+		}
+	}
+	w, err := client.Data()
+	if err != nil {
+		return fmt.Errorf("メールの送信に失敗しました: %w", err)
+	}
+	if _, err := w.Write(message); err != nil {
+		w.Close()
+		return fmt.Errorf("メールの送信に失敗しました: %w", err)
+	}
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("メールの送信に失敗しました: %w", err)
+	}
+	return client.Quit()
+}
+
+// SaveDeliveryTarget は送信先を追加または上書きし、設定を返します（フロントエンド用）
+func (a *App) SaveDeliveryTarget(target DeliveryTarget) (Settings, error) {
+	target.Name = strings.TrimSpace(target.Name)
+	if err := target.validate(); err != nil {
+		return a.currentSettings(), fmt.Errorf("送信先 %s の設定が正しくありません: %w", target.Name, err)
+	}
+
+	return a.updateSettings(func(settings *Settings) {
+		if i := slices.IndexFunc(settings.Deliveries, func(t DeliveryTarget) bool { return t.Name == target.Name }); i >= 0 {
+			settings.Deliveries[i] = target
+		} else {
+			settings.Deliveries = append(settings.Deliveries, target)
+		}
+	})
+}
+
+// DeleteDeliveryTarget は送信先を削除し、設定を返します（フロントエンド用）
+func (a *App) DeleteDeliveryTarget(name string) (Settings, error) {
+	return a.updateSettings(func(settings *Settings) {
+		settings.Deliveries = slices.DeleteFunc(settings.Deliveries, func(t DeliveryTarget) bool { return t.Name == name })
+	})
+}
@@ -0,0 +1,396 @@
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net"
+	"net/mail"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// fakeSMTPServer はメールを受け取って記録するだけのSMTPサーバー（テスト用）
+type fakeSMTPServer struct {
+	listener net.Listener
+	mu       sync.Mutex
+	messages [][]byte
+}
+
+// newFakeSMTPServer はSMTPサーバーを開始し、接続するための設定を返します
+func newFakeSMTPServer(t *testing.T) (*fakeSMTPServer, EmailDelivery) {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen() error = %v", err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	s := &fakeSMTPServer{listener: listener}
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go s.handle(conn)
+		}
+	}()
+
+	port := listener.Addr().(*net.TCPAddr).Port
+	return s, EmailDelivery{Host: "127.0.0.1", Port: port, From: "送信元 <from@example.com>", To: []string{"reader@example.com"}}
+}
+
+// handle は1つの接続でSMTPのコマンドに応答します
+func (s *fakeSMTPServer) handle(conn net.Conn) {
+	defer conn.Close()
+	c := textproto.NewConn(conn)
+	c.PrintfLine("220 localhost ESMTP")
+	for {
+		line, err := c.ReadLine()
+		if err != nil {
+			return
+		}
+		command, _, _ := strings.Cut(strings.ToUpper(line), " ")
+		switch command {
+		case "EHLO", "HELO":
+			c.PrintfLine("250 localhost")
+		case "MAIL", "RCPT", "RSET", "NOOP":
+			c.PrintfLine("250 OK")
+		case "DATA":
+			c.PrintfLine("354 End data with <CR><LF>.<CR><LF>")
+			data, err := io.ReadAll(c.DotReader())
+			if err != nil {
+				return
+			}
+			s.mu.Lock()
+			s.messages = append(s.messages, data)
+			s.mu.Unlock()
+			c.PrintfLine("250 OK")
+		case "QUIT":
+			c.PrintfLine("221 Bye")
+			return
+		default:
+			c.PrintfLine("502 Command not implemented")
+		}
+	}
+}
+
+// received は受け取ったメールを返します
+func (s *fakeSMTPServer) received() [][]byte {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.messages
+}
+
+// receivedMail は受け取ったメールを解析した結果を表す構造体
+type receivedMail struct {
+	subject     string
+	filenames   []string
+	attachments [][]byte
+}
+
+// parseReceivedMail は受け取ったメールの件名と添付ファイルを取り出します
+func parseReceivedMail(t *testing.T, data []byte) receivedMail {
+	t.Helper()
+	msg, err := mail.ReadMessage(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("ReadMessage() error = %v", err)
+	}
+	subject, err := new(mime.WordDecoder).DecodeHeader(msg.Header.Get("Subject"))
+	if err != nil {
+		t.Fatalf("DecodeHeader() error = %v", err)
+	}
+	_, params, err := mime.ParseMediaType(msg.Header.Get("Content-Type"))
+	if err != nil {
+		t.Fatalf("ParseMediaType() error = %v", err)
+	}
+
+	result := receivedMail{subject: subject}
+	reader := multipart.NewReader(msg.Body, params["boundary"])
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("NextPart() error = %v", err)
+		}
+		if part.FileName() == "" {
+			continue
+		}
+		content, err := io.ReadAll(base64.NewDecoder(base64.StdEncoding, part))
+		if err != nil {
+			t.Fatalf("添付ファイルのデコードに失敗しました: %v", err)
+		}
+		result.filenames = append(result.filenames, part.FileName())
+		result.attachments = append(result.attachments, content)
+	}
+	return result
+}
+
+// addNovelText は小説全体のテキストファイルを保存先に追加します
+func addNovelText(t *testing.T, store *novelStore, data []byte) {
+	t.Helper()
+	manifest, err := store.loadManifest()
+	if err != nil {
+		t.Fatalf("loadManifest() error = %v", err)
+	}
+	file, err := store.writeFile("テスト小説.txt", data, "txt", "UTF-8")
+	if err != nil {
+		t.Fatalf("writeFile() error = %v", err)
+	}
+	manifest.recordFile(file)
+	if err := store.saveManifest(manifest); err != nil {
+		t.Fatalf("saveManifest() error = %v", err)
+	}
+}
+
+func TestSplitText(t *testing.T) {
+	tests := []struct {
+		name     string
+		data     string
+		limit    int
+		encoding string
+		want     []string
+		wantErr  bool
+	}{
+		{name: "上限以下", data: "一\n二\n", limit: 100, want: []string{"一\n二\n"}},
+		{name: "改行の直後で分割", data: "ab\ncd\nef\n", limit: 7, want: []string{"ab\ncd\n", "ef\n"}},
+		{name: "CR+LFを分けない", data: "ab\r\ncd\r\nef\r\n", limit: 9, want: []string{"ab\r\ncd\r\n", "ef\r\n"}},
+		{name: "改行のない長い行", data: "abcdefgh\n", limit: 4, wantErr: true},
+		// "ਊ" は "\n\n" と同じバイト列（0A 0A）になるが、2バイト単位の改行ではない
+		{name: "UTF-16LEは2バイト単位の改行", data: "a\x00\n\x00ਊ\x00\n\x00b\x00", limit: 9, encoding: "UTF-16LE", want: []string{"a\x00\n\x00", "ਊ\x00\n\x00b\x00"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			chunks, err := splitText([]byte(tt.data), tt.limit, tt.encoding)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("splitText() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if len(chunks) != len(tt.want) {
+				t.Fatalf("splitText() = %q, want %q", chunks, tt.want)
+			}
+			for i, chunk := range chunks {
+				if string(chunk) != tt.want[i] {
+					t.Errorf("chunks[%d] = %q, want %q", i, chunk, tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestDeliveryTarget_Validate(t *testing.T) {
+	email := EmailDelivery{Host: "smtp.example.com", From: "from@example.com", To: []string{"reader@example.com"}}
+	folder := FolderDelivery{Path: t.TempDir(), Layout: "{author}/{title}"}
+
+	tests := []struct {
+		name    string
+		target  DeliveryTarget
+		wantErr bool
+	}{
+		{name: "メール", target: DeliveryTarget{Name: "Kindle", Kind: deliveryEmail, Email: email}},
+		{name: "フォルダ", target: DeliveryTarget{Name: "Kobo", Kind: deliveryFolder, Formats: []string{"epub"}, Folder: folder}},
+		{name: "名前なし", target: DeliveryTarget{Kind: deliveryEmail, Email: email}, wantErr: true},
+		{name: "不明な種類", target: DeliveryTarget{Name: "FTP", Kind: "ftp"}, wantErr: true},
+		{name: "送れない形式", target: DeliveryTarget{Name: "Kindle", Kind: deliveryEmail, Formats: []string{"html"}, Email: email}, wantErr: true},
+		{name: "宛先なし", target: DeliveryTarget{Name: "Kindle", Kind: deliveryEmail, Email: EmailDelivery{Host: "smtp.example.com", From: "from@example.com"}}, wantErr: true},
+		{name: "上限が小さすぎる", target: DeliveryTarget{Name: "Kindle", Kind: deliveryEmail, Email: EmailDelivery{Host: "smtp.example.com", From: "from@example.com", To: email.To, MaxSize: 1000}}, wantErr: true},
+		{name: "相対パスのフォルダ", target: DeliveryTarget{Name: "Kobo", Kind: deliveryFolder, Folder: FolderDelivery{Path: "books"}}, wantErr: true},
+		{name: "話ごとのプレースホルダ", target: DeliveryTarget{Name: "Kobo", Kind: deliveryFolder, Folder: FolderDelivery{Path: folder.Path, Layout: "{title}_{ep}"}}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.target.validate(); (err != nil) != tt.wantErr {
+				t.Errorf("validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestDeliverNovel_Email(t *testing.T) {
+	app, store, _ := newVerifiedNovelStore(t)
+	server, config := newFakeSMTPServer(t)
+
+	var text strings.Builder
+	for i := 1; i <= 100; i++ {
+		fmt.Fprintf(&text, "%03d行目の本文です。\n", i)
+	}
+	addNovelText(t, store, []byte(text.String()))
+
+	tests := []struct {
+		name          string
+		formats       []string
+		maxSize       int64
+		wantSubjects  []string
+		wantFilenames []string
+	}{
+		{
+			name:          "1通にまとめる",
+			wantSubjects:  []string{"テスト小説"},
+			wantFilenames: []string{"テスト小説.epub", "テスト小説.txt"},
+		},
+		{
+			// 添付できるのは1通あたり2052バイトまで
+			name:          "テキストを分割して複数のメールで送る",
+			formats:       []string{"txt"},
+			maxSize:       8000,
+			wantSubjects:  []string{"テスト小説（1/2）", "テスト小説（2/2）"},
+			wantFilenames: []string{"テスト小説_1.txt", "テスト小説_2.txt"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			before := len(server.received())
+			target := DeliveryTarget{Name: tt.name, Kind: deliveryEmail, Enabled: true, Formats: tt.formats, Email: config}
+			target.Email.MaxSize = tt.maxSize
+
+			manifest, _ := store.loadManifest()
+			results, err := app.deliverNovel(store, manifest, []DeliveryTarget{target}, false)
+			if err != nil {
+				t.Fatalf("deliverNovel() error = %v", err)
+			}
+			if len(results) != 1 || results[0].Error != "" {
+				t.Fatalf("deliverNovel() = %+v", results)
+			}
+
+			messages := server.received()[before:]
+			if len(messages) != len(tt.wantSubjects) {
+				t.Fatalf("メールの数 = %d, want %d", len(messages), len(tt.wantSubjects))
+			}
+			var filenames []string
+			var txt []byte
+			for i, message := range messages {
+				received := parseReceivedMail(t, message)
+				if received.subject != tt.wantSubjects[i] {
+					t.Errorf("件名 = %q, want %q", received.subject, tt.wantSubjects[i])
+				}
+				filenames = append(filenames, received.filenames...)
+				for j, name := range received.filenames {
+					if strings.Contains(name, ".txt") {
+						txt = append(txt, received.attachments[j]...)
+					}
+				}
+			}
+			if strings.Join(filenames, ",") != strings.Join(tt.wantFilenames, ",") {
+				t.Errorf("添付ファイル = %v, want %v", filenames, tt.wantFilenames)
+			}
+			// 分割した場合もつなげると元のファイルに戻る
+			if string(txt) != text.String() {
+				t.Errorf("添付したテキストが元のファイルと異なります:\n%s", txt)
+			}
+
+			// 変わっていないファイルは再び送らない
+			results, err = app.deliverNovel(store, manifest, []DeliveryTarget{target}, false)
+			if err != nil || len(results) != 0 || len(server.received()) != before+len(tt.wantSubjects) {
+				t.Errorf("2回目の deliverNovel() = %+v, %v", results, err)
+			}
+		})
+	}
+}
+
+func TestDeliverNovel_Folder(t *testing.T) {
+	app, store, _ := newVerifiedNovelStore(t)
+	device := filepath.Join(t.TempDir(), "device")
+	target := DeliveryTarget{
+		Name:    "Kobo",
+		Kind:    deliveryFolder,
+		Enabled: true,
+		Formats: []string{"epub"},
+		Folder:  FolderDelivery{Path: device, Layout: "{author}/{title}"},
+	}
+	manifest, _ := store.loadManifest()
+
+	// 機器が接続されていない場合はエラーにせずに何もせず、次の機会に送る
+	results, err := app.deliverNovel(store, manifest, []DeliveryTarget{target}, false)
+	if err != nil || len(results) != 0 {
+		t.Fatalf("接続前の deliverNovel() = %+v, %v", results, err)
+	}
+	if _, err := os.Stat(device); !os.IsNotExist(err) {
+		t.Errorf("コピー先のフォルダが作成されました: %v", err)
+	}
+
+	if err := os.Mkdir(device, 0755); err != nil {
+		t.Fatal(err)
+	}
+	results, err = app.deliverNovel(store, manifest, []DeliveryTarget{target}, false)
+	if err != nil || len(results) != 1 || results[0].Error != "" || len(results[0].Files) != 1 {
+		t.Fatalf("deliverNovel() = %+v, %v", results, err)
+	}
+	copied, err := os.ReadFile(filepath.Join(device, "作者", "テスト小説.epub"))
+	if err != nil {
+		t.Fatalf("コピーしたファイルがありません: %v", err)
+	}
+	original, _ := os.ReadFile(store.path(manifest.Files[0].Path))
+	if !bytes.Equal(copied, original) {
+		t.Errorf("コピーしたファイルが元のファイルと異なります")
+	}
+
+	// force の場合は変わっていないファイルも送る
+	if results, _ := app.deliverNovel(store, manifest, []DeliveryTarget{target}, false); len(results) != 0 {
+		t.Errorf("変わっていないファイルを送りました: %+v", results)
+	}
+	if results, _ := app.deliverNovel(store, manifest, []DeliveryTarget{target}, true); len(results) != 1 {
+		t.Errorf("force で送りませんでした: %+v", results)
+	}
+}
+
+func TestDeliverNovel_FolderDefaultLayout(t *testing.T) {
+	app := NewApp()
+	device := t.TempDir()
+	target := DeliveryTarget{Name: "Kobo", Kind: deliveryFolder, Enabled: true, Formats: []string{"txt"}, Folder: FolderDelivery{Path: device}}
+	formats := []FormatRequest{{Name: "txt", Options: map[string]interface{}{"combined": true}}}
+	episodeWriters, novelWriters, err := buildWriters(formats)
+	if err != nil {
+		t.Fatalf("buildWriters() error = %v", err)
+	}
+
+	// 小説全体のテキストはどの小説も all.txt として保存される
+	for _, title := range []string{"一作目", "二作目"} {
+		novel := newTestNovel()
+		novel.Title = title
+		store, manifest, err := app.openNovelStore(t.TempDir(), novel, formats)
+		if err != nil {
+			t.Fatalf("openNovelStore() error = %v", err)
+		}
+		for _, episode := range novel.Episodes() {
+			if err := app.saveEpisode(store, manifest, novel, episode, episodeWriters); err != nil {
+				t.Fatalf("saveEpisode() error = %v", err)
+			}
+		}
+		for _, w := range novelWriters {
+			if err := app.saveNovelFile(store, manifest, novel, w); err != nil {
+				t.Fatalf("saveNovelFile() error = %v", err)
+			}
+		}
+		results, err := app.deliverNovel(store, manifest, []DeliveryTarget{target}, false)
+		if err != nil || len(results) != 1 || results[0].Error != "" {
+			t.Fatalf("deliverNovel() = %+v, %v", results, err)
+		}
+	}
+
+	// テンプレートが空の場合は小説のタイトルを使い、他の小説を上書きしない
+	for _, title := range []string{"一作目", "二作目"} {
+		data, err := os.ReadFile(filepath.Join(device, title+".txt"))
+		if err != nil {
+			t.Errorf("コピーしたファイルがありません: %v", err)
+			continue
+		}
+		if !strings.Contains(string(data), title) {
+			t.Errorf("%s.txt に %s の本文がありません", title, title)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(device, "all.txt")); !os.IsNotExist(err) {
+		t.Errorf("保存先と同じ名前でコピーしました")
+	}
+}
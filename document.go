@@ -0,0 +1,115 @@
+package main
+
+// Novel は出力形式に依存しない小説全体の文書モデルを表す構造体
+type Novel struct {
+	NCode    string
+	Title    string
+	Author   string
+	URL      string
+	Short    bool
+	Chapters []*Chapter
+}
+
+// Chapter は章を表す構造体（章のない小説は Title が空の章を1つだけ持つ）
+type Chapter struct {
+	Title    string
+	Episodes []*Episode
+}
+
+// Episode は1話分の文書モデルを表す構造体
+type Episode struct {
+	Index   int    // 目次上の通し番号（1から始まる）
+	Number  string // URL上のエピソード番号
+	Title   string
+	URL     string
+	Revised string // 目次に表示される改稿日時（改稿されていない場合は空）
+	Blocks  []Block
+}
+
+// newNovelFromResult はスクレイピング結果から文書モデルを作成します
+// 連載の場合、各話の本文（Blocks）は取得後に設定します
+func newNovelFromResult(result ScrapeResult, url string) *Novel {
+	novel := &Novel{
+		Title:  result.Title,
+		Author: result.Author,
+		URL:    url,
+		Short:  result.PageType == "short",
+	}
+
+	if novel.Short {
+		novel.NCode = extractNovelCodeFromURL(url)
+		novel.Chapters = []*Chapter{{
+			Episodes: []*Episode{{
+				Index:  1,
+				Number: "1", // 短編は常にエピソード1
+				URL:    url,
+				Blocks: result.Blocks,
+			}},
+		}}
+		return novel
+	}
+
+	if len(result.Chapters) > 0 {
+		novel.NCode = extractNovelCodeFromURL(result.Chapters[0].URL) // 最初のエピソードURLから小説番号を取得
+	}
+
+	var current *Chapter
+	for i, info := range result.Chapters {
+		// 章タイトルが変わったら新しい章を始める
+		if current == nil || current.Title != info.ChapterTitle {
+			current = &Chapter{Title: info.ChapterTitle}
+			novel.Chapters = append(novel.Chapters, current)
+		}
+
+		// 話数はURLから求める（目次上の位置とは限らないため、位置からは補わない）
+		current.Episodes = append(current.Episodes, &Episode{
+			Index:   i + 1,
+			Number:  extractEpisodeNumberFromURL(info.URL),
+			Title:   info.Title,
+			URL:     info.URL,
+			Revised: info.Revised,
+			Blocks:  info.Blocks,
+		})
+	}
+
+	return novel
+}
+
+// Episodes は全章のエピソードを目次順に返します
+func (n *Novel) Episodes() []*Episode {
+	var episodes []*Episode
+	for _, chapter := range n.Chapters {
+		episodes = append(episodes, chapter.Episodes...)
+	}
+	return episodes
+}
+
+// ChapterOf はエピソードが属する章を返します
+func (n *Novel) ChapterOf(episode *Episode) *Chapter {
+	for _, chapter := range n.Chapters {
+		for _, e := range chapter.Episodes {
+			if e == episode {
+				return chapter
+			}
+		}
+	}
+	return nil
+}
+
+// withEpisodes は指定したエピソードのみを含む小説のコピーを返します（章構成は保つ）
+func (n *Novel) withEpisodes(keep func(*Episode) bool) *Novel {
+	copied := *n
+	copied.Chapters = nil
+	for _, chapter := range n.Chapters {
+		var episodes []*Episode
+		for _, episode := range chapter.Episodes {
+			if keep(episode) {
+				episodes = append(episodes, episode)
+			}
+		}
+		if len(episodes) > 0 {
+			copied.Chapters = append(copied.Chapters, &Chapter{Title: chapter.Title, Episodes: episodes})
+		}
+	}
+	return &copied
+}
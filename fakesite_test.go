@@ -0,0 +1,199 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeSyosetuDir は偽のなろうのサイトが返すページ（実際のページを記録して簡略化したもの）のディレクトリ
+const fakeSyosetuDir = "testdata/syosetu"
+
+// fakeNovelPath は偽のサイトの目次・各話のURLのパス
+var fakeNovelPath = regexp.MustCompile(`^/(n[0-9]+[a-z]+)/(?:([0-9]+)/)?$`)
+
+// fakeSyosetu は httptest で立てた偽のなろうのサイト（小説家になろう・R18のサイト・API）
+// testdata/syosetu の ncode・novel18 の下のページを返し、ページがない場合は404を返します
+type fakeSyosetu struct {
+	site syosetuSite
+
+	mu       sync.Mutex
+	requests map[string]int           // URL（ホストなし）ごとのリクエストの回数
+	failures map[string]int           // URLごとに503を返す残りの回数（-1 の場合はずっと返す）
+	delays   map[string]time.Duration // URLごとに応答を遅らせる時間（1回だけ）
+	pages    map[string][]byte        // 書き換えたページ（testdata/syosetu からの相対パスごと）
+}
+
+// newFakeSyosetu は偽のなろうのサイトを立て、取得の間隔を0にします
+func newFakeSyosetu(t *testing.T) *fakeSyosetu {
+	t.Helper()
+
+	savedRequest, savedEpisode, savedRetry := requestInterval, episodeInterval, retryInterval
+	requestInterval, episodeInterval, retryInterval = 0, 0, 0
+	t.Cleanup(func() {
+		requestInterval, episodeInterval, retryInterval = savedRequest, savedEpisode, savedRetry
+	})
+
+	f := &fakeSyosetu{
+		requests: map[string]int{},
+		failures: map[string]int{},
+		delays:   map[string]time.Duration{},
+		pages:    map[string][]byte{},
+	}
+	novel := httptest.NewServer(f.handler("ncode", false))
+	novel18 := httptest.NewServer(f.handler("novel18", true))
+	t.Cleanup(novel.Close)
+	t.Cleanup(novel18.Close)
+	f.site = syosetuSite{Novel: novel.URL, Novel18: novel18.URL, API: novel.URL}
+	return f
+}
+
+// app は偽のサイトから取得する App を返します
+func (f *fakeSyosetu) app() *App {
+	app := NewApp()
+	app.site = f.site
+	return app
+}
+
+// fail は path へのリクエストに times 回（-1 の場合はずっと）503を返すようにします
+func (f *fakeSyosetu) fail(path string, times int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.failures[path] = times
+}
+
+// delay は path への次のリクエストの応答を d だけ遅らせます
+func (f *fakeSyosetu) delay(path string, d time.Duration) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.delays[path] = d
+}
+
+// edit は testdata/syosetu からの相対パスのページの old を new に置き換えたものを、以降のリクエストに返すようにします
+// 作者による改稿など、前回の取得の後にサイトのページが変わった場合を再現します（testdata のファイルは変更しない）
+func (f *fakeSyosetu) edit(t *testing.T, page, old, new string) {
+	t.Helper()
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	data, ok := f.pages[page]
+	if !ok {
+		var err error
+		if data, err = os.ReadFile(filepath.Join(fakeSyosetuDir, filepath.FromSlash(page))); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if !strings.Contains(string(data), old) {
+		t.Fatalf("%s に %q がありません", page, old)
+	}
+	f.pages[page] = []byte(strings.Replace(string(data), old, new, 1))
+}
+
+// count は path へのリクエストの回数を返します
+func (f *fakeSyosetu) count(path string) int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.requests[path]
+}
+
+// handler は dir の下のページを返すハンドラーを返します（r18 が true の場合は年齢確認のCookieが必要）
+func (f *fakeSyosetu) handler(dir string, r18 bool) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := r.URL.RequestURI()
+		f.mu.Lock()
+		f.requests[key]++
+		failing := f.failures[key] != 0
+		if f.failures[key] > 0 {
+			f.failures[key]--
+		}
+		delay := f.delays[key]
+		delete(f.delays, key)
+		f.mu.Unlock()
+
+		if delay > 0 {
+			select {
+			case <-time.After(delay):
+			case <-r.Context().Done():
+				return
+			}
+		}
+		if failing {
+			http.Error(w, "メンテナンス中です", http.StatusServiceUnavailable)
+			return
+		}
+
+		if strings.HasSuffix(r.URL.Path, "api/api/") {
+			f.serveAPI(w, r)
+			return
+		}
+		if cookie, err := r.Cookie("over18"); r18 && (err != nil || cookie.Value != "yes") {
+			f.serveFile(w, filepath.Join(fakeSyosetuDir, dir, "over18.html"))
+			return
+		}
+
+		matches := fakeNovelPath.FindStringSubmatch(r.URL.Path)
+		if matches == nil {
+			http.NotFound(w, r)
+			return
+		}
+		name := "index.html"
+		if matches[2] != "" {
+			name = matches[2] + ".html"
+		} else if page := r.URL.Query().Get("p"); page != "" && page != "1" {
+			name = "index-" + page + ".html"
+		}
+		f.serveFile(w, filepath.Join(fakeSyosetuDir, dir, matches[1], name))
+	})
+}
+
+// serveAPI はなろう小説APIとして testdata/syosetu/api の小説の情報（ユーザID指定の場合は user-<ID>.json の作品一覧）を返します
+// 取得開始位置（st）と件数（lim）を指定した場合は、件数の後の要素をその範囲に絞ります
+func (f *fakeSyosetu) serveAPI(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	name := query.Get("ncode")
+	if id := query.Get("userid") + query.Get("xid"); id != "" {
+		name = "user-" + id
+	}
+	data, err := os.ReadFile(filepath.Join(fakeSyosetuDir, "api", name+".json"))
+	if err != nil {
+		data = []byte(`[{"allcount":0}]`)
+	}
+	var items []json.RawMessage
+	if json.Unmarshal(data, &items) == nil && len(items) > 0 {
+		results := items[1:]
+		if st, err := strconv.Atoi(query.Get("st")); err == nil && st > 1 {
+			results = results[min(st-1, len(results)):]
+		}
+		if lim, err := strconv.Atoi(query.Get("lim")); err == nil && lim < len(results) {
+			results = results[:lim]
+		}
+		data, _ = json.Marshal(append([]json.RawMessage{items[0]}, results...))
+	}
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	w.Write(data)
+}
+
+// serveFile はページを返します（edit で書き換えたページはその内容、ページがない場合は404）
+func (f *fakeSyosetu) serveFile(w http.ResponseWriter, path string) {
+	f.mu.Lock()
+	data, ok := f.pages[filepath.ToSlash(strings.TrimPrefix(path, fakeSyosetuDir+string(filepath.Separator)))]
+	f.mu.Unlock()
+	var err error
+	if !ok {
+		data, err = os.ReadFile(path)
+	}
+	if err != nil {
+		http.Error(w, fmt.Sprintf("ページが見つかりません: %s", filepath.Base(path)), http.StatusNotFound)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=UTF-8")
+	w.Write(data)
+}
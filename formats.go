@@ -0,0 +1,350 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+)
+
+// FormatRequest はダウンロード時に指定する出力形式とその設定を表す構造体
+type FormatRequest struct {
+	Name    string                 `json:"name"`
+	Options map[string]interface{} `json:"options,omitempty"`
+	// EpisodeFileName・NovelFileName はファイル名のテンプレート（空の場合は既定の名前）
+	EpisodeFileName string `json:"episodeFileName,omitempty"`
+	NovelFileName   string `json:"novelFileName,omitempty"`
+}
+
+// FormatField は出力形式の設定項目を表す構造体（フロントエンドの入力欄の生成に使用）
+type FormatField struct {
+	Key     string   `json:"key"`
+	Label   string   `json:"label"`
+	Type    string   `json:"type"` // "select"・"bool"・"text"・"number" のいずれか
+	Choices []string `json:"choices,omitempty"`
+}
+
+// OutputFormat は選択できる出力形式の情報を表す構造体
+type OutputFormat struct {
+	Name     string                 `json:"name"`
+	Label    string                 `json:"label"`
+	Fields   []FormatField          `json:"fields"`
+	Defaults map[string]interface{} `json:"defaults"`
+	// EpisodeFiles・NovelFile は各話ごとのファイル・小説全体のファイルを出力できるかどうか
+	EpisodeFiles bool `json:"episodeFiles"`
+	NovelFile    bool `json:"novelFile"`
+}
+
+// formatOptions は出力形式ごとの設定が実装するインターフェース
+type formatOptions interface {
+	// validate は設定値が正しいかどうかを検証します
+	validate() error
+	// writers は設定に応じた各話用・小説全体用の出力形式を返します（不要な場合は nil）
+	writers(naming fileNaming) (EpisodeWriter, NovelWriter)
+}
+
+// outputFormatEntry は出力形式の登録情報を表す構造体
+type outputFormatEntry struct {
+	name       string
+	label      string
+	fields     []FormatField
+	newOptions func() formatOptions // 既定値で初期化した設定を返す
+}
+
+// outputFormats は選択できる出力形式の一覧（表示順）
+var outputFormats = []outputFormatEntry{
+	{
+		name:  "txt",
+		label: "TXT",
+		fields: []FormatField{
+			{Key: "encoding", Label: "文字コード", Type: "select", Choices: []string{"UTF-8", "UTF-16LE", "Shift-JIS"}},
+			{Key: "lineEnding", Label: "改行コード", Type: "select", Choices: []string{"CR+LF", "LF", "CR"}},
+			{Key: "notation", Label: "ルビ・傍点", Type: "select", Choices: []string{"aozora", "plain"}},
+			{Key: "combined", Label: "連結ファイルの作成", Type: "bool"},
+		},
+		newOptions: func() formatOptions {
+			return &txtOptions{Encoding: "UTF-8", LineEnding: "CR+LF", Notation: "aozora"}
+		},
+	},
+	{
+		name:       "html",
+		label:      "HTML",
+		newOptions: func() formatOptions { return &htmlOptions{} },
+	},
+	{
+		name:       "single-html",
+		label:      "HTML（1ファイル）",
+		newOptions: func() formatOptions { return &singleHTMLOptions{} },
+	},
+	{
+		name:  "markdown",
+		label: "Markdown",
+		fields: []FormatField{
+			{Key: "ruby", Label: "ルビの表記", Type: "select", Choices: []string{"html", "paren"}},
+		},
+		newOptions: func() formatOptions { return &markdownOptions{Ruby: "html"} },
+	},
+	{
+		name:  "epub",
+		label: "EPUB",
+		fields: []FormatField{
+			{Key: "vertical", Label: "縦書き", Type: "bool"},
+		},
+		newOptions: func() formatOptions { return &epubOptions{Vertical: true} },
+	},
+	{
+		name:  "pdf",
+		label: "PDF（縦書き）",
+		fields: []FormatField{
+			{Key: "fontPath", Label: "フォント（.ttf/.ttc）", Type: "text"},
+			{Key: "pageSize", Label: "用紙サイズ", Type: "select", Choices: []string{"A4", "A5", "A6", "B6"}},
+			{Key: "fontSize", Label: "文字サイズ", Type: "number"},
+		},
+		newOptions: func() formatOptions { return &pdfOptions{PageSize: "A5", FontSize: 10} },
+	},
+}
+
+// txtOptions はテキスト形式の設定を表す構造体
+type txtOptions struct {
+	Encoding   string `json:"encoding"`
+	LineEnding string `json:"lineEnding"`
+	Notation   string `json:"notation"` // "aozora"（青空文庫形式）または "plain"（括弧書き）
+	Combined   bool   `json:"combined"`
+}
+
+func (o *txtOptions) validate() error {
+	switch o.Encoding {
+	case "UTF-8", "UTF-16LE", "Shift-JIS":
+	default:
+		return fmt.Errorf("不明な文字コードです: %s", o.Encoding)
+	}
+	switch o.LineEnding {
+	case "CR+LF", "LF", "CR":
+	default:
+		return fmt.Errorf("不明な改行コードです: %s", o.LineEnding)
+	}
+	switch o.Notation {
+	case "aozora", "plain":
+	default:
+		return fmt.Errorf("不明なルビの表記です: %s", o.Notation)
+	}
+	return nil
+}
+
+func (o *txtOptions) writers(naming fileNaming) (EpisodeWriter, NovelWriter) {
+	encoding := textEncoding{Encoding: o.Encoding, LineEnding: o.LineEnding}
+	var w interface {
+		EpisodeWriter
+		NovelWriter
+	} = aozoraWriter{encoding}
+	if o.Notation == "plain" {
+		w = plainTextWriter{encoding}
+	}
+	if !o.Combined {
+		return naming.episodeWriter(w), nil
+	}
+	return naming.episodeWriter(w), naming.novelWriter(w)
+}
+
+// htmlOptions はHTML形式の設定を表す構造体
+type htmlOptions struct{}
+
+func (o *htmlOptions) validate() error { return nil }
+
+func (o *htmlOptions) writers(naming fileNaming) (EpisodeWriter, NovelWriter) {
+	// 各話とエピソード一覧が互いにリンクするため、テンプレートは出力形式自身が扱う
+	w := htmlWriter{Naming: naming}
+	return w, w
+}
+
+// singleHTMLOptions は1ファイルのHTML形式の設定を表す構造体
+type singleHTMLOptions struct{}
+
+func (o *singleHTMLOptions) validate() error { return nil }
+
+func (o *singleHTMLOptions) writers(naming fileNaming) (EpisodeWriter, NovelWriter) {
+	return nil, naming.novelWriter(singleHTMLWriter{})
+}
+
+// markdownOptions はMarkdown形式の設定を表す構造体
+type markdownOptions struct {
+	Ruby string `json:"ruby"` // "html"（<ruby>タグ）または "paren"（漢字(かんじ)）
+}
+
+func (o *markdownOptions) validate() error {
+	switch o.Ruby {
+	case "html", "paren":
+		return nil
+	default:
+		return fmt.Errorf("不明なルビの表記です: %s", o.Ruby)
+	}
+}
+
+func (o *markdownOptions) writers(naming fileNaming) (EpisodeWriter, NovelWriter) {
+	return nil, naming.novelWriter(markdownWriter{Ruby: o.Ruby})
+}
+
+// epubOptions はEPUB形式の設定を表す構造体
+type epubOptions struct {
+	Vertical bool `json:"vertical"`
+}
+
+func (o *epubOptions) validate() error { return nil }
+
+func (o *epubOptions) writers(naming fileNaming) (EpisodeWriter, NovelWriter) {
+	return nil, naming.novelWriter(epubWriter{Vertical: o.Vertical})
+}
+
+// pdfOptions はPDF形式の設定を表す構造体
+type pdfOptions struct {
+	FontPath string  `json:"fontPath"`
+	PageSize string  `json:"pageSize"`
+	FontSize float64 `json:"fontSize"`
+}
+
+func (o *pdfOptions) validate() error {
+	if _, ok := pdfPageSizes[o.PageSize]; !ok {
+		return fmt.Errorf("不明な用紙サイズです: %s", o.PageSize)
+	}
+	if o.FontSize < 6 || o.FontSize > 24 {
+		return fmt.Errorf("文字サイズは6から24の間で指定してください: %g", o.FontSize)
+	}
+	_, err := findPDFFont(o.FontPath)
+	return err
+}
+
+func (o *pdfOptions) writers(naming fileNaming) (EpisodeWriter, NovelWriter) {
+	return nil, naming.novelWriter(pdfWriter{FontPath: o.FontPath, PageSize: o.PageSize, FontSize: o.FontSize})
+}
+
+// lookupOutputFormat は名前から出力形式を探します
+func lookupOutputFormat(name string) (outputFormatEntry, bool) {
+	for _, entry := range outputFormats {
+		if entry.name == name {
+			return entry, true
+		}
+	}
+	return outputFormatEntry{}, false
+}
+
+// GetOutputFormats は選択できる出力形式の一覧を返します（フロントエンド用）
+func (a *App) GetOutputFormats() ([]OutputFormat, error) {
+	formats := make([]OutputFormat, 0, len(outputFormats))
+	for _, entry := range outputFormats {
+		// 既定値の設定をJSONを経由してマップに変換する
+		options := entry.newOptions()
+		data, err := json.Marshal(options)
+		if err != nil {
+			return nil, fmt.Errorf("%sの既定値の変換に失敗しました: %w", entry.name, err)
+		}
+		defaults := make(map[string]interface{})
+		if err := json.Unmarshal(data, &defaults); err != nil {
+			return nil, fmt.Errorf("%sの既定値の変換に失敗しました: %w", entry.name, err)
+		}
+
+		fields := entry.fields
+		if fields == nil {
+			fields = []FormatField{}
+		}
+		// 出力できるファイルの種類（テンプレートの入力欄の表示に使用）
+		// txt の連結ファイルのように設定で有効になるものも含める
+		episodeWriter, novelWriter := options.writers(fileNaming{})
+		novelFile := novelWriter != nil || entry.name == "txt"
+
+		formats = append(formats, OutputFormat{
+			Name:         entry.name,
+			Label:        entry.label,
+			Fields:       fields,
+			Defaults:     defaults,
+			EpisodeFiles: episodeWriter != nil,
+			NovelFile:    novelFile,
+		})
+	}
+	return formats, nil
+}
+
+// parseFormatOptions は出力形式の設定を既定値に上書きして検証します
+func parseFormatOptions(request FormatRequest) (formatOptions, error) {
+	entry, ok := lookupOutputFormat(request.Name)
+	if !ok {
+		return nil, fmt.Errorf("不明な出力形式です: %s", request.Name)
+	}
+
+	options := entry.newOptions()
+	if len(request.Options) > 0 {
+		data, err := json.Marshal(request.Options)
+		if err != nil {
+			return nil, fmt.Errorf("%sの設定を読み込めませんでした: %w", entry.label, err)
+		}
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(options); err != nil {
+			return nil, fmt.Errorf("%sの設定が正しくありません: %w", entry.label, err)
+		}
+	}
+
+	if err := options.validate(); err != nil {
+		return nil, fmt.Errorf("%sの設定が正しくありません: %w", entry.label, err)
+	}
+	return options, nil
+}
+
+// buildWriters は指定された出力形式から各話用・小説全体用の出力形式の一覧を作成します
+func buildWriters(formats []FormatRequest) ([]EpisodeWriter, []NovelWriter, error) {
+	if len(formats) == 0 {
+		return nil, nil, fmt.Errorf("出力形式が選択されていません")
+	}
+
+	var episodeWriters []EpisodeWriter
+	var novelWriters []NovelWriter
+	seen := make(map[string]bool)
+	for _, request := range formats {
+		if seen[request.Name] {
+			return nil, nil, fmt.Errorf("出力形式が重複しています: %s", request.Name)
+		}
+		seen[request.Name] = true
+
+		options, err := parseFormatOptions(request)
+		if err != nil {
+			return nil, nil, err
+		}
+		naming, err := newFileNaming(request.EpisodeFileName, request.NovelFileName)
+		if err != nil {
+			return nil, nil, fmt.Errorf("%sのファイル名の設定が正しくありません: %w", request.Name, err)
+		}
+		episodeWriter, novelWriter := options.writers(naming)
+		if episodeWriter != nil {
+			episodeWriters = append(episodeWriters, episodeWriter)
+		}
+		if novelWriter != nil {
+			novelWriters = append(novelWriters, novelWriter)
+		}
+	}
+
+	return episodeWriters, novelWriters, nil
+}
+
+// defaultFormats は既定の出力形式（TXTのみ）を返します
+func defaultFormats() []FormatRequest {
+	return []FormatRequest{{
+		Name:    "txt",
+		Options: map[string]interface{}{"encoding": "UTF-8", "lineEnding": "CR+LF", "notation": "aozora", "combined": false},
+	}}
+}
+
+// legacyFormats は旧形式の設定（createTxt などの真偽値）を出力形式の一覧に変換します
+// HTMLの出力は旧形式では無効化されていたため変換しません
+func legacyFormats(encoding, lineEnding string, createTxt, createCombined bool) []FormatRequest {
+	if !createTxt {
+		return []FormatRequest{}
+	}
+	if encoding == "" {
+		encoding = "UTF-8"
+	}
+	if lineEnding == "" {
+		lineEnding = "CR+LF"
+	}
+	return []FormatRequest{{
+		Name:    "txt",
+		Options: map[string]interface{}{"encoding": encoding, "lineEnding": lineEnding, "notation": "aozora", "combined": createCombined},
+	}}
+}
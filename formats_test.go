@@ -0,0 +1,103 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestBuildWriters(t *testing.T) {
+	tests := []struct {
+		name           string
+		formats        []FormatRequest
+		episodeWriters int
+		novelWriters   int
+		wantErr        bool
+	}{
+		{
+			name:           "既定値のTXT",
+			formats:        []FormatRequest{{Name: "txt"}},
+			episodeWriters: 1,
+		},
+		{
+			name: "連結ファイル付きのTXTとEPUB",
+			formats: []FormatRequest{
+				{Name: "txt", Options: map[string]interface{}{"encoding": "Shift-JIS", "combined": true}},
+				{Name: "epub", Options: map[string]interface{}{"vertical": false}},
+			},
+			episodeWriters: 1,
+			novelWriters:   2,
+		},
+		{
+			name:    "出力形式なし",
+			formats: nil,
+			wantErr: true,
+		},
+		{
+			name:    "不明な出力形式",
+			formats: []FormatRequest{{Name: "docx"}},
+			wantErr: true,
+		},
+		{
+			name:    "不明な文字コード",
+			formats: []FormatRequest{{Name: "txt", Options: map[string]interface{}{"encoding": "EUC-JP"}}},
+			wantErr: true,
+		},
+		{
+			name:    "型の異なる設定値",
+			formats: []FormatRequest{{Name: "txt", Options: map[string]interface{}{"combined": "yes"}}},
+			wantErr: true,
+		},
+		{
+			name:    "不明な設定項目",
+			formats: []FormatRequest{{Name: "epub", Options: map[string]interface{}{"font": "serif"}}},
+			wantErr: true,
+		},
+		{
+			name:           "ファイル名のテンプレート",
+			formats:        []FormatRequest{{Name: "txt", EpisodeFileName: "{title}/{ep:04d}", NovelFileName: "{title}"}},
+			episodeWriters: 1,
+		},
+		{
+			name:    "小説全体のファイル名に各話の値",
+			formats: []FormatRequest{{Name: "epub", NovelFileName: "{eptitle}"}},
+			wantErr: true,
+		},
+		{
+			name:    "重複した出力形式",
+			formats: []FormatRequest{{Name: "txt"}, {Name: "txt"}},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			episodeWriters, novelWriters, err := buildWriters(tt.formats)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("buildWriters() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if len(episodeWriters) != tt.episodeWriters || len(novelWriters) != tt.novelWriters {
+				t.Errorf("buildWriters() = %d, %d writers, want %d, %d", len(episodeWriters), len(novelWriters), tt.episodeWriters, tt.novelWriters)
+			}
+		})
+	}
+}
+
+func TestGetOutputFormats(t *testing.T) {
+	app := NewApp()
+	formats, err := app.GetOutputFormats()
+	if err != nil {
+		t.Fatalf("GetOutputFormats() error = %v", err)
+	}
+
+	// 既定値はそのまま指定しても検証を通ること
+	for _, format := range formats {
+		if format.Name == "pdf" {
+			// フォントの有無は実行環境に依存するため、フォントがない場合は検証しない
+			if _, err := findPDFFont(""); err != nil {
+				continue
+			}
+		}
+		if _, err := parseFormatOptions(FormatRequest{Name: format.Name, Options: format.Defaults}); err != nil {
+			t.Errorf("%s の既定値が検証に失敗しました: %v", format.Name, err)
+		}
+	}
+}
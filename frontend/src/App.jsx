@@ -1,12 +1,24 @@
 import './App.css';
 import NarouDownload from './pages/NarouDownload';
-import { MantineProvider } from '@mantine/core';
+import Reader from './pages/Reader';
+import { MantineProvider, Tabs } from '@mantine/core';
 
 function App() {
     return (
         <MantineProvider>
             <div id="app">
-                <NarouDownload />
+                <Tabs defaultValue="download">
+                    <Tabs.List>
+                        <Tabs.Tab value="download">ダウンロード</Tabs.Tab>
+                        <Tabs.Tab value="reader">リーダー</Tabs.Tab>
+                    </Tabs.List>
+                    <Tabs.Panel value="download">
+                        <NarouDownload />
+                    </Tabs.Panel>
+                    <Tabs.Panel value="reader">
+                        <Reader />
+                    </Tabs.Panel>
+                </Tabs>
             </div>
         </MantineProvider>
     )
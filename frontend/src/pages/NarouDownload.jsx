@@ -1,5 +1,6 @@
 import {
   TextInput,
+  NumberInput,
   Checkbox,
   Textarea,
   Button,
@@ -9,7 +10,9 @@ import {
   Stack,
   Text,
   Grid,
-  Progress
+  Progress,
+  Modal,
+  ScrollArea
 } from '@mantine/core'
 import { useState, useEffect, useRef } from 'react'
 import { 
@@ -21,23 +24,65 @@ import {
   LoadSettings,
   Quit,
   GetTitle,
+  GetAuthorWorks,
+  DownloadAuthorWorks,
+  SelectBookmarkFile,
+  ImportBookmarks,
+  DownloadQueue,
+  GetOutputFormats,
+  VerifyNovel,
+  SaveProfile,
+  DeleteProfile,
+  ApplySchedule,
+  GetScheduleStatus,
+  SaveDeliveryTarget,
+  DeleteDeliveryTarget,
+  SaveHook,
+  DeleteHook,
+  ApplyNotifications,
+  SendTestNotification,
+  ShowDesktopNotification,
+  ApplyCacheSettings,
+  ClearCache,
 } from '../../wailsjs/go/main/App'
 
 export default function NarouDownload() {
   const [log, setLog] = useState('')
   const logTextareaRef = useRef(null)
-  const [encoding, setEncoding] = useState('UTF-8')
-  const [lineEnding, setLineEnding] = useState('CR+LF')
   const [progress, setProgress] = useState(0)
   const [savePath, setSavePath] = useState('')
   const [url, setUrl] = useState('')
   const [showInFront, setShowInFront] = useState(false)
-  const [createHtml, setCreateHtml] = useState(true)
-  const [createTxt, setCreateTxt] = useState(true)
-  const [createCombined, setCreateCombined] = useState(false)
+  const [formats, setFormats] = useState([])
+  const [availableFormats, setAvailableFormats] = useState([])
   const [title, setTitle] = useState('')
   const [progressText, setProgressText] = useState('')
   const [isDownloading, setIsDownloading] = useState(false)
+  const [authorWorks, setAuthorWorks] = useState(null)
+  const [selectedWorks, setSelectedWorks] = useState([])
+  const [bookmarkOpened, setBookmarkOpened] = useState(false)
+  const [bookmarkSource, setBookmarkSource] = useState('')
+  const [bookmarkCookie, setBookmarkCookie] = useState('')
+  const [bookmarkImport, setBookmarkImport] = useState(null)
+  const [profiles, setProfiles] = useState([])
+  const [activeProfile, setActiveProfile] = useState('')
+  const [profileName, setProfileName] = useState('')
+  const [profileSkipHooks, setProfileSkipHooks] = useState(false)
+  const [settingsLoaded, setSettingsLoaded] = useState(false)
+  const [schedule, setSchedule] = useState({ enabled: false, interval: '6h', cron: '', folder: '', autoDownload: false })
+  const [scheduleStatus, setScheduleStatus] = useState(null)
+  const [deliveries, setDeliveries] = useState([])
+  const [deliveryOpened, setDeliveryOpened] = useState(false)
+  const [deliveryDraft, setDeliveryDraft] = useState(newDeliveryTarget())
+  const [hooks, setHooks] = useState([])
+  const [hookOpened, setHookOpened] = useState(false)
+  const [hookDraft, setHookDraft] = useState(newHook())
+  const [notifications, setNotifications] = useState({ desktop: true, events: [], webhooks: [] })
+  const [notificationOpened, setNotificationOpened] = useState(false)
+  const [notificationDraft, setNotificationDraft] = useState({ desktop: true, events: [], webhooks: [] })
+  const [cache, setCache] = useState({ enabled: true, offline: false, tocTTL: '0s', episodeTTL: '24h' })
+  const [cacheOpened, setCacheOpened] = useState(false)
+  const [cacheDraft, setCacheDraft] = useState({ enabled: true, offline: false, tocTTL: '0s', episodeTTL: '24h' })
 
   // 設定の読み込み
   useEffect(() => {
@@ -46,18 +91,37 @@ export default function NarouDownload() {
         const settings = await LoadSettings()
         setUrl(settings.url || '')
         setSavePath(settings.savePath || '')
-        setEncoding(settings.encoding || 'UTF-8')
-        setLineEnding(settings.lineEnding || 'CR+LF')
-        setCreateHtml(settings.createHtml ?? true)
-        setCreateTxt(settings.createTxt ?? true)
-        setCreateCombined(settings.createCombined ?? false)
+        setFormats(settings.formats || [])
         setShowInFront(settings.showInFront ?? false)
+        setProfiles(settings.profiles || [])
+        setActiveProfile(settings.activeProfile || '')
+        setProfileName(settings.activeProfile || '')
+        setProfileSkipHooks((settings.profiles || []).find((p) => p.name === settings.activeProfile)?.skipHooks ?? false)
+        if (settings.schedule) setSchedule(settings.schedule)
+        setDeliveries(settings.deliveries || [])
+        setHooks(settings.hooks || [])
+        if (settings.notifications) setNotifications(settings.notifications)
+        if (settings.cache) setCache(settings.cache)
+        setScheduleStatus(await GetScheduleStatus())
       } catch (error) {
         console.error('設定の読み込み中にエラーが発生しました:', error)
+      } finally {
+        // 読み込みが終わるまでは設定を保存しない（読み込み前の空の値で上書きしないため）
+        setSettingsLoaded(true)
       }
     }
     loadSettings()
 
+    // 選択できる出力形式の取得
+    const loadOutputFormats = async () => {
+      try {
+        setAvailableFormats(await GetOutputFormats())
+      } catch (error) {
+        console.error('出力形式の取得中にエラーが発生しました:', error)
+      }
+    }
+    loadOutputFormats()
+
     // イベントリスナーの登録
     const progressUnsubscribe = window.runtime.EventsOn("progress", (value) => {
       setProgress(value)
@@ -71,11 +135,31 @@ export default function NarouDownload() {
       setProgressText(text)
     })
 
+    const newEpisodesUnsubscribe = window.runtime.EventsOn("newEpisodes", (check) => {
+      const titles = check.newEpisodes.map((e) => `${e.number}話 ${e.title}`).join('、')
+      setLog(prev => prev + `\n新着: ${check.title} - ${titles}`)
+    })
+
+    // WebViewが通知に対応していない場合や許可されない場合はOSの機能で表示する
+    const notificationUnsubscribe = window.runtime.EventsOn("notification", async (notification) => {
+      try {
+        if ('Notification' in window && await window.Notification.requestPermission() === 'granted') {
+          new window.Notification(notification.title, { body: notification.message })
+          return
+        }
+        await ShowDesktopNotification(notification.title, notification.message)
+      } catch (error) {
+        console.error('通知の表示中にエラーが発生しました:', error)
+      }
+    })
+
     // クリーンアップ関数
     return () => {
       progressUnsubscribe()
       logUnsubscribe()
       if (progressTextUnsubscribe) progressTextUnsubscribe()
+      newEpisodesUnsubscribe()
+      notificationUnsubscribe()
     }
   }, [])
 
@@ -91,8 +175,23 @@ export default function NarouDownload() {
       return
     }
     
-    if (!createHtml && !createTxt) {
-      setLog('エラー: HTMLまたはTXTのどちらかを選択してください')
+    if (formats.length === 0) {
+      setLog('エラー: 出力形式を1つ以上選択してください')
+      return
+    }
+
+    // 作者のマイページURLの場合は作品一覧を取得して選択させる
+    if (url.includes('mypage.syosetu.com')) {
+      try {
+        setLog('作品一覧を取得しています...')
+        const works = await GetAuthorWorks(url)
+        setAuthorWorks(works)
+        setSelectedWorks(works.works.map((work) => work.ncode))
+        setTitle(works.author)
+      } catch (error) {
+        console.error('作品一覧の取得中にエラーが発生しました:', error)
+        setLog(prev => prev + '\nエラー: 作品一覧を取得できませんでした - ' + error)
+      }
       return
     }
 
@@ -111,15 +210,7 @@ export default function NarouDownload() {
         setTitle('タイトルを取得できませんでした')
       }
       
-      const options = {
-        encoding,
-        lineEnding,
-        createHtml,
-        createTxt,
-        createCombined,
-        showInFront
-      }
-      await DownloadNovel(url, savePath, options)
+      await DownloadNovel(url, savePath, formats)
       setProgressText('完了')
     } catch (error) {
       console.error('ダウンロード中にエラーが発生しました:', error)
@@ -132,6 +223,94 @@ export default function NarouDownload() {
     }
   }
 
+  const handleDownloadAuthorWorks = async () => {
+    const works = authorWorks
+    setAuthorWorks(null)
+    if (selectedWorks.length === 0) {
+      setLog(prev => prev + '\nエラー: 作品が選択されていません')
+      return
+    }
+
+    try {
+      setIsDownloading(true)
+      setProgress(0)
+      setProgressText('初期化中...')
+
+      await DownloadAuthorWorks(works, selectedWorks, savePath, formats)
+      setProgressText('完了')
+    } catch (error) {
+      console.error('ダウンロード中にエラーが発生しました:', error)
+      setLog(prev => prev + '\nエラー: ダウンロードに失敗しました - ' + error)
+      setProgressText('エラー')
+    } finally {
+      setIsDownloading(false)
+    }
+  }
+
+  const handleSelectBookmarkFile = async () => {
+    try {
+      const path = await SelectBookmarkFile()
+      setBookmarkSource(path)
+    } catch (error) {
+      console.error('ファイル選択中にエラーが発生しました:', error)
+    }
+  }
+
+  const handleImportBookmarks = async () => {
+    try {
+      const imported = await ImportBookmarks(bookmarkSource, bookmarkCookie, savePath)
+      setBookmarkImport(imported)
+    } catch (error) {
+      console.error('ブックマークの読み込み中にエラーが発生しました:', error)
+      setLog(prev => prev + '\nエラー: ブックマークを読み込めませんでした - ' + error)
+    }
+  }
+
+  const handleDownloadBookmarks = async () => {
+    const queue = bookmarkImport?.queue || []
+    setBookmarkOpened(false)
+    setBookmarkImport(null)
+    if (queue.length === 0) {
+      setLog(prev => prev + '\nすべての小説が保存済みです')
+      return
+    }
+
+    try {
+      setIsDownloading(true)
+      setProgress(0)
+      setProgressText('初期化中...')
+
+      await DownloadQueue(queue, formats)
+      setProgressText('完了')
+    } catch (error) {
+      console.error('ダウンロード中にエラーが発生しました:', error)
+      setLog(prev => prev + '\nエラー: ダウンロードに失敗しました - ' + error)
+      setProgressText('エラー')
+    } finally {
+      setIsDownloading(false)
+    }
+  }
+
+  const handleToggleFormat = (format, checked) => {
+    if (checked) {
+      setFormats(prev => [...prev, { name: format.name, options: { ...format.defaults } }])
+    } else {
+      setFormats(prev => prev.filter((f) => f.name !== format.name))
+    }
+  }
+
+  const handleFormatOptionChange = (format, key, value) => {
+    setFormats(prev => prev.map((f) => (
+      f.name === format.name ? { ...f, options: { ...format.defaults, ...f.options, [key]: value } } : f
+    )))
+  }
+
+  const handleFileNameChange = (format, key, value) => {
+    setFormats(prev => prev.map((f) => (
+      f.name === format.name ? { ...f, [key]: value || undefined } : f
+    )))
+  }
+
   const handleSelectFolder = async () => {
     try {
       const path = await SelectFolder()
@@ -142,6 +321,28 @@ export default function NarouDownload() {
     }
   }
 
+  const handleVerify = async (repair) => {
+    if (!savePath) {
+      setLog(prev => prev + '\nエラー: 保存先パスが指定されていません')
+      return
+    }
+    setIsDownloading(true)
+    try {
+      const reports = await VerifyNovel(savePath, { url: '', online: true, deep: false, repair })
+      for (const report of reports) {
+        const lines = report.issues.map((issue) => (
+          `  [${issue.kind}] ${issue.number ? `${issue.number}話 ${issue.title}` : issue.path}: ${issue.detail}`
+        ))
+        setLog(prev => [prev, `${report.title}: 問題 ${report.issues.length}件${repair ? `、修復 ${report.repaired}件` : ''}`, ...lines].join('\n'))
+      }
+    } catch (error) {
+      console.error('検証中にエラーが発生しました:', error)
+      setLog(prev => prev + '\nエラー: 検証に失敗しました - ' + error)
+    } finally {
+      setIsDownloading(false)
+    }
+  }
+
   const handleOpenFolder = async () => {
     if (!savePath) {
       setLog(prev => prev + '\nエラー: 保存先パスが指定されていません')
@@ -182,18 +383,180 @@ export default function NarouDownload() {
     setTitle('')
   }
 
+  // プロファイルを選択すると出力形式と保存先を切り替える
+  const handleSelectProfile = (name) => {
+    const profile = profiles.find((p) => p.name === name)
+    setActiveProfile(name || '')
+    setProfileName(name || '')
+    setProfileSkipHooks(profile?.skipHooks ?? false)
+    if (!profile) return
+    setFormats(profile.formats || [])
+    if (profile.savePath) setSavePath(profile.savePath)
+  }
+
+  const applySettings = (settings) => {
+    setProfiles(settings.profiles || [])
+    setActiveProfile(settings.activeProfile || '')
+    setDeliveries(settings.deliveries || [])
+    setHooks(settings.hooks || [])
+    if (settings.notifications) setNotifications(settings.notifications)
+    if (settings.cache) setCache(settings.cache)
+  }
+
+  const handleSaveProfile = async () => {
+    try {
+      applySettings(await SaveProfile({ name: profileName, formats, savePath: '', skipHooks: profileSkipHooks }))
+      setLog(prev => prev + `\nプロファイル「${profileName}」を保存しました`)
+    } catch (error) {
+      console.error('プロファイルの保存中にエラーが発生しました:', error)
+      setLog(prev => prev + '\nエラー: プロファイルを保存できませんでした - ' + error)
+    }
+  }
+
+  const handleDeleteProfile = async () => {
+    try {
+      applySettings(await DeleteProfile(activeProfile))
+      setProfileName('')
+    } catch (error) {
+      console.error('プロファイルの削除中にエラーが発生しました:', error)
+    }
+  }
+
+  // 送信先を選択すると編集中の内容を切り替える
+  const handleSelectDelivery = (name) => {
+    const target = deliveries.find((t) => t.name === name)
+    setDeliveryDraft(target ? { ...newDeliveryTarget(), ...target } : newDeliveryTarget())
+  }
+
+  const updateDeliveryDraft = (key, value) => {
+    setDeliveryDraft((draft) => ({ ...draft, [key]: { ...draft[key], ...value } }))
+  }
+
+  const handleSaveDelivery = async () => {
+    try {
+      applySettings(await SaveDeliveryTarget(deliveryDraft))
+      setLog(prev => prev + `\n送信先「${deliveryDraft.name}」を保存しました`)
+    } catch (error) {
+      console.error('送信先の保存中にエラーが発生しました:', error)
+      setLog(prev => prev + '\nエラー: 送信先を保存できませんでした - ' + error)
+    }
+  }
+
+  const handleDeleteDelivery = async () => {
+    try {
+      applySettings(await DeleteDeliveryTarget(deliveryDraft.name))
+      setDeliveryDraft(newDeliveryTarget())
+    } catch (error) {
+      console.error('送信先の削除中にエラーが発生しました:', error)
+    }
+  }
+
+  const handleSelectDeliveryFolder = async () => {
+    try {
+      const path = await SelectFolder()
+      if (path) updateDeliveryDraft('folder', { path })
+    } catch (error) {
+      console.error('フォルダ選択中にエラーが発生しました:', error)
+    }
+  }
+
+  const handleSaveHook = async () => {
+    try {
+      applySettings(await SaveHook(hookDraft))
+      setLog(prev => prev + `\nフック「${hookDraft.name}」を保存しました`)
+    } catch (error) {
+      console.error('フックの保存中にエラーが発生しました:', error)
+      setLog(prev => prev + '\nエラー: フックを保存できませんでした - ' + error)
+    }
+  }
+
+  const handleDeleteHook = async () => {
+    try {
+      applySettings(await DeleteHook(hookDraft.name))
+      setHookDraft(newHook())
+    } catch (error) {
+      console.error('フックの削除中にエラーが発生しました:', error)
+    }
+  }
+
+  const updateWebhook = (index, value) => {
+    setNotificationDraft((draft) => ({
+      ...draft,
+      webhooks: draft.webhooks.map((webhook, i) => i === index ? { ...webhook, ...value } : webhook),
+    }))
+  }
+
+  const handleSaveNotifications = async () => {
+    try {
+      applySettings(await ApplyNotifications(notificationDraft))
+      setNotificationOpened(false)
+      setLog(prev => prev + '\n通知の設定を保存しました')
+    } catch (error) {
+      console.error('通知の設定の保存中にエラーが発生しました:', error)
+      setLog(prev => prev + '\nエラー: 通知の設定を保存できませんでした - ' + error)
+    }
+  }
+
+  const handleTestNotification = async () => {
+    try {
+      await SendTestNotification(notificationDraft)
+      setLog(prev => prev + '\n確認の通知を送りました')
+    } catch (error) {
+      setLog(prev => prev + '\nエラー: 通知を送れませんでした - ' + error)
+    }
+  }
+
+  const handleSaveCache = async () => {
+    try {
+      applySettings(await ApplyCacheSettings(cacheDraft))
+      setCacheOpened(false)
+      setLog(prev => prev + (cacheDraft.enabled && cacheDraft.offline
+        ? '\nオフラインモードにしました（キャッシュにないページは取得できません）'
+        : '\nキャッシュの設定を保存しました'))
+    } catch (error) {
+      console.error('キャッシュの設定の保存中にエラーが発生しました:', error)
+      setLog(prev => prev + '\nエラー: キャッシュの設定を保存できませんでした - ' + error)
+    }
+  }
+
+  const handleClearCache = async () => {
+    try {
+      await ClearCache()
+    } catch (error) {
+      setLog(prev => prev + '\nエラー: キャッシュを削除できませんでした - ' + error)
+    }
+  }
+
+  const handleApplySchedule = async () => {
+    try {
+      await ApplySchedule(schedule)
+      const status = await GetScheduleStatus()
+      setScheduleStatus(status)
+      setLog(prev => prev + (status.enabled
+        ? `\n定期確認を開始しました（次回: ${new Date(status.nextRun).toLocaleString()}）`
+        : '\n定期確認を停止しました'))
+    } catch (error) {
+      console.error('定期確認の設定中にエラーが発生しました:', error)
+      setLog(prev => prev + '\nエラー: 定期確認を設定できませんでした - ' + error)
+    }
+  }
+
   useEffect(() => {
+    if (!settingsLoaded) return
     const syncSettings = async () => {
       try {
         const settings = {
           url,
           savePath,
-          encoding,
-          lineEnding,
-          createHtml,
-          createTxt,
-          createCombined,
-          showInFront
+          formats,
+          showInFront,
+          profiles,
+          activeProfile,
+          schedule,
+          deliveries,
+          hooks,
+          notifications,
+          cache
         }
         await SaveSettings(settings)
       } catch (error) {
@@ -202,7 +565,7 @@ export default function NarouDownload() {
     }
   
     syncSettings()
-  }, [url, savePath, encoding, lineEnding, createHtml, createTxt, createCombined, showInFront])
+  }, [settingsLoaded, url, savePath, formats, showInFront, profiles, activeProfile, schedule, deliveries, hooks, notifications, cache])
 
   // ログが更新されたときに自動スクロール
   useEffect(() => {
@@ -216,7 +579,7 @@ export default function NarouDownload() {
       padding="md"
       radius={0}
       withBorder={false}
-      style={{ width: '100vw', height: '100vh' }}
+      style={{ width: '100vw', height: 'calc(100vh - 48px)' }}
     >
       <Stack spacing="md">
         <Grid>
@@ -268,35 +631,234 @@ export default function NarouDownload() {
               >
                 開く
               </Button>
+              <Button 
+                variant="default"
+                onClick={() => handleVerify(false)}
+                disabled={isDownloading}
+              >
+                検証
+              </Button>
+              <Button 
+                variant="default"
+                onClick={() => handleVerify(true)}
+                disabled={isDownloading}
+              >
+                修復
+              </Button>
             </Group>
           </Grid.Col>
 
-          <Grid.Col span={10} offset={2}>
-            <Group>
-              {/* <Checkbox 
-                checked={createHtml}
-                onChange={(event) => setCreateHtml(event.currentTarget.checked)}
-                label="HTML" 
-              /> */}
-              <Checkbox 
-                checked={createTxt}
-                onChange={(event) => setCreateTxt(event.currentTarget.checked)}
-                label="TXT" 
-              />
+          <Grid.Col span={2}>プロファイル</Grid.Col>
+          <Grid.Col span={10}>
+            <Group spacing="xs">
               <Select
-                value={encoding}
-                onChange={setEncoding}
-                data={['UTF-8', 'UTF-16LE', 'Shift-JIS']}
+                placeholder="プロファイルを選択"
+                value={activeProfile || null}
+                onChange={handleSelectProfile}
+                data={profiles.map((p) => p.name)}
+                clearable
                 style={{ flex: 1 }}
               />
-              <Select
-                value={lineEnding}
-                onChange={setLineEnding}
-                data={['CR+LF', 'LF', 'CR']}
+              <TextInput
+                placeholder="保存する名前"
+                value={profileName}
+                onChange={(e) => setProfileName(e.target.value)}
+                style={{ flex: 1 }}
+              />
+              <Checkbox
+                label="フックを実行"
+                checked={!profileSkipHooks}
+                onChange={(e) => setProfileSkipHooks(!e.currentTarget.checked)}
+              />
+              <Button variant="default" onClick={handleSaveProfile} disabled={!profileName}>
+                保存
+              </Button>
+              <Button variant="default" onClick={handleDeleteProfile} disabled={!activeProfile}>
+                削除
+              </Button>
+            </Group>
+          </Grid.Col>
+
+          <Grid.Col span={2}>定期確認</Grid.Col>
+          <Grid.Col span={10}>
+            <Group spacing="xs">
+              <Checkbox
+                label="有効"
+                checked={schedule.enabled}
+                onChange={(e) => setSchedule({ ...schedule, enabled: e.currentTarget.checked })}
+              />
+              <TextInput
+                placeholder="間隔（例: 6h）"
+                value={schedule.interval}
+                onChange={(e) => setSchedule({ ...schedule, interval: e.target.value })}
+                disabled={!!schedule.cron}
+                style={{ width: 100 }}
+              />
+              <TextInput
+                placeholder="cron形式（例: 0 */3 * * *）"
+                value={schedule.cron}
+                onChange={(e) => setSchedule({ ...schedule, cron: e.target.value })}
                 style={{ flex: 1 }}
               />
+              <Checkbox
+                label="自動ダウンロード"
+                checked={schedule.autoDownload}
+                onChange={(e) => setSchedule({ ...schedule, autoDownload: e.currentTarget.checked })}
+              />
+              <Button variant="default" onClick={handleApplySchedule}>
+                適用
+              </Button>
+            </Group>
+            {scheduleStatus?.enabled && (
+              <Text size="xs" c="dimmed" align="left">
+                次回の確認: {new Date(scheduleStatus.nextRun).toLocaleString()}
+              </Text>
+            )}
+          </Grid.Col>
+
+          <Grid.Col span={2}>送信先</Grid.Col>
+          <Grid.Col span={10}>
+            <Group spacing="xs">
+              <Text size="sm" style={{ flex: 1 }} align="left">
+                {deliveries.length === 0
+                  ? 'なし'
+                  : deliveries.map((t) => t.enabled ? t.name : `${t.name}（無効）`).join('、')}
+              </Text>
+              <Button variant="default" onClick={() => setDeliveryOpened(true)}>
+                設定
+              </Button>
             </Group>
           </Grid.Col>
+
+          <Grid.Col span={2}>フック</Grid.Col>
+          <Grid.Col span={10}>
+            <Group spacing="xs">
+              <Text size="sm" style={{ flex: 1 }} align="left">
+                {hooks.length === 0
+                  ? 'なし'
+                  : hooks.map((h) => h.enabled ? h.name : `${h.name}（無効）`).join('、')}
+              </Text>
+              <Button variant="default" onClick={() => setHookOpened(true)}>
+                設定
+              </Button>
+            </Group>
+          </Grid.Col>
+
+          <Grid.Col span={2}>通知</Grid.Col>
+          <Grid.Col span={10}>
+            <Group spacing="xs">
+              <Text size="sm" style={{ flex: 1 }} align="left">
+                {[
+                  notifications.desktop ? 'デスクトップ' : null,
+                  ...(notifications.webhooks || []).filter((w) => w.enabled).map((w) => w.name),
+                ].filter((name) => name).join('、') || 'なし'}
+              </Text>
+              <Button variant="default" onClick={() => { setNotificationDraft(notifications); setNotificationOpened(true) }}>
+                設定
+              </Button>
+            </Group>
+          </Grid.Col>
+
+          <Grid.Col span={2}>キャッシュ</Grid.Col>
+          <Grid.Col span={10}>
+            <Group spacing="xs">
+              <Text size="sm" style={{ flex: 1 }} align="left">
+                {!cache.enabled
+                  ? '無効'
+                  : cache.offline
+                    ? 'オフライン（キャッシュだけを読む）'
+                    : `有効（目次 ${cache.tocTTL || '0s'}・本文 ${cache.episodeTTL || '0s'}）`}
+              </Text>
+              <Button variant="default" onClick={() => { setCacheDraft(cache); setCacheOpened(true) }}>
+                設定
+              </Button>
+            </Group>
+          </Grid.Col>
+
+          <Grid.Col span={10} offset={2}>
+            <Stack spacing="xs">
+              {availableFormats.map((format) => {
+                const selected = formats.find((f) => f.name === format.name)
+                const options = { ...format.defaults, ...selected?.options }
+                return (
+                  <Group key={format.name}>
+                    <Checkbox
+                      checked={!!selected}
+                      onChange={(event) => handleToggleFormat(format, event.currentTarget.checked)}
+                      label={format.label}
+                      style={{ width: 80 }}
+                    />
+                    {format.fields.map((field) => {
+                      switch (field.type) {
+                        case 'select':
+                          return (
+                            <Select
+                              key={field.key}
+                              value={options[field.key]}
+                              onChange={(value) => handleFormatOptionChange(format, field.key, value)}
+                              data={field.choices}
+                              disabled={!selected}
+                              title={field.label}
+                              style={{ flex: 1 }}
+                            />
+                          )
+                        case 'text':
+                          return (
+                            <TextInput
+                              key={field.key}
+                              value={options[field.key] || ''}
+                              onChange={(event) => handleFormatOptionChange(format, field.key, event.currentTarget.value)}
+                              disabled={!selected}
+                              placeholder={field.label}
+                              style={{ flex: 2 }}
+                            />
+                          )
+                        case 'number':
+                          return (
+                            <NumberInput
+                              key={field.key}
+                              value={options[field.key]}
+                              onChange={(value) => handleFormatOptionChange(format, field.key, Number(value))}
+                              disabled={!selected}
+                              title={field.label}
+                              style={{ width: 80 }}
+                            />
+                          )
+                        default:
+                          return (
+                            <Checkbox
+                              key={field.key}
+                              checked={!!options[field.key]}
+                              onChange={(event) => handleFormatOptionChange(format, field.key, event.currentTarget.checked)}
+                              disabled={!selected}
+                              label={field.label}
+                            />
+                          )
+                      }
+                    })}
+                    {selected && format.episodeFiles && (
+                      <TextInput
+                        value={selected.episodeFileName || ''}
+                        onChange={(event) => handleFileNameChange(format, 'episodeFileName', event.currentTarget.value)}
+                        placeholder="各話のファイル名（例: {author}/{title}/{ep:04d}_{eptitle}）"
+                        title="使用できる値: {ncode} {title} {author} {ep} {ep:04d} {eptitle} {chapter}"
+                        style={{ flex: 2 }}
+                      />
+                    )}
+                    {selected && format.novelFile && (
+                      <TextInput
+                        value={selected.novelFileName || ''}
+                        onChange={(event) => handleFileNameChange(format, 'novelFileName', event.currentTarget.value)}
+                        placeholder="ファイル名（例: {title}）"
+                        title="使用できる値: {ncode} {title} {author}"
+                        style={{ flex: 2 }}
+                      />
+                    )}
+                  </Group>
+                )
+              })}
+            </Stack>
+          </Grid.Col>
         </Grid>
 
         <Textarea 
@@ -317,11 +879,6 @@ export default function NarouDownload() {
 
         <Group position="right" align="flex-end">
           <Stack>
-            <Checkbox 
-              label="連結ファイルの作成"
-              checked={createCombined}
-              onChange={(event) => setCreateCombined(event.currentTarget.checked)}
-            />
             <Checkbox 
               label="手前に表示" 
               checked={showInFront}
@@ -329,12 +886,422 @@ export default function NarouDownload() {
             />
           </Stack>
 
+          <Button variant="default" onClick={() => setBookmarkOpened(true)} disabled={isDownloading}>
+            ブックマーク
+          </Button>
           <Button ml={50} onClick={handleDownload} disabled={isDownloading || !url}>
             {isDownloading ? 'ダウンロード中...' : 'ダウンロード'}
           </Button>
           <Button variant="default" onClick={handleExit}>終了</Button>
         </Group>
       </Stack>
+
+      <Modal
+        opened={authorWorks !== null}
+        onClose={() => setAuthorWorks(null)}
+        title={authorWorks ? `${authorWorks.author} の作品` : ''}
+        size="lg"
+      >
+        <ScrollArea h={360}>
+          <Checkbox.Group value={selectedWorks} onChange={setSelectedWorks}>
+            <Stack spacing="xs">
+              {authorWorks?.works.map((work) => (
+                <Checkbox
+                  key={work.ncode}
+                  value={work.ncode}
+                  label={work.page_type === 'short' ? `${work.title}（短編）` : `${work.title}（全${work.episodes}話）`}
+                />
+              ))}
+            </Stack>
+          </Checkbox.Group>
+        </ScrollArea>
+        <Group position="right" mt="md">
+          <Button variant="default" onClick={() => setSelectedWorks(authorWorks.works.map((work) => work.ncode))}>
+            すべて選択
+          </Button>
+          <Button variant="default" onClick={() => setSelectedWorks([])}>
+            選択解除
+          </Button>
+          <Button onClick={handleDownloadAuthorWorks} disabled={selectedWorks.length === 0}>
+            ダウンロード
+          </Button>
+        </Group>
+      </Modal>
+
+      <Modal
+        opened={bookmarkOpened}
+        onClose={() => { setBookmarkOpened(false); setBookmarkImport(null) }}
+        title="ブックマークの読み込み"
+        size="lg"
+      >
+        <Stack spacing="xs">
+          <Group spacing="xs">
+            <TextInput
+              placeholder="保存したブックマーク一覧のファイル、またはブックマーク一覧のURL"
+              style={{ flex: 1 }}
+              value={bookmarkSource}
+              onChange={(e) => setBookmarkSource(e.target.value)}
+            />
+            <Button variant="default" onClick={handleSelectBookmarkFile}>参照</Button>
+          </Group>
+          <TextInput
+            placeholder="Cookie（URLから読み込む場合のみ）"
+            value={bookmarkCookie}
+            onChange={(e) => setBookmarkCookie(e.target.value)}
+          />
+          <Group position="right">
+            <Button variant="default" onClick={handleImportBookmarks} disabled={!bookmarkSource}>読込</Button>
+          </Group>
+          {bookmarkImport && (
+            <ScrollArea h={240}>
+              <Stack spacing={4}>
+                {bookmarkImport.entries.map((entry) => (
+                  <Text key={entry.ncode} size="sm" c={entry.downloaded ? 'dimmed' : undefined}>
+                    {entry.title || entry.ncode}{entry.downloaded ? '（保存済み）' : ''}
+                  </Text>
+                ))}
+              </Stack>
+            </ScrollArea>
+          )}
+          <Group position="right">
+            <Button onClick={handleDownloadBookmarks} disabled={!bookmarkImport || (bookmarkImport.queue || []).length === 0}>
+              {bookmarkImport ? `${(bookmarkImport.queue || []).length}件をダウンロード` : 'ダウンロード'}
+            </Button>
+          </Group>
+        </Stack>
+      </Modal>
+
+      <Modal
+        opened={deliveryOpened}
+        onClose={() => setDeliveryOpened(false)}
+        title="送信先（ダウンロード後に自動で送る）"
+        size="lg"
+      >
+        <Stack spacing="xs">
+          <Select
+            placeholder="新しい送信先"
+            value={deliveries.some((t) => t.name === deliveryDraft.name) ? deliveryDraft.name : null}
+            onChange={handleSelectDelivery}
+            data={deliveries.map((t) => t.name)}
+            clearable
+          />
+          <Group spacing="xs">
+            <TextInput
+              placeholder="名前（例: Kindle）"
+              value={deliveryDraft.name}
+              onChange={(e) => setDeliveryDraft({ ...deliveryDraft, name: e.target.value })}
+              style={{ flex: 1 }}
+            />
+            <Select
+              value={deliveryDraft.kind}
+              onChange={(kind) => setDeliveryDraft({ ...deliveryDraft, kind })}
+              data={[{ value: 'email', label: 'メール' }, { value: 'folder', label: 'フォルダ' }]}
+              style={{ width: 120 }}
+            />
+            <Checkbox
+              label="有効"
+              checked={deliveryDraft.enabled}
+              onChange={(e) => setDeliveryDraft({ ...deliveryDraft, enabled: e.currentTarget.checked })}
+            />
+          </Group>
+          <Checkbox.Group
+            value={deliveryDraft.formats}
+            onChange={(formats) => setDeliveryDraft({ ...deliveryDraft, formats })}
+          >
+            <Group spacing="xs">
+              <Checkbox value="epub" label="EPUB" />
+              <Checkbox value="txt" label="テキスト" />
+              <Checkbox value="pdf" label="PDF" />
+              <Checkbox value="md" label="Markdown" />
+            </Group>
+          </Checkbox.Group>
+
+          {deliveryDraft.kind === 'email' ? (
+            <>
+              <Group spacing="xs">
+                <TextInput
+                  placeholder="SMTPサーバー"
+                  value={deliveryDraft.email.host}
+                  onChange={(e) => updateDeliveryDraft('email', { host: e.target.value })}
+                  style={{ flex: 1 }}
+                />
+                <NumberInput
+                  placeholder="ポート"
+                  value={deliveryDraft.email.port || ''}
+                  onChange={(port) => updateDeliveryDraft('email', { port: port || 0 })}
+                  style={{ width: 100 }}
+                />
+                <Checkbox
+                  label="SSL/TLS"
+                  checked={deliveryDraft.email.tls}
+                  onChange={(e) => updateDeliveryDraft('email', { tls: e.currentTarget.checked })}
+                />
+              </Group>
+              <Group spacing="xs">
+                <TextInput
+                  placeholder="ユーザー名"
+                  value={deliveryDraft.email.username}
+                  onChange={(e) => updateDeliveryDraft('email', { username: e.target.value })}
+                  style={{ flex: 1 }}
+                />
+                <TextInput
+                  type="password"
+                  placeholder="パスワード"
+                  value={deliveryDraft.email.password}
+                  onChange={(e) => updateDeliveryDraft('email', { password: e.target.value })}
+                  style={{ flex: 1 }}
+                />
+              </Group>
+              <TextInput
+                placeholder="送信元のメールアドレス"
+                value={deliveryDraft.email.from}
+                onChange={(e) => updateDeliveryDraft('email', { from: e.target.value })}
+              />
+              <TextInput
+                placeholder="宛先のメールアドレス（複数の場合は「,」で区切る）"
+                value={(deliveryDraft.email.to || []).join(', ')}
+                onChange={(e) => updateDeliveryDraft('email', { to: e.target.value.split(',').map((to) => to.trim()).filter((to) => to) })}
+              />
+              <NumberInput
+                label="1通の大きさの上限（MB）"
+                value={deliveryDraft.email.maxSize ? deliveryDraft.email.maxSize / (1 << 20) : 10}
+                onChange={(size) => updateDeliveryDraft('email', { maxSize: Math.round((size || 10) * (1 << 20)) })}
+                min={1}
+              />
+            </>
+          ) : (
+            <>
+              <Group spacing="xs">
+                <TextInput
+                  placeholder="コピー先のフォルダ（電子書籍リーダーや同期フォルダ）"
+                  value={deliveryDraft.folder.path}
+                  onChange={(e) => updateDeliveryDraft('folder', { path: e.target.value })}
+                  style={{ flex: 1 }}
+                />
+                <Button variant="default" onClick={handleSelectDeliveryFolder}>参照</Button>
+              </Group>
+              <TextInput
+                placeholder="ファイル名（例: {author}/{title}。空の場合は小説のタイトル）"
+                value={deliveryDraft.folder.layout}
+                onChange={(e) => updateDeliveryDraft('folder', { layout: e.target.value })}
+              />
+            </>
+          )}
+
+          <Group position="right">
+            <Button variant="default" onClick={handleDeleteDelivery} disabled={!deliveries.some((t) => t.name === deliveryDraft.name)}>
+              削除
+            </Button>
+            <Button onClick={handleSaveDelivery} disabled={!deliveryDraft.name}>
+              保存
+            </Button>
+          </Group>
+        </Stack>
+      </Modal>
+
+      <Modal
+        opened={hookOpened}
+        onClose={() => setHookOpened(false)}
+        title="フック（ダウンロード後に実行するコマンド）"
+        size="lg"
+      >
+        <Stack spacing="xs">
+          <Select
+            placeholder="新しいフック"
+            value={hooks.some((h) => h.name === hookDraft.name) ? hookDraft.name : null}
+            onChange={(name) => setHookDraft({ ...newHook(), ...hooks.find((h) => h.name === name) })}
+            data={hooks.map((h) => h.name)}
+            clearable
+          />
+          <Group spacing="xs">
+            <TextInput
+              placeholder="名前（例: AozoraEpub3）"
+              value={hookDraft.name}
+              onChange={(e) => setHookDraft({ ...hookDraft, name: e.target.value })}
+              style={{ flex: 1 }}
+            />
+            <NumberInput
+              placeholder="制限時間（秒）"
+              value={hookDraft.timeout || ''}
+              onChange={(timeout) => setHookDraft({ ...hookDraft, timeout: timeout || 0 })}
+              min={0}
+              style={{ width: 140 }}
+            />
+            <Checkbox
+              label="有効"
+              checked={hookDraft.enabled}
+              onChange={(e) => setHookDraft({ ...hookDraft, enabled: e.currentTarget.checked })}
+            />
+          </Group>
+          <Textarea
+            placeholder="コマンド（保存先のフォルダで実行する。例: java -jar AozoraEpub3.jar -enc UTF-8 all.txt）"
+            value={hookDraft.command}
+            onChange={(e) => setHookDraft({ ...hookDraft, command: e.target.value })}
+            minRows={3}
+          />
+          <Text size="xs" c="dimmed" align="left">
+            環境変数: NAROU_NCODE・NAROU_TITLE・NAROU_AUTHOR・NAROU_URL・NAROU_PROFILE・NAROU_SAVE_PATH・NAROU_FILES・NAROU_NEW_EPISODES・NAROU_NEW_EPISODES_JSON
+          </Text>
+          <Group position="right">
+            <Button variant="default" onClick={handleDeleteHook} disabled={!hooks.some((h) => h.name === hookDraft.name)}>
+              削除
+            </Button>
+            <Button onClick={handleSaveHook} disabled={!hookDraft.name || !hookDraft.command}>
+              保存
+            </Button>
+          </Group>
+        </Stack>
+      </Modal>
+
+      <Modal
+        opened={notificationOpened}
+        onClose={() => setNotificationOpened(false)}
+        title="通知"
+        size="lg"
+      >
+        <Stack spacing="xs">
+          <Group spacing="xs">
+            <Checkbox
+              label="デスクトップ通知"
+              checked={notificationDraft.desktop}
+              onChange={(e) => setNotificationDraft({ ...notificationDraft, desktop: e.currentTarget.checked })}
+            />
+            <Checkbox.Group
+              value={notificationDraft.events || []}
+              onChange={(events) => setNotificationDraft({ ...notificationDraft, events })}
+            >
+              <Group spacing="xs">
+                {notificationEventChoices.map((choice) => (
+                  <Checkbox key={choice.value} value={choice.value} label={choice.label} />
+                ))}
+              </Group>
+            </Checkbox.Group>
+          </Group>
+          <Text size="xs" c="dimmed" align="left">
+            出来事を選ばない場合はすべて通知します
+          </Text>
+
+          <Text size="sm" align="left">Webhook</Text>
+          {(notificationDraft.webhooks || []).map((webhook, index) => (
+            <Group key={index} spacing="xs">
+              <Checkbox
+                checked={webhook.enabled}
+                onChange={(e) => updateWebhook(index, { enabled: e.currentTarget.checked })}
+              />
+              <TextInput
+                placeholder="名前"
+                value={webhook.name}
+                onChange={(e) => updateWebhook(index, { name: e.target.value })}
+                style={{ width: 100 }}
+              />
+              <TextInput
+                placeholder="URL"
+                value={webhook.url}
+                onChange={(e) => updateWebhook(index, { url: e.target.value })}
+                style={{ flex: 1 }}
+              />
+              <Select
+                value={webhook.format || 'json'}
+                onChange={(format) => updateWebhook(index, { format })}
+                data={[{ value: 'json', label: 'JSON' }, { value: 'discord', label: 'Discord' }, { value: 'slack', label: 'Slack' }]}
+                style={{ width: 100 }}
+              />
+              <Button
+                variant="default"
+                onClick={() => setNotificationDraft({ ...notificationDraft, webhooks: notificationDraft.webhooks.filter((_, i) => i !== index) })}
+              >
+                削除
+              </Button>
+            </Group>
+          ))}
+          <Group position="apart">
+            <Button
+              variant="default"
+              onClick={() => setNotificationDraft({
+                ...notificationDraft,
+                webhooks: [...(notificationDraft.webhooks || []), { name: '', url: '', format: 'json', events: [], enabled: true }],
+              })}
+            >
+              Webhookを追加
+            </Button>
+            <Group spacing="xs">
+              <Button variant="default" onClick={handleTestNotification}>
+                確認の通知
+              </Button>
+              <Button onClick={handleSaveNotifications}>
+                保存
+              </Button>
+            </Group>
+          </Group>
+        </Stack>
+      </Modal>
+
+      <Modal
+        opened={cacheOpened}
+        onClose={() => setCacheOpened(false)}
+        title="キャッシュ"
+      >
+        <Stack spacing="xs">
+          <Checkbox
+            label="取得したページをキャッシュする"
+            checked={cacheDraft.enabled}
+            onChange={(e) => setCacheDraft({ ...cacheDraft, enabled: e.currentTarget.checked })}
+          />
+          <Checkbox
+            label="オフラインモード（通信せずにキャッシュだけを読む）"
+            checked={cacheDraft.offline}
+            disabled={!cacheDraft.enabled}
+            onChange={(e) => setCacheDraft({ ...cacheDraft, offline: e.currentTarget.checked })}
+          />
+          <TextInput
+            label="目次・小説の情報の有効期限"
+            description="例: 0s（毎回サーバーに確認する）、10m"
+            value={cacheDraft.tocTTL}
+            onChange={(e) => setCacheDraft({ ...cacheDraft, tocTTL: e.target.value })}
+          />
+          <TextInput
+            label="本文の有効期限"
+            description="例: 24h、720h"
+            value={cacheDraft.episodeTTL}
+            onChange={(e) => setCacheDraft({ ...cacheDraft, episodeTTL: e.target.value })}
+          />
+          <Text size="xs" c="dimmed" align="left">
+            有効期限を過ぎたページは ETag・Last-Modified で変更がないかサーバーに確認します
+          </Text>
+          <Group position="apart">
+            <Button variant="default" onClick={handleClearCache}>
+              キャッシュを削除
+            </Button>
+            <Button onClick={handleSaveCache}>
+              保存
+            </Button>
+          </Group>
+        </Stack>
+      </Modal>
     </Card>
   )
 }
+
+// notificationEventChoices は通知できる出来事の選択肢
+const notificationEventChoices = [
+  { value: 'completed', label: '完了' },
+  { value: 'failed', label: '失敗' },
+  { value: 'newEpisodes', label: '新しい話' },
+]
+
+// newHook は新しいフックの初期値を返します
+function newHook() {
+  return { name: '', command: '', timeout: 0, enabled: true }
+}
+
+// newDeliveryTarget は新しい送信先の初期値を返します
+function newDeliveryTarget() {
+  return {
+    name: '',
+    kind: 'email',
+    enabled: true,
+    formats: ['epub'],
+    email: { host: '', port: 0, tls: false, username: '', password: '', from: '', to: [], maxSize: 0 },
+    folder: { path: '', layout: '' },
+  }
+}
@@ -0,0 +1,229 @@
+import {
+  TextInput,
+  Button,
+  Select,
+  Card,
+  Group,
+  Stack,
+  Text,
+  SegmentedControl,
+} from '@mantine/core'
+import { useState, useEffect, useRef } from 'react'
+import {
+  SelectFolder,
+  ListLibrary,
+  GetEpisodeContent,
+  SaveReadingPosition,
+  MarkEpisodesRead,
+} from '../../wailsjs/go/main/App'
+
+// 段落内の要素を表示します（ルビ・傍点・縦中横）
+function ReaderInlines({ inlines }) {
+  return (inlines || []).map((inline, i) => {
+    switch (inline.kind) {
+      case 'ruby':
+        return <ruby key={i}>{inline.text}<rp>(</rp><rt>{inline.ruby}</rt><rp>)</rp></ruby>
+      case 'emphasis':
+        return <em key={i} className="reader-emphasis">{inline.text}</em>
+      case 'tcy':
+        return <span key={i} className="reader-tcy">{inline.text}</span>
+      default:
+        return <span key={i}>{inline.text}</span>
+    }
+  })
+}
+
+export default function Reader() {
+  const [folder, setFolder] = useState('')
+  const [novels, setNovels] = useState([])
+  const [selected, setSelected] = useState(null)
+  const [layout, setLayout] = useState('horizontal')
+  const [content, setContent] = useState(null)
+  const [error, setError] = useState('')
+  const bodyRef = useRef(null)
+  const restoreRef = useRef(0)
+  const saveTimerRef = useRef(null)
+
+  const novel = novels.find((n) => n.ncode === selected)
+
+  const loadLibrary = async () => {
+    try {
+      setNovels(await ListLibrary(folder))
+      setError('')
+    } catch (error) {
+      console.error('本棚の読み込み中にエラーが発生しました:', error)
+      setError('本棚を読み込めませんでした - ' + error)
+    }
+  }
+
+  useEffect(() => {
+    loadLibrary()
+  }, [])
+
+  // 話の読み込み（number を省略した場合は読んでいる話から再開する）
+  const openEpisode = async (target, number, nextLayout, paragraph = 0) => {
+    try {
+      const episode = await GetEpisodeContent(target.folder, target.ncode, number, nextLayout)
+      restoreRef.current = paragraph
+      setContent(episode)
+      setError('')
+    } catch (error) {
+      console.error('本文の読み込み中にエラーが発生しました:', error)
+      setError('本文を読み込めませんでした - ' + error)
+    }
+  }
+
+  const handleSelectNovel = (ncode) => {
+    setSelected(ncode)
+    const target = novels.find((n) => n.ncode === ncode)
+    if (!target) return
+    const position = target.position
+    const nextLayout = position?.layout || layout
+    setLayout(nextLayout)
+    openEpisode(target, position?.number || '', nextLayout, position?.paragraph || 0)
+  }
+
+  // 次の話へ進むときは読み終えた話を既読にする
+  const handleNext = async () => {
+    try {
+      await MarkEpisodesRead(novel.folder, novel.ncode, [content.number], true)
+      loadLibrary()
+    } catch (error) {
+      console.error('既読の保存中にエラーが発生しました:', error)
+    }
+    openEpisode(novel, content.next.number, layout)
+  }
+
+  const handleLayoutChange = (value) => {
+    setLayout(value)
+    if (novel && content) {
+      openEpisode(novel, content.number, value, currentParagraph())
+    }
+  }
+
+  const handleSelectFolder = async () => {
+    try {
+      const selectedFolder = await SelectFolder()
+      if (selectedFolder) setFolder(selectedFolder)
+    } catch (error) {
+      console.error('フォルダ選択中にエラーが発生しました:', error)
+    }
+  }
+
+  // 画面の先頭（縦書きでは右端）にある段落の位置
+  const currentParagraph = () => {
+    const body = bodyRef.current
+    if (!body) return 0
+    const rect = body.getBoundingClientRect()
+    for (const element of body.querySelectorAll('[data-paragraph]')) {
+      const r = element.getBoundingClientRect()
+      if (layout === 'vertical' ? r.left < rect.right : r.bottom > rect.top) {
+        return Number(element.dataset.paragraph)
+      }
+    }
+    return 0
+  }
+
+  // 読んでいる位置の保存（スクロールが落ち着いてから保存する）
+  const savePosition = () => {
+    if (!novel || !content) return
+    clearTimeout(saveTimerRef.current)
+    saveTimerRef.current = setTimeout(async () => {
+      try {
+        await SaveReadingPosition(novel.folder, novel.ncode, { number: content.number, paragraph: currentParagraph(), layout })
+      } catch (error) {
+        console.error('読んでいる位置の保存中にエラーが発生しました:', error)
+      }
+    }, 500)
+  }
+
+  // 話を開いたら保存されていた位置までスクロールする
+  useEffect(() => {
+    const body = bodyRef.current
+    if (!body || !content) return
+    const element = body.querySelector(`[data-paragraph="${restoreRef.current}"]`)
+    if (element && restoreRef.current > 0) {
+      element.scrollIntoView({ block: 'start', inline: 'start' })
+    } else if (layout === 'vertical') {
+      body.scrollLeft = body.scrollWidth
+    } else {
+      body.scrollTop = 0
+    }
+    savePosition()
+  }, [content])
+
+  useEffect(() => () => clearTimeout(saveTimerRef.current), [])
+
+  return (
+    <Card padding="md" radius={0} withBorder={false} style={{ width: '100vw', height: 'calc(100vh - 48px)' }}>
+      <Stack spacing="sm" style={{ height: '100%' }}>
+        <Group spacing="xs">
+          <TextInput
+            placeholder="本棚のフォルダ（空欄の場合は既定の保存先）"
+            style={{ flex: 1 }}
+            value={folder}
+            onChange={(e) => setFolder(e.target.value)}
+          />
+          <Button variant="default" onClick={handleSelectFolder}>参照</Button>
+          <Button variant="default" onClick={loadLibrary}>再読み込み</Button>
+        </Group>
+
+        <Group spacing="xs">
+          <Select
+            placeholder="小説を選択してください"
+            style={{ flex: 1 }}
+            searchable
+            data={novels.map((n) => ({ value: n.ncode, label: `${n.title}（未読 ${n.unread} / ${n.episodes}話）` }))}
+            value={selected}
+            onChange={handleSelectNovel}
+          />
+          <SegmentedControl
+            value={layout}
+            onChange={handleLayoutChange}
+            data={[
+              { value: 'horizontal', label: '横書き' },
+              { value: 'vertical', label: '縦書き' },
+            ]}
+          />
+        </Group>
+
+        {error && <Text c="red" size="sm" align="left">{error}</Text>}
+
+        {content && (
+          <>
+            <Group justify="space-between">
+              <Button variant="default" size="xs" disabled={!content.prev} onClick={() => openEpisode(novel, content.prev.number, layout)}>
+                前の話
+              </Button>
+              <Text size="sm">
+                {content.chapter && `${content.chapter} / `}{content.episode}（{content.index} / {content.total}）
+              </Text>
+              <Button variant="default" size="xs" disabled={!content.next} onClick={handleNext}>
+                次の話
+              </Button>
+            </Group>
+
+            <div
+              ref={bodyRef}
+              className={`reader-body reader-${content.layout}`}
+              onScroll={savePosition}
+            >
+              {content.blocks.map((block, i) => {
+                switch (block.kind) {
+                  case 'paragraph':
+                    return <p key={i} data-paragraph={i}><ReaderInlines inlines={block.inlines} /></p>
+                  case 'illustration':
+                    return <p key={i} data-paragraph={i}><img src={block.src} alt="挿絵" /></p>
+                  case 'separator':
+                    return <hr key={i} data-paragraph={i} />
+                  default:
+                    return <p key={i} data-paragraph={i}>{' '}</p>
+                }
+              })}
+            </div>
+          </>
+        )}
+      </Stack>
+    </Card>
+  )
+}
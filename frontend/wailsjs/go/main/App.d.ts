@@ -2,24 +2,92 @@
 // This file is automatically generated. DO NOT EDIT
 import {main} from '../models';
 
-export function DownloadNovel(arg1:string,arg2:string,arg3:Record<string, any>):Promise<void>;
+export function ApplyCacheSettings(arg1:main.CacheSettings):Promise<main.Settings>;
+
+export function ApplyNotifications(arg1:main.NotificationSettings):Promise<main.Settings>;
+
+export function ApplySchedule(arg1:main.ScheduleSettings):Promise<void>;
+
+export function CheckUpdates(arg1:string,arg2:boolean):Promise<Array<main.UpdateCheck>>;
+
+export function ClearCache():Promise<void>;
+
+export function DeleteDeliveryTarget(arg1:string):Promise<main.Settings>;
+
+export function DeleteHook(arg1:string):Promise<main.Settings>;
+
+export function DeleteProfile(arg1:string):Promise<main.Settings>;
+
+export function DeliverNovel(arg1:string,arg2:string,arg3:boolean):Promise<Array<main.DeliveryResult>>;
+
+export function DiffEpisode(arg1:main.DiffRequest):Promise<main.EpisodeDiff>;
+
+export function DownloadAuthorWorks(arg1:main.AuthorWorks,arg2:Array<string>,arg3:string,arg4:Array<main.FormatRequest>):Promise<void>;
+
+export function DownloadNovel(arg1:string,arg2:string,arg3:Array<main.FormatRequest>):Promise<void>;
+
+export function DownloadNovelWithProfile(arg1:string,arg2:string,arg3:string):Promise<void>;
+
+export function DownloadQueue(arg1:Array<main.QueueItem>,arg2:Array<main.FormatRequest>):Promise<void>;
+
+export function ExportUnread(arg1:main.UnreadExportRequest):Promise<string>;
+
+export function GetAuthorWorks(arg1:string):Promise<main.AuthorWorks>;
+
+export function GetEpisodeContent(arg1:string,arg2:string,arg3:string,arg4:string):Promise<main.ReaderEpisode>;
+
+export function GetOutputFormats():Promise<Array<main.OutputFormat>>;
+
+export function GetReadingPosition(arg1:string,arg2:string):Promise<main.ReadingPosition>;
+
+export function GetReadingProgress(arg1:string,arg2:string):Promise<main.ReadingProgress>;
+
+export function GetScheduleStatus():Promise<main.ScheduleStatus>;
 
 export function GetTitle(arg1:string):Promise<string>;
 
+export function GetUpdateChecks(arg1:string):Promise<Array<main.UpdateCheck>>;
+
+export function ImportBookmarks(arg1:string,arg2:string,arg3:string):Promise<main.BookmarkImport>;
+
+export function ListEpisodeVersions(arg1:string,arg2:string,arg3:string):Promise<Array<main.EpisodeVersion>>;
+
+export function ListLibrary(arg1:string):Promise<Array<main.LibraryNovel>>;
+
 export function LoadSettings():Promise<main.Settings>;
 
+export function MarkEpisodesRead(arg1:string,arg2:string,arg3:Array<string>,arg4:boolean):Promise<void>;
+
 export function OpenFolder(arg1:string):Promise<void>;
 
 export function Quit():Promise<void>;
 
+export function SaveDeliveryTarget(arg1:main.DeliveryTarget):Promise<main.Settings>;
+
+export function SaveHook(arg1:main.Hook):Promise<main.Settings>;
+
+export function SaveProfile(arg1:main.Profile):Promise<main.Settings>;
+
+export function SaveReadingPosition(arg1:string,arg2:string,arg3:main.ReadingPosition):Promise<void>;
+
 export function SaveSettings(arg1:main.Settings):Promise<void>;
 
 export function ScrapeChapter(arg1:string):Promise<string>;
 
 export function ScrapeChapterWithHTML(arg1:string):Promise<string>;
 
+export function SearchLibrary(arg1:main.SearchRequest):Promise<main.SearchResult>;
+
+export function SelectBookmarkFile():Promise<string>;
+
 export function SelectFolder():Promise<string>;
 
+export function SendTestNotification(arg1:main.NotificationSettings):Promise<void>;
+
 export function SetAlwaysOnTop(arg1:boolean):Promise<void>;
 
+export function ShowDesktopNotification(arg1:string,arg2:string):Promise<void>;
+
 export function StartScraping(arg1:string):Promise<main.ScrapeResult>;
+
+export function VerifyNovel(arg1:string,arg2:main.VerifyOptions):Promise<Array<main.VerifyReport>>;
@@ -2,18 +2,118 @@
 // Cynhyrchwyd y ffeil hon yn awtomatig. PEIDIWCH Â MODIWL
 // This file is automatically generated. DO NOT EDIT
 
+export function ApplyCacheSettings(arg1) {
+  return window['go']['main']['App']['ApplyCacheSettings'](arg1);
+}
+
+export function ApplyNotifications(arg1) {
+  return window['go']['main']['App']['ApplyNotifications'](arg1);
+}
+
+export function ApplySchedule(arg1) {
+  return window['go']['main']['App']['ApplySchedule'](arg1);
+}
+
+export function CheckUpdates(arg1, arg2) {
+  return window['go']['main']['App']['CheckUpdates'](arg1, arg2);
+}
+
+export function ClearCache() {
+  return window['go']['main']['App']['ClearCache']();
+}
+
+export function DeleteDeliveryTarget(arg1) {
+  return window['go']['main']['App']['DeleteDeliveryTarget'](arg1);
+}
+
+export function DeleteHook(arg1) {
+  return window['go']['main']['App']['DeleteHook'](arg1);
+}
+
+export function DeleteProfile(arg1) {
+  return window['go']['main']['App']['DeleteProfile'](arg1);
+}
+
+export function DeliverNovel(arg1, arg2, arg3) {
+  return window['go']['main']['App']['DeliverNovel'](arg1, arg2, arg3);
+}
+
+export function DiffEpisode(arg1) {
+  return window['go']['main']['App']['DiffEpisode'](arg1);
+}
+
+export function DownloadAuthorWorks(arg1, arg2, arg3, arg4) {
+  return window['go']['main']['App']['DownloadAuthorWorks'](arg1, arg2, arg3, arg4);
+}
+
 export function DownloadNovel(arg1, arg2, arg3) {
   return window['go']['main']['App']['DownloadNovel'](arg1, arg2, arg3);
 }
 
+export function DownloadNovelWithProfile(arg1, arg2, arg3) {
+  return window['go']['main']['App']['DownloadNovelWithProfile'](arg1, arg2, arg3);
+}
+
+export function DownloadQueue(arg1, arg2) {
+  return window['go']['main']['App']['DownloadQueue'](arg1, arg2);
+}
+
+export function ExportUnread(arg1) {
+  return window['go']['main']['App']['ExportUnread'](arg1);
+}
+
+export function GetAuthorWorks(arg1) {
+  return window['go']['main']['App']['GetAuthorWorks'](arg1);
+}
+
+export function GetEpisodeContent(arg1, arg2, arg3, arg4) {
+  return window['go']['main']['App']['GetEpisodeContent'](arg1, arg2, arg3, arg4);
+}
+
+export function GetOutputFormats() {
+  return window['go']['main']['App']['GetOutputFormats']();
+}
+
+export function GetReadingPosition(arg1, arg2) {
+  return window['go']['main']['App']['GetReadingPosition'](arg1, arg2);
+}
+
+export function GetReadingProgress(arg1, arg2) {
+  return window['go']['main']['App']['GetReadingProgress'](arg1, arg2);
+}
+
+export function GetScheduleStatus() {
+  return window['go']['main']['App']['GetScheduleStatus']();
+}
+
 export function GetTitle(arg1) {
   return window['go']['main']['App']['GetTitle'](arg1);
 }
 
+export function GetUpdateChecks(arg1) {
+  return window['go']['main']['App']['GetUpdateChecks'](arg1);
+}
+
+export function ImportBookmarks(arg1, arg2, arg3) {
+  return window['go']['main']['App']['ImportBookmarks'](arg1, arg2, arg3);
+}
+
+export function ListEpisodeVersions(arg1, arg2, arg3) {
+  return window['go']['main']['App']['ListEpisodeVersions'](arg1, arg2, arg3);
+}
+
+export function ListLibrary(arg1) {
+  return window['go']['main']['App']['ListLibrary'](arg1);
+}
+
 export function LoadSettings() {
   return window['go']['main']['App']['LoadSettings']();
 }
 
+export function MarkEpisodesRead(arg1, arg2, arg3, arg4) {
+  return window['go']['main']['App']['MarkEpisodesRead'](arg1, arg2, arg3, arg4);
+}
+
 export function OpenFolder(arg1) {
   return window['go']['main']['App']['OpenFolder'](arg1);
 }
@@ -22,6 +122,22 @@ export function Quit() {
   return window['go']['main']['App']['Quit']();
 }
 
+export function SaveDeliveryTarget(arg1) {
+  return window['go']['main']['App']['SaveDeliveryTarget'](arg1);
+}
+
+export function SaveHook(arg1) {
+  return window['go']['main']['App']['SaveHook'](arg1);
+}
+
+export function SaveProfile(arg1) {
+  return window['go']['main']['App']['SaveProfile'](arg1);
+}
+
+export function SaveReadingPosition(arg1, arg2, arg3) {
+  return window['go']['main']['App']['SaveReadingPosition'](arg1, arg2, arg3);
+}
+
 export function SaveSettings(arg1) {
   return window['go']['main']['App']['SaveSettings'](arg1);
 }
@@ -34,14 +150,34 @@ export function ScrapeChapterWithHTML(arg1) {
   return window['go']['main']['App']['ScrapeChapterWithHTML'](arg1);
 }
 
+export function SearchLibrary(arg1) {
+  return window['go']['main']['App']['SearchLibrary'](arg1);
+}
+
+export function SelectBookmarkFile() {
+  return window['go']['main']['App']['SelectBookmarkFile']();
+}
+
 export function SelectFolder() {
   return window['go']['main']['App']['SelectFolder']();
 }
 
+export function SendTestNotification(arg1) {
+  return window['go']['main']['App']['SendTestNotification'](arg1);
+}
+
 export function SetAlwaysOnTop(arg1) {
   return window['go']['main']['App']['SetAlwaysOnTop'](arg1);
 }
 
+export function ShowDesktopNotification(arg1, arg2) {
+  return window['go']['main']['App']['ShowDesktopNotification'](arg1, arg2);
+}
+
 export function StartScraping(arg1) {
   return window['go']['main']['App']['StartScraping'](arg1);
 }
+
+export function VerifyNovel(arg1, arg2) {
+  return window['go']['main']['App']['VerifyNovel'](arg1, arg2);
+}
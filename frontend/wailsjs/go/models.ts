@@ -1,28 +1,1000 @@
 export namespace main {
 	
+	export class AuthorWork {
+	    ncode: string;
+	    title: string;
+	    url: string;
+	    page_type: string;
+	    episodes: number;
+	    updated_at: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new AuthorWork(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.ncode = source["ncode"];
+	        this.title = source["title"];
+	        this.url = source["url"];
+	        this.page_type = source["page_type"];
+	        this.episodes = source["episodes"];
+	        this.updated_at = source["updated_at"];
+	    }
+	}
+	export class AuthorWorks {
+	    user_id: string;
+	    author: string;
+	    works: AuthorWork[];
+	
+	    static createFrom(source: any = {}) {
+	        return new AuthorWorks(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.user_id = source["user_id"];
+	        this.author = source["author"];
+	        this.works = this.convertValues(source["works"], AuthorWork);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class Inline {
+	    kind: string;
+	    text: string;
+	    ruby?: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new Inline(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.kind = source["kind"];
+	        this.text = source["text"];
+	        this.ruby = source["ruby"];
+	    }
+	}
+	export class Block {
+	    kind: string;
+	    inlines?: Inline[];
+	    src?: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new Block(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.kind = source["kind"];
+	        this.inlines = this.convertValues(source["inlines"], Inline);
+	        this.src = source["src"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class BookmarkEntry {
+	    ncode: string;
+	    title: string;
+	    url: string;
+	    downloaded: boolean;
+	
+	    static createFrom(source: any = {}) {
+	        return new BookmarkEntry(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.ncode = source["ncode"];
+	        this.title = source["title"];
+	        this.url = source["url"];
+	        this.downloaded = source["downloaded"];
+	    }
+	}
+	export class QueueItem {
+	    url: string;
+	    title: string;
+	    savePath: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new QueueItem(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.url = source["url"];
+	        this.title = source["title"];
+	        this.savePath = source["savePath"];
+	    }
+	}
+	export class BookmarkImport {
+	    entries: BookmarkEntry[];
+	    queue: QueueItem[];
+	    duplicates: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new BookmarkImport(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.entries = this.convertValues(source["entries"], BookmarkEntry);
+	        this.queue = this.convertValues(source["queue"], QueueItem);
+	        this.duplicates = source["duplicates"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class CacheSettings {
+	    enabled: boolean;
+	    offline: boolean;
+	    tocTTL: string;
+	    episodeTTL: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new CacheSettings(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.enabled = source["enabled"];
+	        this.offline = source["offline"];
+	        this.tocTTL = source["tocTTL"];
+	        this.episodeTTL = source["episodeTTL"];
+	    }
+	}
 	export class ChapterInfo {
 	    title: string;
-	    url: string;
-	    content: string;
-	    raw_html: string;
-	    full_page_html: string;
-	    retry_count: number;
-	    failed: boolean;
+	    chapter_title?: string;
+	    url: string;
+	    content: string;
+	    raw_html: string;
+	    full_page_html: string;
+	    blocks?: Block[];
+	    retry_count: number;
+	    failed: boolean;
+	    revised?: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new ChapterInfo(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.title = source["title"];
+	        this.chapter_title = source["chapter_title"];
+	        this.url = source["url"];
+	        this.content = source["content"];
+	        this.raw_html = source["raw_html"];
+	        this.full_page_html = source["full_page_html"];
+	        this.blocks = this.convertValues(source["blocks"], Block);
+	        this.retry_count = source["retry_count"];
+	        this.failed = source["failed"];
+	        this.revised = source["revised"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class DeliveryResult {
+	    target: string;
+	    files: string[];
+	    error?: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new DeliveryResult(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.target = source["target"];
+	        this.files = source["files"];
+	        this.error = source["error"];
+	    }
+	}
+	export class FolderDelivery {
+	    path: string;
+	    layout: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new FolderDelivery(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.path = source["path"];
+	        this.layout = source["layout"];
+	    }
+	}
+	export class EmailDelivery {
+	    host: string;
+	    port: number;
+	    tls: boolean;
+	    username: string;
+	    password: string;
+	    from: string;
+	    to: string[];
+	    maxSize: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new EmailDelivery(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.host = source["host"];
+	        this.port = source["port"];
+	        this.tls = source["tls"];
+	        this.username = source["username"];
+	        this.password = source["password"];
+	        this.from = source["from"];
+	        this.to = source["to"];
+	        this.maxSize = source["maxSize"];
+	    }
+	}
+	export class DeliveryTarget {
+	    name: string;
+	    kind: string;
+	    enabled: boolean;
+	    formats: string[];
+	    email: EmailDelivery;
+	    folder: FolderDelivery;
+	
+	    static createFrom(source: any = {}) {
+	        return new DeliveryTarget(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.name = source["name"];
+	        this.kind = source["kind"];
+	        this.enabled = source["enabled"];
+	        this.formats = source["formats"];
+	        this.email = this.convertValues(source["email"], EmailDelivery);
+	        this.folder = this.convertValues(source["folder"], FolderDelivery);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class DiffOp {
+	    kind: string;
+	    text: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new DiffOp(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.kind = source["kind"];
+	        this.text = source["text"];
+	    }
+	}
+	export class DiffRequest {
+	    folder: string;
+	    ncode: string;
+	    number: string;
+	    from: string;
+	    to: string;
+	    mode: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new DiffRequest(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.folder = source["folder"];
+	        this.ncode = source["ncode"];
+	        this.number = source["number"];
+	        this.from = source["from"];
+	        this.to = source["to"];
+	        this.mode = source["mode"];
+	    }
+	}
+	
+	export class EpisodeDiff {
+	    ncode: string;
+	    number: string;
+	    title: string;
+	    from: string;
+	    to: string;
+	    mode: string;
+	    ops: DiffOp[];
+	
+	    static createFrom(source: any = {}) {
+	        return new EpisodeDiff(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.ncode = source["ncode"];
+	        this.number = source["number"];
+	        this.title = source["title"];
+	        this.from = source["from"];
+	        this.to = source["to"];
+	        this.mode = source["mode"];
+	        this.ops = this.convertValues(source["ops"], DiffOp);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class EpisodeProgress {
+	    number: string;
+	    title: string;
+	    chapter: string;
+	    read: boolean;
+	    // Go type: time
+	    readAt: any;
+	    paragraph: number;
+	    // Go type: time
+	    fetchedAt: any;
+	
+	    static createFrom(source: any = {}) {
+	        return new EpisodeProgress(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.number = source["number"];
+	        this.title = source["title"];
+	        this.chapter = source["chapter"];
+	        this.read = source["read"];
+	        this.readAt = this.convertValues(source["readAt"], null);
+	        this.paragraph = source["paragraph"];
+	        this.fetchedAt = this.convertValues(source["fetchedAt"], null);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class EpisodeVersion {
+	    id: string;
+	    // Go type: time
+	    fetchedAt: any;
+	    current: boolean;
+	
+	    static createFrom(source: any = {}) {
+	        return new EpisodeVersion(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.id = source["id"];
+	        this.fetchedAt = this.convertValues(source["fetchedAt"], null);
+	        this.current = source["current"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	
+	export class FormatField {
+	    key: string;
+	    label: string;
+	    type: string;
+	    choices?: string[];
+	
+	    static createFrom(source: any = {}) {
+	        return new FormatField(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.key = source["key"];
+	        this.label = source["label"];
+	        this.type = source["type"];
+	        this.choices = source["choices"];
+	    }
+	}
+	export class FormatRequest {
+	    name: string;
+	    options?: Record<string, any>;
+	    episodeFileName?: string;
+	    novelFileName?: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new FormatRequest(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.name = source["name"];
+	        this.options = source["options"];
+	        this.episodeFileName = source["episodeFileName"];
+	        this.novelFileName = source["novelFileName"];
+	    }
+	}
+	export class Hook {
+	    name: string;
+	    command: string;
+	    timeout: number;
+	    enabled: boolean;
+	
+	    static createFrom(source: any = {}) {
+	        return new Hook(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.name = source["name"];
+	        this.command = source["command"];
+	        this.timeout = source["timeout"];
+	        this.enabled = source["enabled"];
+	    }
+	}
+	
+	export class ReadingPosition {
+	    number: string;
+	    paragraph: number;
+	    layout: string;
+	    // Go type: time
+	    updatedAt: any;
+	
+	    static createFrom(source: any = {}) {
+	        return new ReadingPosition(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.number = source["number"];
+	        this.paragraph = source["paragraph"];
+	        this.layout = source["layout"];
+	        this.updatedAt = this.convertValues(source["updatedAt"], null);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class LibraryNovel {
+	    folder: string;
+	    ncode: string;
+	    title: string;
+	    author: string;
+	    short: boolean;
+	    episodes: number;
+	    unread: number;
+	    // Go type: time
+	    updatedAt: any;
+	    position?: ReadingPosition;
+	
+	    static createFrom(source: any = {}) {
+	        return new LibraryNovel(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.folder = source["folder"];
+	        this.ncode = source["ncode"];
+	        this.title = source["title"];
+	        this.author = source["author"];
+	        this.short = source["short"];
+	        this.episodes = source["episodes"];
+	        this.unread = source["unread"];
+	        this.updatedAt = this.convertValues(source["updatedAt"], null);
+	        this.position = this.convertValues(source["position"], ReadingPosition);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class NewEpisode {
+	    number: string;
+	    title: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new NewEpisode(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.number = source["number"];
+	        this.title = source["title"];
+	    }
+	}
+	export class Webhook {
+	    name: string;
+	    url: string;
+	    format: string;
+	    events: string[];
+	    enabled: boolean;
+	
+	    static createFrom(source: any = {}) {
+	        return new Webhook(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.name = source["name"];
+	        this.url = source["url"];
+	        this.format = source["format"];
+	        this.events = source["events"];
+	        this.enabled = source["enabled"];
+	    }
+	}
+	export class NotificationSettings {
+	    desktop: boolean;
+	    events: string[];
+	    webhooks: Webhook[];
+	
+	    static createFrom(source: any = {}) {
+	        return new NotificationSettings(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.desktop = source["desktop"];
+	        this.events = source["events"];
+	        this.webhooks = this.convertValues(source["webhooks"], Webhook);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class OutputFormat {
+	    name: string;
+	    label: string;
+	    fields: FormatField[];
+	    defaults: Record<string, any>;
+	    episodeFiles: boolean;
+	    novelFile: boolean;
+	
+	    static createFrom(source: any = {}) {
+	        return new OutputFormat(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.name = source["name"];
+	        this.label = source["label"];
+	        this.fields = this.convertValues(source["fields"], FormatField);
+	        this.defaults = source["defaults"];
+	        this.episodeFiles = source["episodeFiles"];
+	        this.novelFile = source["novelFile"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class Profile {
+	    name: string;
+	    formats: FormatRequest[];
+	    savePath: string;
+	    skipHooks: boolean;
+	
+	    static createFrom(source: any = {}) {
+	        return new Profile(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.name = source["name"];
+	        this.formats = this.convertValues(source["formats"], FormatRequest);
+	        this.savePath = source["savePath"];
+	        this.skipHooks = source["skipHooks"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	
+	export class ReaderInline {
+	    kind: string;
+	    text: string;
+	    ruby?: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new ReaderInline(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.kind = source["kind"];
+	        this.text = source["text"];
+	        this.ruby = source["ruby"];
+	    }
+	}
+	export class ReaderBlock {
+	    kind: string;
+	    inlines?: ReaderInline[];
+	    src?: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new ReaderBlock(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.kind = source["kind"];
+	        this.inlines = this.convertValues(source["inlines"], ReaderInline);
+	        this.src = source["src"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class ReaderEpisodeLink {
+	    number: string;
+	    title: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new ReaderEpisodeLink(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.number = source["number"];
+	        this.title = source["title"];
+	    }
+	}
+	export class ReaderEpisode {
+	    ncode: string;
+	    title: string;
+	    number: string;
+	    index: number;
+	    total: number;
+	    chapter: string;
+	    episode: string;
+	    layout: string;
+	    blocks: ReaderBlock[];
+	    prev?: ReaderEpisodeLink;
+	    next?: ReaderEpisodeLink;
+	
+	    static createFrom(source: any = {}) {
+	        return new ReaderEpisode(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.ncode = source["ncode"];
+	        this.title = source["title"];
+	        this.number = source["number"];
+	        this.index = source["index"];
+	        this.total = source["total"];
+	        this.chapter = source["chapter"];
+	        this.episode = source["episode"];
+	        this.layout = source["layout"];
+	        this.blocks = this.convertValues(source["blocks"], ReaderBlock);
+	        this.prev = this.convertValues(source["prev"], ReaderEpisodeLink);
+	        this.next = this.convertValues(source["next"], ReaderEpisodeLink);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	
+	
+	
+	export class ReadingProgress {
+	    folder: string;
+	    ncode: string;
+	    title: string;
+	    read: number;
+	    unread: number;
+	    position?: ReadingPosition;
+	    episodes: EpisodeProgress[];
 	
 	    static createFrom(source: any = {}) {
-	        return new ChapterInfo(source);
+	        return new ReadingProgress(source);
 	    }
 	
 	    constructor(source: any = {}) {
 	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.folder = source["folder"];
+	        this.ncode = source["ncode"];
 	        this.title = source["title"];
-	        this.url = source["url"];
-	        this.content = source["content"];
-	        this.raw_html = source["raw_html"];
-	        this.full_page_html = source["full_page_html"];
-	        this.retry_count = source["retry_count"];
-	        this.failed = source["failed"];
+	        this.read = source["read"];
+	        this.unread = source["unread"];
+	        this.position = this.convertValues(source["position"], ReadingPosition);
+	        this.episodes = this.convertValues(source["episodes"], EpisodeProgress);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class ScheduleSettings {
+	    enabled: boolean;
+	    interval: string;
+	    cron: string;
+	    folder: string;
+	    autoDownload: boolean;
+	
+	    static createFrom(source: any = {}) {
+	        return new ScheduleSettings(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.enabled = source["enabled"];
+	        this.interval = source["interval"];
+	        this.cron = source["cron"];
+	        this.folder = source["folder"];
+	        this.autoDownload = source["autoDownload"];
+	    }
+	}
+	export class ScheduleStatus {
+	    enabled: boolean;
+	    checking: boolean;
+	    // Go type: time
+	    nextRun: any;
+	    // Go type: time
+	    lastRun: any;
+	
+	    static createFrom(source: any = {}) {
+	        return new ScheduleStatus(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.enabled = source["enabled"];
+	        this.checking = source["checking"];
+	        this.nextRun = this.convertValues(source["nextRun"], null);
+	        this.lastRun = this.convertValues(source["lastRun"], null);
 	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
 	}
 	export class ScrapeResult {
 	    page_type: string;
@@ -33,6 +1005,7 @@ export namespace main {
 	    full_page_html: string;
 	    index_pages_html: string[];
 	    chapters?: ChapterInfo[];
+	    blocks?: Block[];
 	    error?: string;
 	
 	    static createFrom(source: any = {}) {
@@ -49,6 +1022,7 @@ export namespace main {
 	        this.full_page_html = source["full_page_html"];
 	        this.index_pages_html = source["index_pages_html"];
 	        this.chapters = this.convertValues(source["chapters"], ChapterInfo);
+	        this.blocks = this.convertValues(source["blocks"], Block);
 	        this.error = source["error"];
 	    }
 	
@@ -70,15 +1044,99 @@ export namespace main {
 		    return a;
 		}
 	}
+	export class SearchHit {
+	    folder: string;
+	    ncode: string;
+	    title: string;
+	    number: string;
+	    episodeTitle: string;
+	    paragraph: number;
+	    offset: number;
+	    snippet: string;
+	    matchStart: number;
+	    matchEnd: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new SearchHit(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.folder = source["folder"];
+	        this.ncode = source["ncode"];
+	        this.title = source["title"];
+	        this.number = source["number"];
+	        this.episodeTitle = source["episodeTitle"];
+	        this.paragraph = source["paragraph"];
+	        this.offset = source["offset"];
+	        this.snippet = source["snippet"];
+	        this.matchStart = source["matchStart"];
+	        this.matchEnd = source["matchEnd"];
+	    }
+	}
+	export class SearchRequest {
+	    folder: string;
+	    query: string;
+	    ncode: string;
+	    limit: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new SearchRequest(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.folder = source["folder"];
+	        this.query = source["query"];
+	        this.ncode = source["ncode"];
+	        this.limit = source["limit"];
+	    }
+	}
+	export class SearchResult {
+	    hits: SearchHit[];
+	    truncated: boolean;
+	
+	    static createFrom(source: any = {}) {
+	        return new SearchResult(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.hits = this.convertValues(source["hits"], SearchHit);
+	        this.truncated = source["truncated"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
 	export class Settings {
+	    version: number;
 	    url: string;
 	    savePath: string;
-	    encoding: string;
-	    lineEnding: string;
-	    createHtml: boolean;
-	    createTxt: boolean;
-	    createCombined: boolean;
+	    formats: FormatRequest[];
 	    showInFront: boolean;
+	    profiles: Profile[];
+	    activeProfile: string;
+	    schedule: ScheduleSettings;
+	    deliveries: DeliveryTarget[];
+	    hooks: Hook[];
+	    notifications: NotificationSettings;
+	    cache: CacheSettings;
 	
 	    static createFrom(source: any = {}) {
 	        return new Settings(source);
@@ -86,15 +1144,198 @@ export namespace main {
 	
 	    constructor(source: any = {}) {
 	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.version = source["version"];
 	        this.url = source["url"];
 	        this.savePath = source["savePath"];
-	        this.encoding = source["encoding"];
-	        this.lineEnding = source["lineEnding"];
-	        this.createHtml = source["createHtml"];
-	        this.createTxt = source["createTxt"];
-	        this.createCombined = source["createCombined"];
+	        this.formats = this.convertValues(source["formats"], FormatRequest);
 	        this.showInFront = source["showInFront"];
+	        this.profiles = this.convertValues(source["profiles"], Profile);
+	        this.activeProfile = source["activeProfile"];
+	        this.schedule = this.convertValues(source["schedule"], ScheduleSettings);
+	        this.deliveries = this.convertValues(source["deliveries"], DeliveryTarget);
+	        this.hooks = this.convertValues(source["hooks"], Hook);
+	        this.notifications = this.convertValues(source["notifications"], NotificationSettings);
+	        this.cache = this.convertValues(source["cache"], CacheSettings);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class UnreadExportRequest {
+	    folder: string;
+	    ncode: string;
+	    format: FormatRequest;
+	    output: string;
+	    markRead: boolean;
+	
+	    static createFrom(source: any = {}) {
+	        return new UnreadExportRequest(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.folder = source["folder"];
+	        this.ncode = source["ncode"];
+	        this.format = this.convertValues(source["format"], FormatRequest);
+	        this.output = source["output"];
+	        this.markRead = source["markRead"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class UpdateCheck {
+	    folder: string;
+	    ncode: string;
+	    title: string;
+	    // Go type: time
+	    checkedAt: any;
+	    total: number;
+	    newEpisodes: NewEpisode[];
+	    downloaded: boolean;
+	    error?: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new UpdateCheck(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.folder = source["folder"];
+	        this.ncode = source["ncode"];
+	        this.title = source["title"];
+	        this.checkedAt = this.convertValues(source["checkedAt"], null);
+	        this.total = source["total"];
+	        this.newEpisodes = this.convertValues(source["newEpisodes"], NewEpisode);
+	        this.downloaded = source["downloaded"];
+	        this.error = source["error"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class VerifyIssue {
+	    kind: string;
+	    number?: string;
+	    title?: string;
+	    path?: string;
+	    detail: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new VerifyIssue(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.kind = source["kind"];
+	        this.number = source["number"];
+	        this.title = source["title"];
+	        this.path = source["path"];
+	        this.detail = source["detail"];
+	    }
+	}
+	export class VerifyOptions {
+	    url: string;
+	    online: boolean;
+	    deep: boolean;
+	    repair: boolean;
+	
+	    static createFrom(source: any = {}) {
+	        return new VerifyOptions(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.url = source["url"];
+	        this.online = source["online"];
+	        this.deep = source["deep"];
+	        this.repair = source["repair"];
+	    }
+	}
+	export class VerifyReport {
+	    folder: string;
+	    ncode: string;
+	    title: string;
+	    checked: number;
+	    issues: VerifyIssue[];
+	    repaired: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new VerifyReport(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.folder = source["folder"];
+	        this.ncode = source["ncode"];
+	        this.title = source["title"];
+	        this.checked = source["checked"];
+	        this.issues = this.convertValues(source["issues"], VerifyIssue);
+	        this.repaired = source["repaired"];
 	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
 	}
 
 }
@@ -0,0 +1,460 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// historyTimeFormat は過去の版のファイル名に使う日時の書式
+const historyTimeFormat = "20060102-150405"
+
+// currentVersion は最新の版（本文のキャッシュ）を表す版の識別子
+const currentVersion = "current"
+
+// EpisodeVersion は1話分の版（改稿前の本文または最新の本文）の情報を表す構造体
+type EpisodeVersion struct {
+	ID        string    `json:"id"` // 取得日時（20060102-150405）または "current"
+	FetchedAt time.Time `json:"fetchedAt"`
+	Current   bool      `json:"current"`
+}
+
+// DiffRequest は2つの版の差分を求める際の指定を表す構造体
+type DiffRequest struct {
+	Folder string `json:"folder"`
+	NCode  string `json:"ncode"` // 保存先に小説が1つしかない場合は省略できる
+	Number string `json:"number"`
+	From   string `json:"from"` // 省略時は最新の1つ前の版
+	To     string `json:"to"`   // 省略時は最新の版
+	Mode   string `json:"mode"` // "line"（段落単位）または "char"（文字単位）
+}
+
+// DiffOp は差分の1区間を表す構造体
+type DiffOp struct {
+	Kind string `json:"kind"` // "equal"・"insert"・"delete" のいずれか
+	Text string `json:"text"`
+}
+
+// EpisodeDiff は2つの版の差分を表す構造体
+type EpisodeDiff struct {
+	NCode  string   `json:"ncode"`
+	Number string   `json:"number"`
+	Title  string   `json:"title"`
+	From   string   `json:"from"`
+	To     string   `json:"to"`
+	Mode   string   `json:"mode"`
+	Ops    []DiffOp `json:"ops"`
+}
+
+// revisedTimeFormat は目次に表示される改稿日時の書式（日本時間）
+const revisedTimeFormat = "2006/01/02 15:04"
+
+// isRevisedSince は保存済みの話が、目次に表示される改稿日時より前に取得したものかどうかを返します
+// 改稿日時を記録する前に保存した話は、取得した日時と比べます
+func isRevisedSince(record *ManifestEpisode, episode *Episode) bool {
+	if episode.Revised == "" || episode.Revised == record.Revised {
+		return false
+	}
+	if record.Revised != "" {
+		return true
+	}
+	revised, err := time.ParseInLocation(revisedTimeFormat, episode.Revised, time.FixedZone("JST", 9*60*60))
+	return err == nil && revised.After(record.FetchedAt)
+}
+
+// historyDir は話ごとの過去の版を保存するディレクトリのパスを返します
+func (s *novelStore) historyDir(number string) string {
+	return filepath.Join(s.dir(), "history", sanitizeFileName(number))
+}
+
+// saveHistory は上書きされる前の本文を過去の版として圧縮して保存します
+func (s *novelStore) saveHistory(record ManifestEpisode) error {
+	blocks, err := s.loadBlocks(record.Number)
+	if errors.Is(err, os.ErrNotExist) {
+		// キャッシュがない場合は残す本文がない
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(blocks)
+	if err != nil {
+		return fmt.Errorf("本文のJSON変換に失敗しました: %w", err)
+	}
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if _, err := zw.Write(data); err != nil {
+		return err
+	}
+	if err := zw.Close(); err != nil {
+		return err
+	}
+
+	fetchedAt := record.FetchedAt
+	if fetchedAt.IsZero() {
+		fetchedAt = time.Now()
+	}
+	path := filepath.Join(s.historyDir(record.Number), fetchedAt.Format(historyTimeFormat)+".json.gz")
+	return writeFileAtomic(path, buf.Bytes(), s.tmpDir())
+}
+
+// episodeVersions は話の版を古い順に返します（最後が最新の版）
+func (s *novelStore) episodeVersions(record ManifestEpisode) ([]EpisodeVersion, error) {
+	entries, err := os.ReadDir(s.historyDir(record.Number))
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("過去の版の一覧を取得できませんでした: %w", err)
+	}
+
+	var versions []EpisodeVersion
+	for _, entry := range entries {
+		id, ok := strings.CutSuffix(entry.Name(), ".json.gz")
+		if !ok {
+			continue
+		}
+		fetchedAt, err := time.ParseInLocation(historyTimeFormat, id, time.Local)
+		if err != nil {
+			continue
+		}
+		versions = append(versions, EpisodeVersion{ID: id, FetchedAt: fetchedAt})
+	}
+	sort.Slice(versions, func(i, j int) bool { return versions[i].ID < versions[j].ID })
+
+	return append(versions, EpisodeVersion{ID: currentVersion, FetchedAt: record.FetchedAt, Current: true}), nil
+}
+
+// loadVersion は指定した版の本文を読み込みます
+func (s *novelStore) loadVersion(number, id string) ([]Block, error) {
+	if id == currentVersion {
+		return s.loadBlocks(number)
+	}
+
+	f, err := os.Open(filepath.Join(s.historyDir(number), sanitizeFileName(id)+".json.gz"))
+	if err != nil {
+		return nil, fmt.Errorf("版 %s が見つかりません: %w", id, err)
+	}
+	defer f.Close()
+	zr, err := gzip.NewReader(f)
+	if err != nil {
+		return nil, fmt.Errorf("版 %s が壊れています: %w", id, err)
+	}
+	data, err := io.ReadAll(zr)
+	if err != nil {
+		return nil, fmt.Errorf("版 %s が壊れています: %w", id, err)
+	}
+
+	var blocks []Block
+	if err := json.Unmarshal(data, &blocks); err != nil {
+		return nil, fmt.Errorf("版 %s が壊れています: %w", id, err)
+	}
+	return blocks, nil
+}
+
+// openNovelStoreByNCode は保存先から小説番号に対応する novelStore を探します（省略時は唯一の小説）
+func openNovelStoreByNCode(folder, ncode string) (*novelStore, *Manifest, error) {
+	stores, err := findNovelStores(folder)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var found *novelStore
+	for _, store := range stores {
+		if ncode == "" || strings.EqualFold(store.ncode, ncode) {
+			if found != nil {
+				return nil, nil, fmt.Errorf("複数の小説が見つかりました。小説番号を指定してください: %s", folder)
+			}
+			found = store
+		}
+	}
+	if found == nil {
+		return nil, nil, fmt.Errorf("小説が見つかりません: %s %s", folder, ncode)
+	}
+
+	manifest, err := found.loadManifest()
+	if err != nil {
+		return nil, nil, err
+	}
+	return found, manifest, nil
+}
+
+// ListEpisodeVersions は話の版の一覧を返します（フロントエンド・CLI用）
+func (a *App) ListEpisodeVersions(folder, ncode, number string) ([]EpisodeVersion, error) {
+	store, manifest, err := openNovelStoreByNCode(folder, ncode)
+	if err != nil {
+		return nil, err
+	}
+	record := manifest.episode(number)
+	if record == nil {
+		return nil, fmt.Errorf("%s話は保存されていません", number)
+	}
+	return store.episodeVersions(*record)
+}
+
+// DiffEpisode は話の2つの版の差分を返します（フロントエンド・CLI用）
+// ルビと傍点は青空文庫形式の1つの単位として扱い、途中で分割しません
+func (a *App) DiffEpisode(request DiffRequest) (EpisodeDiff, error) {
+	store, manifest, err := openNovelStoreByNCode(request.Folder, request.NCode)
+	if err != nil {
+		return EpisodeDiff{}, err
+	}
+	record := manifest.episode(request.Number)
+	if record == nil {
+		return EpisodeDiff{}, fmt.Errorf("%s話は保存されていません", request.Number)
+	}
+
+	mode := request.Mode
+	if mode == "" {
+		mode = "line"
+	}
+	if mode != "line" && mode != "char" {
+		return EpisodeDiff{}, fmt.Errorf("不明な差分の単位です: %s", mode)
+	}
+
+	from, to := request.From, request.To
+	if to == "" {
+		to = currentVersion
+	}
+	if from == "" {
+		versions, err := store.episodeVersions(*record)
+		if err != nil {
+			return EpisodeDiff{}, err
+		}
+		if len(versions) < 2 {
+			return EpisodeDiff{}, fmt.Errorf("%s話には過去の版がありません", request.Number)
+		}
+		from = versions[len(versions)-2].ID
+	}
+
+	oldBlocks, err := store.loadVersion(record.Number, from)
+	if err != nil {
+		return EpisodeDiff{}, err
+	}
+	newBlocks, err := store.loadVersion(record.Number, to)
+	if err != nil {
+		return EpisodeDiff{}, err
+	}
+
+	return EpisodeDiff{
+		NCode:  manifest.NCode,
+		Number: record.Number,
+		Title:  record.Title,
+		From:   from,
+		To:     to,
+		Mode:   mode,
+		Ops:    diffBlocks(oldBlocks, newBlocks, mode == "char"),
+	}, nil
+}
+
+// blockLines は本文を段落ごとの行（ルビ・傍点は青空文庫形式）に変換します
+func blockLines(blocks []Block) []string {
+	lines := make([]string, len(blocks))
+	for i, block := range blocks {
+		lines[i] = renderAozora([]Block{block})
+	}
+	return lines
+}
+
+// lineTokens は1行を文字単位の要素に分割します（ルビ・傍点は1つの要素として扱う）
+func lineTokens(block Block) []string {
+	var tokens []string
+	switch block.Kind {
+	case BlockParagraph:
+		for _, inline := range block.Inlines {
+			if inline.Kind == InlineText {
+				for _, r := range escapeAozora(inline.Text) {
+					tokens = append(tokens, string(r))
+				}
+				continue
+			}
+			tokens = append(tokens, renderAozoraInlines([]Inline{inline}))
+		}
+	default:
+		tokens = append(tokens, renderAozora([]Block{block}))
+	}
+	return tokens
+}
+
+// maxCharDiffCells は文字単位の差分で扱う表の大きさの上限（超える場合は段落単位の差分にする）
+const maxCharDiffCells = 4_000_000
+
+// diffBlocks は2つの本文の差分を求めます（char が true の場合は変更された段落を文字単位で比較する）
+func diffBlocks(oldBlocks, newBlocks []Block, char bool) []DiffOp {
+	lineOps := diffTokenIndexes(blockLines(oldBlocks), blockLines(newBlocks))
+
+	var ops []DiffOp
+	var deleted, inserted []Block
+	flush := func() {
+		if len(deleted) == 0 && len(inserted) == 0 {
+			return
+		}
+		// 書き換えられた段落は文字単位で比較する
+		if char && len(deleted) > 0 && len(inserted) > 0 {
+			oldTokens, newTokens := joinedTokens(deleted), joinedTokens(inserted)
+			if len(oldTokens)*len(newTokens) <= maxCharDiffCells {
+				ops = appendOps(ops, diffTokens(oldTokens, newTokens)...)
+				deleted, inserted = nil, nil
+				return
+			}
+		}
+		for _, block := range deleted {
+			ops = appendOps(ops, DiffOp{Kind: "delete", Text: renderAozora([]Block{block}) + "\n"})
+		}
+		for _, block := range inserted {
+			ops = appendOps(ops, DiffOp{Kind: "insert", Text: renderAozora([]Block{block}) + "\n"})
+		}
+		deleted, inserted = nil, nil
+	}
+
+	for _, op := range lineOps {
+		switch op.kind {
+		case "delete":
+			deleted = append(deleted, oldBlocks[op.index])
+		case "insert":
+			inserted = append(inserted, newBlocks[op.index])
+		default:
+			flush()
+			ops = appendOps(ops, DiffOp{Kind: "equal", Text: renderAozora([]Block{oldBlocks[op.index]}) + "\n"})
+		}
+	}
+	flush()
+	return ops
+}
+
+// joinedTokens は複数の段落を改行で区切った文字単位の要素に変換します
+func joinedTokens(blocks []Block) []string {
+	var tokens []string
+	for _, block := range blocks {
+		tokens = append(tokens, lineTokens(block)...)
+		tokens = append(tokens, "\n")
+	}
+	return tokens
+}
+
+// appendOps は差分の区間を追加します（同じ種類の区間が続く場合は連結する）
+func appendOps(ops []DiffOp, added ...DiffOp) []DiffOp {
+	for _, op := range added {
+		if op.Text == "" {
+			continue
+		}
+		if n := len(ops); n > 0 && ops[n-1].Kind == op.Kind {
+			ops[n-1].Text += op.Text
+			continue
+		}
+		ops = append(ops, op)
+	}
+	return ops
+}
+
+// diffTokens は2つの要素の列の差分を求めます
+func diffTokens(a, b []string) []DiffOp {
+	var ops []DiffOp
+	for _, op := range diffTokenIndexes(a, b) {
+		if op.kind == "insert" {
+			ops = appendOps(ops, DiffOp{Kind: op.kind, Text: b[op.index]})
+		} else {
+			ops = appendOps(ops, DiffOp{Kind: op.kind, Text: a[op.index]})
+		}
+	}
+	return ops
+}
+
+// tokenOp は差分の1要素（equal・delete は a の位置、insert は b の位置）を表す構造体
+type tokenOp struct {
+	kind  string
+	index int
+}
+
+// diffTokenIndexes は最長共通部分列を使って2つの列の差分を求めます
+// 先頭と末尾の共通部分は表を作らずに処理します
+func diffTokenIndexes(a, b []string) []tokenOp {
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+
+	ops := make([]tokenOp, 0, len(a)+len(b))
+	for i := 0; i < prefix; i++ {
+		ops = append(ops, tokenOp{"equal", i})
+	}
+
+	midA, midB := a[prefix:len(a)-suffix], b[prefix:len(b)-suffix]
+	lcs := make([][]int32, len(midA)+1)
+	for i := range lcs {
+		lcs[i] = make([]int32, len(midB)+1)
+	}
+	for i := len(midA) - 1; i >= 0; i-- {
+		for j := len(midB) - 1; j >= 0; j-- {
+			if midA[i] == midB[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	i, j := 0, 0
+	for i < len(midA) || j < len(midB) {
+		switch {
+		case i < len(midA) && j < len(midB) && midA[i] == midB[j]:
+			ops = append(ops, tokenOp{"equal", prefix + i})
+			i++
+			j++
+		case j >= len(midB) || (i < len(midA) && lcs[i+1][j] >= lcs[i][j+1]):
+			ops = append(ops, tokenOp{"delete", prefix + i})
+			i++
+		default:
+			ops = append(ops, tokenOp{"insert", prefix + j})
+			j++
+		}
+	}
+
+	for k := len(a) - suffix; k < len(a); k++ {
+		ops = append(ops, tokenOp{"equal", k})
+	}
+	return ops
+}
+
+// formatDiff は差分を人が読める形式に変換します
+// 段落単位の場合は行頭に " "・"-"・"+" を付け、文字単位の場合は [-削除-]{+追加+} で表します
+func formatDiff(diff EpisodeDiff) string {
+	var out strings.Builder
+	for _, op := range diff.Ops {
+		if diff.Mode == "char" {
+			switch op.Kind {
+			case "delete":
+				out.WriteString("[-" + op.Text + "-]")
+			case "insert":
+				out.WriteString("{+" + op.Text + "+}")
+			default:
+				out.WriteString(op.Text)
+			}
+			continue
+		}
+
+		prefix := " "
+		switch op.Kind {
+		case "delete":
+			prefix = "-"
+		case "insert":
+			prefix = "+"
+		}
+		for _, line := range strings.SplitAfter(op.Text, "\n") {
+			if line != "" {
+				out.WriteString(prefix + line)
+			}
+		}
+	}
+	return out.String()
+}
@@ -0,0 +1,175 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestDiffBlocks(t *testing.T) {
+	text := func(s string) Inline { return Inline{Kind: InlineText, Text: s} }
+	paragraph := func(inlines ...Inline) Block { return Block{Kind: BlockParagraph, Inlines: inlines} }
+
+	tests := []struct {
+		name     string
+		old      []Block
+		new      []Block
+		char     bool
+		expected []DiffOp
+	}{
+		{
+			name:     "変更なし",
+			old:      []Block{paragraph(text("本文"))},
+			new:      []Block{paragraph(text("本文"))},
+			expected: []DiffOp{{Kind: "equal", Text: "本文\n"}},
+		},
+		{
+			name: "段落単位の書き換え",
+			old:  []Block{paragraph(text("一行目")), paragraph(text("二行目"))},
+			new:  []Block{paragraph(text("一行目")), paragraph(text("二行目を直した"))},
+			expected: []DiffOp{
+				{Kind: "equal", Text: "一行目\n"},
+				{Kind: "delete", Text: "二行目\n"},
+				{Kind: "insert", Text: "二行目を直した\n"},
+			},
+		},
+		{
+			name: "文字単位の書き換え",
+			old:  []Block{paragraph(text("青い空"))},
+			new:  []Block{paragraph(text("赤い空"))},
+			char: true,
+			expected: []DiffOp{
+				{Kind: "delete", Text: "青"},
+				{Kind: "insert", Text: "赤"},
+				{Kind: "equal", Text: "い空\n"},
+			},
+		},
+		{
+			name: "ルビは分割しない",
+			old:  []Block{paragraph(text("彼は"), Inline{Kind: InlineRuby, Text: "魔法", Ruby: "まほう"}, text("を使う"))},
+			new:  []Block{paragraph(text("彼は"), Inline{Kind: InlineRuby, Text: "魔法", Ruby: "マジック"}, text("を使う"))},
+			char: true,
+			expected: []DiffOp{
+				{Kind: "equal", Text: "彼は"},
+				{Kind: "delete", Text: "魔法《まほう》"},
+				{Kind: "insert", Text: "魔法《マジック》"},
+				{Kind: "equal", Text: "を使う\n"},
+			},
+		},
+		{
+			name: "傍点の追加",
+			old:  []Block{paragraph(text("本当に"))},
+			new:  []Block{paragraph(Inline{Kind: InlineEmphasis, Text: "本当"}, text("に"))},
+			char: true,
+			expected: []DiffOp{
+				{Kind: "delete", Text: "本当"},
+				{Kind: "insert", Text: "［＃傍点］本当［＃傍点終わり］"},
+				{Kind: "equal", Text: "に\n"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := diffBlocks(tt.old, tt.new, tt.char)
+			if len(got) != len(tt.expected) {
+				t.Fatalf("diffBlocks() = %+v, want %+v", got, tt.expected)
+			}
+			for i := range got {
+				if got[i] != tt.expected[i] {
+					t.Errorf("diffBlocks()[%d] = %+v, want %+v", i, got[i], tt.expected[i])
+				}
+			}
+		})
+	}
+}
+
+func TestDiffEpisode(t *testing.T) {
+	app, store, novel := newVerifiedNovelStore(t)
+	manifest, err := store.loadManifest()
+	if err != nil {
+		t.Fatalf("loadManifest() error = %v", err)
+	}
+
+	// 同じ本文で取得し直しても過去の版は増えない
+	episode := novel.Episodes()[1]
+	if err := app.saveEpisode(store, manifest, novel, episode, nil); err != nil {
+		t.Fatalf("saveEpisode() error = %v", err)
+	}
+	if _, err := os.Stat(store.historyDir("2")); !os.IsNotExist(err) {
+		t.Errorf("本文が変わっていないのに過去の版が保存されました")
+	}
+
+	episode.Blocks = []Block{{Kind: BlockParagraph, Inlines: []Inline{{Kind: InlineText, Text: "本文二（改稿）"}}}}
+	if err := app.saveEpisode(store, manifest, novel, episode, nil); err != nil {
+		t.Fatalf("saveEpisode() error = %v", err)
+	}
+
+	versions, err := app.ListEpisodeVersions(store.root, "", "2")
+	if err != nil {
+		t.Fatalf("ListEpisodeVersions() error = %v", err)
+	}
+	if len(versions) != 2 || !versions[1].Current {
+		t.Fatalf("versions = %+v, want 過去の版と最新の版", versions)
+	}
+	matches, _ := filepath.Glob(filepath.Join(store.historyDir("2"), "*.json.gz"))
+	if len(matches) != 1 {
+		t.Errorf("過去の版のファイル = %v, want 1件", matches)
+	}
+
+	diff, err := app.DiffEpisode(DiffRequest{Folder: store.root, Number: "2", Mode: "char"})
+	if err != nil {
+		t.Fatalf("DiffEpisode() error = %v", err)
+	}
+	expected := []DiffOp{{Kind: "equal", Text: "本文二"}, {Kind: "insert", Text: "（改稿）"}, {Kind: "equal", Text: "\n"}}
+	if len(diff.Ops) != len(expected) {
+		t.Fatalf("Ops = %+v, want %+v", diff.Ops, expected)
+	}
+	for i := range expected {
+		if diff.Ops[i] != expected[i] {
+			t.Errorf("Ops[%d] = %+v, want %+v", i, diff.Ops[i], expected[i])
+		}
+	}
+	if diff.From != versions[0].ID || diff.To != currentVersion {
+		t.Errorf("From, To = %s, %s, want %s, %s", diff.From, diff.To, versions[0].ID, currentVersion)
+	}
+
+	if _, err := app.DiffEpisode(DiffRequest{Folder: store.root, Number: "1"}); err == nil {
+		t.Errorf("過去の版がない話で DiffEpisode() がエラーになりません")
+	}
+}
+
+func TestIsRevisedSince(t *testing.T) {
+	jst := time.FixedZone("JST", 9*60*60)
+	tests := []struct {
+		name     string
+		record   ManifestEpisode
+		revised  string // 最新の目次の改稿日時
+		expected bool
+	}{
+		{name: "改稿されていない", record: ManifestEpisode{}, revised: "", expected: false},
+		{name: "改稿日時が同じ", record: ManifestEpisode{Revised: "2024/02/01 09:30"}, revised: "2024/02/01 09:30", expected: false},
+		{name: "改稿日時が変わった", record: ManifestEpisode{Revised: "2024/02/01 09:30"}, revised: "2024/03/01 10:00", expected: true},
+		{
+			name:     "改稿日時の記録がなく改稿の後に取得した",
+			record:   ManifestEpisode{FetchedAt: time.Date(2024, 2, 2, 0, 0, 0, 0, jst)},
+			revised:  "2024/02/01 09:30",
+			expected: false,
+		},
+		{
+			name:     "改稿日時の記録がなく改稿の前に取得した",
+			record:   ManifestEpisode{FetchedAt: time.Date(2024, 1, 2, 0, 0, 0, 0, jst)},
+			revised:  "2024/02/01 09:30",
+			expected: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isRevisedSince(&tt.record, &Episode{Revised: tt.revised}); got != tt.expected {
+				t.Errorf("isRevisedSince() = %v, want %v", got, tt.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,221 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"golang.org/x/text/encoding/japanese"
+)
+
+// defaultHookTimeout はフックの制限時間を指定しない場合の制限時間
+const defaultHookTimeout = 10 * time.Minute
+
+// hookWaitDelay は制限時間を過ぎてフックを終了させた後、出力が閉じられるまで待つ時間
+// （フックが起動した別のプログラムが出力を開いたまま残っている場合に待ち続けないため）
+const hookWaitDelay = 5 * time.Second
+
+// Hook はダウンロードが完了した後に実行する外部のコマンドを表す構造体
+// AozoraEpub3 や kindlegen での変換などに使い、小説の情報は NAROU_ で始まる環境変数で渡します
+type Hook struct {
+	Name    string `json:"name"`
+	Command string `json:"command"` // シェル（Windowsでは cmd.exe、それ以外は sh）で実行するコマンド
+	Timeout int    `json:"timeout"` // 制限時間（秒。0 の場合は10分）
+	Enabled bool   `json:"enabled"`
+}
+
+// timeout はフックの制限時間を返します
+func (h Hook) timeout() time.Duration {
+	if h.Timeout > 0 {
+		return time.Duration(h.Timeout) * time.Second
+	}
+	return defaultHookTimeout
+}
+
+// validate はフックの設定に誤りがないか確認します
+func (h Hook) validate() error {
+	if h.Name == "" {
+		return fmt.Errorf("フックの名前を入力してください")
+	}
+	if strings.TrimSpace(h.Command) == "" {
+		return fmt.Errorf("フックのコマンドを入力してください")
+	}
+	if h.Timeout < 0 {
+		return fmt.Errorf("フックの制限時間が正しくありません: %d", h.Timeout)
+	}
+	return nil
+}
+
+// hooksFor はプロファイルでダウンロードした場合に実行するフックを返します（profileName が空の場合はプロファイルなし）
+func (s Settings) hooksFor(profileName string) []Hook {
+	if profile, ok := s.findProfile(profileName); ok && profile.SkipHooks {
+		return nil
+	}
+	var hooks []Hook
+	for _, hook := range s.Hooks {
+		if hook.Enabled {
+			hooks = append(hooks, hook)
+		}
+	}
+	return hooks
+}
+
+// hookEnv はフックに渡す環境変数を作成します
+// since 以降に保存した話（新しい話と更新された話）を今回のダウンロードで保存した話とします
+func hookEnv(store *novelStore, manifest *Manifest, profileName string, since time.Time) ([]string, error) {
+	newEpisodes := []NewEpisode{}
+	var numbers []string
+	var files []string
+	for _, file := range manifest.bookFiles() {
+		files = append(files, store.path(file.Path))
+	}
+	for _, episode := range manifest.fetchedSince(since) {
+		newEpisodes = append(newEpisodes, NewEpisode{Number: episode.Number, Title: episode.Title})
+		numbers = append(numbers, episode.Number)
+		// 短編の話のファイルは小説全体のファイルとして追加済み
+		if !manifest.Short {
+			for _, file := range episode.Files {
+				files = append(files, store.path(file.Path))
+			}
+		}
+	}
+	episodesJSON, err := json.Marshal(newEpisodes)
+	if err != nil {
+		return nil, fmt.Errorf("新しい話の一覧のJSON変換に失敗しました: %w", err)
+	}
+
+	return append(os.Environ(),
+		"NAROU_NCODE="+manifest.NCode,
+		"NAROU_TITLE="+manifest.Title,
+		"NAROU_AUTHOR="+manifest.Author,
+		"NAROU_URL="+manifest.URL,
+		"NAROU_PROFILE="+profileName,
+		"NAROU_SAVE_PATH="+store.root,
+		// PATH と同じく区切り文字（Windowsでは ;、それ以外は :）で区切る
+		"NAROU_FILES="+strings.Join(files, string(filepath.ListSeparator)),
+		"NAROU_NEW_EPISODES="+strings.Join(numbers, ","),
+		"NAROU_NEW_EPISODES_JSON="+string(episodesJSON),
+	), nil
+}
+
+// runHooks はダウンロードが完了した小説についてフックを順に実行します
+// フックが失敗してもログに記録するだけで、残りのフックやダウンロードは続けます
+func (a *App) runHooks(hooks []Hook, savePath, ncode, profileName string, since time.Time) {
+	store, manifest, err := openNovelStoreByNCode(savePath, ncode)
+	if err != nil {
+		a.emit("log", fmt.Sprintf("フックを実行できませんでした: %v", err))
+		return
+	}
+	env, err := hookEnv(store, manifest, profileName, since)
+	if err != nil {
+		a.emit("log", fmt.Sprintf("フックを実行できませんでした: %v", err))
+		return
+	}
+
+	for _, hook := range hooks {
+		a.emit("log", fmt.Sprintf("フック %s を実行しています...", hook.Name))
+		if err := a.runHook(hook, store.root, env); err != nil {
+			a.emit("log", fmt.Sprintf("フック %s が失敗しました: %v", hook.Name, err))
+			continue
+		}
+		a.emit("log", fmt.Sprintf("フック %s が完了しました", hook.Name))
+	}
+}
+
+// runHook はフックを1つ実行し、出力を1行ずつ log イベントとして送信します
+func (a *App) runHook(hook Hook, dir string, env []string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), hook.timeout())
+	defer cancel()
+
+	output := &hookLogWriter{app: a, prefix: "[" + hook.Name + "] "}
+	cmd := hookCommand(ctx, hook.Command)
+	cmd.Dir = dir
+	cmd.Env = env
+	cmd.Stdout = output
+	cmd.Stderr = output
+	cmd.WaitDelay = hookWaitDelay
+
+	err := cmd.Run()
+	output.flush()
+	if ctx.Err() == context.DeadlineExceeded {
+		return fmt.Errorf("制限時間（%v）を過ぎたため終了しました", hook.timeout())
+	}
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		return fmt.Errorf("終了コード %d", exitErr.ExitCode())
+	}
+	return err
+}
+
+// hookLogWriter はフックの出力を1行ずつ log イベントとして送信する io.Writer
+// Windowsのコマンドが出力するShift-JISの行はUTF-8に変換します
+type hookLogWriter struct {
+	app    *App
+	prefix string
+	buf    []byte
+}
+
+// Write は出力を受け取り、改行までの行を送信します
+func (w *hookLogWriter) Write(p []byte) (int, error) {
+	w.buf = append(w.buf, p...)
+	for {
+		i := bytes.IndexByte(w.buf, '\n')
+		if i < 0 {
+			break
+		}
+		w.emitLine(w.buf[:i])
+		w.buf = w.buf[i+1:]
+	}
+	return len(p), nil
+}
+
+// flush は改行で終わっていない最後の行を送信します
+func (w *hookLogWriter) flush() {
+	if len(w.buf) > 0 {
+		w.emitLine(w.buf)
+		w.buf = nil
+	}
+}
+
+// emitLine は1行を log イベントとして送信します
+func (w *hookLogWriter) emitLine(line []byte) {
+	line = bytes.TrimRight(line, "\r")
+	if !utf8.Valid(line) {
+		if decoded, err := japanese.ShiftJIS.NewDecoder().Bytes(line); err == nil {
+			line = decoded
+		}
+	}
+	w.app.emit("log", w.prefix+string(line))
+}
+
+// SaveHook はフックを追加または上書きし、設定を返します（フロントエンド用）
+func (a *App) SaveHook(hook Hook) (Settings, error) {
+	hook.Name = strings.TrimSpace(hook.Name)
+	if err := hook.validate(); err != nil {
+		return a.currentSettings(), fmt.Errorf("フック %s の設定が正しくありません: %w", hook.Name, err)
+	}
+
+	return a.updateSettings(func(settings *Settings) {
+		if i := slices.IndexFunc(settings.Hooks, func(h Hook) bool { return h.Name == hook.Name }); i >= 0 {
+			settings.Hooks[i] = hook
+		} else {
+			settings.Hooks = append(settings.Hooks, hook)
+		}
+	})
+}
+
+// DeleteHook はフックを削除し、設定を返します（フロントエンド用）
+func (a *App) DeleteHook(name string) (Settings, error) {
+	return a.updateSettings(func(settings *Settings) {
+		settings.Hooks = slices.DeleteFunc(settings.Hooks, func(h Hook) bool { return h.Name == name })
+	})
+}
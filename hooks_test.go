@@ -0,0 +1,108 @@
+package main
+
+import (
+	"fmt"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSettings_HooksFor(t *testing.T) {
+	settings := Settings{
+		Profiles: []Profile{{Name: "保存用"}, {Name: "確認用", SkipHooks: true}},
+		Hooks: []Hook{
+			{Name: "AozoraEpub3", Command: "echo 変換", Enabled: true},
+			{Name: "kindlegen", Command: "echo 変換", Enabled: false},
+		},
+	}
+
+	tests := []struct {
+		name    string
+		profile string
+		want    []string
+	}{
+		{name: "プロファイルなし", profile: "", want: []string{"AozoraEpub3"}},
+		{name: "フックを実行するプロファイル", profile: "保存用", want: []string{"AozoraEpub3"}},
+		{name: "フックを実行しないプロファイル", profile: "確認用", want: nil},
+		{name: "存在しないプロファイル", profile: "削除済み", want: []string{"AozoraEpub3"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, hook := range settings.hooksFor(tt.profile) {
+				got = append(got, hook.Name)
+			}
+			if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("hooksFor(%q) = %v, want %v", tt.profile, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRunHooks(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("sh で実行するコマンドで確認する")
+	}
+	app, store, _ := newVerifiedNovelStore(t)
+	var logs []string
+	app.emitter = func(name string, data ...interface{}) {
+		if name == "log" {
+			logs = append(logs, fmt.Sprint(data...))
+		}
+	}
+
+	hooks := []Hook{
+		{Name: "失敗", Command: "echo 変換できません >&2; exit 3"},
+		{Name: "時間切れ", Command: "sleep 10", Timeout: 1},
+		{Name: "情報", Command: `echo "$NAROU_NCODE $NAROU_TITLE $NAROU_AUTHOR $NAROU_PROFILE"; echo "$NAROU_NEW_EPISODES"; echo "$NAROU_FILES" | tr ':' '\n' | wc -l | tr -d ' '; printf 改行なし`},
+	}
+	start := time.Now()
+	app.runHooks(hooks, store.root, "N1234AB", "保存用", time.Time{})
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("制限時間を過ぎたフックが終了していません: %v", elapsed)
+	}
+
+	// 失敗したフックがあっても残りのフックを実行し、出力を1行ずつ送信する
+	output := strings.Join(logs, "\n")
+	for _, want := range []string{
+		"[失敗] 変換できません",
+		"フック 失敗 が失敗しました: 終了コード 3",
+		"フック 時間切れ が失敗しました: 制限時間（1s）を過ぎたため終了しました",
+		"[情報] N1234AB テスト小説 作者 保存用",
+		"[情報] 1,2,3",
+		"[情報] 4", // 小説全体のEPUBと各話のテキスト3件
+		"[情報] 改行なし",
+		"フック 情報 が完了しました",
+	} {
+		if !strings.Contains(output, want) {
+			t.Errorf("ログに %q が含まれていません:\n%s", want, output)
+		}
+	}
+}
+
+func TestHookEnv_NewEpisodes(t *testing.T) {
+	_, store, _ := newVerifiedNovelStore(t)
+	manifest, _ := store.loadManifest()
+	manifest.Episodes[0].FetchedAt = time.Now().Add(-time.Hour)
+	manifest.Episodes[1].FetchedAt = time.Now().Add(-time.Hour)
+
+	env, err := hookEnv(store, manifest, "", time.Now().Add(-time.Minute))
+	if err != nil {
+		t.Fatalf("hookEnv() error = %v", err)
+	}
+	for _, want := range []string{
+		"NAROU_NEW_EPISODES=3",
+		`NAROU_NEW_EPISODES_JSON=[{"number":"3","title":"三話"}]`,
+		"NAROU_SAVE_PATH=" + store.root,
+	} {
+		found := false
+		for _, v := range env {
+			found = found || v == want
+		}
+		if !found {
+			t.Errorf("環境変数 %q がありません", want)
+		}
+	}
+}
@@ -0,0 +1,17 @@
+//go:build windows
+
+package main
+
+import (
+	"context"
+	"os/exec"
+	"syscall"
+)
+
+// hookCommand はフックのコマンドを cmd.exe で実行する *exec.Cmd を作成します
+// 引数の引用符をGoの規則でエスケープすると cmd.exe が解釈できないため、コマンドラインをそのまま渡します
+func hookCommand(ctx context.Context, command string) *exec.Cmd {
+	cmd := exec.CommandContext(ctx, "cmd.exe")
+	cmd.SysProcAttr = &syscall.SysProcAttr{CmdLine: `cmd.exe /S /C "` + command + `"`}
+	return cmd
+}
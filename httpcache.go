@@ -0,0 +1,438 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// offlineEnv は値が設定されているとオフラインモード（キャッシュだけを読む）になる環境変数の名前
+// 設定ファイルを読み込まないコマンドラインのサブコマンドでもオフラインで実行できるようにするため
+const offlineEnv = "NAROU_DOWNLOAD_OFFLINE"
+
+// cacheDirName はキャッシュディレクトリ内に作るHTTPキャッシュのディレクトリの名前
+const cacheDirName = "http"
+
+// cacheStatusHeader はキャッシュから返したレスポンスに付けるヘッダーの名前
+const cacheStatusHeader = "X-Narou-Cache"
+
+// キャッシュからレスポンスを返した理由（cacheStatusHeader の値）
+const (
+	cacheHit         = "hit"         // 有効期限内のため通信しなかった
+	cacheOffline     = "offline"     // オフラインモードのため通信しなかった
+	cacheRevalidated = "revalidated" // サーバーに確認して変更がなかった（304）
+)
+
+// maxCacheSize はキャッシュの合計の大きさの上限（超えた場合は保存・確認した日時が古いものから削除する）
+var maxCacheSize int64 = 512 << 20
+
+// errOfflineMiss はオフラインモードでキャッシュにないページを取得しようとした場合のエラー
+var errOfflineMiss = errors.New("オフラインモードのため、キャッシュにないページは取得できません")
+
+// episodePathPattern は連載の各話のURLのパス（/n1234ab/5/ など）
+var episodePathPattern = regexp.MustCompile(`(?i)^/n[0-9a-z]+/\d+/?$`)
+
+// CacheSettings は取得したページのキャッシュの設定を表す構造体
+// 有効期限内のページは通信せずにキャッシュを使い、期限を過ぎたページは ETag・Last-Modified でサーバーに変更を確認します
+type CacheSettings struct {
+	Enabled    bool   `json:"enabled"`
+	Offline    bool   `json:"offline"`    // 通信せずにキャッシュだけを読む（出力のやり直しや解析の不具合の調査用）
+	TOCTTL     string `json:"tocTTL"`     // 目次・小説の情報の有効期限（例: "0s" は毎回確認する）
+	EpisodeTTL string `json:"episodeTTL"` // 各話の本文の有効期限（例: "24h"）
+}
+
+// cacheEntry はキャッシュしたレスポンス1件の情報を表す構造体（本文は別のファイルに保存する）
+type cacheEntry struct {
+	URL          string      `json:"url"`
+	Header       http.Header `json:"header"`
+	ETag         string      `json:"etag,omitempty"`
+	LastModified string      `json:"lastModified,omitempty"`
+	StoredAt     time.Time   `json:"storedAt"` // 取得またはサーバーに変更がないことを確認した日時
+}
+
+// defaultCacheSettings はキャッシュの既定の設定を返します
+// 目次は更新の確認に使うため毎回サーバーに確認し、本文は1日の間は取得し直しません
+func defaultCacheSettings() CacheSettings {
+	return CacheSettings{Enabled: true, TOCTTL: "0s", EpisodeTTL: "24h"}
+}
+
+// validate はキャッシュの設定に誤りがないか確認します
+func (s CacheSettings) validate() error {
+	for _, ttl := range []string{s.TOCTTL, s.EpisodeTTL} {
+		if ttl == "" {
+			continue
+		}
+		if d, err := time.ParseDuration(ttl); err != nil || d < 0 {
+			return fmt.Errorf("キャッシュの有効期限が正しくありません: %q", ttl)
+		}
+	}
+	return nil
+}
+
+// ttl はURLのページのキャッシュの有効期限を返します（設定が空や誤りの場合は毎回確認する）
+func (s CacheSettings) ttl(rawURL string) time.Duration {
+	value := s.TOCTTL
+	if u, err := url.Parse(rawURL); err == nil && episodePathPattern.MatchString(u.Path) {
+		value = s.EpisodeTTL
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil || d < 0 {
+		return 0
+	}
+	return d
+}
+
+// cacheDir はHTTPキャッシュを保存するディレクトリを返します
+// 通常はユーザーのキャッシュディレクトリを使い、ポータブルモードの場合は実行ファイルと同じディレクトリの cache を使います
+func cacheDir() (string, error) {
+	exePath, err := os.Executable()
+	if err != nil {
+		return "", fmt.Errorf("実行ファイルのパスを取得できませんでした: %w", err)
+	}
+	exeDir := filepath.Dir(exePath)
+	if isPortable(exeDir) {
+		return filepath.Join(exeDir, "cache", cacheDirName), nil
+	}
+
+	userDir, err := os.UserCacheDir()
+	if err != nil {
+		return "", fmt.Errorf("キャッシュディレクトリを取得できませんでした: %w", err)
+	}
+	return filepath.Join(userDir, configDirName, cacheDirName), nil
+}
+
+// responseCache は httpClient のすべてのGETリクエストが通るキャッシュ
+var responseCache = &cachingTransport{
+	next: &http.Transport{
+		MaxIdleConns:       100,
+		IdleConnTimeout:    90 * time.Second,
+		DisableCompression: true,
+	},
+}
+
+// cachingTransport はGETリクエストのレスポンスをURLごとにディスクにキャッシュする http.RoundTripper
+// サーバーに接続する場合だけ fetchLimiter で間隔を空けるため、キャッシュから返すページは待たずに取得できます
+type cachingTransport struct {
+	next http.RoundTripper
+
+	mu       sync.Mutex
+	dir      string // 空の場合は cacheDir() を使う（ポータブルモードの切り替えに合わせて毎回求める）
+	settings CacheSettings
+
+	sizeMu  sync.Mutex
+	size    int64  // sizeDir のキャッシュの合計の大きさ（前回数えてから保存した分を足した概算）
+	sizeDir string // size を数えたディレクトリ（空の場合はまだ数えていない）
+}
+
+// configure はキャッシュの設定を反映します
+func (c *cachingTransport) configure(settings CacheSettings) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.settings = settings
+}
+
+// current は現在の設定とキャッシュディレクトリを返します
+// 環境変数 NAROU_DOWNLOAD_OFFLINE が設定されている場合は設定にかかわらずオフラインモードにします
+func (c *cachingTransport) current() (CacheSettings, string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	settings := c.settings
+	if os.Getenv(offlineEnv) != "" {
+		settings.Enabled, settings.Offline = true, true
+	}
+	if !settings.Enabled || c.dir != "" {
+		return settings, c.dir, nil
+	}
+	dir, err := cacheDir()
+	return settings, dir, err
+}
+
+// RoundTrip はキャッシュが有効期限内であればキャッシュを返し、そうでなければサーバーに接続して結果をキャッシュします
+func (c *cachingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	// Webhookの送信などGET以外のリクエストはそのまま送る
+	if req.Method != http.MethodGet {
+		return c.next.RoundTrip(req)
+	}
+	settings, dir, err := c.current()
+	if err != nil {
+		return nil, err
+	}
+	if !settings.Enabled {
+		fetchLimiter.wait(requestInterval)
+		return c.next.RoundTrip(req)
+	}
+
+	key := req.URL.String()
+	entry, body, _ := loadCacheEntry(dir, key)
+	if settings.Offline {
+		if entry == nil {
+			return nil, fmt.Errorf("%w: %s", errOfflineMiss, key)
+		}
+		return entry.response(req, body, cacheOffline), nil
+	}
+	// Cache-Control: no-cache のリクエストは有効期限内でもサーバーに確認する
+	if entry != nil && !revalidateRequested(req) && time.Since(entry.StoredAt) < settings.ttl(key) {
+		return entry.response(req, body, cacheHit), nil
+	}
+
+	// 期限を過ぎたキャッシュは条件付きリクエストで変更がないか確認する
+	outReq := req
+	if entry != nil && (entry.ETag != "" || entry.LastModified != "") {
+		outReq = req.Clone(req.Context())
+		if entry.ETag != "" {
+			outReq.Header.Set("If-None-Match", entry.ETag)
+		}
+		if entry.LastModified != "" {
+			outReq.Header.Set("If-Modified-Since", entry.LastModified)
+		}
+	}
+
+	fetchLimiter.wait(requestInterval)
+	resp, err := c.next.RoundTrip(outReq)
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case resp.StatusCode == http.StatusNotModified && entry != nil:
+		resp.Body.Close()
+		entry.StoredAt = time.Now()
+		if err := saveCacheEntry(dir, entry, nil); err != nil {
+			return nil, err
+		}
+		return entry.response(req, body, cacheRevalidated), nil
+
+	case resp.StatusCode == http.StatusOK:
+		data, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		entry := &cacheEntry{
+			URL:          key,
+			Header:       resp.Header.Clone(),
+			ETag:         resp.Header.Get("ETag"),
+			LastModified: resp.Header.Get("Last-Modified"),
+			StoredAt:     time.Now(),
+		}
+		// キャッシュに保存できなくても取得したページは返す
+		if saveCacheEntry(dir, entry, data) == nil {
+			c.stored(dir, key)
+		}
+		resp.Body = io.NopCloser(bytes.NewReader(data))
+		resp.ContentLength = int64(len(data))
+		return resp, nil
+	}
+	return resp, nil
+}
+
+// revalidateRequested はリクエストがキャッシュの有効期限にかかわらずサーバーへの確認を求めているか（Cache-Control: no-cache）を返します
+func revalidateRequested(req *http.Request) bool {
+	for _, directive := range strings.Split(req.Header.Get("Cache-Control"), ",") {
+		if strings.EqualFold(strings.TrimSpace(directive), "no-cache") {
+			return true
+		}
+	}
+	return false
+}
+
+// stored はURLのキャッシュを保存したことを記録し、合計が maxCacheSize を超えた場合は古いキャッシュを削除します
+// 毎回ディレクトリを数えないよう、最初に数えた後は保存したファイルの大きさを足して概算します（上書きした分は多めに数える）
+func (c *cachingTransport) stored(dir, key string) {
+	c.sizeMu.Lock()
+	defer c.sizeMu.Unlock()
+	if c.sizeDir != dir {
+		c.sizeDir, c.size = dir, 0
+		for _, file := range listCacheFiles(dir) {
+			c.size += file.size
+		}
+	} else {
+		metaPath, bodyPath := cachePaths(dir, key)
+		for _, path := range []string{metaPath, bodyPath} {
+			if info, err := os.Stat(path); err == nil {
+				c.size += info.Size()
+			}
+		}
+	}
+	if c.size > maxCacheSize {
+		// 削除が続かないよう、上限の3/4まで減らす
+		c.size = pruneCache(dir, maxCacheSize*3/4)
+	}
+}
+
+// cacheFile はキャッシュ1件分（情報と本文のファイル）の大きさと、最後に保存または確認した日時を表す構造体
+type cacheFile struct {
+	base    string // 拡張子を除いたパス
+	size    int64
+	modTime time.Time
+}
+
+// listCacheFiles はキャッシュの一覧を返します
+func listCacheFiles(dir string) []cacheFile {
+	files := make(map[string]*cacheFile)
+	filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		// 保存中の一時ファイルは数えない
+		ext := filepath.Ext(path)
+		if err != nil || d.IsDir() || (ext != ".json" && ext != ".body") {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return nil
+		}
+		base := strings.TrimSuffix(path, ext)
+		file := files[base]
+		if file == nil {
+			file = &cacheFile{base: base}
+			files[base] = file
+		}
+		file.size += info.Size()
+		// サーバーに確認すると情報のファイルだけを更新するため、新しい方の日時を使う
+		if info.ModTime().After(file.modTime) {
+			file.modTime = info.ModTime()
+		}
+		return nil
+	})
+
+	list := make([]cacheFile, 0, len(files))
+	for _, file := range files {
+		list = append(list, *file)
+	}
+	return list
+}
+
+// pruneCache は保存または確認した日時が古いキャッシュから削除して合計を limit 以下にし、残った合計の大きさを返します
+func pruneCache(dir string, limit int64) int64 {
+	files := listCacheFiles(dir)
+	slices.SortFunc(files, func(a, b cacheFile) int { return a.modTime.Compare(b.modTime) })
+
+	var total int64
+	for _, file := range files {
+		total += file.size
+	}
+	for _, file := range files {
+		if total <= limit {
+			break
+		}
+		os.Remove(file.base + ".json")
+		os.Remove(file.base + ".body")
+		total -= file.size
+	}
+	return total
+}
+
+// response はキャッシュした内容から200のレスポンスを作成します
+func (e *cacheEntry) response(req *http.Request, body []byte, status string) *http.Response {
+	header := e.Header.Clone()
+	if header == nil {
+		header = http.Header{}
+	}
+	header.Set(cacheStatusHeader, status)
+	header.Set("Content-Length", strconv.Itoa(len(body)))
+	return &http.Response{
+		Status:        "200 OK",
+		StatusCode:    http.StatusOK,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}
+}
+
+// fromCache はレスポンスがサーバーに接続せずにキャッシュから返されたかどうかを返します
+func fromCache(resp *http.Response) bool {
+	status := resp.Header.Get(cacheStatusHeader)
+	return status == cacheHit || status == cacheOffline
+}
+
+// cachePaths はURLのキャッシュの情報と本文のファイルのパスを返します
+func cachePaths(dir, key string) (metaPath, bodyPath string) {
+	sum := sha256.Sum256([]byte(key))
+	name := hex.EncodeToString(sum[:])
+	base := filepath.Join(dir, name[:2], name)
+	return base + ".json", base + ".body"
+}
+
+// loadCacheEntry はURLのキャッシュを読み込みます（キャッシュがない場合は nil）
+func loadCacheEntry(dir, key string) (*cacheEntry, []byte, error) {
+	metaPath, bodyPath := cachePaths(dir, key)
+	data, err := os.ReadFile(metaPath)
+	if err != nil {
+		return nil, nil, err
+	}
+	var entry cacheEntry
+	if err := json.Unmarshal(data, &entry); err != nil {
+		return nil, nil, err
+	}
+	// ハッシュが衝突した場合は別のURLのキャッシュとみなす
+	if entry.URL != key {
+		return nil, nil, os.ErrNotExist
+	}
+	body, err := os.ReadFile(bodyPath)
+	if err != nil {
+		return nil, nil, err
+	}
+	return &entry, body, nil
+}
+
+// saveCacheEntry はキャッシュを保存します（body が nil の場合は情報だけを更新する）
+func saveCacheEntry(dir string, entry *cacheEntry, body []byte) error {
+	metaPath, bodyPath := cachePaths(dir, entry.URL)
+	if err := os.MkdirAll(filepath.Dir(metaPath), 0755); err != nil {
+		return fmt.Errorf("キャッシュディレクトリの作成に失敗しました: %w", err)
+	}
+	if body != nil {
+		if err := writeFileAtomic(bodyPath, body, ""); err != nil {
+			return fmt.Errorf("キャッシュの保存に失敗しました: %w", err)
+		}
+	}
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("キャッシュのJSON変換に失敗しました: %w", err)
+	}
+	if err := writeFileAtomic(metaPath, data, ""); err != nil {
+		return fmt.Errorf("キャッシュの保存に失敗しました: %w", err)
+	}
+	return nil
+}
+
+// ApplyCacheSettings はキャッシュの設定を確認して保存します（フロントエンド用）
+func (a *App) ApplyCacheSettings(cache CacheSettings) (Settings, error) {
+	if err := cache.validate(); err != nil {
+		return a.currentSettings(), err
+	}
+	return a.updateSettings(func(settings *Settings) {
+		settings.Cache = cache
+	})
+}
+
+// ClearCache はキャッシュしたページをすべて削除します（フロントエンド用）
+func (a *App) ClearCache() error {
+	dir, err := cacheDir()
+	if err != nil {
+		return err
+	}
+	if err := os.RemoveAll(dir); err != nil {
+		return fmt.Errorf("キャッシュの削除に失敗しました: %w", err)
+	}
+	a.emit("log", fmt.Sprintf("キャッシュを削除しました: %s", dir))
+	return nil
+}
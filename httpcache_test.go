@@ -0,0 +1,168 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCacheSettings_TTL(t *testing.T) {
+	settings := CacheSettings{TOCTTL: "10m", EpisodeTTL: "24h"}
+
+	tests := []struct {
+		name     string
+		settings CacheSettings
+		url      string
+		want     time.Duration
+	}{
+		{name: "連載の話", settings: settings, url: "https://ncode.syosetu.com/n1234ab/5/", want: 24 * time.Hour},
+		{name: "ノクターンの話", settings: settings, url: "https://novel18.syosetu.com/N1234AB/12", want: 24 * time.Hour},
+		{name: "目次", settings: settings, url: "https://ncode.syosetu.com/n1234ab/", want: 10 * time.Minute},
+		{name: "目次の2ページ目", settings: settings, url: "https://ncode.syosetu.com/n1234ab/?p=2", want: 10 * time.Minute},
+		{name: "小説の情報", settings: settings, url: "https://api.syosetu.com/novelapi/api/?ncode=n1234ab", want: 10 * time.Minute},
+		{name: "有効期限なし", settings: CacheSettings{}, url: "https://ncode.syosetu.com/n1234ab/5/", want: 0},
+		{name: "誤った有効期限", settings: CacheSettings{EpisodeTTL: "1日"}, url: "https://ncode.syosetu.com/n1234ab/5/", want: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.settings.ttl(tt.url); got != tt.want {
+				t.Errorf("ttl(%q) = %v, want %v", tt.url, got, tt.want)
+			}
+		})
+	}
+
+	if err := (CacheSettings{TOCTTL: "-1h"}).validate(); err == nil {
+		t.Error("負の有効期限でエラーになりません")
+	}
+}
+
+func TestCachingTransport(t *testing.T) {
+	saved := requestInterval
+	requestInterval = 0
+	t.Cleanup(func() { requestInterval = saved })
+
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Header().Set("ETag", `"v1"`)
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		io.WriteString(w, "<html>"+r.URL.Path+"</html>")
+	}))
+	defer server.Close()
+
+	transport := &cachingTransport{next: http.DefaultTransport, dir: t.TempDir()}
+	client := &http.Client{Transport: transport}
+	transport.configure(CacheSettings{Enabled: true, TOCTTL: "0s", EpisodeTTL: "1h"})
+
+	steps := []struct {
+		name         string
+		offline      bool
+		revalidate   bool
+		path         string
+		wantStatus   string
+		wantRequests int
+		wantErr      error
+	}{
+		{name: "初回の話", path: "/n1234ab/1/", wantStatus: "", wantRequests: 1},
+		{name: "有効期限内の話", path: "/n1234ab/1/", wantStatus: cacheHit, wantRequests: 1},
+		{name: "確認を求めた有効期限内の話", revalidate: true, path: "/n1234ab/1/", wantStatus: cacheRevalidated, wantRequests: 2},
+		{name: "初回の目次", path: "/n1234ab/", wantStatus: "", wantRequests: 3},
+		{name: "毎回確認する目次", path: "/n1234ab/", wantStatus: cacheRevalidated, wantRequests: 4},
+		{name: "オフラインの目次", offline: true, path: "/n1234ab/", wantStatus: cacheOffline, wantRequests: 4},
+		{name: "オフラインで未取得の話", offline: true, path: "/n1234ab/2/", wantRequests: 4, wantErr: errOfflineMiss},
+	}
+
+	for _, step := range steps {
+		transport.configure(CacheSettings{Enabled: true, Offline: step.offline, TOCTTL: "0s", EpisodeTTL: "1h"})
+		req, _ := http.NewRequest(http.MethodGet, server.URL+step.path, nil)
+		if step.revalidate {
+			req.Header.Set("Cache-Control", "no-cache")
+		}
+		resp, err := client.Do(req)
+		if step.wantErr != nil {
+			if !errors.Is(err, step.wantErr) {
+				t.Errorf("%s: error = %v, want %v", step.name, err, step.wantErr)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("%s: Get() error = %v", step.name, err)
+		}
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+
+		if string(body) != "<html>"+step.path+"</html>" {
+			t.Errorf("%s: 本文 = %q", step.name, body)
+		}
+		if got := resp.Header.Get(cacheStatusHeader); got != step.wantStatus {
+			t.Errorf("%s: %s = %q, want %q", step.name, cacheStatusHeader, got, step.wantStatus)
+		}
+		if requests != step.wantRequests {
+			t.Errorf("%s: サーバーへのリクエスト = %d回, want %d回", step.name, requests, step.wantRequests)
+		}
+	}
+
+	// キャッシュが無効な場合は毎回サーバーに接続し、環境変数でオフラインにできる
+	transport.configure(CacheSettings{})
+	if resp, err := client.Get(server.URL + "/n1234ab/1/"); err != nil || fromCache(resp) || requests != 5 {
+		t.Errorf("キャッシュが無効なのにキャッシュを使いました: %v", err)
+	}
+	t.Setenv(offlineEnv, "1")
+	if resp, err := client.Get(server.URL + "/n1234ab/1/"); err != nil || !fromCache(resp) || requests != 5 {
+		t.Errorf("環境変数でオフラインになりません: %v", err)
+	}
+}
+
+func TestCachingTransport_SizeLimit(t *testing.T) {
+	savedInterval, savedSize := requestInterval, maxCacheSize
+	requestInterval, maxCacheSize = 0, 4096
+	t.Cleanup(func() { requestInterval, maxCacheSize = savedInterval, savedSize })
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, strings.Repeat("あ", 300)) // 900バイト
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	transport := &cachingTransport{next: http.DefaultTransport, dir: dir}
+	transport.configure(CacheSettings{Enabled: true, TOCTTL: "0s", EpisodeTTL: "1h"})
+	client := &http.Client{Transport: transport}
+
+	// 上限を超えると、保存した日時が古いキャッシュから削除される
+	for i := 1; i <= 10; i++ {
+		resp, err := client.Get(fmt.Sprintf("%s/n1234ab/%d/", server.URL, i))
+		if err != nil {
+			t.Fatalf("Get() error = %v", err)
+		}
+		resp.Body.Close()
+		// 保存した日時の順を確実にする
+		metaPath, bodyPath := cachePaths(dir, resp.Request.URL.String())
+		stored := time.Now().Add(time.Duration(i-10) * time.Minute)
+		os.Chtimes(metaPath, stored, stored)
+		os.Chtimes(bodyPath, stored, stored)
+	}
+
+	var total int64
+	for _, file := range listCacheFiles(dir) {
+		total += file.size
+	}
+	if total > maxCacheSize {
+		t.Errorf("キャッシュの合計 = %dバイト, want %dバイト以下", total, maxCacheSize)
+	}
+	for i, want := range map[int]bool{1: false, 10: true} {
+		_, _, err := loadCacheEntry(dir, fmt.Sprintf("%s/n1234ab/%d/", server.URL, i))
+		if got := err == nil; got != want {
+			t.Errorf("%d話のキャッシュが残っている = %v, want %v", i, got, want)
+		}
+	}
+}
@@ -0,0 +1,273 @@
+package main
+
+import (
+	"fmt"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// maxFileNameBytes はファイル名（パスの1要素）の最大バイト数
+// 多くのファイルシステムの上限（255バイト）に一時ファイル用の余裕を持たせた値
+const maxFileNameBytes = 200
+
+// reservedFileNames はWindowsで予約されているデバイス名にマッチします
+var reservedFileNames = regexp.MustCompile(`(?i)^(CON|PRN|AUX|NUL|COM[0-9]|LPT[0-9])$`)
+
+// sanitizeFileName はファイル名に使用できない文字を安全な文字に置換します
+// Windows・macOS・Linuxのいずれでも使える名前にし、UTF-8の文字を分断しないようにバイト数で切り詰めます
+func sanitizeFileName(fileName string) string {
+	return sanitizeFileNameWithExt(fileName, "")
+}
+
+// sanitizeFileNameWithExt は拡張子を残したままファイル名を安全な名前に変換します
+func sanitizeFileNameWithExt(fileName, ext string) string {
+	// ファイル名に使用できない文字・制御文字を置換
+	fileName = strings.Map(func(r rune) rune {
+		switch {
+		case r < 0x20 || r == 0x7f:
+			return '_'
+		case strings.ContainsRune(`/\:*?"<>|`, r):
+			return '_'
+		}
+		return r
+	}, strings.TrimSpace(fileName))
+
+	// 長すぎる場合は文字の境界で切り詰める
+	fileName = truncateBytes(fileName, maxFileNameBytes-len(ext))
+
+	// Windowsでは末尾のドットと空白が取り除かれるため置換しておく
+	fileName = strings.TrimRight(fileName, ". ")
+	if fileName == "" {
+		fileName = "_"
+	}
+
+	// 予約されたデバイス名（拡張子を除いた部分で判定）は先頭に記号を付ける
+	base := fileName
+	if i := strings.Index(base, "."); i >= 0 {
+		base = base[:i]
+	}
+	if reservedFileNames.MatchString(strings.TrimSpace(base)) {
+		fileName = "_" + fileName
+	}
+
+	return fileName + ext
+}
+
+// truncateBytes は文字列をUTF-8の文字を分断せずに最大 limit バイトに切り詰めます
+func truncateBytes(s string, limit int) string {
+	if len(s) <= limit {
+		return s
+	}
+	if limit <= 0 {
+		return ""
+	}
+	for limit > 0 && !utf8.RuneStart(s[limit]) {
+		limit--
+	}
+	return s[:limit]
+}
+
+// namingPlaceholders はファイル名のテンプレートで使用できるプレースホルダと、各話ごとの値かどうか
+var namingPlaceholders = map[string]bool{
+	"ncode":   false,
+	"title":   false,
+	"author":  false,
+	"ep":      true,
+	"eptitle": true,
+	"chapter": true,
+}
+
+// namingPattern はテンプレート中のプレースホルダ（{ep:04d} など）にマッチします
+var namingPattern = regexp.MustCompile(`\{([a-z]+)(?::([^}]*))?\}`)
+
+// numberFormatPattern は数値の書式指定（04d など）にマッチします
+var numberFormatPattern = regexp.MustCompile(`^0?[0-9]{0,2}d$`)
+
+// driveLetterPattern はWindowsのドライブ名（C: など）で始まるパスにマッチします
+var driveLetterPattern = regexp.MustCompile(`^[A-Za-z]:`)
+
+// namingTemplate はファイル名のテンプレート（{author}/{title}/{ep:04d}_{eptitle}.txt など）を表す型
+type namingTemplate string
+
+// validate はテンプレートの書式を検証します（episode が false の場合は各話ごとの値を使えない）
+func (t namingTemplate) validate(episode bool) error {
+	template := string(t)
+	if strings.TrimSpace(template) == "" {
+		return fmt.Errorf("ファイル名のテンプレートが空です")
+	}
+	if strings.HasPrefix(template, "/") || strings.HasPrefix(template, `\`) || driveLetterPattern.MatchString(template) {
+		return fmt.Errorf("ファイル名のテンプレートに絶対パスは指定できません: %s", template)
+	}
+	for _, segment := range splitTemplatePath(template) {
+		if strings.TrimSpace(segment) == "" || segment == "." || segment == ".." {
+			return fmt.Errorf("ファイル名のテンプレートのフォルダ指定が正しくありません: %s", template)
+		}
+	}
+
+	hasEpisodeNumber := false
+	for _, match := range namingPattern.FindAllStringSubmatch(template, -1) {
+		hasEpisodeNumber = hasEpisodeNumber || match[1] == "ep"
+		perEpisode, ok := namingPlaceholders[match[1]]
+		if !ok {
+			return fmt.Errorf("不明なプレースホルダです: %s", match[0])
+		}
+		if perEpisode && !episode {
+			return fmt.Errorf("小説全体のファイル名には使用できないプレースホルダです: %s", match[0])
+		}
+		if match[2] != "" && (match[1] != "ep" || !numberFormatPattern.MatchString(match[2])) {
+			return fmt.Errorf("プレースホルダの書式指定が正しくありません: %s", match[0])
+		}
+	}
+
+	// 置換した後に括弧が残る場合は閉じ忘れなどの誤り
+	if strings.ContainsAny(namingPattern.ReplaceAllString(template, ""), "{}") {
+		return fmt.Errorf("ファイル名のテンプレートの括弧が対応していません: %s", template)
+	}
+
+	// 話のタイトルや章は重複することがあるため、各話のファイル名には話数が必要
+	if episode && !hasEpisodeNumber {
+		return fmt.Errorf("各話のファイル名のテンプレートには話数（{ep}）が必要です: %s", template)
+	}
+	return nil
+}
+
+// splitTemplatePath はテンプレートをフォルダの区切り（/ または \）で分割します
+func splitTemplatePath(template string) []string {
+	return strings.FieldsFunc(template, func(r rune) bool { return r == '/' || r == '\\' })
+}
+
+// render はテンプレートのプレースホルダを置換し、安全な相対パスを返します
+// 最後の要素が拡張子 ext で終わらない場合は拡張子を付け足します
+func (t namingTemplate) render(novel *Novel, episode *Episode, ext string) string {
+	segments := splitTemplatePath(string(t))
+	for i, segment := range segments {
+		segment = namingPattern.ReplaceAllStringFunc(segment, func(placeholder string) string {
+			match := namingPattern.FindStringSubmatch(placeholder)
+			return namingValue(novel, episode, match[1], match[2])
+		})
+
+		if i < len(segments)-1 {
+			segments[i] = sanitizeFileName(segment)
+			continue
+		}
+
+		// 最後の要素は拡張子を残して切り詰める
+		suffix := "." + ext
+		if strings.HasSuffix(strings.ToLower(segment), strings.ToLower(suffix)) {
+			suffix = segment[len(segment)-len(suffix):]
+			segment = segment[:len(segment)-len(suffix)]
+		}
+		segments[i] = sanitizeFileNameWithExt(segment, suffix)
+	}
+	return path.Join(segments...)
+}
+
+// namingValue はプレースホルダに対応する値を返します
+func namingValue(novel *Novel, episode *Episode, name, format string) string {
+	switch name {
+	case "ncode":
+		return novel.NCode
+	case "title":
+		return novel.Title
+	case "author":
+		return novel.Author
+	}
+
+	if episode == nil {
+		return ""
+	}
+	switch name {
+	case "ep":
+		if format == "" {
+			return episode.Number
+		}
+		number, err := strconv.Atoi(episode.Number)
+		if err != nil {
+			number = episode.Index
+		}
+		return fmt.Sprintf("%"+format, number)
+	case "eptitle":
+		if episode.Title == "" {
+			return novel.Title
+		}
+		return episode.Title
+	case "chapter":
+		if chapter := novel.ChapterOf(episode); chapter != nil {
+			return chapter.Title
+		}
+	}
+	return ""
+}
+
+// fileNaming は出力形式ごとのファイル名のテンプレートを表す構造体（空の場合は既定の名前）
+type fileNaming struct {
+	Episode namingTemplate
+	Novel   namingTemplate
+}
+
+// newFileNaming はテンプレートを検証して fileNaming を作成します
+func newFileNaming(episode, novel string) (fileNaming, error) {
+	naming := fileNaming{Episode: namingTemplate(episode), Novel: namingTemplate(novel)}
+	if episode != "" {
+		if err := naming.Episode.validate(true); err != nil {
+			return fileNaming{}, err
+		}
+	}
+	if novel != "" {
+		if err := naming.Novel.validate(false); err != nil {
+			return fileNaming{}, err
+		}
+	}
+	return naming, nil
+}
+
+// episodeWriter は各話のファイル名にテンプレートを適用した出力形式を返します
+func (n fileNaming) episodeWriter(w EpisodeWriter) EpisodeWriter {
+	if n.Episode == "" {
+		return w
+	}
+	return namedEpisodeWriter{EpisodeWriter: w, template: n.Episode}
+}
+
+// novelWriter は小説全体のファイル名にテンプレートを適用した出力形式を返します
+func (n fileNaming) novelWriter(w NovelWriter) NovelWriter {
+	if n.Novel == "" {
+		return w
+	}
+	return namedNovelWriter{NovelWriter: w, template: n.Novel}
+}
+
+// namedEpisodeWriter は各話のファイル名をテンプレートから決める出力形式
+type namedEpisodeWriter struct {
+	EpisodeWriter
+	template namingTemplate
+}
+
+// EpisodeFileName はテンプレートから各話のファイル名を返します
+func (w namedEpisodeWriter) EpisodeFileName(novel *Novel, episode *Episode) string {
+	return w.template.render(novel, episode, w.Ext())
+}
+
+// namedNovelWriter は小説全体のファイル名をテンプレートから決める出力形式
+type namedNovelWriter struct {
+	NovelWriter
+	template namingTemplate
+}
+
+// NovelFileName はテンプレートから小説全体のファイル名を返します
+func (w namedNovelWriter) NovelFileName(novel *Novel) string {
+	return w.template.render(novel, nil, w.Ext())
+}
+
+// relativeLink は from のファイルから to のファイルへの相対リンク（区切りは /）を返します
+func relativeLink(from, to string) string {
+	rel, err := filepath.Rel(filepath.Dir(filepath.FromSlash(from)), filepath.FromSlash(to))
+	if err != nil {
+		return to
+	}
+	return filepath.ToSlash(rel)
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestSanitizeFileName(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{name: "使用できない文字", input: `a/b\c:d*e?f"g<h>i|j`, expected: "a_b_c_d_e_f_g_h_i_j"},
+		{name: "制御文字", input: "a\tb\nc", expected: "a_b_c"},
+		{name: "末尾のドットと空白", input: "タイトル。.. ", expected: "タイトル。"},
+		{name: "予約されたデバイス名", input: "con", expected: "_con"},
+		{name: "予約されたデバイス名（拡張子付き）", input: "NUL.txt", expected: "_NUL.txt"},
+		{name: "空文字", input: "", expected: "_"},
+		{name: "ドットのみ", input: "..", expected: "_"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := sanitizeFileName(tt.input); got != tt.expected {
+				t.Errorf("sanitizeFileName(%q) = %q, want %q", tt.input, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestSanitizeFileName_長い名前(t *testing.T) {
+	got := sanitizeFileName(strings.Repeat("あ", 100))
+	if len(got) > maxFileNameBytes {
+		t.Errorf("len(sanitizeFileName()) = %d, want <= %d", len(got), maxFileNameBytes)
+	}
+	if !utf8.ValidString(got) {
+		t.Errorf("sanitizeFileName() が文字の途中で切り詰められています: %q", got)
+	}
+
+	withExt := sanitizeFileNameWithExt(strings.Repeat("あ", 100), ".epub")
+	if !strings.HasSuffix(withExt, ".epub") || len(withExt) > maxFileNameBytes {
+		t.Errorf("sanitizeFileNameWithExt() = %q, 拡張子を残して切り詰める必要があります", withExt)
+	}
+}
+
+func TestNamingTemplate_Render(t *testing.T) {
+	novel := newTestNovel()
+	episode := novel.Chapters[1].Episodes[0]
+	novel.Author = "作者/名"
+
+	tests := []struct {
+		name     string
+		template string
+		episode  *Episode
+		ext      string
+		expected string
+	}{
+		{name: "フォルダと書式指定", template: "{author}/{title}/{ep:04d}_{eptitle}.txt", episode: episode, ext: "txt", expected: "作者_名/テスト小説/0003_三話.txt"},
+		{name: "拡張子の補完", template: "{ncode}-{ep}", episode: episode, ext: "html", expected: "N1234AB-3.html"},
+		{name: "章タイトル", template: "{chapter}/{ep}", episode: episode, ext: "md", expected: "第二章/3.md"},
+		{name: "小説全体", template: "{title}.epub", ext: "epub", expected: "テスト小説.epub"},
+		{name: "区切り文字の統一", template: `{ncode}\{title}`, ext: "pdf", expected: "N1234AB/テスト小説.pdf"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := namingTemplate(tt.template).render(novel, tt.episode, tt.ext); got != tt.expected {
+				t.Errorf("render() = %q, want %q", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestNamingTemplate_Validate(t *testing.T) {
+	tests := []struct {
+		name     string
+		template string
+		episode  bool
+		wantErr  bool
+	}{
+		{name: "各話のテンプレート", template: "{author}/{title}/{ep:04d}_{eptitle}.txt", episode: true},
+		{name: "小説全体のテンプレート", template: "{title}.epub"},
+		{name: "小説全体で各話の値を使用", template: "{title}/{ep}", wantErr: true},
+		{name: "各話のテンプレートに話数がない", template: "{title}/{eptitle}.txt", episode: true, wantErr: true},
+		{name: "各話のテンプレートに章だけ", template: "{title}/{chapter}.txt", episode: true, wantErr: true},
+		{name: "各話のテンプレートに固定のファイル名", template: "{title}/本文.txt", episode: true, wantErr: true},
+		{name: "不明なプレースホルダ", template: "{name}{ep}", episode: true, wantErr: true},
+		{name: "不正な書式指定", template: "{ep:x}", episode: true, wantErr: true},
+		{name: "ep以外の書式指定", template: "{title:04d}{ep}", episode: true, wantErr: true},
+		{name: "括弧の閉じ忘れ", template: "{title", wantErr: true},
+		{name: "絶対パス", template: "/tmp/{title}", wantErr: true},
+		{name: "ドライブ名", template: "C:{title}", wantErr: true},
+		{name: "親フォルダ", template: "../{title}", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := namingTemplate(tt.template).validate(tt.episode)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestHTMLWriter_相対リンク(t *testing.T) {
+	novel := newTestNovel()
+	w := htmlWriter{Naming: fileNaming{Episode: "{chapter}/{ep}", Novel: "index"}}
+
+	if got := w.EpisodeFileName(novel, novel.Chapters[0].Episodes[0]); got != "第一章/1.html" {
+		t.Errorf("EpisodeFileName() = %q, want %q", got, "第一章/1.html")
+	}
+
+	var buf strings.Builder
+	if err := w.WriteEpisode(&buf, novel, novel.Chapters[0].Episodes[1]); err != nil {
+		t.Fatalf("WriteEpisode() error = %v", err)
+	}
+	for _, want := range []string{`href="1.html"`, `href="../第二章/3.html"`, `href="../index.html"`} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("WriteEpisode() の出力に %s が含まれていません", want)
+		}
+	}
+}
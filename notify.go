@@ -0,0 +1,265 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"slices"
+	"strings"
+	"time"
+
+	goruntime "runtime"
+)
+
+// 通知する出来事
+const (
+	notifyCompleted   = "completed"   // ダウンロードが完了した
+	notifyFailed      = "failed"      // ダウンロードに失敗した（取得の失敗が続いて中止した場合を含む）
+	notifyNewEpisodes = "newEpisodes" // 定期確認で新しい話が見つかった
+)
+
+// webhookTimeout はWebhookの送信1回の制限時間
+const webhookTimeout = 10 * time.Second
+
+// notificationEvents は通知できる出来事の一覧
+var notificationEvents = []string{notifyCompleted, notifyFailed, notifyNewEpisodes}
+
+// NotificationSettings はダウンロードの完了・失敗などの通知の設定を表す構造体
+type NotificationSettings struct {
+	Desktop  bool      `json:"desktop"` // デスクトップ通知を表示する
+	Events   []string  `json:"events"`  // デスクトップ通知する出来事（空の場合はすべて）
+	Webhooks []Webhook `json:"webhooks"`
+}
+
+// Webhook は通知をJSONで送るURLを表す構造体
+type Webhook struct {
+	Name    string   `json:"name"`
+	URL     string   `json:"url"`
+	Format  string   `json:"format"` // "json"（Notification をそのまま送る）・"discord"・"slack"
+	Events  []string `json:"events"` // 送る出来事（空の場合はすべて）
+	Enabled bool     `json:"enabled"`
+}
+
+// Notification は通知1件分の内容を表す構造体（Webhookの "json" 形式ではこのまま送る）
+type Notification struct {
+	Event      string       `json:"event"` // completed・failed・newEpisodes
+	Title      string       `json:"title"`
+	Message    string       `json:"message"`
+	NCode      string       `json:"ncode,omitempty"`
+	NovelTitle string       `json:"novelTitle,omitempty"`
+	URL        string       `json:"url,omitempty"`
+	Episodes   []NewEpisode `json:"episodes,omitempty"` // 新しく保存した話・見つかった新しい話
+	Time       time.Time    `json:"time"`
+}
+
+// defaultNotificationSettings は通知の既定の設定を返します
+func defaultNotificationSettings() NotificationSettings {
+	return NotificationSettings{Desktop: true, Events: []string{}, Webhooks: []Webhook{}}
+}
+
+// wantsEvent は出来事の一覧 events（空の場合はすべて）に event が含まれるかどうかを返します
+func wantsEvent(events []string, event string) bool {
+	return len(events) == 0 || slices.Contains(events, event)
+}
+
+// validate はWebhookの設定に誤りがないか確認します
+func (w Webhook) validate() error {
+	if w.Name == "" {
+		return fmt.Errorf("Webhookの名前を入力してください")
+	}
+	u, err := url.Parse(w.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("WebhookのURLが正しくありません: %q", w.URL)
+	}
+	switch w.Format {
+	case "", "json", "discord", "slack":
+	default:
+		return fmt.Errorf("不明なWebhookの形式です: %q", w.Format)
+	}
+	for _, event := range w.Events {
+		if !slices.Contains(notificationEvents, event) {
+			return fmt.Errorf("不明な出来事です: %q", event)
+		}
+	}
+	return nil
+}
+
+// payload はWebhookの形式に合わせて送信する内容を作成します
+// Discord は content、Slack は text の文字列を表示します
+func (w Webhook) payload(n Notification) ([]byte, error) {
+	text := n.Title + "\n" + n.Message
+	if n.URL != "" {
+		text += "\n" + n.URL
+	}
+	switch w.Format {
+	case "discord":
+		return json.Marshal(map[string]string{"content": text})
+	case "slack":
+		return json.Marshal(map[string]string{"text": text})
+	default:
+		return json.Marshal(n)
+	}
+}
+
+// send はWebhookに通知を送信します
+func (w Webhook) send(ctx context.Context, n Notification) error {
+	body, err := w.payload(n)
+	if err != nil {
+		return fmt.Errorf("通知のJSON変換に失敗しました: %w", err)
+	}
+	ctx, cancel := context.WithTimeout(ctx, webhookTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("HTTPステータス %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// notify は設定に従ってデスクトップ通知とWebhookで通知します
+// 通知の失敗はログに記録するだけで、ダウンロードなどの処理は続けます
+func (a *App) notify(n Notification) {
+	if n.Time.IsZero() {
+		n.Time = time.Now()
+	}
+	settings := a.currentSettings().Notifications
+
+	if settings.Desktop && wantsEvent(settings.Events, n.Event) {
+		// 画面を表示している場合はフロントエンドが表示し、CLIの場合はOSの機能で表示する
+		a.emit("notification", n)
+		if a.ctx == nil {
+			if err := showDesktopNotification(n.Title, n.Message); err != nil {
+				a.emit("log", fmt.Sprintf("デスクトップ通知を表示できませんでした: %v", err))
+			}
+		}
+	}
+
+	for _, webhook := range settings.Webhooks {
+		if !webhook.Enabled || !wantsEvent(webhook.Events, n.Event) {
+			continue
+		}
+		if err := webhook.send(context.Background(), n); err != nil {
+			a.emit("log", fmt.Sprintf("Webhook %s に通知できませんでした: %v", webhook.Name, err))
+		}
+	}
+}
+
+// notifyDownloaded はダウンロードの完了を通知します（since 以降に保存した話を新しく保存した話とする）
+func (a *App) notifyDownloaded(savePath string, novel *Novel, since time.Time) {
+	n := Notification{
+		Event:      notifyCompleted,
+		Title:      "ダウンロードが完了しました",
+		NCode:      novel.NCode,
+		NovelTitle: novel.Title,
+		URL:        novel.URL,
+		Episodes:   []NewEpisode{},
+	}
+	if _, manifest, err := openNovelStoreByNCode(savePath, novel.NCode); err == nil {
+		for _, episode := range manifest.fetchedSince(since) {
+			n.Episodes = append(n.Episodes, NewEpisode{Number: episode.Number, Title: episode.Title})
+		}
+	}
+	n.Message = fmt.Sprintf("%s（全%d話、新しく保存した話: %d話）", novel.Title, len(novel.Episodes()), len(n.Episodes))
+	a.notify(n)
+}
+
+// showDesktopNotification はOSの機能でデスクトップ通知を表示します（画面を表示していない場合用）
+// 見出しと本文はコマンドの引数や環境変数で渡し、スクリプトとして解釈されないようにします
+func showDesktopNotification(title, message string) error {
+	var cmd *exec.Cmd
+	switch goruntime.GOOS {
+	case "windows":
+		script := `Add-Type -AssemblyName System.Windows.Forms
+$n = New-Object System.Windows.Forms.NotifyIcon
+$n.Icon = [System.Drawing.SystemIcons]::Information
+$n.Visible = $true
+$n.ShowBalloonTip(10000, $env:NAROU_NOTIFY_TITLE, $env:NAROU_NOTIFY_MESSAGE, 'Info')
+Start-Sleep -Seconds 10
+$n.Dispose()`
+		cmd = exec.Command("powershell", "-NoProfile", "-NonInteractive", "-Command", script)
+		cmd.Env = append(os.Environ(), "NAROU_NOTIFY_TITLE="+title, "NAROU_NOTIFY_MESSAGE="+message)
+	case "darwin":
+		cmd = exec.Command("osascript",
+			"-e", "on run argv",
+			"-e", "display notification (item 2 of argv) with title (item 1 of argv)",
+			"-e", "end run",
+			title, message)
+	default:
+		cmd = exec.Command("notify-send", "--app-name=narou_download", "--", title, message)
+	}
+
+	// 通知が消えるまで待つコマンドがあるため、終了を待たない
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	go cmd.Wait()
+	return nil
+}
+
+// ShowDesktopNotification はOSの機能でデスクトップ通知を表示します（通知を表示できないWebViewのフロントエンド用）
+func (a *App) ShowDesktopNotification(title, message string) error {
+	return showDesktopNotification(title, message)
+}
+
+// SendTestNotification は有効なすべての通知先に試験の通知を送ります（フロントエンド用）
+func (a *App) SendTestNotification(settings NotificationSettings) error {
+	n := Notification{Event: "test", Title: "通知の確認", Message: "narou_download からの通知です", Time: time.Now()}
+
+	var errs []error
+	if settings.Desktop {
+		a.emit("notification", n)
+		if a.ctx == nil {
+			if err := showDesktopNotification(n.Title, n.Message); err != nil {
+				errs = append(errs, fmt.Errorf("デスクトップ通知: %w", err))
+			}
+		}
+	}
+	for _, webhook := range settings.Webhooks {
+		if !webhook.Enabled {
+			continue
+		}
+		if err := webhook.validate(); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if err := webhook.send(context.Background(), n); err != nil {
+			errs = append(errs, fmt.Errorf("Webhook %s: %w", webhook.Name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// ApplyNotifications は通知の設定を確認して保存します（フロントエンド用）
+func (a *App) ApplyNotifications(notifications NotificationSettings) (Settings, error) {
+	for i := range notifications.Webhooks {
+		notifications.Webhooks[i].Name = strings.TrimSpace(notifications.Webhooks[i].Name)
+		if err := notifications.Webhooks[i].validate(); err != nil {
+			return a.currentSettings(), err
+		}
+	}
+	for _, event := range notifications.Events {
+		if !slices.Contains(notificationEvents, event) {
+			return a.currentSettings(), fmt.Errorf("不明な出来事です: %q", event)
+		}
+	}
+
+	return a.updateSettings(func(settings *Settings) {
+		settings.Notifications = notifications
+	})
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestWebhook_Payload(t *testing.T) {
+	n := Notification{Event: notifyCompleted, Title: "ダウンロードが完了しました", Message: "テスト小説（全3話）", URL: "https://ncode.syosetu.com/n1234ab/"}
+	text := "ダウンロードが完了しました\nテスト小説（全3話）\nhttps://ncode.syosetu.com/n1234ab/"
+
+	tests := []struct {
+		format string
+		key    string
+		want   string
+	}{
+		{format: "", key: "event", want: notifyCompleted},
+		{format: "json", key: "message", want: "テスト小説（全3話）"},
+		{format: "discord", key: "content", want: text},
+		{format: "slack", key: "text", want: text},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			data, err := Webhook{Format: tt.format}.payload(n)
+			if err != nil {
+				t.Fatalf("payload() error = %v", err)
+			}
+			var got map[string]interface{}
+			if err := json.Unmarshal(data, &got); err != nil {
+				t.Fatalf("Unmarshal() error = %v", err)
+			}
+			if got[tt.key] != tt.want {
+				t.Errorf("payload()[%q] = %v, want %q", tt.key, got[tt.key], tt.want)
+			}
+		})
+	}
+}
+
+func TestWebhook_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		webhook Webhook
+		wantErr bool
+	}{
+		{name: "Discord", webhook: Webhook{Name: "Discord", URL: "https://discord.com/api/webhooks/1/abc", Format: "discord"}},
+		{name: "出来事の指定", webhook: Webhook{Name: "失敗だけ", URL: "http://localhost:8080/hook", Events: []string{notifyFailed}}},
+		{name: "名前なし", webhook: Webhook{URL: "https://example.com/"}, wantErr: true},
+		{name: "httpでないURL", webhook: Webhook{Name: "FTP", URL: "ftp://example.com/"}, wantErr: true},
+		{name: "不明な形式", webhook: Webhook{Name: "Teams", URL: "https://example.com/", Format: "teams"}, wantErr: true},
+		{name: "不明な出来事", webhook: Webhook{Name: "開始", URL: "https://example.com/", Events: []string{"started"}}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.webhook.validate(); (err != nil) != tt.wantErr {
+				t.Errorf("validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestApp_Notify(t *testing.T) {
+	var mu sync.Mutex
+	received := map[string][]string{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		received[r.URL.Path] = append(received[r.URL.Path], string(body))
+		mu.Unlock()
+		if r.URL.Path == "/error" {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer server.Close()
+
+	app := NewApp()
+	var events, logs []string
+	app.emitter = func(name string, data ...interface{}) {
+		switch name {
+		case "notification":
+			events = append(events, data[0].(Notification).Event)
+		case "log":
+			logs = append(logs, data[0].(string))
+		}
+	}
+	app.settings.Notifications = NotificationSettings{
+		Desktop: false,
+		Webhooks: []Webhook{
+			{Name: "すべて", URL: server.URL + "/all", Enabled: true},
+			{Name: "失敗だけ", URL: server.URL + "/failed", Format: "slack", Events: []string{notifyFailed}, Enabled: true},
+			{Name: "無効", URL: server.URL + "/disabled"},
+			{Name: "エラー", URL: server.URL + "/error", Enabled: true},
+		},
+	}
+
+	app.notify(Notification{Event: notifyNewEpisodes, Title: "新しい話があります", Message: "テスト小説 に新しい話が1話あります"})
+	app.notify(Notification{Event: notifyFailed, Title: "ダウンロードに失敗しました", Message: "Chapterの取得に3回失敗したため、ダウンロードを停止します"})
+
+	tests := []struct {
+		path string
+		want int
+	}{
+		{"/all", 2},
+		{"/failed", 1},
+		{"/disabled", 0},
+		{"/error", 2},
+	}
+	for _, tt := range tests {
+		if got := len(received[tt.path]); got != tt.want {
+			t.Errorf("%s に届いた通知 = %d件, want %d件", tt.path, got, tt.want)
+		}
+	}
+	if !strings.Contains(received["/failed"][0], `"text":"ダウンロードに失敗しました\n`) {
+		t.Errorf("Slack形式の通知 = %s", received["/failed"][0])
+	}
+	if len(events) != 0 {
+		t.Errorf("デスクトップ通知が無効なのに通知しました: %v", events)
+	}
+	if !strings.Contains(strings.Join(logs, "\n"), "Webhook エラー に通知できませんでした: HTTPステータス 500") {
+		t.Errorf("Webhookの失敗がログにありません: %v", logs)
+	}
+
+	// デスクトップ通知はフロントエンドに notification イベントで送る
+	app.ctx = context.Background()
+	app.settings.Notifications = NotificationSettings{Desktop: true, Events: []string{notifyCompleted}}
+	app.notify(Notification{Event: notifyFailed})
+	app.notify(Notification{Event: notifyCompleted})
+	if strings.Join(events, ",") != notifyCompleted {
+		t.Errorf("デスクトップ通知 = %v, want [%s]", events, notifyCompleted)
+	}
+}
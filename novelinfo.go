@@ -0,0 +1,119 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// narouGenres はなろう小説APIのジャンル番号とジャンル名
+var narouGenres = map[int]string{
+	101:  "異世界〔恋愛〕",
+	102:  "現実世界〔恋愛〕",
+	201:  "ハイファンタジー〔ファンタジー〕",
+	202:  "ローファンタジー〔ファンタジー〕",
+	301:  "純文学〔文芸〕",
+	302:  "ヒューマンドラマ〔文芸〕",
+	303:  "歴史〔文芸〕",
+	304:  "推理〔文芸〕",
+	305:  "ホラー〔文芸〕",
+	306:  "アクション〔文芸〕",
+	307:  "コメディー〔文芸〕",
+	401:  "VRゲーム〔SF〕",
+	402:  "宇宙〔SF〕",
+	403:  "空想科学〔SF〕",
+	404:  "パニック〔SF〕",
+	9901: "童話〔その他〕",
+	9902: "詩〔その他〕",
+	9903: "エッセイ〔その他〕",
+	9904: "リプレイ〔その他〕",
+	9999: "その他〔その他〕",
+	9801: "ノンジャンル〔ノンジャンル〕",
+}
+
+// nocturneGenres はR18小説APIの掲載サイト番号と掲載サイト名（R18小説にはジャンルがないため代わりに使う）
+var nocturneGenres = map[int]string{
+	1: "ノクターンノベルズ（男性向け）",
+	2: "ムーンライトノベルズ（女性向け）",
+	3: "ムーンライトノベルズ（BL）",
+	4: "ミッドナイトノベルズ（大人向け）",
+}
+
+// narouAPINovel はなろう小説APIの小説1件分のあらすじ・キーワード・ジャンルです
+type narouAPINovel struct {
+	Story    string `json:"story"`
+	Keyword  string `json:"keyword"`
+	Genre    int    `json:"genre"`
+	NocGenre int    `json:"nocgenre"`
+}
+
+// genreName はジャンル番号からジャンル名を返します
+func (n narouAPINovel) genreName() string {
+	if name, ok := nocturneGenres[n.NocGenre]; ok {
+		return name
+	}
+	return narouGenres[n.Genre]
+}
+
+// fetchNovelInfo はなろう小説APIから小説のあらすじ・キーワード・ジャンルを取得します
+func (a *App) fetchNovelInfo(ncode, novelURL string) (narouAPINovel, error) {
+	params := url.Values{}
+	params.Set("out", "json")
+	params.Set("ncode", strings.ToLower(ncode))
+
+	r18 := a.site.isR18(novelURL)
+	apiURL := a.site.novelAPI(r18)
+	if r18 {
+		params.Set("of", "s-k-ng")
+	} else {
+		params.Set("of", "s-k-g")
+	}
+
+	req, err := http.NewRequest("GET", apiURL+"?"+params.Encode(), nil)
+	if err != nil {
+		return narouAPINovel{}, err
+	}
+	req.Header.Set("User-Agent", "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/136.0.0.0 Safari/537.36")
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return narouAPINovel{}, fmt.Errorf("小説の情報の取得に失敗しました: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return narouAPINovel{}, fmt.Errorf("小説の情報の取得に失敗しました: %s", resp.Status)
+	}
+
+	// 先頭の要素は件数（allcount）なので読み飛ばす
+	var items []json.RawMessage
+	if err := json.NewDecoder(resp.Body).Decode(&items); err != nil {
+		return narouAPINovel{}, fmt.Errorf("小説の情報のJSON解析に失敗しました: %w", err)
+	}
+	if len(items) < 2 {
+		return narouAPINovel{}, fmt.Errorf("小説の情報が見つかりませんでした: %s", ncode)
+	}
+	var info narouAPINovel
+	if err := json.Unmarshal(items[1], &info); err != nil {
+		return narouAPINovel{}, fmt.Errorf("小説の情報のJSON解析に失敗しました: %w", err)
+	}
+	return info, nil
+}
+
+// updateNovelInfo はあらすじ・キーワード・ジャンルを取得してマニフェストに記録します
+// 本棚の表示（OPDSなど）に使うだけなので、取得に失敗してもダウンロードは続けます
+func (a *App) updateNovelInfo(store *novelStore, manifest *Manifest) {
+	info, err := a.fetchNovelInfo(manifest.NCode, manifest.URL)
+	if err != nil {
+		a.emit("log", err.Error())
+		return
+	}
+	manifest.Summary = strings.TrimSpace(info.Story)
+	manifest.Keywords = strings.Fields(info.Keyword)
+	manifest.Genre = info.genreName()
+	if err := store.saveManifest(manifest); err != nil {
+		a.emit("log", err.Error())
+	}
+}
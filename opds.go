@@ -0,0 +1,536 @@
+package main
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"mime"
+	"net/http"
+	"net/url"
+	"path"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+)
+
+// OPDSカタログの種類
+const (
+	opdsNavigationType  = "application/atom+xml;profile=opds-catalog;kind=navigation"
+	opdsAcquisitionType = "application/atom+xml;profile=opds-catalog;kind=acquisition"
+	opds2Type           = "application/opds+json"
+)
+
+// opdsRecentLimit は「最近更新」のフィードに載せる小説の最大数
+const opdsRecentLimit = 50
+
+// opdsNoGenre はジャンルを取得できなかった小説のジャンル名
+const opdsNoGenre = "未分類"
+
+// opdsFileTypes はカタログからダウンロードできる形式とそのMIMEタイプ
+// HTMLは目次のファイルと各話のファイルに分かれていることがあるため載せない
+var opdsFileTypes = map[string]string{
+	"epub": "application/epub+zip",
+	"txt":  "text/plain",
+	"pdf":  "application/pdf",
+	"md":   "text/markdown",
+}
+
+// opdsServer は本棚の小説をOPDSカタログ（1.2・2.0）として公開する構造体
+// 電子書籍リーダーのアプリ（KOReader・Moon+ Reader など）から本棚を閲覧・ダウンロードするためのものです
+type opdsServer struct {
+	app    *App
+	folder string
+}
+
+// opdsBook はカタログに載せる小説1件分を表す構造体
+type opdsBook struct {
+	store    *novelStore
+	manifest *Manifest
+}
+
+// genre はカタログで分類に使うジャンル名を返します
+func (b opdsBook) genre() string {
+	if b.manifest.Genre == "" {
+		return opdsNoGenre
+	}
+	return b.manifest.Genre
+}
+
+// files はカタログからダウンロードできるファイルを返します
+func (b opdsBook) files() []ManifestFile {
+	return slices.DeleteFunc(b.manifest.bookFiles(), func(f ManifestFile) bool { return opdsFileTypes[f.Format] == "" })
+}
+
+// opdsFeed はOPDS 1.2・2.0のどちらの形式でも出力できるフィードの内容を表す構造体
+// acquisition の場合は小説の一覧、そうでない場合はほかのフィードへの案内（navigation）になります
+type opdsFeed struct {
+	path        string // /opds/ 以下のパス（トップは空）
+	title       string
+	updated     time.Time
+	acquisition bool
+	links       []opdsNavLink
+	books       []opdsBook
+}
+
+// opdsNavLink はナビゲーションのフィードに載せるほかのフィードへのリンクを表す構造体
+type opdsNavLink struct {
+	title       string
+	path        string
+	rel         string
+	summary     string
+	updated     time.Time
+	acquisition bool // リンク先が小説の一覧かどうか
+}
+
+// atomType はフィードのOPDS 1.2でのMIMEタイプを返します
+func (f opdsFeed) atomType() string {
+	if f.acquisition {
+		return opdsAcquisitionType
+	}
+	return opdsNavigationType
+}
+
+// loadBooks は本棚の小説を読み込みます（同じ小説が複数のフォルダにある場合は最初のものだけを使う）
+func (s *opdsServer) loadBooks() ([]opdsBook, error) {
+	folder, err := libraryFolder(s.folder)
+	if err != nil {
+		return nil, err
+	}
+	stores, err := findNovelStores(folder)
+	if err != nil {
+		return nil, err
+	}
+
+	books := []opdsBook{}
+	seen := make(map[string]bool)
+	for _, store := range stores {
+		manifest, err := store.loadManifest()
+		if err != nil {
+			s.app.emit("log", fmt.Sprintf("%s を読み込めませんでした: %v", store.dir(), err))
+			continue
+		}
+		if seen[manifest.NCode] {
+			continue
+		}
+		seen[manifest.NCode] = true
+		books = append(books, opdsBook{store: store, manifest: manifest})
+	}
+	sort.SliceStable(books, func(i, j int) bool { return books[i].manifest.Title < books[j].manifest.Title })
+	return books, nil
+}
+
+// latestUpdate は小説の中で最も新しい更新日時を返します
+func latestUpdate(books []opdsBook) time.Time {
+	var latest time.Time
+	for _, book := range books {
+		if book.manifest.UpdatedAt.After(latest) {
+			latest = book.manifest.UpdatedAt
+		}
+	}
+	return latest
+}
+
+// groupLinks は小説を key ごとにまとめたフィードへのリンクを作成します
+func groupLinks(books []opdsBook, base string, key func(opdsBook) string) []opdsNavLink {
+	groups := make(map[string][]opdsBook)
+	for _, book := range books {
+		groups[key(book)] = append(groups[key(book)], book)
+	}
+	names := make([]string, 0, len(groups))
+	for name := range groups {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	links := []opdsNavLink{}
+	for _, name := range names {
+		links = append(links, opdsNavLink{
+			title:       name,
+			path:        base + "/" + url.PathEscape(name),
+			rel:         "subsection",
+			summary:     fmt.Sprintf("%d作品", len(groups[name])),
+			updated:     latestUpdate(groups[name]),
+			acquisition: true,
+		})
+	}
+	return links
+}
+
+// buildOPDSFeed はパスに対応するフィードを作成します（存在しないパスの場合は ok が false）
+func buildOPDSFeed(feedPath string, books []opdsBook) (feed opdsFeed, ok bool) {
+	feedPath = strings.Trim(feedPath, "/")
+	kind, name, hasName := strings.Cut(feedPath, "/")
+	feed = opdsFeed{path: feedPath, updated: latestUpdate(books)}
+
+	switch {
+	case feedPath == "":
+		feed.title = "小説家になろう 本棚"
+		feed.links = []opdsNavLink{
+			{title: "すべての作品", path: "all", rel: "subsection", summary: fmt.Sprintf("%d作品", len(books)), acquisition: true},
+			{title: "最近更新された作品", path: "recent", rel: "http://opds-spec.org/sort/new", summary: "更新日時の新しい順", acquisition: true},
+			{title: "作者別", path: "authors", rel: "subsection", summary: "作者ごとの作品"},
+			{title: "ジャンル別", path: "genres", rel: "subsection", summary: "ジャンルごとの作品"},
+		}
+	case feedPath == "all":
+		feed.title = "すべての作品"
+		feed.acquisition = true
+		feed.books = books
+	case feedPath == "recent":
+		feed.title = "最近更新された作品"
+		feed.acquisition = true
+		feed.books = slices.Clone(books)
+		sort.SliceStable(feed.books, func(i, j int) bool {
+			return feed.books[i].manifest.UpdatedAt.After(feed.books[j].manifest.UpdatedAt)
+		})
+		feed.books = feed.books[:min(len(feed.books), opdsRecentLimit)]
+	case feedPath == "authors":
+		feed.title = "作者別"
+		feed.links = groupLinks(books, "authors", func(b opdsBook) string { return b.manifest.Author })
+	case feedPath == "genres":
+		feed.title = "ジャンル別"
+		feed.links = groupLinks(books, "genres", opdsBook.genre)
+	case hasName && (kind == "authors" || kind == "genres"):
+		feed.title = name
+		feed.acquisition = true
+		for _, book := range books {
+			if (kind == "authors" && book.manifest.Author == name) || (kind == "genres" && book.genre() == name) {
+				feed.books = append(feed.books, book)
+			}
+		}
+		if len(feed.books) == 0 {
+			return opdsFeed{}, false
+		}
+	default:
+		return opdsFeed{}, false
+	}
+
+	for i := range feed.links {
+		if feed.links[i].updated.IsZero() {
+			feed.links[i].updated = feed.updated
+		}
+	}
+	return feed, true
+}
+
+// handler はOPDSカタログのエンドポイントを返します
+// /opds/ 以下はOPDS 1.2（Atom）、/opds/v2/ 以下は同じ内容のOPDS 2.0（JSON）です
+func (s *opdsServer) handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /opds/files/{ncode}/{file...}", s.handleFile)
+	mux.HandleFunc("GET /opds/v2/{feed...}", s.handleFeed)
+	mux.HandleFunc("GET /opds/{feed...}", s.handleFeed)
+	mux.Handle("GET /{$}", http.RedirectHandler("/opds/", http.StatusFound))
+	return mux
+}
+
+// handleFeed はフィードをOPDS 1.2または2.0の形式で返します
+func (s *opdsServer) handleFeed(w http.ResponseWriter, r *http.Request) {
+	books, err := s.loadBooks()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	feed, ok := buildOPDSFeed(r.PathValue("feed"), books)
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+
+	if strings.HasPrefix(r.URL.Path, "/opds/v2/") {
+		w.Header().Set("Content-Type", opds2Type)
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		encoder.Encode(newOPDS2Feed(feed))
+		return
+	}
+
+	data, err := xml.MarshalIndent(newAtomFeed(feed), "", "  ")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", feed.atomType()+";charset=utf-8")
+	w.Write([]byte(xml.Header))
+	w.Write(data)
+}
+
+// handleFile は小説のファイルを返します（マニフェストに記録されたファイルだけを返す）
+func (s *opdsServer) handleFile(w http.ResponseWriter, r *http.Request) {
+	books, err := s.loadBooks()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	ncode, rel := r.PathValue("ncode"), r.PathValue("file")
+	for _, book := range books {
+		if !strings.EqualFold(book.manifest.NCode, ncode) {
+			continue
+		}
+		for _, file := range book.files() {
+			if file.Path != rel {
+				continue
+			}
+			w.Header().Set("Content-Type", opdsFileType(file))
+			w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": path.Base(file.Path)}))
+			http.ServeFile(w, r, book.store.path(file.Path))
+			return
+		}
+	}
+	http.NotFound(w, r)
+}
+
+// opdsFileType はファイルのMIMEタイプを返します（テキストは文字コードも付ける）
+func opdsFileType(file ManifestFile) string {
+	contentType := opdsFileTypes[file.Format]
+	switch strings.ToUpper(file.Encoding) {
+	case "":
+	case "SHIFT-JIS", "SHIFT_JIS":
+		contentType += "; charset=Shift_JIS"
+	default:
+		contentType += "; charset=" + file.Encoding
+	}
+	return contentType
+}
+
+// opdsFileHref はファイルのダウンロード用のパスを返します
+func opdsFileHref(book opdsBook, file ManifestFile) string {
+	segments := strings.Split(file.Path, "/")
+	for i, segment := range segments {
+		segments[i] = url.PathEscape(segment)
+	}
+	return "/opds/files/" + url.PathEscape(book.manifest.NCode) + "/" + strings.Join(segments, "/")
+}
+
+// atomFeed はAtomのフィード（OPDS 1.2のカタログ・新着のフィード）を表す構造体
+type atomFeed struct {
+	XMLName   xml.Name    `xml:"feed"`
+	Xmlns     string      `xml:"xmlns,attr"`
+	XmlnsDC   string      `xml:"xmlns:dc,attr,omitempty"`
+	XmlnsOPDS string      `xml:"xmlns:opds,attr,omitempty"`
+	ID        string      `xml:"id"`
+	Title     string      `xml:"title"`
+	Updated   string      `xml:"updated"`
+	Links     []atomLink  `xml:"link"`
+	Entries   []atomEntry `xml:"entry"`
+}
+
+// atomEntry はAtomのフィードの項目を表す構造体
+type atomEntry struct {
+	ID         string         `xml:"id"`
+	Title      string         `xml:"title"`
+	Updated    string         `xml:"updated"`
+	Authors    []atomPerson   `xml:"author"`
+	Language   string         `xml:"dc:language,omitempty"`
+	Categories []atomCategory `xml:"category"`
+	Summary    *atomText      `xml:"summary"`
+	Content    *atomText      `xml:"content"`
+	Links      []atomLink     `xml:"link"`
+}
+
+// atomLink はAtomのリンクを表す構造体
+type atomLink struct {
+	Rel   string `xml:"rel,attr,omitempty"`
+	Href  string `xml:"href,attr"`
+	Type  string `xml:"type,attr,omitempty"`
+	Title string `xml:"title,attr,omitempty"`
+}
+
+// atomPerson はAtomの作者を表す構造体
+type atomPerson struct {
+	Name string `xml:"name"`
+	URI  string `xml:"uri,omitempty"`
+}
+
+// atomCategory はAtomの分類を表す構造体
+type atomCategory struct {
+	Scheme string `xml:"scheme,attr,omitempty"`
+	Term   string `xml:"term,attr"`
+	Label  string `xml:"label,attr,omitempty"`
+}
+
+// atomText はAtomのテキストを表す構造体
+type atomText struct {
+	Type string `xml:"type,attr"`
+	Text string `xml:",chardata"`
+}
+
+// atomTime はAtomの日時の形式（RFC 3339）に変換します
+func atomTime(t time.Time) string {
+	if t.IsZero() {
+		t = time.Now()
+	}
+	return t.UTC().Format(time.RFC3339)
+}
+
+// newAtomFeed はフィードをOPDS 1.2（Atom）の形式に変換します
+func newAtomFeed(feed opdsFeed) atomFeed {
+	atom := atomFeed{
+		Xmlns:     "http://www.w3.org/2005/Atom",
+		XmlnsDC:   "http://purl.org/dc/terms/",
+		XmlnsOPDS: "http://opds-spec.org/2010/catalog",
+		ID:        "urn:narou-download:opds:" + feed.path,
+		Title:     feed.title,
+		Updated:   atomTime(feed.updated),
+		Links: []atomLink{
+			{Rel: "self", Href: "/opds/" + feed.path, Type: feed.atomType()},
+			{Rel: "start", Href: "/opds/", Type: opdsNavigationType},
+			{Rel: "alternate", Href: "/opds/v2/" + feed.path, Type: opds2Type},
+		},
+		Entries: []atomEntry{},
+	}
+
+	for _, link := range feed.links {
+		linkType := opdsNavigationType
+		if link.acquisition {
+			linkType = opdsAcquisitionType
+		}
+		atom.Entries = append(atom.Entries, atomEntry{
+			ID:      "urn:narou-download:opds:" + link.path,
+			Title:   link.title,
+			Updated: atomTime(link.updated),
+			Content: &atomText{Type: "text", Text: link.summary},
+			Links:   []atomLink{{Rel: link.rel, Href: "/opds/" + link.path, Type: linkType}},
+		})
+	}
+
+	for _, book := range feed.books {
+		m := book.manifest
+		entry := atomEntry{
+			ID:       "urn:ncode:" + strings.ToLower(m.NCode),
+			Title:    m.Title,
+			Updated:  atomTime(m.UpdatedAt),
+			Authors:  []atomPerson{{Name: m.Author}},
+			Language: "ja",
+			Links: []atomLink{
+				{Rel: "alternate", Href: m.URL, Type: "text/html", Title: "小説家になろう"},
+				{Rel: "related", Href: "/opds/authors/" + url.PathEscape(m.Author), Type: opdsAcquisitionType, Title: m.Author + " の作品"},
+			},
+		}
+		if m.Summary != "" {
+			entry.Summary = &atomText{Type: "text", Text: m.Summary}
+		}
+		entry.Categories = append(entry.Categories, atomCategory{Scheme: "urn:narou:genre", Term: book.genre(), Label: book.genre()})
+		for _, keyword := range m.Keywords {
+			entry.Categories = append(entry.Categories, atomCategory{Scheme: "urn:narou:keyword", Term: keyword, Label: keyword})
+		}
+		for _, file := range book.files() {
+			entry.Links = append(entry.Links, atomLink{
+				Rel:   "http://opds-spec.org/acquisition",
+				Href:  opdsFileHref(book, file),
+				Type:  opdsFileType(file),
+				Title: strings.ToUpper(file.Format),
+			})
+		}
+		atom.Entries = append(atom.Entries, entry)
+	}
+	return atom
+}
+
+// opds2Feed はOPDS 2.0（JSON）のフィードを表す構造体
+type opds2Feed struct {
+	Metadata     opds2FeedMetadata  `json:"metadata"`
+	Links        []opds2Link        `json:"links"`
+	Navigation   []opds2Link        `json:"navigation,omitempty"`
+	Publications []opds2Publication `json:"publications,omitempty"`
+}
+
+// opds2FeedMetadata はOPDS 2.0のフィードの情報を表す構造体
+type opds2FeedMetadata struct {
+	Title         string    `json:"title"`
+	Modified      time.Time `json:"modified"`
+	NumberOfItems int       `json:"numberOfItems"`
+}
+
+// opds2Link はOPDS 2.0のリンクを表す構造体
+type opds2Link struct {
+	Rel   string `json:"rel,omitempty"`
+	Href  string `json:"href"`
+	Type  string `json:"type,omitempty"`
+	Title string `json:"title,omitempty"`
+}
+
+// opds2Publication はOPDS 2.0の出版物（小説1件分）を表す構造体
+type opds2Publication struct {
+	Metadata opds2Metadata `json:"metadata"`
+	Links    []opds2Link   `json:"links"`
+}
+
+// opds2Metadata はOPDS 2.0の出版物の情報を表す構造体
+type opds2Metadata struct {
+	Type        string         `json:"@type"`
+	Identifier  string         `json:"identifier"`
+	Title       string         `json:"title"`
+	Author      []opds2Name    `json:"author"`
+	Description string         `json:"description,omitempty"`
+	Subject     []opds2Subject `json:"subject,omitempty"`
+	Language    string         `json:"language"`
+	Modified    time.Time      `json:"modified"`
+}
+
+// opds2Name はOPDS 2.0の作者を表す構造体
+type opds2Name struct {
+	Name  string      `json:"name"`
+	Links []opds2Link `json:"links,omitempty"`
+}
+
+// opds2Subject はOPDS 2.0の分類を表す構造体
+type opds2Subject struct {
+	Name   string `json:"name"`
+	Scheme string `json:"scheme,omitempty"`
+}
+
+// newOPDS2Feed はフィードをOPDS 2.0（JSON）の形式に変換します
+func newOPDS2Feed(feed opdsFeed) opds2Feed {
+	result := opds2Feed{
+		Metadata: opds2FeedMetadata{Title: feed.title, Modified: feed.updated},
+		Links: []opds2Link{
+			{Rel: "self", Href: "/opds/v2/" + feed.path, Type: opds2Type},
+			{Rel: "start", Href: "/opds/v2/", Type: opds2Type},
+			{Rel: "alternate", Href: "/opds/" + feed.path, Type: feed.atomType()},
+		},
+	}
+
+	for _, link := range feed.links {
+		result.Navigation = append(result.Navigation, opds2Link{Rel: link.rel, Href: "/opds/v2/" + link.path, Type: opds2Type, Title: link.title})
+	}
+	result.Metadata.NumberOfItems = len(feed.links)
+
+	for _, book := range feed.books {
+		m := book.manifest
+		metadata := opds2Metadata{
+			Type:        "http://schema.org/Book",
+			Identifier:  "urn:ncode:" + strings.ToLower(m.NCode),
+			Title:       m.Title,
+			Author:      []opds2Name{{Name: m.Author, Links: []opds2Link{{Href: "/opds/v2/authors/" + url.PathEscape(m.Author), Type: opds2Type}}}},
+			Description: m.Summary,
+			Subject:     []opds2Subject{{Name: book.genre(), Scheme: "urn:narou:genre"}},
+			Language:    "ja",
+			Modified:    m.UpdatedAt,
+		}
+		for _, keyword := range m.Keywords {
+			metadata.Subject = append(metadata.Subject, opds2Subject{Name: keyword, Scheme: "urn:narou:keyword"})
+		}
+
+		publication := opds2Publication{
+			Metadata: metadata,
+			Links:    []opds2Link{{Rel: "alternate", Href: m.URL, Type: "text/html"}},
+		}
+		for _, file := range book.files() {
+			publication.Links = append(publication.Links, opds2Link{
+				Rel:   "http://opds-spec.org/acquisition",
+				Href:  opdsFileHref(book, file),
+				Type:  opdsFileType(file),
+				Title: strings.ToUpper(file.Format),
+			})
+		}
+		result.Publications = append(result.Publications, publication)
+	}
+	if feed.acquisition {
+		result.Metadata.NumberOfItems = len(feed.books)
+		if result.Publications == nil {
+			result.Publications = []opds2Publication{}
+		}
+	}
+	return result
+}
@@ -0,0 +1,193 @@
+package main
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+// newTestOPDSServer はあらすじなどを記録した小説1件の本棚をOPDSカタログとして公開します
+func newTestOPDSServer(t *testing.T) (*httptest.Server, *Manifest) {
+	t.Helper()
+	app, store, _ := newVerifiedNovelStore(t)
+	manifest, err := store.loadManifest()
+	if err != nil {
+		t.Fatalf("loadManifest() error = %v", err)
+	}
+	manifest.Summary = "テストのあらすじ"
+	manifest.Keywords = []string{"異世界転生", "チート"}
+	manifest.Genre = "ハイファンタジー〔ファンタジー〕"
+	if err := store.saveManifest(manifest); err != nil {
+		t.Fatalf("saveManifest() error = %v", err)
+	}
+
+	server := httptest.NewServer((&opdsServer{app: app, folder: store.root}).handler())
+	t.Cleanup(server.Close)
+	return server, manifest
+}
+
+func TestOPDSServer_Feeds(t *testing.T) {
+	server, _ := newTestOPDSServer(t)
+
+	tests := []struct {
+		name     string
+		path     string
+		status   int
+		wantType string
+		contains []string
+	}{
+		{
+			name:     "トップ",
+			path:     "/opds/",
+			status:   http.StatusOK,
+			wantType: opdsNavigationType,
+			contains: []string{`href="/opds/all"`, `rel="http://opds-spec.org/sort/new"`, `href="/opds/authors"`, `href="/opds/genres"`},
+		},
+		{
+			name:     "すべての作品",
+			path:     "/opds/all",
+			status:   http.StatusOK,
+			wantType: opdsAcquisitionType,
+			contains: []string{
+				"<title>テスト小説</title>",
+				"<name>作者</name>",
+				"<summary type=\"text\">テストのあらすじ</summary>",
+				`term="異世界転生"`,
+				`term="ハイファンタジー〔ファンタジー〕"`,
+				`rel="http://opds-spec.org/acquisition" href="/opds/files/N1234AB/`,
+				`type="application/epub+zip"`,
+			},
+		},
+		{
+			name:     "作者別",
+			path:     "/opds/authors",
+			status:   http.StatusOK,
+			wantType: opdsNavigationType,
+			contains: []string{`href="/opds/authors/` + url.PathEscape("作者") + `"`, "1作品"},
+		},
+		{name: "作者の作品", path: "/opds/authors/" + url.PathEscape("作者"), status: http.StatusOK, wantType: opdsAcquisitionType, contains: []string{"テスト小説"}},
+		{name: "ジャンルの作品", path: "/opds/genres/" + url.PathEscape("ハイファンタジー〔ファンタジー〕"), status: http.StatusOK, wantType: opdsAcquisitionType, contains: []string{"テスト小説"}},
+		{name: "作品のない作者", path: "/opds/authors/" + url.PathEscape("別の作者"), status: http.StatusNotFound},
+		{name: "存在しないフィード", path: "/opds/series", status: http.StatusNotFound},
+		{
+			name:     "OPDS 2.0",
+			path:     "/opds/v2/recent",
+			status:   http.StatusOK,
+			wantType: opds2Type,
+			contains: []string{`"title": "テスト小説"`, `"description": "テストのあらすじ"`, `"name": "チート"`, `"rel": "http://opds-spec.org/acquisition"`},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := http.Get(server.URL + tt.path)
+			if err != nil {
+				t.Fatalf("GET %s error = %v", tt.path, err)
+			}
+			defer resp.Body.Close()
+			body, _ := io.ReadAll(resp.Body)
+			if resp.StatusCode != tt.status {
+				t.Fatalf("StatusCode = %d, want %d", resp.StatusCode, tt.status)
+			}
+			if tt.status != http.StatusOK {
+				return
+			}
+			if got := resp.Header.Get("Content-Type"); !strings.HasPrefix(got, tt.wantType) {
+				t.Errorf("Content-Type = %q, want %q", got, tt.wantType)
+			}
+			for _, want := range tt.contains {
+				if !strings.Contains(string(body), want) {
+					t.Errorf("%q が含まれていません:\n%s", want, body)
+				}
+			}
+
+			// 電子書籍リーダーが読めるように、XMLとJSONとして正しいことを確認する
+			if tt.wantType == opds2Type {
+				var feed opds2Feed
+				if err := json.Unmarshal(body, &feed); err != nil {
+					t.Errorf("json.Unmarshal() error = %v", err)
+				}
+				return
+			}
+			decoder := xml.NewDecoder(strings.NewReader(string(body)))
+			for {
+				if _, err := decoder.Token(); err == io.EOF {
+					break
+				} else if err != nil {
+					t.Fatalf("XMLの解析に失敗しました: %v", err)
+				}
+			}
+		})
+	}
+}
+
+func TestOPDSServer_Files(t *testing.T) {
+	server, manifest := newTestOPDSServer(t)
+	book := opdsBook{manifest: manifest}
+	files := book.files()
+	if len(files) != 1 || files[0].Format != "epub" {
+		t.Fatalf("files() = %+v, want epub only", files)
+	}
+
+	tests := []struct {
+		name     string
+		path     string
+		status   int
+		wantType string
+		wantSize int64
+	}{
+		{name: "EPUB", path: opdsFileHref(book, files[0]), status: http.StatusOK, wantType: "application/epub+zip", wantSize: files[0].Size},
+		{name: "マニフェストにないファイル", path: "/opds/files/N1234AB/.narou/N1234AB/manifest.json", status: http.StatusNotFound},
+		{name: "存在しない小説", path: "/opds/files/N9999ZZ/" + files[0].Path, status: http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := http.Get(server.URL + tt.path)
+			if err != nil {
+				t.Fatalf("GET %s error = %v", tt.path, err)
+			}
+			defer resp.Body.Close()
+			body, _ := io.ReadAll(resp.Body)
+			if resp.StatusCode != tt.status {
+				t.Fatalf("StatusCode = %d, want %d", resp.StatusCode, tt.status)
+			}
+			if tt.status != http.StatusOK {
+				return
+			}
+			if got := resp.Header.Get("Content-Type"); got != tt.wantType {
+				t.Errorf("Content-Type = %q, want %q", got, tt.wantType)
+			}
+			if int64(len(body)) != tt.wantSize {
+				t.Errorf("len(body) = %d, want %d", len(body), tt.wantSize)
+			}
+			if !strings.HasPrefix(resp.Header.Get("Content-Disposition"), "attachment") {
+				t.Errorf("Content-Disposition = %q", resp.Header.Get("Content-Disposition"))
+			}
+		})
+	}
+}
+
+func TestOPDSFileType(t *testing.T) {
+	tests := []struct {
+		file ManifestFile
+		want string
+	}{
+		{ManifestFile{Format: "epub"}, "application/epub+zip"},
+		{ManifestFile{Format: "txt", Encoding: "Shift-JIS"}, "text/plain; charset=Shift_JIS"},
+		{ManifestFile{Format: "txt", Encoding: "UTF-8"}, "text/plain; charset=UTF-8"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.want, func(t *testing.T) {
+			if got := opdsFileType(tt.file); got != tt.want {
+				t.Errorf("opdsFileType() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,522 @@
+package main
+
+import (
+	"encoding/binary"
+	"fmt"
+	"os"
+	"sort"
+)
+
+// trueTypeFont はPDFに埋め込むTrueTypeフォントを表す構造体
+type trueTypeFont struct {
+	tables     map[string][]byte
+	unitsPerEm int
+	ascent     int
+	descent    int
+	capHeight  int
+	bbox       [4]int
+	advances   []uint16          // グリフごとの横書きの送り幅
+	cmap       map[rune]uint16   // 文字からグリフ番号への対応
+	vert       map[uint16]uint16 // 縦書き用グリフへの置き換え（GSUB vert/vrt2）
+	used       map[uint16]rune   // 使用したグリフと元の文字（ToUnicode用）
+}
+
+// loadTrueTypeFont はTrueType（.ttf）またはTrueTypeコレクション（.ttc の先頭のフォント）を読み込みます
+func loadTrueTypeFont(path string) (*trueTypeFont, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("フォントファイルを読み込めませんでした: %w", err)
+	}
+	return parseTrueTypeFont(data)
+}
+
+// parseTrueTypeFont はフォントファイルのテーブルを解析します
+func parseTrueTypeFont(data []byte) (*trueTypeFont, error) {
+	offset := 0
+	if len(data) >= 16 && string(data[:4]) == "ttcf" {
+		// コレクションの場合は最初のフォントを使用する
+		offset = int(binary.BigEndian.Uint32(data[12:16]))
+	}
+	if offset < 0 || len(data) < offset+12 {
+		return nil, fmt.Errorf("フォントファイルの形式が正しくありません")
+	}
+	if string(data[offset:offset+4]) == "OTTO" {
+		return nil, fmt.Errorf("CFF形式のOpenTypeフォントには対応していません。TrueType形式（.ttf/.ttc）のフォントを指定してください")
+	}
+
+	numTables := int(binary.BigEndian.Uint16(data[offset+4:]))
+	f := &trueTypeFont{tables: make(map[string][]byte), used: make(map[uint16]rune)}
+	for i := 0; i < numTables; i++ {
+		record := offset + 12 + i*16
+		if len(data) < record+16 {
+			return nil, fmt.Errorf("フォントファイルのテーブルが壊れています")
+		}
+		tag := string(data[record : record+4])
+		start := int(binary.BigEndian.Uint32(data[record+8:]))
+		length := int(binary.BigEndian.Uint32(data[record+12:]))
+		if start < 0 || length < 0 || len(data) < start+length {
+			return nil, fmt.Errorf("フォントファイルのテーブルが壊れています: %s", tag)
+		}
+		f.tables[tag] = data[start : start+length]
+	}
+
+	for _, tag := range []string{"head", "hhea", "hmtx", "maxp", "loca", "glyf", "cmap"} {
+		if _, ok := f.tables[tag]; !ok {
+			return nil, fmt.Errorf("フォントに %s テーブルがありません（TrueType形式のフォントを指定してください）", tag)
+		}
+	}
+
+	if err := f.parseMetrics(); err != nil {
+		return nil, err
+	}
+	if err := f.parseCmap(); err != nil {
+		return nil, err
+	}
+	f.parseVert()
+
+	return f, nil
+}
+
+// parseMetrics は head・hhea・hmtx・OS/2 テーブルから寸法を読み込みます
+func (f *trueTypeFont) parseMetrics() error {
+	head, hhea, hmtx := f.tables["head"], f.tables["hhea"], f.tables["hmtx"]
+	if len(head) < 54 || len(hhea) < 36 {
+		return fmt.Errorf("フォントの head/hhea テーブルが壊れています")
+	}
+	f.unitsPerEm = int(binary.BigEndian.Uint16(head[18:]))
+	if f.unitsPerEm == 0 {
+		return fmt.Errorf("フォントの unitsPerEm が0です")
+	}
+	for i := range f.bbox {
+		f.bbox[i] = int(int16(binary.BigEndian.Uint16(head[36+i*2:])))
+	}
+	f.ascent = int(int16(binary.BigEndian.Uint16(hhea[4:])))
+	f.descent = int(int16(binary.BigEndian.Uint16(hhea[6:])))
+	f.capHeight = f.ascent
+	if os2 := f.tables["OS/2"]; len(os2) >= 90 && binary.BigEndian.Uint16(os2) >= 2 {
+		f.capHeight = int(int16(binary.BigEndian.Uint16(os2[88:])))
+	}
+
+	maxp := f.tables["maxp"]
+	if len(maxp) < 6 {
+		return fmt.Errorf("フォントの maxp テーブルが壊れています")
+	}
+	numGlyphs := int(binary.BigEndian.Uint16(maxp[4:]))
+	numHMetrics := int(binary.BigEndian.Uint16(hhea[34:]))
+	if numHMetrics == 0 || len(hmtx) < numHMetrics*4 {
+		return fmt.Errorf("フォントの hmtx テーブルが壊れています")
+	}
+	f.advances = make([]uint16, numGlyphs)
+	for gid := range f.advances {
+		if gid < numHMetrics {
+			f.advances[gid] = binary.BigEndian.Uint16(hmtx[gid*4:])
+		} else {
+			f.advances[gid] = f.advances[numHMetrics-1]
+		}
+	}
+	return nil
+}
+
+// parseCmap は Unicode の cmap サブテーブル（形式4または12）を読み込みます
+func (f *trueTypeFont) parseCmap() error {
+	cmap := f.tables["cmap"]
+	if len(cmap) < 4 {
+		return fmt.Errorf("フォントの cmap テーブルが壊れています")
+	}
+
+	// 全Unicode（形式12）を優先し、なければBMP（形式4）を使用する
+	var best []byte
+	bestScore := 0
+	numSubtables := int(binary.BigEndian.Uint16(cmap[2:]))
+	for i := 0; i < numSubtables; i++ {
+		record := 4 + i*8
+		if len(cmap) < record+8 {
+			break
+		}
+		platform := binary.BigEndian.Uint16(cmap[record:])
+		encoding := binary.BigEndian.Uint16(cmap[record+2:])
+		offset := int(binary.BigEndian.Uint32(cmap[record+4:]))
+		if offset < 0 || len(cmap) < offset+4 {
+			continue
+		}
+		score := 0
+		switch {
+		case platform == 3 && encoding == 10, platform == 0 && encoding >= 4:
+			score = 2
+		case platform == 3 && encoding == 1, platform == 0:
+			score = 1
+		}
+		if score > bestScore {
+			best, bestScore = cmap[offset:], score
+		}
+	}
+	if best == nil {
+		return fmt.Errorf("フォントにUnicodeの文字対応表がありません")
+	}
+
+	f.cmap = make(map[rune]uint16)
+	switch binary.BigEndian.Uint16(best) {
+	case 4:
+		segCount := 0
+		if len(best) >= 8 {
+			segCount = int(binary.BigEndian.Uint16(best[6:])) / 2
+		}
+		if len(best) < 16+segCount*8 {
+			return fmt.Errorf("フォントの cmap テーブルが壊れています")
+		}
+		endCodes := best[14:]
+		startCodes := best[16+segCount*2:]
+		deltas := best[16+segCount*4:]
+		rangeOffsets := best[16+segCount*6:]
+		for seg := 0; seg < segCount; seg++ {
+			end := int(binary.BigEndian.Uint16(endCodes[seg*2:]))
+			start := int(binary.BigEndian.Uint16(startCodes[seg*2:]))
+			delta := binary.BigEndian.Uint16(deltas[seg*2:])
+			rangeOffset := int(binary.BigEndian.Uint16(rangeOffsets[seg*2:]))
+			for c := start; c <= end && c != 0xFFFF; c++ {
+				var gid uint16
+				if rangeOffset == 0 {
+					gid = uint16(c) + delta
+				} else {
+					index := seg*2 + rangeOffset + (c-start)*2
+					if len(rangeOffsets) < index+2 {
+						continue
+					}
+					gid = binary.BigEndian.Uint16(rangeOffsets[index:])
+					if gid != 0 {
+						gid += delta
+					}
+				}
+				if gid != 0 {
+					f.cmap[rune(c)] = gid
+				}
+			}
+		}
+	case 12:
+		if len(best) < 16 {
+			return fmt.Errorf("フォントの cmap テーブルが壊れています")
+		}
+		numGroups := int(binary.BigEndian.Uint32(best[12:]))
+		for i := 0; i < numGroups && len(best) >= 16+(i+1)*12; i++ {
+			group := best[16+i*12:]
+			start := binary.BigEndian.Uint32(group)
+			end := binary.BigEndian.Uint32(group[4:])
+			gid := binary.BigEndian.Uint32(group[8:])
+			for c := start; c <= end && c <= 0x10FFFF; c++ {
+				f.cmap[rune(c)] = uint16(gid + c - start)
+			}
+		}
+	default:
+		return fmt.Errorf("対応していない形式の cmap テーブルです")
+	}
+
+	return nil
+}
+
+// parseVert は GSUB テーブルから縦書き用グリフへの置き換え（vert/vrt2 の単純置換）を読み込みます
+func (f *trueTypeFont) parseVert() {
+	f.vert = make(map[uint16]uint16)
+	gsub := f.tables["GSUB"]
+	if len(gsub) < 10 {
+		return
+	}
+	featureList := tableAt(gsub, readUint16(gsub, 6))
+	lookupList := tableAt(gsub, readUint16(gsub, 8))
+
+	lookups := make(map[int]bool)
+	for i := 0; i < readUint16(featureList, 0); i++ {
+		tag := ""
+		if len(featureList) >= 2+i*6+4 {
+			tag = string(featureList[2+i*6 : 2+i*6+4])
+		}
+		if tag != "vert" && tag != "vrt2" {
+			continue
+		}
+		feature := tableAt(featureList, readUint16(featureList, 2+i*6+4))
+		for j := 0; j < readUint16(feature, 2); j++ {
+			lookups[readUint16(feature, 4+j*2)] = true
+		}
+	}
+
+	for index := range lookups {
+		if index >= readUint16(lookupList, 0) {
+			continue
+		}
+		lookup := tableAt(lookupList, readUint16(lookupList, 2+index*2))
+		lookupType := readUint16(lookup, 0)
+		for i := 0; i < readUint16(lookup, 4); i++ {
+			subtable := tableAt(lookup, readUint16(lookup, 6+i*2))
+			subtableType := lookupType
+			if lookupType == 7 && len(subtable) >= 8 {
+				// 拡張サブテーブルは実体を参照する
+				subtableType = readUint16(subtable, 2)
+				subtable = tableAt(subtable, int(binary.BigEndian.Uint32(subtable[4:])))
+			}
+			if subtableType == 1 {
+				f.parseSingleSubst(subtable)
+			}
+		}
+	}
+}
+
+// parseSingleSubst は単純置換のサブテーブルを読み込みます
+func (f *trueTypeFont) parseSingleSubst(subtable []byte) {
+	coverage := parseCoverage(tableAt(subtable, readUint16(subtable, 2)))
+	switch readUint16(subtable, 0) {
+	case 1:
+		delta := uint16(readUint16(subtable, 4))
+		for _, gid := range coverage {
+			f.vert[gid] = gid + delta
+		}
+	case 2:
+		for i, gid := range coverage {
+			if i < readUint16(subtable, 4) {
+				f.vert[gid] = uint16(readUint16(subtable, 6+i*2))
+			}
+		}
+	}
+}
+
+// parseCoverage はカバレッジテーブルの対象グリフを順に返します
+func parseCoverage(coverage []byte) []uint16 {
+	var glyphs []uint16
+	switch readUint16(coverage, 0) {
+	case 1:
+		for i := 0; i < readUint16(coverage, 2); i++ {
+			glyphs = append(glyphs, uint16(readUint16(coverage, 4+i*2)))
+		}
+	case 2:
+		for i := 0; i < readUint16(coverage, 2); i++ {
+			start, end := readUint16(coverage, 4+i*6), readUint16(coverage, 6+i*6)
+			for gid := start; gid <= end; gid++ {
+				glyphs = append(glyphs, uint16(gid))
+			}
+		}
+	}
+	return glyphs
+}
+
+// readUint16 はテーブル内の位置から符号なし16ビット整数を読み込みます（範囲外の場合は0）
+func readUint16(b []byte, off int) int {
+	if off < 0 || len(b) < off+2 {
+		return 0
+	}
+	return int(binary.BigEndian.Uint16(b[off:]))
+}
+
+// tableAt はテーブル内の位置から始まる部分を返します（範囲外の場合は空）
+func tableAt(b []byte, off int) []byte {
+	if off < 0 || off > len(b) {
+		return nil
+	}
+	return b[off:]
+}
+
+// hasGlyph はフォントが文字のグリフを持っているかどうかを返します
+func (f *trueTypeFont) hasGlyph(r rune) bool {
+	_, ok := f.cmap[r]
+	return ok
+}
+
+// glyph は文字のグリフ番号を返し、使用済みとして記録します（vertical の場合は縦書き用グリフ）
+func (f *trueTypeFont) glyph(r rune, vertical bool) uint16 {
+	gid := f.cmap[r]
+	if vertical {
+		if v, ok := f.vert[gid]; ok {
+			gid = v
+		}
+	}
+	if _, ok := f.used[gid]; !ok {
+		f.used[gid] = r
+	}
+	return gid
+}
+
+// advance は文字の横書きの送り幅をフォントサイズ1あたりの値で返します
+func (f *trueTypeFont) advance(r rune) float64 {
+	gid := f.cmap[r]
+	if int(gid) >= len(f.advances) {
+		return 1
+	}
+	return float64(f.advances[gid]) / float64(f.unitsPerEm)
+}
+
+// scale はフォント単位をPDFのグリフ空間（1000単位）に変換します
+func (f *trueTypeFont) scale(v int) int {
+	return v * 1000 / f.unitsPerEm
+}
+
+// usedGlyphs は使用したグリフ番号を昇順で返します
+func (f *trueTypeFont) usedGlyphs() []uint16 {
+	glyphs := make([]uint16, 0, len(f.used))
+	for gid := range f.used {
+		glyphs = append(glyphs, gid)
+	}
+	sort.Slice(glyphs, func(i, j int) bool { return glyphs[i] < glyphs[j] })
+	return glyphs
+}
+
+// subset は使用したグリフのみを残したフォントファイルを作成します
+// グリフ番号は変えずに、使用しないグリフの輪郭データを空にします
+func (f *trueTypeFont) subset() ([]byte, error) {
+	head, loca, glyf := f.tables["head"], f.tables["loca"], f.tables["glyf"]
+	if len(head) < 54 {
+		return nil, fmt.Errorf("フォントの head テーブルが壊れています")
+	}
+	longLoca := binary.BigEndian.Uint16(head[50:]) == 1
+	numGlyphs := len(f.advances)
+
+	// glyphRange はグリフの輪郭データの範囲を返します（loca が壊れている場合は空の範囲）
+	glyphRange := func(gid int) (int, int) {
+		var start, end int
+		switch {
+		case longLoca && len(loca) >= (gid+2)*4:
+			start, end = int(binary.BigEndian.Uint32(loca[gid*4:])), int(binary.BigEndian.Uint32(loca[gid*4+4:]))
+		case !longLoca && len(loca) >= (gid+2)*2:
+			start, end = int(binary.BigEndian.Uint16(loca[gid*2:]))*2, int(binary.BigEndian.Uint16(loca[gid*2+2:]))*2
+		}
+		if start < 0 || end < start || len(glyf) < end {
+			return 0, 0
+		}
+		return start, end
+	}
+
+	// 複合グリフが参照する部品のグリフも含める
+	keep := make(map[int]bool)
+	var visit func(gid int)
+	visit = func(gid int) {
+		if keep[gid] || gid >= numGlyphs {
+			return
+		}
+		keep[gid] = true
+		start, end := glyphRange(gid)
+		if end-start < 10 || int16(binary.BigEndian.Uint16(glyf[start:])) >= 0 {
+			return
+		}
+		p := start + 10
+		for p+4 <= end {
+			flags := binary.BigEndian.Uint16(glyf[p:])
+			visit(int(binary.BigEndian.Uint16(glyf[p+2:])))
+			p += 4
+			if flags&0x0001 != 0 {
+				p += 4
+			} else {
+				p += 2
+			}
+			switch {
+			case flags&0x0008 != 0:
+				p += 2
+			case flags&0x0040 != 0:
+				p += 4
+			case flags&0x0080 != 0:
+				p += 8
+			}
+			if flags&0x0020 == 0 {
+				break
+			}
+		}
+	}
+	visit(0)
+	for _, gid := range f.usedGlyphs() {
+		visit(int(gid))
+	}
+
+	var newGlyf []byte
+	newLoca := make([]byte, (numGlyphs+1)*4)
+	for gid := 0; gid < numGlyphs; gid++ {
+		binary.BigEndian.PutUint32(newLoca[gid*4:], uint32(len(newGlyf)))
+		if !keep[gid] {
+			continue
+		}
+		start, end := glyphRange(gid)
+		if start < end {
+			newGlyf = append(newGlyf, glyf[start:end]...)
+			for len(newGlyf)%4 != 0 {
+				newGlyf = append(newGlyf, 0)
+			}
+		}
+	}
+	binary.BigEndian.PutUint32(newLoca[numGlyphs*4:], uint32(len(newGlyf)))
+
+	newHead := append([]byte(nil), head...)
+	binary.BigEndian.PutUint32(newHead[8:], 0)  // checkSumAdjustment
+	binary.BigEndian.PutUint16(newHead[50:], 1) // indexToLocFormat（long）
+
+	tables := map[string][]byte{"head": newHead, "loca": newLoca, "glyf": newGlyf, "cmap": emptyCmap()}
+	for _, tag := range []string{"cvt ", "fpgm", "prep", "hhea", "hmtx", "maxp", "vhea", "vmtx", "OS/2", "name", "post"} {
+		if data, ok := f.tables[tag]; ok {
+			tables[tag] = data
+		}
+	}
+	return buildSfnt(tables), nil
+}
+
+// emptyCmap は文字の対応を持たない cmap テーブルを返します
+// PDFではグリフ番号で参照するため対応表は不要だが、テーブル自体を必須とするビューアーがあるため含める
+func emptyCmap() []byte {
+	cmap := make([]byte, 12+24)
+	binary.BigEndian.PutUint16(cmap[2:], 1)  // サブテーブル数
+	binary.BigEndian.PutUint16(cmap[4:], 3)  // Windows
+	binary.BigEndian.PutUint16(cmap[6:], 1)  // Unicode BMP
+	binary.BigEndian.PutUint32(cmap[8:], 12) // サブテーブルの位置
+	sub := cmap[12:]
+	binary.BigEndian.PutUint16(sub, 4)           // 形式4
+	binary.BigEndian.PutUint16(sub[2:], 24)      // 長さ
+	binary.BigEndian.PutUint16(sub[6:], 2)       // segCountX2
+	binary.BigEndian.PutUint16(sub[8:], 2)       // searchRange
+	binary.BigEndian.PutUint16(sub[14:], 0xFFFF) // endCode
+	binary.BigEndian.PutUint16(sub[18:], 0xFFFF) // startCode
+	binary.BigEndian.PutUint16(sub[20:], 1)      // idDelta
+	return cmap
+}
+
+// buildSfnt はテーブルの集合からTrueTypeフォントファイルを組み立てます
+func buildSfnt(tables map[string][]byte) []byte {
+	tags := make([]string, 0, len(tables))
+	for tag := range tables {
+		tags = append(tags, tag)
+	}
+	sort.Strings(tags)
+
+	numTables := len(tags)
+	entrySelector := 0
+	for 1<<(entrySelector+1) <= numTables {
+		entrySelector++
+	}
+	searchRange := (1 << entrySelector) * 16
+
+	header := make([]byte, 12+numTables*16)
+	binary.BigEndian.PutUint32(header, 0x00010000)
+	binary.BigEndian.PutUint16(header[4:], uint16(numTables))
+	binary.BigEndian.PutUint16(header[6:], uint16(searchRange))
+	binary.BigEndian.PutUint16(header[8:], uint16(entrySelector))
+	binary.BigEndian.PutUint16(header[10:], uint16(numTables*16-searchRange))
+
+	var body []byte
+	offset := len(header)
+	for i, tag := range tags {
+		data := tables[tag]
+		record := header[12+i*16:]
+		copy(record, tag)
+		binary.BigEndian.PutUint32(record[4:], sfntChecksum(data))
+		binary.BigEndian.PutUint32(record[8:], uint32(offset+len(body)))
+		binary.BigEndian.PutUint32(record[12:], uint32(len(data)))
+		body = append(body, data...)
+		for len(body)%4 != 0 {
+			body = append(body, 0)
+		}
+	}
+
+	return append(header, body...)
+}
+
+// sfntChecksum はテーブルのチェックサムを計算します
+func sfntChecksum(data []byte) uint32 {
+	var sum uint32
+	for i := 0; i < len(data); i += 4 {
+		var word [4]byte
+		copy(word[:], data[i:])
+		sum += binary.BigEndian.Uint32(word[:])
+	}
+	return sum
+}
@@ -0,0 +1,228 @@
+package main
+
+import (
+	"encoding/binary"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+)
+
+// testFontPath はテスト用の小さなTrueTypeフォント
+// グリフは .notdef・あ・い・う（いを参照する複合グリフ）・、・縦書き用の、の6つで、
+// 縦書き用のグリフは GSUB の vert で、から置き換えます
+const testFontPath = "testdata/fonts/mini.ttf"
+
+// loadTestFontTables はテスト用のフォントのテーブルを読み込みます
+func loadTestFontTables(t *testing.T) map[string][]byte {
+	t.Helper()
+	f, err := loadTrueTypeFont(testFontPath)
+	if err != nil {
+		t.Fatalf("loadTrueTypeFont() error = %v", err)
+	}
+	return f.tables
+}
+
+func TestParseTrueTypeFont(t *testing.T) {
+	f, err := loadTrueTypeFont(testFontPath)
+	if err != nil {
+		t.Fatalf("loadTrueTypeFont() error = %v", err)
+	}
+
+	if f.unitsPerEm != 1000 || f.ascent != 880 || f.descent != -120 || f.capHeight != 880 {
+		t.Errorf("unitsPerEm, ascent, descent, capHeight = %d, %d, %d, %d, want 1000, 880, -120, 880",
+			f.unitsPerEm, f.ascent, f.descent, f.capHeight)
+	}
+	if f.bbox != [4]int{0, -120, 1000, 880} {
+		t.Errorf("bbox = %v", f.bbox)
+	}
+	// hmtx にない最後のグリフは最後の送り幅を引き継ぐ
+	if want := []uint16{500, 1000, 900, 1000, 800, 800}; fmt.Sprint(f.advances) != fmt.Sprint(want) {
+		t.Errorf("advances = %v, want %v", f.advances, want)
+	}
+	for r, want := range map[rune]uint16{'あ': 1, 'い': 2, 'う': 3, '、': 4} {
+		if got := f.cmap[r]; got != want {
+			t.Errorf("cmap[%q] = %d, want %d", r, got, want)
+		}
+	}
+	if f.hasGlyph('え') {
+		t.Error("フォントにない文字のグリフがあると判定しました")
+	}
+	if got := f.advance('い'); got != 0.9 {
+		t.Errorf("advance('い') = %v, want 0.9", got)
+	}
+	if got := f.glyph('、', true); got != 5 {
+		t.Errorf("glyph('、', true) = %d, want 5", got)
+	}
+	if got := f.glyph('、', false); got != 4 {
+		t.Errorf("glyph('、', false) = %d, want 4", got)
+	}
+}
+
+func TestParseTrueTypeFont_Broken(t *testing.T) {
+	tests := []struct {
+		name    string
+		modify  func(tables map[string][]byte)
+		wantErr string
+	}{
+		{
+			name:    "maxp が短い",
+			modify:  func(tables map[string][]byte) { tables["maxp"] = tables["maxp"][:4] },
+			wantErr: "maxp",
+		},
+		{
+			name:    "hhea が短い",
+			modify:  func(tables map[string][]byte) { tables["hhea"] = tables["hhea"][:20] },
+			wantErr: "head/hhea",
+		},
+		{
+			name:    "head が短い",
+			modify:  func(tables map[string][]byte) { tables["head"] = tables["head"][:50] },
+			wantErr: "head/hhea",
+		},
+		{
+			name:    "hmtx が空",
+			modify:  func(tables map[string][]byte) { tables["hmtx"] = nil },
+			wantErr: "hmtx",
+		},
+		{
+			name:    "cmap が短い",
+			modify:  func(tables map[string][]byte) { tables["cmap"] = tables["cmap"][:2] },
+			wantErr: "cmap",
+		},
+		{
+			name:    "cmap のサブテーブルが途中で切れている",
+			modify:  func(tables map[string][]byte) { tables["cmap"] = tables["cmap"][:30] },
+			wantErr: "cmap",
+		},
+		{
+			name:    "glyf がない",
+			modify:  func(tables map[string][]byte) { delete(tables, "glyf") },
+			wantErr: "glyf テーブルがありません",
+		},
+		{
+			name:   "GSUB が壊れている",
+			modify: func(tables map[string][]byte) { tables["GSUB"] = tables["GSUB"][:12] },
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tables := loadTestFontTables(t)
+			tt.modify(tables)
+			_, err := parseTrueTypeFont(buildSfnt(tables))
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("parseTrueTypeFont() error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("parseTrueTypeFont() error = %v, want %q を含む", err, tt.wantErr)
+			}
+		})
+	}
+
+	t.Run("ファイルが途中で切れている", func(t *testing.T) {
+		data, err := os.ReadFile(testFontPath)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, size := range []int{0, 8, 40, 200} {
+			if _, err := parseTrueTypeFont(data[:size]); err == nil {
+				t.Errorf("parseTrueTypeFont(%dバイト) error = nil", size)
+			}
+		}
+	})
+}
+
+// subsetGlyphSizes はサブセットのフォントのグリフごとの輪郭データの大きさを返します
+func subsetGlyphSizes(t *testing.T, data []byte) []int {
+	t.Helper()
+	f, err := parseTrueTypeFont(data)
+	if err != nil {
+		t.Fatalf("サブセットのフォントを読み込めません: %v", err)
+	}
+	loca := f.tables["loca"]
+	sizes := make([]int, len(f.advances))
+	for gid := range sizes {
+		sizes[gid] = int(binary.BigEndian.Uint32(loca[gid*4+4:]) - binary.BigEndian.Uint32(loca[gid*4:]))
+	}
+	return sizes
+}
+
+func TestTrueTypeFont_Subset(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(tables map[string][]byte)
+		use    func(f *trueTypeFont)
+		want   []bool // グリフごとの輪郭データを残すかどうか
+	}{
+		{
+			name: "使用したグリフと複合グリフの部品を残す",
+			use: func(f *trueTypeFont) {
+				f.glyph('う', false)
+				f.glyph('、', true)
+			},
+			want: []bool{true, false, true, true, false, true},
+		},
+		{
+			name: "使用したグリフがない",
+			use:  func(f *trueTypeFont) {},
+			want: []bool{true, false, false, false, false, false},
+		},
+		{
+			name: "loca が glyf の範囲外を指している",
+			modify: func(tables map[string][]byte) {
+				loca := append([]byte(nil), tables["loca"]...)
+				binary.BigEndian.PutUint16(loca[4:], 0xFFFF)
+				tables["loca"] = loca
+			},
+			use: func(f *trueTypeFont) {
+				f.glyph('あ', false)
+				f.glyph('い', false)
+			},
+			want: []bool{true, false, false, false, false, false},
+		},
+		{
+			name: "複合グリフが途中で切れている",
+			modify: func(tables map[string][]byte) {
+				// 複合グリフ（う）の範囲を部品の番号の手前までにする
+				loca := append([]byte(nil), tables["loca"]...)
+				start := binary.BigEndian.Uint16(loca[6:])
+				binary.BigEndian.PutUint16(loca[8:], start+6)
+				tables["loca"] = loca
+			},
+			use:  func(f *trueTypeFont) { f.glyph('う', false) },
+			want: []bool{true, false, false, true, false, false},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tables := loadTestFontTables(t)
+			if tt.modify != nil {
+				tt.modify(tables)
+			}
+			f, err := parseTrueTypeFont(buildSfnt(tables))
+			if err != nil {
+				t.Fatalf("parseTrueTypeFont() error = %v", err)
+			}
+			tt.use(f)
+
+			data, err := f.subset()
+			if err != nil {
+				t.Fatalf("subset() error = %v", err)
+			}
+			sizes := subsetGlyphSizes(t, data)
+			if len(sizes) != len(tt.want) {
+				t.Fatalf("グリフ数 = %d, want %d", len(sizes), len(tt.want))
+			}
+			for gid, keep := range tt.want {
+				if (sizes[gid] > 0) != keep {
+					t.Errorf("グリフ%d の輪郭データ = %dバイト, want 残す %v", gid, sizes[gid], keep)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,335 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// readingState は小説ごとの読んでいる位置と既読の状態を表す構造体（.narou/<NCODE>/reading.json）
+// 既読の状態は小説の話数ごとに記録し、目次の変更で話数が変わった場合は新しい話数に移します
+// 既読にした時点の本文のハッシュも残すため、改稿された話は未読に戻ります
+type readingState struct {
+	NCode    string                    `json:"ncode"`
+	Position *ReadingPosition          `json:"position,omitempty"`
+	Episodes map[string]EpisodeReading `json:"episodes,omitempty"` // 話数ごとの状態
+}
+
+// EpisodeReading は1話分の既読の状態と最後に読んだ位置を表す構造体
+type EpisodeReading struct {
+	Read      bool      `json:"read"`
+	ReadAt    time.Time `json:"readAt,omitempty"`
+	Hash      string    `json:"hash,omitempty"` // 既読にした時点の本文のハッシュ
+	Paragraph int       `json:"paragraph"`
+}
+
+// EpisodeProgress は話ごとの読書状況を表す構造体
+type EpisodeProgress struct {
+	Number    string    `json:"number"`
+	Title     string    `json:"title"`
+	Chapter   string    `json:"chapter"`
+	Read      bool      `json:"read"`
+	ReadAt    time.Time `json:"readAt"`
+	Paragraph int       `json:"paragraph"`
+	FetchedAt time.Time `json:"fetchedAt"`
+}
+
+// ReadingProgress は小説ごとの読書状況を表す構造体
+type ReadingProgress struct {
+	Folder   string            `json:"folder"`
+	NCode    string            `json:"ncode"`
+	Title    string            `json:"title"`
+	Read     int               `json:"read"`
+	Unread   int               `json:"unread"`
+	Position *ReadingPosition  `json:"position"`
+	Episodes []EpisodeProgress `json:"episodes"`
+}
+
+// UnreadExportRequest は未読の話だけをまとめたファイルを出力する際の指定を表す構造体
+type UnreadExportRequest struct {
+	Folder   string        `json:"folder"`
+	NCode    string        `json:"ncode"`
+	Format   FormatRequest `json:"format"`
+	Output   string        `json:"output"`   // 出力先フォルダ（省略時は保存先フォルダ）
+	MarkRead bool          `json:"markRead"` // 出力した話を既読にする
+}
+
+// loadReadingState は読んでいる位置と既読の状態を読み込みます
+func (s *novelStore) loadReadingState() (*readingState, error) {
+	state := &readingState{NCode: s.ncode, Episodes: make(map[string]EpisodeReading)}
+	data, err := os.ReadFile(s.readingPath())
+	if errors.Is(err, os.ErrNotExist) {
+		return state, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("読書状況の読み込みに失敗しました: %w", err)
+	}
+	if err := json.Unmarshal(data, state); err != nil {
+		return nil, fmt.Errorf("読書状況の解析に失敗しました: %w", err)
+	}
+	if state.Episodes == nil {
+		state.Episodes = make(map[string]EpisodeReading)
+	}
+	return state, nil
+}
+
+// saveReadingState は読んでいる位置と既読の状態を保存します
+func (s *novelStore) saveReadingState(state *readingState) error {
+	state.NCode = s.ncode
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return fmt.Errorf("読書状況のJSON変換に失敗しました: %w", err)
+	}
+	return writeFileAtomic(s.readingPath(), data, s.tmpDir())
+}
+
+// renumberReadingState は目次の変更に合わせて保存済みの既読の状態と読んでいる位置を移します
+func (s *novelStore) renumberReadingState(diff tocDiff) error {
+	if _, err := os.Stat(s.readingPath()); errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	state, err := s.loadReadingState()
+	if err != nil {
+		return err
+	}
+	state.renumber(diff)
+	return s.saveReadingState(state)
+}
+
+// isRead は話が既読かどうかを返します（既読にした後に改稿された話は未読）
+func (state *readingState) isRead(record ManifestEpisode) bool {
+	reading := state.Episodes[record.Number]
+	return reading.Read && reading.Hash == record.Hash
+}
+
+// readCount は既読の話の数を返します
+func (state *readingState) readCount(manifest *Manifest) int {
+	count := 0
+	for _, record := range manifest.Episodes {
+		if state.isRead(record) {
+			count++
+		}
+	}
+	return count
+}
+
+// setRead は話の既読・未読を設定します
+func (state *readingState) setRead(record ManifestEpisode, read bool) {
+	reading := state.Episodes[record.Number]
+	reading.Read = read
+	reading.ReadAt = time.Time{}
+	reading.Hash = ""
+	if read {
+		reading.ReadAt = time.Now()
+		reading.Hash = record.Hash
+	}
+	state.Episodes[record.Number] = reading
+}
+
+// renumber は目次の変更に合わせて話数ごとの状態を移します（削除された話の状態は捨てる）
+func (state *readingState) renumber(diff tocDiff) {
+	moved := make(map[string]string)
+	removed := make(map[string]bool)
+	for _, change := range diff.Changes {
+		switch change.Kind {
+		case tocRenumbered:
+			moved[change.Old.Number] = change.New.Number
+		case tocRemoved:
+			removed[change.Old.Number] = true
+		}
+	}
+
+	episodes := make(map[string]EpisodeReading, len(state.Episodes))
+	for number, reading := range state.Episodes {
+		if to, ok := moved[number]; ok {
+			episodes[to] = reading
+		}
+	}
+	for number, reading := range state.Episodes {
+		if _, ok := moved[number]; ok || removed[number] {
+			continue
+		}
+		if _, ok := episodes[number]; !ok {
+			episodes[number] = reading
+		}
+	}
+	state.Episodes = episodes
+
+	if state.Position != nil {
+		if to, ok := moved[state.Position.Number]; ok {
+			state.Position.Number = to
+		} else if removed[state.Position.Number] {
+			state.Position = nil
+		}
+	}
+}
+
+// GetReadingProgress は小説の話ごとの既読・未読と読んでいる位置を返します（フロントエンド・CLI用）
+func (a *App) GetReadingProgress(folder, ncode string) (ReadingProgress, error) {
+	store, manifest, err := openNovelStoreByNCode(folder, ncode)
+	if err != nil {
+		return ReadingProgress{}, err
+	}
+	state, err := store.loadReadingState()
+	if err != nil {
+		return ReadingProgress{}, err
+	}
+
+	progress := ReadingProgress{
+		Folder:   store.root,
+		NCode:    manifest.NCode,
+		Title:    manifest.Title,
+		Position: state.Position,
+		Episodes: []EpisodeProgress{},
+	}
+	for _, record := range manifest.Episodes {
+		reading := state.Episodes[record.Number]
+		read := state.isRead(record)
+		if read {
+			progress.Read++
+		} else {
+			progress.Unread++
+		}
+		progress.Episodes = append(progress.Episodes, EpisodeProgress{
+			Number:    record.Number,
+			Title:     record.Title,
+			Chapter:   record.Chapter,
+			Read:      read,
+			ReadAt:    reading.ReadAt,
+			Paragraph: reading.Paragraph,
+			FetchedAt: record.FetchedAt,
+		})
+	}
+	return progress, nil
+}
+
+// MarkEpisodesRead は話を既読または未読にします（numbers が空の場合はすべての話、フロントエンド・CLI用）
+func (a *App) MarkEpisodesRead(folder, ncode string, numbers []string, read bool) error {
+	store, manifest, err := openNovelStoreByNCode(folder, ncode)
+	if err != nil {
+		return err
+	}
+	state, err := store.loadReadingState()
+	if err != nil {
+		return err
+	}
+
+	if len(numbers) == 0 {
+		for _, record := range manifest.Episodes {
+			state.setRead(record, read)
+		}
+	}
+	for _, number := range numbers {
+		record := manifest.episode(number)
+		if record == nil {
+			return fmt.Errorf("%s話は保存されていません", number)
+		}
+		state.setRead(*record, read)
+	}
+	return store.saveReadingState(state)
+}
+
+// ExportUnread は未読の話だけをまとめたファイルを出力し、そのパスを返します（フロントエンド・CLI用）
+// 電子書籍リーダーに新しい話だけを送るためのもので、マニフェストには記録しません
+func (a *App) ExportUnread(request UnreadExportRequest) (string, error) {
+	w, err := exportWriter(request.Format)
+	if err != nil {
+		return "", err
+	}
+
+	store, manifest, err := openNovelStoreByNCode(request.Folder, request.NCode)
+	if err != nil {
+		return "", err
+	}
+	state, err := store.loadReadingState()
+	if err != nil {
+		return "", err
+	}
+
+	// 未読の話だけを残した小説を組み立てる
+	novel := manifest.novel()
+	var exported []ManifestEpisode
+	var chapters []*Chapter
+	for _, chapter := range novel.Chapters {
+		var episodes []*Episode
+		for _, episode := range chapter.Episodes {
+			record := manifest.episode(episode.Number)
+			if state.isRead(*record) {
+				continue
+			}
+			blocks, err := store.loadBlocks(episode.Number)
+			if err != nil {
+				return "", fmt.Errorf("%s話の本文を読み込めませんでした: %w", episode.Number, err)
+			}
+			episode.Blocks = blocks
+			episodes = append(episodes, episode)
+			exported = append(exported, *record)
+		}
+		if len(episodes) > 0 {
+			chapter.Episodes = episodes
+			chapters = append(chapters, chapter)
+		}
+	}
+	novel.Chapters = chapters
+	if len(exported) == 0 {
+		return "", fmt.Errorf("%s に未読の話はありません", manifest.Title)
+	}
+
+	var buf bytes.Buffer
+	if err := w.WriteNovel(&buf, novel); err != nil {
+		return "", fmt.Errorf("%sファイルの生成に失敗しました: %w", strings.ToUpper(w.Ext()), err)
+	}
+
+	output := request.Output
+	if output == "" {
+		output = store.root
+	}
+	// 電子書籍リーダーの一覧で区別できるように、小説のタイトルと話数の範囲をファイル名にする
+	name := sanitizeFileNameWithExt(fmt.Sprintf("%s_未読_%s-%s", novel.Title, exported[0].Number, exported[len(exported)-1].Number), "."+w.Ext())
+	path := filepath.Join(output, name)
+	if err := writeFileAtomic(path, buf.Bytes(), store.tmpDir()); err != nil {
+		return "", fmt.Errorf("ファイルの保存に失敗しました: %w", err)
+	}
+	a.emit("log", fmt.Sprintf("未読の%d話を出力しました: %s", len(exported), path))
+
+	if request.MarkRead {
+		for _, record := range exported {
+			state.setRead(record, true)
+		}
+		if err := store.saveReadingState(state); err != nil {
+			return path, err
+		}
+	}
+	return path, nil
+}
+
+// exportWriter は未読の話の出力に使う、小説全体を1つのファイルにする出力形式を返します
+func exportWriter(format FormatRequest) (NovelWriter, error) {
+	episodeWriters, novelWriters, err := buildWriters([]FormatRequest{format})
+	if err != nil {
+		return nil, err
+	}
+	for _, w := range novelWriters {
+		// エピソード一覧のHTMLは各話のファイルがないと読めない
+		if _, ok := w.(htmlWriter); !ok {
+			return w, nil
+		}
+	}
+	// テキスト形式は1ファイルにまとめる設定でなくてもまとめて出力する
+	for _, w := range episodeWriters {
+		if named, ok := w.(namedEpisodeWriter); ok {
+			w = named.EpisodeWriter
+		}
+		if nw, ok := w.(NovelWriter); ok {
+			if _, ok := nw.(htmlWriter); !ok {
+				return nw, nil
+			}
+		}
+	}
+	return nil, fmt.Errorf("%s は1つのファイルにまとめて出力できない形式です", format.Name)
+}
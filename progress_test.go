@@ -0,0 +1,154 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestReadingProgress(t *testing.T) {
+	app, store, novel := newVerifiedNovelStore(t)
+
+	// 取得しただけの話は未読
+	progress, err := app.GetReadingProgress(store.root, "")
+	if err != nil {
+		t.Fatalf("GetReadingProgress() error = %v", err)
+	}
+	if progress.Read != 0 || progress.Unread != 3 {
+		t.Errorf("Read, Unread = %d, %d, want 0, 3", progress.Read, progress.Unread)
+	}
+
+	if err := app.MarkEpisodesRead(store.root, "", []string{"1", "2"}, true); err != nil {
+		t.Fatalf("MarkEpisodesRead() error = %v", err)
+	}
+	if err := app.SaveReadingPosition(store.root, "", ReadingPosition{Number: "2", Paragraph: 4}); err != nil {
+		t.Fatalf("SaveReadingPosition() error = %v", err)
+	}
+
+	// 改稿された話は未読に戻る
+	manifest, _ := store.loadManifest()
+	episode := novel.Episodes()[0]
+	episode.Blocks = []Block{{Kind: BlockParagraph, Inlines: []Inline{{Kind: InlineText, Text: "本文一（改稿）"}}}}
+	if err := app.saveEpisode(store, manifest, novel, episode, nil); err != nil {
+		t.Fatalf("saveEpisode() error = %v", err)
+	}
+
+	progress, err = app.GetReadingProgress(store.root, "")
+	if err != nil {
+		t.Fatalf("GetReadingProgress() error = %v", err)
+	}
+	var states []bool
+	for _, episode := range progress.Episodes {
+		states = append(states, episode.Read)
+	}
+	if len(states) != 3 || states[0] || !states[1] || states[2] {
+		t.Errorf("Read = %v, want [false true false]", states)
+	}
+	if progress.Episodes[1].Paragraph != 4 || progress.Position == nil || progress.Position.Number != "2" {
+		t.Errorf("Paragraph, Position = %d, %+v, want 4, 2話", progress.Episodes[1].Paragraph, progress.Position)
+	}
+
+	novels, err := app.ListLibrary(store.root)
+	if err != nil {
+		t.Fatalf("ListLibrary() error = %v", err)
+	}
+	if novels[0].Unread != 2 {
+		t.Errorf("Unread = %d, want 2", novels[0].Unread)
+	}
+
+	if err := app.MarkEpisodesRead(store.root, "", []string{"9"}, true); err == nil {
+		t.Errorf("保存されていない話で MarkEpisodesRead() がエラーになりません")
+	}
+}
+
+func TestReadingProgress_Renumbered(t *testing.T) {
+	app := NewApp()
+	writers := []EpisodeWriter{plainTextWriter{textEncoding{Encoding: "UTF-8", LineEnding: "LF"}}}
+
+	old := newTOCNovel("一話", "二話", "三話")
+	store, manifest, err := app.openNovelStore(t.TempDir(), old, nil)
+	if err != nil {
+		t.Fatalf("openNovelStore() error = %v", err)
+	}
+	for _, episode := range old.Episodes() {
+		if err := app.saveEpisode(store, manifest, old, episode, writers); err != nil {
+			t.Fatalf("saveEpisode() error = %v", err)
+		}
+	}
+	if err := app.MarkEpisodesRead(store.root, "", []string{"3"}, true); err != nil {
+		t.Fatalf("MarkEpisodesRead() error = %v", err)
+	}
+	if err := app.SaveReadingPosition(store.root, "", ReadingPosition{Number: "3", Paragraph: 1}); err != nil {
+		t.Fatalf("SaveReadingPosition() error = %v", err)
+	}
+
+	// 二話が削除され、既読の三話が2話に繰り上がった
+	current := newTOCNovel("一話", "三話")
+	for _, episode := range current.Episodes() {
+		episode.Blocks = nil
+	}
+	if err := app.applyTOCChanges(store, manifest, current, diffTOC(manifest, current), writers); err != nil {
+		t.Fatalf("applyTOCChanges() error = %v", err)
+	}
+
+	progress, err := app.GetReadingProgress(store.root, "")
+	if err != nil {
+		t.Fatalf("GetReadingProgress() error = %v", err)
+	}
+	var states []string
+	for _, episode := range progress.Episodes {
+		states = append(states, fmt.Sprintf("%s:%v", episode.Number, episode.Read))
+	}
+	if strings.Join(states, ",") != "1:false,2:true" {
+		t.Errorf("Read = %v, want [1:false 2:true]", states)
+	}
+	if progress.Position == nil || progress.Position.Number != "2" || progress.Episodes[1].Paragraph != 1 {
+		t.Errorf("Position = %+v, want 2話", progress.Position)
+	}
+}
+
+func TestExportUnread(t *testing.T) {
+	app, store, _ := newVerifiedNovelStore(t)
+	if err := app.MarkEpisodesRead(store.root, "", []string{"1"}, true); err != nil {
+		t.Fatalf("MarkEpisodesRead() error = %v", err)
+	}
+
+	output := t.TempDir()
+	path, err := app.ExportUnread(UnreadExportRequest{
+		Folder:   store.root,
+		Format:   FormatRequest{Name: "txt", Options: map[string]interface{}{"encoding": "UTF-8", "lineEnding": "LF"}},
+		Output:   output,
+		MarkRead: true,
+	})
+	if err != nil {
+		t.Fatalf("ExportUnread() error = %v", err)
+	}
+	if filepath.Base(path) != "テスト小説_未読_2-3.txt" {
+		t.Errorf("ファイル名 = %s, want テスト小説_未読_2-3.txt", filepath.Base(path))
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("出力したファイルを読み込めません: %v", err)
+	}
+	text := string(data)
+	if strings.Contains(text, "本文一") || !strings.Contains(text, "本文二") || !strings.Contains(text, "本文三") {
+		t.Errorf("未読の話だけが出力されていません: %q", text)
+	}
+	if !strings.Contains(text, "第一章") || !strings.Contains(text, "第二章") {
+		t.Errorf("章の見出しが出力されていません: %q", text)
+	}
+
+	// 出力した話は既読になり、未読がなくなる
+	novels, _ := app.ListLibrary(store.root)
+	if novels[0].Unread != 0 {
+		t.Errorf("Unread = %d, want 0", novels[0].Unread)
+	}
+	if _, err := app.ExportUnread(UnreadExportRequest{Folder: store.root, Format: FormatRequest{Name: "epub"}}); err == nil {
+		t.Errorf("未読がないのに ExportUnread() がエラーになりません")
+	}
+	if _, err := app.ExportUnread(UnreadExportRequest{Folder: store.root, Format: FormatRequest{Name: "html"}}); err == nil {
+		t.Errorf("エピソード一覧のHTMLで ExportUnread() がエラーになりません")
+	}
+}
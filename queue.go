@@ -0,0 +1,43 @@
+package main
+
+import (
+	"fmt"
+	"time"
+)
+
+// QueueItem はまとめてダウンロードする小説1件分の情報を表す構造体
+type QueueItem struct {
+	URL      string `json:"url"`
+	Title    string `json:"title"`
+	SavePath string `json:"savePath"`
+}
+
+// DownloadQueue は複数の小説を順番にダウンロードします
+func (a *App) DownloadQueue(items []QueueItem, formats []FormatRequest) error {
+	if len(items) == 0 {
+		return fmt.Errorf("ダウンロードする小説がありません")
+	}
+
+	var failed int
+	for i, item := range items {
+		a.emit("log", fmt.Sprintf("[%d/%d] %s のダウンロードを開始します", i+1, len(items), item.Title))
+
+		if err := a.DownloadNovel(item.URL, item.SavePath, formats); err != nil {
+			// 1件失敗しても残りの小説は続けてダウンロードする
+			failed++
+			a.emit("log", fmt.Sprintf("[%d/%d] %s のダウンロードに失敗しました: %v", i+1, len(items), item.Title, err))
+		}
+
+		// 次の小説まで連載の各話と同じ間隔を開ける（最後の小説以外）
+		if i < len(items)-1 {
+			time.Sleep(episodeInterval)
+		}
+	}
+
+	a.emit("log", fmt.Sprintf("%d件中%d件のダウンロードが完了しました", len(items), len(items)-failed))
+	if failed > 0 {
+		return fmt.Errorf("%d件のダウンロードに失敗しました", failed)
+	}
+
+	return nil
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// requestInterval はなろうのサーバーへのリクエストの最小間隔
+// 連載の各話の間は episodeInterval だけ待つため、主に目次の確認が続く場合に効く
+var requestInterval = 2 * time.Second
+
+// httpClient はページの取得に共通で使うHTTPクライアント
+// GETリクエストは responseCache を通り、サーバーに接続する場合だけ fetchLimiter で間隔を空けます
+var httpClient = &http.Client{
+	Timeout:   10 * time.Second,
+	Transport: responseCache,
+}
+
+// fetchLimiter はアプリ全体（画面からのダウンロード・定期確認・API）で共有するリクエストの間隔の制限
+var fetchLimiter = &rateLimiter{}
+
+// rateLimiter はリクエストの間隔を一定以上空けるための構造体
+type rateLimiter struct {
+	mu   sync.Mutex
+	next time.Time
+}
+
+// wait は前回のリクエストから interval 経過するまで待ちます
+// 待っている間はほかのリクエストも待たせるため、同時に呼ばれても順番に間隔が空く
+func (l *rateLimiter) wait(interval time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if d := time.Until(l.next); d > 0 {
+		time.Sleep(d)
+	}
+	l.next = time.Now().Add(interval)
+}
+
+// sleepContext は d だけ待ちます
+// 途中で ctx が終了した場合はすぐに戻り、ctx のエラーを返します
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
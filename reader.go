@@ -0,0 +1,288 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"time"
+)
+
+// 本文の組み方
+const (
+	layoutHorizontal = "horizontal" // 横書き
+	layoutVertical   = "vertical"   // 縦書き
+)
+
+// 縦書きで横に並べる（縦中横にする）半角英数字の最大文字数
+const maxTateChuYoko = 3
+
+// tateChuYokoPattern は縦書きで縦中横にする半角英数字・記号の並びにマッチします
+var tateChuYokoPattern = regexp.MustCompile(`[0-9A-Za-z!?]+`)
+
+// LibraryNovel は保存済みの小説（本棚の1冊）を表す構造体
+type LibraryNovel struct {
+	Folder    string           `json:"folder"`
+	NCode     string           `json:"ncode"`
+	Title     string           `json:"title"`
+	Author    string           `json:"author"`
+	Short     bool             `json:"short"`
+	Episodes  int              `json:"episodes"`
+	Unread    int              `json:"unread"`
+	UpdatedAt time.Time        `json:"updatedAt"`
+	Position  *ReadingPosition `json:"position"` // 読みかけでない場合は nil
+}
+
+// ReaderInline は表示用の段落内の要素を表す構造体
+// Kind は "text"・"ruby"・"emphasis" に加え、縦書きの場合は縦中横の "tcy" がある
+type ReaderInline struct {
+	Kind string `json:"kind"`
+	Text string `json:"text"`
+	Ruby string `json:"ruby,omitempty"`
+}
+
+// ReaderBlock は表示用の本文の1ブロックを表す構造体
+type ReaderBlock struct {
+	Kind    string         `json:"kind"` // "paragraph"・"blank"・"illustration"・"separator"
+	Inlines []ReaderInline `json:"inlines,omitempty"`
+	Src     string         `json:"src,omitempty"`
+}
+
+// ReaderEpisodeLink は前後の話へのリンクを表す構造体
+type ReaderEpisodeLink struct {
+	Number string `json:"number"`
+	Title  string `json:"title"`
+}
+
+// ReaderEpisode は表示用の1話分の本文を表す構造体
+type ReaderEpisode struct {
+	NCode   string             `json:"ncode"`
+	Title   string             `json:"title"` // 小説のタイトル
+	Number  string             `json:"number"`
+	Index   int                `json:"index"`
+	Total   int                `json:"total"`
+	Chapter string             `json:"chapter"`
+	Episode string             `json:"episode"` // 話のタイトル
+	Layout  string             `json:"layout"`
+	Blocks  []ReaderBlock      `json:"blocks"`
+	Prev    *ReaderEpisodeLink `json:"prev"`
+	Next    *ReaderEpisodeLink `json:"next"`
+}
+
+// ReadingPosition は小説ごとの読んでいる位置を表す構造体
+type ReadingPosition struct {
+	Number    string    `json:"number"`
+	Paragraph int       `json:"paragraph"` // 本文のブロックの位置
+	Layout    string    `json:"layout"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// readingPath は読んでいる位置と既読の状態を保存するファイルのパスを返します
+func (s *novelStore) readingPath() string {
+	return filepath.Join(s.dir(), "reading.json")
+}
+
+// loadReadingPosition は読んでいる位置を読み込みます（読みかけでない場合は nil）
+func (s *novelStore) loadReadingPosition() (*ReadingPosition, error) {
+	state, err := s.loadReadingState()
+	if err != nil {
+		return nil, err
+	}
+	return state.Position, nil
+}
+
+// libraryFolder は本棚として扱うフォルダを返します（省略時は既定の保存先）
+func libraryFolder(folder string) (string, error) {
+	if folder != "" {
+		return folder, nil
+	}
+	return defaultSaveRoot()
+}
+
+// ListLibrary は保存済みの小説の一覧を、最近読んだ・更新した順に返します（フロントエンド用）
+func (a *App) ListLibrary(folder string) ([]LibraryNovel, error) {
+	folder, err := libraryFolder(folder)
+	if err != nil {
+		return nil, err
+	}
+	stores, err := findNovelStores(folder)
+	if err != nil {
+		return nil, err
+	}
+
+	novels := []LibraryNovel{}
+	for _, store := range stores {
+		manifest, err := store.loadManifest()
+		if err != nil {
+			a.emit("log", fmt.Sprintf("%s を読み込めませんでした: %v", store.dir(), err))
+			continue
+		}
+		state, err := store.loadReadingState()
+		if err != nil {
+			a.emit("log", err.Error())
+			state = &readingState{}
+		}
+		novels = append(novels, LibraryNovel{
+			Folder:    store.root,
+			NCode:     manifest.NCode,
+			Title:     manifest.Title,
+			Author:    manifest.Author,
+			Short:     manifest.Short,
+			Episodes:  len(manifest.Episodes),
+			Unread:    len(manifest.Episodes) - state.readCount(manifest),
+			UpdatedAt: manifest.UpdatedAt,
+			Position:  state.Position,
+		})
+	}
+
+	recent := func(novel LibraryNovel) time.Time {
+		if novel.Position != nil && novel.Position.UpdatedAt.After(novel.UpdatedAt) {
+			return novel.Position.UpdatedAt
+		}
+		return novel.UpdatedAt
+	}
+	sort.SliceStable(novels, func(i, j int) bool { return recent(novels[i]).After(recent(novels[j])) })
+	return novels, nil
+}
+
+// GetEpisodeContent は話の本文を表示用の形式で返します（フロントエンド用）
+// number を省略した場合は読んでいる話（読みかけでない場合は最初の話）を返します
+func (a *App) GetEpisodeContent(folder, ncode, number, layout string) (ReaderEpisode, error) {
+	if layout == "" {
+		layout = layoutHorizontal
+	}
+	if layout != layoutHorizontal && layout != layoutVertical {
+		return ReaderEpisode{}, fmt.Errorf("不明な組み方です: %s", layout)
+	}
+
+	store, manifest, err := openNovelStoreByNCode(folder, ncode)
+	if err != nil {
+		return ReaderEpisode{}, err
+	}
+	if len(manifest.Episodes) == 0 {
+		return ReaderEpisode{}, fmt.Errorf("%s には保存済みの話がありません", manifest.Title)
+	}
+
+	if number == "" {
+		number = manifest.Episodes[0].Number
+		position, err := store.loadReadingPosition()
+		if err != nil {
+			a.emit("log", err.Error())
+		}
+		if position != nil && manifest.episode(position.Number) != nil {
+			number = position.Number
+		}
+	}
+
+	// マニフェストの話は掲載順に並んでいる
+	current := -1
+	for i, episode := range manifest.Episodes {
+		if episode.Number == number {
+			current = i
+			break
+		}
+	}
+	if current < 0 {
+		return ReaderEpisode{}, fmt.Errorf("%s話は保存されていません", number)
+	}
+	record := manifest.Episodes[current]
+
+	blocks, err := store.loadBlocks(record.Number)
+	if err != nil {
+		return ReaderEpisode{}, fmt.Errorf("%s話の本文を読み込めませんでした: %w", record.Number, err)
+	}
+
+	content := ReaderEpisode{
+		NCode:   manifest.NCode,
+		Title:   manifest.Title,
+		Number:  record.Number,
+		Index:   current + 1,
+		Total:   len(manifest.Episodes),
+		Chapter: record.Chapter,
+		Episode: record.Title,
+		Layout:  layout,
+		Blocks:  readerBlocks(blocks, layout),
+	}
+	if current > 0 {
+		prev := manifest.Episodes[current-1]
+		content.Prev = &ReaderEpisodeLink{Number: prev.Number, Title: prev.Title}
+	}
+	if current+1 < len(manifest.Episodes) {
+		next := manifest.Episodes[current+1]
+		content.Next = &ReaderEpisodeLink{Number: next.Number, Title: next.Title}
+	}
+	return content, nil
+}
+
+// GetReadingPosition は小説の読んでいる位置を返します（読みかけでない場合は nil、フロントエンド用）
+func (a *App) GetReadingPosition(folder, ncode string) (*ReadingPosition, error) {
+	store, _, err := openNovelStoreByNCode(folder, ncode)
+	if err != nil {
+		return nil, err
+	}
+	return store.loadReadingPosition()
+}
+
+// SaveReadingPosition は小説の読んでいる位置を保存します（フロントエンド用）
+func (a *App) SaveReadingPosition(folder, ncode string, position ReadingPosition) error {
+	store, manifest, err := openNovelStoreByNCode(folder, ncode)
+	if err != nil {
+		return err
+	}
+	if manifest.episode(position.Number) == nil {
+		return fmt.Errorf("%s話は保存されていません", position.Number)
+	}
+	if position.Layout == "" {
+		position.Layout = layoutHorizontal
+	}
+	position.UpdatedAt = time.Now()
+
+	state, err := store.loadReadingState()
+	if err != nil {
+		return err
+	}
+	state.Position = &position
+	// 話ごとの最後に読んだ位置も残しておく
+	record := manifest.episode(position.Number)
+	reading := state.Episodes[record.Number]
+	reading.Paragraph = position.Paragraph
+	state.Episodes[record.Number] = reading
+	return store.saveReadingState(state)
+}
+
+// readerBlocks は本文を表示用の形式に変換します
+func readerBlocks(blocks []Block, layout string) []ReaderBlock {
+	result := make([]ReaderBlock, len(blocks))
+	for i, block := range blocks {
+		result[i] = ReaderBlock{Kind: string(block.Kind), Src: block.Src}
+		for _, inline := range block.Inlines {
+			if inline.Kind == InlineText && layout == layoutVertical {
+				result[i].Inlines = append(result[i].Inlines, splitTateChuYoko(inline.Text)...)
+				continue
+			}
+			result[i].Inlines = append(result[i].Inlines, ReaderInline{Kind: string(inline.Kind), Text: inline.Text, Ruby: inline.Ruby})
+		}
+	}
+	return result
+}
+
+// splitTateChuYoko は縦書きの文字列から短い半角英数字の並びを縦中横の要素として切り出します
+// 長い英単語などは縦中横にせず、そのまま（横倒しで）表示する
+func splitTateChuYoko(text string) []ReaderInline {
+	var inlines []ReaderInline
+	last := 0
+	for _, loc := range tateChuYokoPattern.FindAllStringIndex(text, -1) {
+		if loc[1]-loc[0] > maxTateChuYoko {
+			continue
+		}
+		if loc[0] > last {
+			inlines = append(inlines, ReaderInline{Kind: string(InlineText), Text: text[last:loc[0]]})
+		}
+		inlines = append(inlines, ReaderInline{Kind: "tcy", Text: text[loc[0]:loc[1]]})
+		last = loc[1]
+	}
+	if last < len(text) {
+		inlines = append(inlines, ReaderInline{Kind: string(InlineText), Text: text[last:]})
+	}
+	return inlines
+}
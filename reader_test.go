@@ -0,0 +1,105 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestSplitTateChuYoko(t *testing.T) {
+	tests := []struct {
+		name     string
+		text     string
+		expected []ReaderInline
+	}{
+		{
+			name:     "英数字なし",
+			text:     "本文",
+			expected: []ReaderInline{{Kind: "text", Text: "本文"}},
+		},
+		{
+			name: "2桁の数字",
+			text: "第12話",
+			expected: []ReaderInline{
+				{Kind: "text", Text: "第"},
+				{Kind: "tcy", Text: "12"},
+				{Kind: "text", Text: "話"},
+			},
+		},
+		{
+			name: "感嘆符",
+			text: "本当に!?",
+			expected: []ReaderInline{
+				{Kind: "text", Text: "本当に"},
+				{Kind: "tcy", Text: "!?"},
+			},
+		},
+		{
+			name:     "長い英単語は縦中横にしない",
+			text:     "Hello世界",
+			expected: []ReaderInline{{Kind: "text", Text: "Hello世界"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := splitTateChuYoko(tt.text)
+			if len(got) != len(tt.expected) {
+				t.Fatalf("splitTateChuYoko(%q) = %+v, want %+v", tt.text, got, tt.expected)
+			}
+			for i := range got {
+				if got[i] != tt.expected[i] {
+					t.Errorf("splitTateChuYoko(%q)[%d] = %+v, want %+v", tt.text, i, got[i], tt.expected[i])
+				}
+			}
+		})
+	}
+}
+
+func TestGetEpisodeContent(t *testing.T) {
+	app, store, _ := newVerifiedNovelStore(t)
+
+	// 読みかけでない場合は最初の話を返す
+	content, err := app.GetEpisodeContent(store.root, "", "", "")
+	if err != nil {
+		t.Fatalf("GetEpisodeContent() error = %v", err)
+	}
+	if content.Number != "1" || content.Prev != nil || content.Next == nil || content.Next.Number != "2" {
+		t.Errorf("content = %+v, want 1話", content)
+	}
+	if content.Layout != layoutHorizontal || content.Chapter != "第一章" || content.Total != 3 {
+		t.Errorf("Layout, Chapter, Total = %s, %s, %d", content.Layout, content.Chapter, content.Total)
+	}
+	if len(content.Blocks) != 1 || content.Blocks[0].Inlines[0].Text != "本文一" {
+		t.Errorf("Blocks = %+v, want 本文一", content.Blocks)
+	}
+
+	if _, err := app.GetEpisodeContent(store.root, "", "1", "diagonal"); err == nil {
+		t.Errorf("不明な組み方で GetEpisodeContent() がエラーになりません")
+	}
+	if _, err := app.GetEpisodeContent(store.root, "", "9", ""); err == nil {
+		t.Errorf("保存されていない話で GetEpisodeContent() がエラーになりません")
+	}
+
+	// 保存した位置から再開する
+	if err := app.SaveReadingPosition(store.root, "N1234AB", ReadingPosition{Number: "3", Paragraph: 5, Layout: layoutVertical}); err != nil {
+		t.Fatalf("SaveReadingPosition() error = %v", err)
+	}
+	content, err = app.GetEpisodeContent(store.root, "", "", layoutVertical)
+	if err != nil {
+		t.Fatalf("GetEpisodeContent() error = %v", err)
+	}
+	if content.Number != "3" || content.Next != nil || content.Prev.Number != "2" {
+		t.Errorf("content = %+v, want 3話", content)
+	}
+
+	novels, err := app.ListLibrary(store.root)
+	if err != nil {
+		t.Fatalf("ListLibrary() error = %v", err)
+	}
+	if len(novels) != 1 || novels[0].Episodes != 3 || novels[0].Position == nil || novels[0].Position.Paragraph != 5 {
+		t.Errorf("ListLibrary() = %+v", novels)
+	}
+
+	if err := app.SaveReadingPosition(store.root, "", ReadingPosition{Number: "9"}); err == nil {
+		t.Errorf("保存されていない話で SaveReadingPosition() がエラーになりません")
+	}
+}
@@ -0,0 +1,340 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// minScheduleInterval は定期確認の最短の間隔（サーバーに負荷をかけないため）
+const minScheduleInterval = 15 * time.Minute
+
+// ScheduleSettings は新しい話の定期確認の設定を表す構造体
+type ScheduleSettings struct {
+	Enabled      bool   `json:"enabled"`
+	Interval     string `json:"interval"`     // 確認する間隔（例: "6h"）。Cron を指定した場合は使わない
+	Cron         string `json:"cron"`         // cron形式の予定（例: "0 */3 * * *"）
+	Folder       string `json:"folder"`       // 確認する本棚のフォルダ（省略時は既定の保存先）
+	AutoDownload bool   `json:"autoDownload"` // 新しい話があれば自動でダウンロードする
+}
+
+// ScheduleStatus は定期確認の状態を表す構造体
+type ScheduleStatus struct {
+	Enabled  bool      `json:"enabled"`
+	Checking bool      `json:"checking"`
+	NextRun  time.Time `json:"nextRun"`
+	LastRun  time.Time `json:"lastRun"`
+}
+
+// NewEpisode は定期確認で見つかった新しい話を表す構造体
+type NewEpisode struct {
+	Number string `json:"number"`
+	Title  string `json:"title"`
+}
+
+// UpdateCheck は小説ごとの最後の定期確認の結果を表す構造体
+type UpdateCheck struct {
+	Folder      string       `json:"folder"`
+	NCode       string       `json:"ncode"`
+	Title       string       `json:"title"`
+	CheckedAt   time.Time    `json:"checkedAt"`
+	Total       int          `json:"total"` // 目次の話数
+	NewEpisodes []NewEpisode `json:"newEpisodes"`
+	Downloaded  bool         `json:"downloaded"`
+	Error       string       `json:"error,omitempty"`
+}
+
+// scheduler は定期確認の実行状態を表す構造体
+type scheduler struct {
+	mu       sync.Mutex
+	cancel   context.CancelFunc
+	done     chan struct{}
+	checking bool
+	nextRun  time.Time
+	lastRun  time.Time
+}
+
+// defaultScheduleSettings は定期確認の既定の設定を返します
+func defaultScheduleSettings() ScheduleSettings {
+	return ScheduleSettings{Interval: "6h"}
+}
+
+// parseSchedule は定期確認の設定から予定を作成します
+func parseSchedule(settings ScheduleSettings) (schedule, error) {
+	if settings.Cron != "" {
+		cron, err := parseCron(settings.Cron)
+		if err != nil {
+			return nil, err
+		}
+		if cron.hasIntervalShorterThan(minScheduleInterval) {
+			return nil, fmt.Errorf("確認する間隔は%v以上にしてください: %q", minScheduleInterval, settings.Cron)
+		}
+		return cron, nil
+	}
+	interval, err := time.ParseDuration(settings.Interval)
+	if err != nil {
+		return nil, fmt.Errorf("確認する間隔が正しくありません: %q", settings.Interval)
+	}
+	if interval < minScheduleInterval {
+		return nil, fmt.Errorf("確認する間隔は%v以上にしてください: %v", minScheduleInterval, interval)
+	}
+	return intervalSchedule{interval: interval}, nil
+}
+
+// updateCheckPath は最後の定期確認の結果を保存するファイルのパスを返します
+func (s *novelStore) updateCheckPath() string {
+	return filepath.Join(s.dir(), "update.json")
+}
+
+// loadUpdateCheck は最後の定期確認の結果を読み込みます（まだ確認していない場合は nil）
+func (s *novelStore) loadUpdateCheck() (*UpdateCheck, error) {
+	data, err := os.ReadFile(s.updateCheckPath())
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("確認結果の読み込みに失敗しました: %w", err)
+	}
+	var check UpdateCheck
+	if err := json.Unmarshal(data, &check); err != nil {
+		return nil, fmt.Errorf("確認結果の解析に失敗しました: %w", err)
+	}
+	return &check, nil
+}
+
+// saveUpdateCheck は定期確認の結果を保存します
+func (s *novelStore) saveUpdateCheck(check UpdateCheck) error {
+	data, err := json.MarshalIndent(check, "", "  ")
+	if err != nil {
+		return fmt.Errorf("確認結果のJSON変換に失敗しました: %w", err)
+	}
+	return writeFileAtomic(s.updateCheckPath(), data, s.tmpDir())
+}
+
+// checkNovelUpdate は小説の目次を取得し、保存していない新しい話を探します
+func (a *App) checkNovelUpdate(store *novelStore, manifest *Manifest) UpdateCheck {
+	check := UpdateCheck{
+		Folder:      store.root,
+		NCode:       manifest.NCode,
+		Title:       manifest.Title,
+		CheckedAt:   time.Now(),
+		NewEpisodes: []NewEpisode{},
+	}
+
+	result := a.StartScraping(manifest.URL)
+	if result.Error != "" {
+		check.Error = fmt.Sprintf("目次の取得に失敗しました: %s", result.Error)
+		return check
+	}
+	novel := newNovelFromResult(result, manifest.URL)
+	check.Total = len(novel.Episodes())
+
+	diff := diffTOC(manifest, novel)
+	for _, change := range diff.Changes {
+		if change.Kind == tocAdded || change.Kind == tocInserted {
+			check.NewEpisodes = append(check.NewEpisodes, NewEpisode{Number: change.New.Number, Title: change.New.Title})
+		}
+	}
+	return check
+}
+
+// CheckUpdates は本棚の連載小説に新しい話がないか確認し、結果を返します（フロントエンド・CLI用）
+// 新しい話が見つかった小説ごとに newEpisodes イベントを送信し、autoDownload の場合はダウンロードします
+func (a *App) CheckUpdates(folder string, autoDownload bool) ([]UpdateCheck, error) {
+	return a.checkUpdates(context.Background(), folder, autoDownload)
+}
+
+// checkUpdates は CheckUpdates と同じ確認を行います
+// ctx が終了した場合は、確認中の小説のダウンロードを次の話の前で止め、残りの小説は確認しません
+func (a *App) checkUpdates(ctx context.Context, folder string, autoDownload bool) ([]UpdateCheck, error) {
+	folder, err := libraryFolder(folder)
+	if err != nil {
+		return nil, err
+	}
+	stores, err := findNovelStores(folder)
+	if err != nil {
+		return nil, err
+	}
+
+	checks := []UpdateCheck{}
+	for _, store := range stores {
+		if err := ctx.Err(); err != nil {
+			return checks, err
+		}
+		manifest, err := store.loadManifest()
+		if err != nil {
+			a.emit("log", fmt.Sprintf("%s を読み込めませんでした: %v", store.dir(), err))
+			continue
+		}
+		// 短編は話が増えない
+		if manifest.Short || manifest.URL == "" {
+			continue
+		}
+
+		a.emit("log", fmt.Sprintf("%s の新しい話を確認しています...", manifest.Title))
+		check := a.checkNovelUpdate(store, manifest)
+		if check.Error != "" {
+			a.emit("log", fmt.Sprintf("%s: %s", manifest.Title, check.Error))
+		} else if len(check.NewEpisodes) > 0 {
+			a.emit("log", fmt.Sprintf("%s に新しい話が%d話あります", manifest.Title, len(check.NewEpisodes)))
+			a.emit("newEpisodes", check)
+			a.notify(Notification{
+				Event:      notifyNewEpisodes,
+				Title:      "新しい話があります",
+				Message:    fmt.Sprintf("%s に新しい話が%d話あります", manifest.Title, len(check.NewEpisodes)),
+				NCode:      manifest.NCode,
+				NovelTitle: manifest.Title,
+				URL:        manifest.URL,
+				Episodes:   check.NewEpisodes,
+			})
+
+			if autoDownload {
+				if err := a.downloadNovel(ctx, manifest.URL, store.root, manifest.Formats, ""); err != nil {
+					check.Error = fmt.Sprintf("ダウンロードに失敗しました: %v", err)
+				} else {
+					check.Downloaded = true
+				}
+			}
+		}
+
+		if err := store.saveUpdateCheck(check); err != nil {
+			a.emit("log", err.Error())
+		}
+		checks = append(checks, check)
+	}
+	return checks, nil
+}
+
+// GetUpdateChecks は本棚の小説ごとの最後の定期確認の結果を返します（フロントエンド用）
+func (a *App) GetUpdateChecks(folder string) ([]UpdateCheck, error) {
+	folder, err := libraryFolder(folder)
+	if err != nil {
+		return nil, err
+	}
+	stores, err := findNovelStores(folder)
+	if err != nil {
+		return nil, err
+	}
+
+	checks := []UpdateCheck{}
+	for _, store := range stores {
+		check, err := store.loadUpdateCheck()
+		if err != nil {
+			a.emit("log", err.Error())
+			continue
+		}
+		if check != nil {
+			checks = append(checks, *check)
+		}
+	}
+	return checks, nil
+}
+
+// startScheduler は定期確認を開始します（実行中の場合は設定を反映して再開する）
+// ctx が終了すると定期確認も終了します
+func (a *App) startScheduler(ctx context.Context, settings ScheduleSettings) error {
+	a.stopScheduler()
+	if !settings.Enabled {
+		return nil
+	}
+	sched, err := parseSchedule(settings)
+	if err != nil {
+		return err
+	}
+
+	next := sched.next(time.Now())
+	if next.IsZero() {
+		return fmt.Errorf("定期確認の予定に一致する日時がありません")
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	done := make(chan struct{})
+	a.scheduler.mu.Lock()
+	a.scheduler.cancel = cancel
+	a.scheduler.done = done
+	a.scheduler.nextRun = next
+	a.scheduler.mu.Unlock()
+
+	go func() {
+		defer close(done)
+		for !next.IsZero() {
+			timer := time.NewTimer(time.Until(next))
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return
+			case <-timer.C:
+			}
+
+			a.scheduler.mu.Lock()
+			a.scheduler.checking = true
+			a.scheduler.mu.Unlock()
+
+			if _, err := a.checkUpdates(ctx, settings.Folder, settings.AutoDownload); err != nil && ctx.Err() == nil {
+				a.emit("log", fmt.Sprintf("定期確認に失敗しました: %v", err))
+			}
+
+			// 確認に時間がかかった場合も、終わった時点から次の予定を決める
+			next = sched.next(time.Now())
+			a.scheduler.mu.Lock()
+			a.scheduler.checking = false
+			a.scheduler.lastRun = time.Now()
+			a.scheduler.nextRun = next
+			a.scheduler.mu.Unlock()
+		}
+	}()
+	return nil
+}
+
+// stopScheduler は定期確認を終了し、実行中の確認が終わるまで待ちます
+// 実行中のダウンロードは次の話の前で中断するため、長く待つことはありません
+func (a *App) stopScheduler() {
+	a.scheduler.mu.Lock()
+	cancel, done := a.scheduler.cancel, a.scheduler.done
+	a.scheduler.cancel, a.scheduler.done = nil, nil
+	a.scheduler.nextRun = time.Time{}
+	a.scheduler.mu.Unlock()
+
+	if cancel != nil {
+		cancel()
+		<-done
+	}
+}
+
+// ApplySchedule は定期確認の設定を保存して反映します（フロントエンド用）
+func (a *App) ApplySchedule(settings ScheduleSettings) error {
+	if settings.Enabled {
+		if _, err := parseSchedule(settings); err != nil {
+			return err
+		}
+	}
+
+	if _, err := a.updateSettings(func(current *Settings) {
+		current.Schedule = settings
+	}); err != nil {
+		return err
+	}
+
+	ctx := a.ctx
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	return a.startScheduler(ctx, settings)
+}
+
+// GetScheduleStatus は定期確認の状態を返します（フロントエンド用）
+func (a *App) GetScheduleStatus() ScheduleStatus {
+	a.scheduler.mu.Lock()
+	defer a.scheduler.mu.Unlock()
+	return ScheduleStatus{
+		Enabled:  a.scheduler.cancel != nil,
+		Checking: a.scheduler.checking,
+		NextRun:  a.scheduler.nextRun,
+		LastRun:  a.scheduler.lastRun,
+	}
+}
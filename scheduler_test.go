@@ -0,0 +1,104 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestParseSchedule(t *testing.T) {
+	tests := []struct {
+		name     string
+		settings ScheduleSettings
+		wantErr  bool
+	}{
+		{"既定の設定", defaultScheduleSettings(), false},
+		{"cron形式", ScheduleSettings{Cron: "0 */3 * * *"}, false},
+		{"cron形式を優先する", ScheduleSettings{Interval: "1m", Cron: "0 0 * * *"}, false},
+		{"短すぎる間隔", ScheduleSettings{Interval: "5m"}, true},
+		{"cron形式で短すぎる間隔", ScheduleSettings{Cron: "* * * * *"}, true},
+		{"cron形式で時をまたぐと短すぎる間隔", ScheduleSettings{Cron: "0,50 * * * *"}, true},
+		{"cron形式で月をまたぐと短すぎる間隔", ScheduleSettings{Cron: "0,55 0,23 1,31 * *"}, true},
+		{"cron形式で最短の間隔", ScheduleSettings{Cron: "*/15 * * * *"}, false},
+		{"間隔の形式が正しくない", ScheduleSettings{Interval: "6時間"}, true},
+		{"cron形式が正しくない", ScheduleSettings{Cron: "every day"}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := parseSchedule(tt.settings); (err != nil) != tt.wantErr {
+				t.Errorf("parseSchedule() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestUpdateCheck_SaveLoad(t *testing.T) {
+	app, store, _ := newVerifiedNovelStore(t)
+
+	// まだ確認していない小説は一覧に含めない
+	checks, err := app.GetUpdateChecks(store.root)
+	if err != nil {
+		t.Fatalf("GetUpdateChecks() error = %v", err)
+	}
+	if len(checks) != 0 {
+		t.Errorf("len(checks) = %d, want 0", len(checks))
+	}
+
+	want := UpdateCheck{
+		Folder:      store.root,
+		NCode:       "N1234AB",
+		Title:       "テスト小説",
+		CheckedAt:   time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+		Total:       4,
+		NewEpisodes: []NewEpisode{{Number: "4", Title: "第四話"}},
+	}
+	if err := store.saveUpdateCheck(want); err != nil {
+		t.Fatalf("saveUpdateCheck() error = %v", err)
+	}
+
+	checks, err = app.GetUpdateChecks(store.root)
+	if err != nil {
+		t.Fatalf("GetUpdateChecks() error = %v", err)
+	}
+	if len(checks) != 1 {
+		t.Fatalf("len(checks) = %d, want 1", len(checks))
+	}
+	got := checks[0]
+	if got.NCode != want.NCode || got.Total != want.Total || !got.CheckedAt.Equal(want.CheckedAt) {
+		t.Errorf("check = %+v, want %+v", got, want)
+	}
+	if len(got.NewEpisodes) != 1 || got.NewEpisodes[0] != want.NewEpisodes[0] {
+		t.Errorf("NewEpisodes = %+v, want %+v", got.NewEpisodes, want.NewEpisodes)
+	}
+}
+
+func TestScheduler_StartStop(t *testing.T) {
+	app := NewApp()
+
+	if err := app.startScheduler(context.Background(), ScheduleSettings{Enabled: true, Interval: "1m"}); err == nil {
+		t.Error("startScheduler() with short interval error = nil, want error")
+	}
+
+	before := time.Now()
+	if err := app.startScheduler(context.Background(), ScheduleSettings{Enabled: true, Interval: "1h"}); err != nil {
+		t.Fatalf("startScheduler() error = %v", err)
+	}
+	status := app.GetScheduleStatus()
+	if !status.Enabled {
+		t.Error("Enabled = false, want true")
+	}
+	if status.NextRun.Before(before.Add(time.Hour)) || status.NextRun.After(time.Now().Add(time.Hour)) {
+		t.Errorf("NextRun = %v, want about 1h later", status.NextRun)
+	}
+
+	// 無効な設定で開始し直すと止まる
+	if err := app.startScheduler(context.Background(), ScheduleSettings{Interval: "1h"}); err != nil {
+		t.Fatalf("startScheduler() error = %v", err)
+	}
+	status = app.GetScheduleStatus()
+	if status.Enabled || !status.NextRun.IsZero() {
+		t.Errorf("status = %+v, want stopped", status)
+	}
+	app.stopScheduler()
+}
@@ -20,69 +20,53 @@ type ScrapeResult struct {
 	FullPageHTML   string        `json:"full_page_html"`
 	IndexPagesHTML []string      `json:"index_pages_html"`
 	Chapters       []ChapterInfo `json:"chapters,omitempty"`
+	Blocks         []Block       `json:"blocks,omitempty"`
 	Error          string        `json:"error,omitempty"`
 }
 
 type ChapterInfo struct {
-	Title        string `json:"title"`
-	URL          string `json:"url"`
-	Content      string `json:"content"`
-	RawHTML      string `json:"raw_html"`
-	FullPageHTML string `json:"full_page_html"`
-	RetryCount   int    `json:"retry_count"`
-	Failed       bool   `json:"failed"`
+	Title        string  `json:"title"`
+	ChapterTitle string  `json:"chapter_title,omitempty"`
+	URL          string  `json:"url"`
+	Content      string  `json:"content"`
+	RawHTML      string  `json:"raw_html"`
+	FullPageHTML string  `json:"full_page_html"`
+	Blocks       []Block `json:"blocks,omitempty"`
+	RetryCount   int     `json:"retry_count"`
+	Failed       bool    `json:"failed"`
+	Revised      string  `json:"revised,omitempty"` // 目次に表示される改稿日時（改稿されていない場合は空）
 }
 
-// StartScraping はWailsのバインディングとして公開される関数です
-func (a *App) StartScraping(url string) ScrapeResult {
-	result := ScrapeResult{}
+// revisedPattern は目次の「（改）」の title 属性（2024/01/02 12:00 改稿）の日時にマッチします
+var revisedPattern = regexp.MustCompile(`([0-9]{4}/[0-9]{2}/[0-9]{2} [0-9]{2}:[0-9]{2}) 改稿`)
 
-	// HTTPクライアントの設定
-	client := &http.Client{
-		Timeout: 10 * time.Second,
-		Transport: &http.Transport{
-			MaxIdleConns:       100,
-			IdleConnTimeout:    90 * time.Second,
-			DisableCompression: true,
-		},
-	}
+// retryInterval は各話の取得に失敗した場合にリトライするまでの待ち時間の単位（n回目のリトライは n 倍待つ）
+var retryInterval = time.Second
 
-	// リクエストの作成
-	req, err := http.NewRequest("GET", url, nil)
-	if err != nil {
-		log.Printf("リクエスト作成エラー: %v\n", err)
-		result.Error = err.Error()
-		return result
-	}
-
-	// ヘッダーの設定
-	req.Header.Set("User-Agent", "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/136.0.0.0 Safari/537.36")
+// episodePage は1話分のページから取得した内容を表す構造体
+type episodePage struct {
+	Blocks       []Block
+	RawHTML      string
+	FullPageHTML string
+	Cached       bool // サーバーに接続せずにキャッシュから読んだ
+}
 
-	// ノクターンノベルズの年齢確認用Cookie
-	if strings.Contains(url, "novel18.syosetu.com") {
-		req.Header.Set("Cookie", "over18=yes")
-	}
+// StartScraping はWailsのバインディングとして公開される関数です
+func (a *App) StartScraping(url string) ScrapeResult {
+	return a.scrapeNovel(url, false)
+}
 
-	// ノクターンノベルズの年齢確認用Cookie
-	if strings.Contains(url, "novel18.syosetu.com") {
-		req.Header.Set("Cookie", "over18=yes")
-	}
+// scrapeNovel は小説のページ（連載の目次または短編の本文）を取得して解析します
+// revalidate が true の場合は、キャッシュの有効期限内でも最初のページの変更をサーバーに確認します
+func (a *App) scrapeNovel(url string, revalidate bool) ScrapeResult {
+	result := ScrapeResult{}
 
-	// HTTPリクエストの実行
-	resp, err := client.Do(req)
+	doc, _, err := a.fetchPageCached(url, revalidate)
 	if err != nil {
 		log.Printf("リクエストエラー: %v\n", err)
 		result.Error = err.Error()
 		return result
 	}
-	defer resp.Body.Close()
-
-	// HTMLの解析
-	doc, err := goquery.NewDocumentFromReader(resp.Body)
-	if err != nil {
-		result.Error = err.Error()
-		return result
-	}
 
 	// タイトルの取得
 	result.Title = doc.Find("h1").Text()
@@ -118,14 +102,15 @@ func (a *App) StartScraping(url string) ScrapeResult {
 		}
 	case "short":
 		// 短編の場合、本文を直接取得
-		content, err := a.extractContent(doc)
+		blocks, err := extractBlocks(doc, url)
 		if err != nil {
 			result.Error = err.Error()
 			return result
 		}
+		result.Blocks = blocks
 
 		// テキストコンテンツを保存（TXTファイル用）
-		result.TextContent = append(result.TextContent, content)
+		result.TextContent = append(result.TextContent, renderAozora(blocks))
 
 		// HTML構造も取得（HTMLファイル用）
 		rawHTML, err := a.extractRawHTML(doc)
@@ -164,9 +149,17 @@ func (a *App) scrapeChapterList(result *ScrapeResult, doc *goquery.Document, bas
 		result.IndexPagesHTML = append(result.IndexPagesHTML, firstPageHTML)
 	}
 
+	// 章タイトルはページをまたいで引き継ぐ
+	var chapterTitle string
+
 	for {
-		// エピソードリストを取得
-		pageDoc.Find(".p-eplist__sublist a").Each(func(i int, s *goquery.Selection) {
+		// エピソードリストを取得（章タイトルとエピソードを出現順に処理する）
+		pageDoc.Find(".p-eplist__chapter-title, .p-eplist__sublist a").Each(func(i int, s *goquery.Selection) {
+			if s.HasClass("p-eplist__chapter-title") {
+				chapterTitle = strings.TrimSpace(s.Text())
+				return
+			}
+
 			chapterURL, exists := s.Attr("href")
 			if !exists {
 				return
@@ -175,21 +168,22 @@ func (a *App) scrapeChapterList(result *ScrapeResult, doc *goquery.Document, bas
 			// 相対URLの場合は絶対URLに変換
 			if !strings.HasPrefix(chapterURL, "http") {
 				if strings.HasPrefix(chapterURL, "/") {
-					if strings.Contains(baseURL, "novel18.syosetu.com") {
-						chapterURL = "https://novel18.syosetu.com" + chapterURL
-					} else {
-						chapterURL = "https://ncode.syosetu.com" + chapterURL
-					}
+					chapterURL = a.site.baseURL(baseURL) + chapterURL
 				} else {
 					chapterURL = baseURL + "/" + chapterURL
 				}
 			}
 
-			chapterTitle := strings.TrimSpace(s.Text())
+			episodeTitle := strings.TrimSpace(s.Text())
 
 			chapter := ChapterInfo{
-				Title: chapterTitle,
-				URL:   chapterURL,
+				Title:        episodeTitle,
+				ChapterTitle: chapterTitle,
+				URL:          chapterURL,
+			}
+			revised, _ := s.Closest(".p-eplist__sublist").Find(".p-eplist__update span[title]").Attr("title")
+			if matches := revisedPattern.FindStringSubmatch(revised); matches != nil {
+				chapter.Revised = matches[1]
 			}
 
 			result.Chapters = append(result.Chapters, chapter)
@@ -206,11 +200,7 @@ func (a *App) scrapeChapterList(result *ScrapeResult, doc *goquery.Document, bas
 		var nextURL string
 		if !strings.HasPrefix(nextLink, "http") {
 			if strings.HasPrefix(nextLink, "/") {
-				if strings.Contains(baseURL, "novel18.syosetu.com") {
-					nextURL = "https://novel18.syosetu.com" + nextLink
-				} else {
-					nextURL = "https://ncode.syosetu.com" + nextLink
-				}
+				nextURL = a.site.baseURL(baseURL) + nextLink
 			} else {
 				// 相対パスの場合
 				nextURL = baseURL + nextLink
@@ -243,95 +233,58 @@ func (a *App) scrapeChapterList(result *ScrapeResult, doc *goquery.Document, bas
 }
 
 // fetchPage はURLからHTMLドキュメントを取得します
+// なろうのサーバーに負荷をかけないよう、サーバーに接続するリクエストは fetchLimiter で間隔を空けます
 func (a *App) fetchPage(url string) (*goquery.Document, error) {
-	client := &http.Client{
-		Timeout: 10 * time.Second,
-		Transport: &http.Transport{
-			MaxIdleConns:       100,
-			IdleConnTimeout:    90 * time.Second,
-			DisableCompression: true,
-		},
-	}
+	doc, _, err := a.fetchPageCached(url, false)
+	return doc, err
+}
 
+// fetchPageCached はURLからHTMLドキュメントを取得し、サーバーに接続せずにキャッシュから読んだかどうかも返します
+// revalidate が true の場合は Cache-Control: no-cache を付け、キャッシュの有効期限内でもサーバーに変更を確認します
+func (a *App) fetchPageCached(url string, revalidate bool) (*goquery.Document, bool, error) {
 	req, err := http.NewRequest("GET", url, nil)
 	if err != nil {
-		return nil, err
+		return nil, false, err
+	}
+	if revalidate {
+		req.Header.Set("Cache-Control", "no-cache")
 	}
 
 	req.Header.Set("User-Agent", "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/136.0.0.0 Safari/537.36")
 
 	// ノクターンノベルズの年齢確認用Cookie
-	if strings.Contains(url, "novel18.syosetu.com") {
+	if a.site.isR18(url) {
 		req.Header.Set("Cookie", "over18=yes")
 	}
 
-	resp, err := client.Do(req)
+	resp, err := httpClient.Do(req)
 	if err != nil {
-		return nil, err
+		return nil, false, err
 	}
 	defer resp.Body.Close()
 
+	// 削除された小説（404）やメンテナンス中（503）のページは本文として解析しない
+	if resp.StatusCode != http.StatusOK {
+		return nil, false, fmt.Errorf("ページの取得に失敗しました: %s: %s", url, resp.Status)
+	}
+
 	doc, err := goquery.NewDocumentFromReader(resp.Body)
 	if err != nil {
-		return nil, err
+		return nil, false, err
 	}
 
-	return doc, nil
+	return doc, fromCache(resp), nil
 }
 
-// extractContent はHTMLドキュメントから本文を抽出します
-func (a *App) extractContent(doc *goquery.Document) (string, error) {
-	var contentParts []string
-
-	// 小説家になろうの現在の構造に対応
-	// p-novel__body 内の p-novel__text を取得
-	doc.Find(".p-novel__body .p-novel__text").Each(func(i int, s *goquery.Selection) {
-		// HTMLを取得してルビ変換処理を適用
-		html, err := s.Html()
-		if err != nil {
-			// HTMLが取得できない場合はテキストのみ取得
-			text := strings.TrimSpace(s.Text())
-			if text != "" {
-				contentParts = append(contentParts, text)
-			}
-			return
-		}
-
-		// ルビ変換処理を適用
-		convertedHTML := a.convertRubyToAozora(html)
-
-		// HTMLタグを除去してテキストのみ抽出
-		cleanText := a.removeHTMLTags(convertedHTML)
-		cleanText = strings.TrimSpace(cleanText)
-
-		if cleanText != "" {
-			contentParts = append(contentParts, cleanText)
-		}
-	})
-
-	if len(contentParts) > 0 {
-		result := strings.Join(contentParts, "\n************************************************\n")
-		log.Printf("本文を取得しました（%d部分）", len(contentParts))
-		return result, nil
+// extractContent はHTMLドキュメントから本文を抽出し、青空文庫形式のテキストとして返します
+func (a *App) extractContent(doc *goquery.Document, pageURL string) (string, error) {
+	blocks, err := extractBlocks(doc, pageURL)
+	if err != nil {
+		return "", err
 	}
 
-	return "", fmt.Errorf("本文を取得できませんでした")
-}
-
-// removeHTMLTags はHTMLタグを除去してテキストのみを返します
-func (a *App) removeHTMLTags(html string) string {
-	// HTMLタグを除去する正規表現
-	htmlTagPattern := regexp.MustCompile(`<[^>]*>`)
-	text := htmlTagPattern.ReplaceAllString(html, "")
-
-	// HTMLエンティティをデコード
-	text = strings.ReplaceAll(text, "&lt;", "<")
-	text = strings.ReplaceAll(text, "&gt;", ">")
-	text = strings.ReplaceAll(text, "&amp;", "&")
-	text = strings.ReplaceAll(text, "&quot;", "\"")
-	text = strings.ReplaceAll(text, "&nbsp;", " ")
-
-	return text
+	log.Printf("本文を取得しました（%dブロック）", len(blocks))
+	return renderAozora(blocks), nil
 }
 
 // ScrapeChapter は個別のエピソードの内容を取得します（リトライ機能付き）
@@ -343,7 +296,7 @@ func (a *App) ScrapeChapter(chapterURL string) (string, error) {
 		if retry > 0 {
 			log.Printf("Chapterの取得をリトライします（%d/%d回目）: %s", retry+1, maxRetries, chapterURL)
 			// リトライ前に少し待機
-			time.Sleep(time.Duration(retry) * time.Second)
+			time.Sleep(time.Duration(retry) * retryInterval)
 		}
 
 		content, err := a.scrapeChapterOnce(chapterURL)
@@ -363,44 +316,27 @@ func (a *App) ScrapeChapter(chapterURL string) (string, error) {
 
 // scrapeChapterOnce は個別のエピソードの内容を1回だけ取得します
 func (a *App) scrapeChapterOnce(chapterURL string) (string, error) {
-	client := &http.Client{
-		Timeout: 10 * time.Second,
-		Transport: &http.Transport{
-			MaxIdleConns:       100,
-			IdleConnTimeout:    90 * time.Second,
-			DisableCompression: true,
-		},
-	}
-
-	req, err := http.NewRequest("GET", chapterURL, nil)
-	if err != nil {
-		return "", err
-	}
-
-	req.Header.Set("User-Agent", "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/136.0.0.0 Safari/537.36")
-
-	// ノクターンノベルズの年齢確認用Cookie
-	if strings.Contains(chapterURL, "novel18.syosetu.com") {
-		req.Header.Set("Cookie", "over18=yes")
-	}
-
-	resp, err := client.Do(req)
-	if err != nil {
-		return "", err
-	}
-	defer resp.Body.Close()
-
-	doc, err := goquery.NewDocumentFromReader(resp.Body)
+	doc, err := a.fetchPage(chapterURL)
 	if err != nil {
 		return "", err
 	}
 
 	// 共通のコンテンツ抽出関数を使用
-	return a.extractContent(doc)
+	return a.extractContent(doc, chapterURL)
 }
 
 // ScrapeChapterWithHTML は個別のエピソードの内容とHTML構造を取得します（リトライ機能付き）
 func (a *App) ScrapeChapterWithHTML(chapterURL string) (string, string, string, error) {
+	page, err := a.scrapeEpisode(chapterURL, false)
+	if err != nil {
+		return "", "", "", err
+	}
+	return renderAozora(page.Blocks), page.RawHTML, page.FullPageHTML, nil
+}
+
+// scrapeEpisode は個別のエピソードの本文ブロックとHTML構造を取得します（リトライ機能付き）
+// revalidate が true の場合は、キャッシュの有効期限内でもサーバーに変更を確認します（改稿された話の取得や検証用）
+func (a *App) scrapeEpisode(chapterURL string, revalidate bool) (episodePage, error) {
 	const maxRetries = 3
 	var lastErr error
 
@@ -408,77 +344,53 @@ func (a *App) ScrapeChapterWithHTML(chapterURL string) (string, string, string,
 		if retry > 0 {
 			log.Printf("ChapterのHTML取得をリトライします（%d/%d回目）: %s", retry+1, maxRetries, chapterURL)
 			// リトライ前に少し待機
-			time.Sleep(time.Duration(retry) * time.Second)
+			time.Sleep(time.Duration(retry) * retryInterval)
 		}
 
-		content, rawHTML, fullPageHTML, err := a.scrapeChapterWithHTMLOnce(chapterURL)
+		page, err := a.scrapeEpisodeOnce(chapterURL, revalidate)
 		if err == nil {
 			if retry > 0 {
 				log.Printf("ChapterのHTML取得に成功しました（%d回目で成功）: %s", retry+1, chapterURL)
 			}
-			return content, rawHTML, fullPageHTML, nil
+			return page, nil
 		}
 
 		lastErr = err
 		log.Printf("ChapterのHTML取得に失敗しました（%d/%d回目）: %s - エラー: %v", retry+1, maxRetries, chapterURL, err)
 	}
 
-	return "", "", "", fmt.Errorf("ChapterのHTML取得に%d回失敗しました: %s - 最後のエラー: %w", maxRetries, chapterURL, lastErr)
+	return episodePage{}, fmt.Errorf("ChapterのHTML取得に%d回失敗しました: %s - 最後のエラー: %w", maxRetries, chapterURL, lastErr)
 }
 
-// scrapeChapterWithHTMLOnce は個別のエピソードの内容とHTML構造を1回だけ取得します
-func (a *App) scrapeChapterWithHTMLOnce(chapterURL string) (string, string, string, error) {
-	client := &http.Client{
-		Timeout: 10 * time.Second,
-		Transport: &http.Transport{
-			MaxIdleConns:       100,
-			IdleConnTimeout:    90 * time.Second,
-			DisableCompression: true,
-		},
-	}
+// scrapeEpisodeOnce は個別のエピソードの本文ブロックとHTML構造を1回だけ取得します
+func (a *App) scrapeEpisodeOnce(chapterURL string, revalidate bool) (episodePage, error) {
+	page := episodePage{}
 
-	req, err := http.NewRequest("GET", chapterURL, nil)
+	doc, cached, err := a.fetchPageCached(chapterURL, revalidate)
 	if err != nil {
-		return "", "", "", err
+		return page, err
 	}
+	page.Cached = cached
 
-	req.Header.Set("User-Agent", "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/136.0.0.0 Safari/537.36")
-
-	// ノクターンノベルズの年齢確認用Cookie
-	if strings.Contains(chapterURL, "novel18.syosetu.com") {
-		req.Header.Set("Cookie", "over18=yes")
-	}
-
-	resp, err := client.Do(req)
+	// 本文ブロックを取得
+	page.Blocks, err = extractBlocks(doc, chapterURL)
 	if err != nil {
-		return "", "", "", err
-	}
-	defer resp.Body.Close()
-
-	doc, err := goquery.NewDocumentFromReader(resp.Body)
-	if err != nil {
-		return "", "", "", err
-	}
-
-	// テキストコンテンツを取得
-	content, err := a.extractContent(doc)
-	if err != nil {
-		return "", "", "", err
+		return page, err
 	}
 
 	// HTML構造を取得
-	rawHTML, err := a.extractRawHTML(doc)
+	page.RawHTML, err = a.extractRawHTML(doc)
 	if err != nil {
-		return content, "", "", err
+		return page, err
 	}
 
 	// ページ全体のHTMLを取得
-	fullPageHTML, err := a.extractFullPageHTML(doc, chapterURL)
+	page.FullPageHTML, err = a.extractFullPageHTML(doc, chapterURL)
 	if err != nil {
-		return content, rawHTML, "", err
+		return page, err
 	}
 
-	return content, rawHTML, fullPageHTML, nil
+	return page, nil
 }
 
 // extractRawHTML は元のHTML構造を取得します
@@ -505,16 +417,8 @@ func (a *App) extractFullPageHTML(doc *goquery.Document, originalURL string) (st
 		return "", fmt.Errorf("ページ全体のHTML取得エラー: %w", err)
 	}
 
-	// URLから適切なベースURLを決定
-	var baseURL string
-	if strings.Contains(originalURL, "novel18.syosetu.com") {
-		baseURL = "https://novel18.syosetu.com"
-	} else {
-		baseURL = "https://ncode.syosetu.com"
-	}
-
 	// 相対パスを絶対パスに変換
-	html = a.convertRelativeToAbsolutePaths(html, baseURL)
+	html = a.convertRelativeToAbsolutePaths(html, a.site.baseURL(originalURL))
 
 	return html, nil
 }
@@ -0,0 +1,168 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestStartScraping(t *testing.T) {
+	fake := newFakeSyosetu(t)
+	fake.fail("/n1111aa/?p=2", 1)
+
+	tests := []struct {
+		name         string
+		url          string
+		wantType     string
+		wantTitle    string
+		wantAuthor   string
+		wantChapters []string // 章タイトル/話のタイトル
+		wantIndex    int      // 取得した目次のページ数
+		wantErr      string
+	}{
+		{
+			name:    "目次の2ページ目が503",
+			url:     fake.site.Novel + "/n1111aa/",
+			wantErr: "次のページの取得に失敗しました",
+		},
+		{
+			name:         "2ページの目次",
+			url:          fake.site.Novel + "/n1111aa/",
+			wantType:     "rensai",
+			wantTitle:    "テスト連載",
+			wantAuthor:   "テスト作者",
+			wantChapters: []string{"第一章　はじまり/一話　出会い", "第一章　はじまり/二話　旅立ち", "第二章　つづき/三話　到着"},
+			wantIndex:    2,
+		},
+		{
+			name:       "短編",
+			url:        fake.site.Novel + "/n2222bb/",
+			wantType:   "short",
+			wantTitle:  "テスト短編",
+			wantAuthor: "テスト作者",
+		},
+		{
+			name:         "R18の小説",
+			url:          fake.site.Novel18 + "/n3333cc/",
+			wantType:     "rensai",
+			wantTitle:    "テストR18連載",
+			wantAuthor:   "R18作者",
+			wantChapters: []string{"/一夜"},
+			wantIndex:    1,
+		},
+		{
+			name:    "削除された小説",
+			url:     fake.site.Novel + "/n9999zz/",
+			wantErr: "404 Not Found",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := fake.app().StartScraping(tt.url)
+			if tt.wantErr != "" {
+				if !strings.Contains(result.Error, tt.wantErr) {
+					t.Errorf("Error = %q, want %q を含む", result.Error, tt.wantErr)
+				}
+				return
+			}
+			if result.Error != "" {
+				t.Fatalf("Error = %q", result.Error)
+			}
+			if result.PageType != tt.wantType || result.Title != tt.wantTitle || result.Author != tt.wantAuthor {
+				t.Errorf("PageType, Title, Author = %q, %q, %q, want %q, %q, %q",
+					result.PageType, result.Title, result.Author, tt.wantType, tt.wantTitle, tt.wantAuthor)
+			}
+
+			var chapters []string
+			for _, chapter := range result.Chapters {
+				chapters = append(chapters, chapter.ChapterTitle+"/"+chapter.Title)
+				// 相対URLは目次と同じサイトのURLにする
+				if !strings.HasPrefix(chapter.URL, fake.site.baseURL(tt.url)+"/") {
+					t.Errorf("話のURL = %q", chapter.URL)
+				}
+			}
+			if strings.Join(chapters, ",") != strings.Join(tt.wantChapters, ",") {
+				t.Errorf("Chapters = %v, want %v", chapters, tt.wantChapters)
+			}
+			if len(result.IndexPagesHTML) != tt.wantIndex {
+				t.Errorf("len(IndexPagesHTML) = %d, want %d", len(result.IndexPagesHTML), tt.wantIndex)
+			}
+			if tt.wantType == "short" && renderAozora(result.Blocks) == "" {
+				t.Error("短編の本文を取得できませんでした")
+			}
+		})
+	}
+}
+
+func TestStartScraping_AgeGate(t *testing.T) {
+	fake := newFakeSyosetu(t)
+	app := fake.app()
+
+	// R18のサイトと判定できないURLには年齢確認のCookieを送らないため、年齢確認のページが返る
+	app.site.Novel18 = fake.site.Novel
+	app.site.Novel = fake.site.Novel18
+	result := app.StartScraping(fake.site.Novel18 + "/n3333cc/")
+	if result.Error != "不明なページタイプです" {
+		t.Errorf("Error = %q, want 年齢確認のページ", result.Error)
+	}
+}
+
+func TestScrapeEpisode(t *testing.T) {
+	tests := []struct {
+		name         string
+		setup        func(fake *fakeSyosetu, path string)
+		wantRequests int
+		wantErr      bool
+	}{
+		{
+			name:         "1回目で取得",
+			setup:        func(fake *fakeSyosetu, path string) {},
+			wantRequests: 1,
+		},
+		{
+			name:         "503の後にリトライして取得",
+			setup:        func(fake *fakeSyosetu, path string) { fake.fail(path, 2) },
+			wantRequests: 3,
+		},
+		{
+			name:         "応答が遅く時間切れになった後にリトライして取得",
+			setup:        func(fake *fakeSyosetu, path string) { fake.delay(path, time.Second) },
+			wantRequests: 2,
+		},
+		{
+			name:         "503が続く",
+			setup:        func(fake *fakeSyosetu, path string) { fake.fail(path, -1) },
+			wantRequests: 3,
+			wantErr:      true,
+		},
+	}
+
+	savedTimeout := httpClient.Timeout
+	httpClient.Timeout = 200 * time.Millisecond
+	t.Cleanup(func() { httpClient.Timeout = savedTimeout })
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := newFakeSyosetu(t)
+			tt.setup(fake, "/n1111aa/1/")
+
+			page, err := fake.app().scrapeEpisode(fake.site.Novel+"/n1111aa/1/", false)
+			if got := fake.count("/n1111aa/1/"); got != tt.wantRequests {
+				t.Errorf("リクエスト = %d回, want %d回", got, tt.wantRequests)
+			}
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("scrapeEpisode() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if text := renderAozora(page.Blocks); !strings.Contains(text, "少年") || !strings.Contains(text, "「おはよう」") {
+				t.Errorf("本文 = %q", text)
+			}
+			if !strings.Contains(page.FullPageHTML, fake.site.Novel+"/view/css/novel.css") {
+				t.Error("ページ全体のHTMLの相対パスが偽のサイトのURLになっていません")
+			}
+		})
+	}
+}
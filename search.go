@@ -0,0 +1,420 @@
+package main
+
+import (
+	"bytes"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/width"
+)
+
+// searchIndexVersion は検索用索引の形式のバージョン（変わった場合は索引を作り直す）
+const searchIndexVersion = 1
+
+// defaultSearchLimit は検索結果の件数の既定の上限
+const defaultSearchLimit = 100
+
+// snippetRunes は検索結果の前後に含める文字数
+const snippetRunes = 30
+
+// searchIndex は小説1件分の検索用索引を表す構造体
+// 文字と2文字組（バイグラム）ごとに、それを含む話の番号（Docs の位置）を記録する
+type searchIndex struct {
+	Version  int
+	Docs     []searchDoc
+	Postings map[string][]int32
+}
+
+// searchDoc は索引に登録された1話分の情報を表す構造体
+type searchDoc struct {
+	Number string
+	Hash   string
+}
+
+// SearchRequest は保存済みの小説を検索する際の指定を表す構造体
+type SearchRequest struct {
+	Folder string `json:"folder"` // 省略時は既定の保存先
+	Query  string `json:"query"`
+	NCode  string `json:"ncode"` // 指定した場合はその小説だけを検索する
+	Limit  int    `json:"limit"` // 省略時は100件
+}
+
+// SearchHit は検索に一致した1箇所を表す構造体
+type SearchHit struct {
+	Folder       string `json:"folder"`
+	NCode        string `json:"ncode"`
+	Title        string `json:"title"`
+	Number       string `json:"number"`
+	EpisodeTitle string `json:"episodeTitle"`
+	Paragraph    int    `json:"paragraph"` // 本文のブロックの位置
+	Offset       int    `json:"offset"`    // 段落内の文字位置
+	Snippet      string `json:"snippet"`
+	MatchStart   int    `json:"matchStart"` // Snippet 内で一致した部分の文字位置
+	MatchEnd     int    `json:"matchEnd"`
+}
+
+// SearchResult は検索結果を表す構造体
+type SearchResult struct {
+	Hits      []SearchHit `json:"hits"`
+	Truncated bool        `json:"truncated"` // 上限を超えたため打ち切った場合は true
+}
+
+// normalizeSearchRunes は検索用に文字列を正規化します（全角・半角の違いと大文字・小文字を区別しない）
+// 文字位置がずれないように1文字ずつ変換する
+func normalizeSearchRunes(text string) []rune {
+	runes := []rune(text)
+	for i, r := range runes {
+		if folded := width.LookupRune(r).Folded(); folded != 0 {
+			r = folded
+		}
+		runes[i] = unicode.ToLower(r)
+	}
+	return runes
+}
+
+// searchTerms は文字列に含まれる文字とバイグラムを重複なく返します
+func searchTerms(runes []rune) []string {
+	seen := make(map[string]bool)
+	var terms []string
+	add := func(term string) {
+		if !seen[term] {
+			seen[term] = true
+			terms = append(terms, term)
+		}
+	}
+	for i, r := range runes {
+		if unicode.IsSpace(r) {
+			continue
+		}
+		add(string(r))
+		if i+1 < len(runes) && !unicode.IsSpace(runes[i+1]) {
+			add(string(runes[i : i+2]))
+		}
+	}
+	return terms
+}
+
+// episodeSearchText は話の本文を段落ごとの検索用の文字列に変換します
+func episodeSearchText(blocks []Block) [][]rune {
+	texts := make([][]rune, len(blocks))
+	for i, block := range blocks {
+		if block.Kind == BlockParagraph {
+			texts[i] = normalizeSearchRunes(block.PlainText())
+		}
+	}
+	return texts
+}
+
+// searchIndexPath は検索用索引のパスを返します
+func (s *novelStore) searchIndexPath() string {
+	return filepath.Join(s.dir(), "search.gob")
+}
+
+// loadSearchIndex は検索用索引を読み込みます（存在しない場合や形式が古い場合は空の索引を返す）
+func (s *novelStore) loadSearchIndex() (*searchIndex, error) {
+	empty := &searchIndex{Version: searchIndexVersion, Postings: make(map[string][]int32)}
+	data, err := os.ReadFile(s.searchIndexPath())
+	if errors.Is(err, os.ErrNotExist) {
+		return empty, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("検索用索引の読み込みに失敗しました: %w", err)
+	}
+
+	var index searchIndex
+	if err := gob.NewDecoder(bytes.NewReader(data)).Decode(&index); err != nil || index.Version != searchIndexVersion {
+		// 壊れた索引は作り直す
+		return empty, nil
+	}
+	if index.Postings == nil {
+		index.Postings = make(map[string][]int32)
+	}
+	return &index, nil
+}
+
+// saveSearchIndex は検索用索引を保存します
+func (s *novelStore) saveSearchIndex(index *searchIndex) error {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(index); err != nil {
+		return fmt.Errorf("検索用索引の変換に失敗しました: %w", err)
+	}
+	if err := writeFileAtomic(s.searchIndexPath(), buf.Bytes(), s.tmpDir()); err != nil {
+		return fmt.Errorf("検索用索引の保存に失敗しました: %w", err)
+	}
+	return nil
+}
+
+// searchUpdate は索引に登録する1話分の本文を表す構造体
+type searchUpdate struct {
+	Number string
+	Hash   string
+	Blocks []Block
+}
+
+// update は話を索引に登録します（登録済みの場合は置き換える）
+func (idx *searchIndex) update(number, hash string, blocks []Block) {
+	idx.apply([]searchUpdate{{Number: number, Hash: hash, Blocks: blocks}}, nil)
+}
+
+// remove は話を索引から削除します
+func (idx *searchIndex) remove(number string) {
+	idx.apply(nil, []string{number})
+}
+
+// apply は複数の話の登録（登録済みの場合は置き換え）と削除をまとめて索引に反映します
+// 取り除く話はすべての文字・バイグラムから1回の走査で除き、追加した話の位置は最後にまとめて並べ直すため、
+// 1話ずつ反映する場合と違って話数が増えても全体の走査を繰り返しません
+func (idx *searchIndex) apply(updates []searchUpdate, removed []string) {
+	ids := make(map[string]int32, len(idx.Docs))
+	var free []int32 // 削除された話の位置（再利用する）
+	for i, doc := range idx.Docs {
+		if doc.Number == "" {
+			free = append(free, int32(i))
+		} else {
+			ids[doc.Number] = int32(i)
+		}
+	}
+
+	// 削除する話と置き換える話を取り除く
+	drop := make(map[int32]bool)
+	for _, number := range removed {
+		if id, ok := ids[number]; ok {
+			drop[id] = true
+			delete(ids, number)
+			idx.Docs[id] = searchDoc{}
+			free = append(free, id)
+		}
+	}
+	for _, update := range updates {
+		if id, ok := ids[update.Number]; ok {
+			drop[id] = true
+		}
+	}
+	if len(drop) > 0 {
+		for term, postings := range idx.Postings {
+			postings = slices.DeleteFunc(postings, func(id int32) bool { return drop[id] })
+			if len(postings) == 0 {
+				delete(idx.Postings, term)
+			} else {
+				idx.Postings[term] = postings
+			}
+		}
+	}
+	slices.Sort(free)
+
+	touched := make(map[string]bool)
+	for _, update := range updates {
+		id, ok := ids[update.Number]
+		if !ok {
+			if len(free) > 0 {
+				id, free = free[0], free[1:]
+			} else {
+				id = int32(len(idx.Docs))
+				idx.Docs = append(idx.Docs, searchDoc{})
+			}
+			ids[update.Number] = id
+		}
+		idx.Docs[id] = searchDoc{Number: update.Number, Hash: update.Hash}
+
+		seen := make(map[string]bool)
+		for _, text := range episodeSearchText(update.Blocks) {
+			for _, term := range searchTerms(text) {
+				if !seen[term] {
+					seen[term] = true
+					idx.Postings[term] = append(idx.Postings[term], id)
+					touched[term] = true
+				}
+			}
+		}
+	}
+	for term := range touched {
+		postings := idx.Postings[term]
+		slices.Sort(postings)
+		idx.Postings[term] = slices.Compact(postings)
+	}
+}
+
+// candidates は検索語のすべての文字・バイグラムを含む話の番号を返します
+// 実際に一致するかどうかは本文を確認する必要がある
+func (idx *searchIndex) candidates(query []rune) []string {
+	var ids []int32
+	for i, term := range searchTerms(query) {
+		postings := idx.Postings[term]
+		if i == 0 {
+			ids = slices.Clone(postings)
+			continue
+		}
+		ids = slices.DeleteFunc(ids, func(id int32) bool {
+			_, found := slices.BinarySearch(postings, id)
+			return !found
+		})
+	}
+
+	numbers := make([]string, 0, len(ids))
+	for _, id := range ids {
+		if number := idx.Docs[id].Number; number != "" {
+			numbers = append(numbers, number)
+		}
+	}
+	return numbers
+}
+
+// syncSearchIndex は索引をマニフェストに合わせて更新します
+// 索引のない小説や、索引の作成後に改稿・削除された話もここで反映する
+func (a *App) syncSearchIndex(store *novelStore, manifest *Manifest) (*searchIndex, error) {
+	index, err := store.loadSearchIndex()
+	if err != nil {
+		return nil, err
+	}
+
+	hashes := make(map[string]string, len(index.Docs))
+	for _, doc := range index.Docs {
+		if doc.Number != "" {
+			hashes[doc.Number] = doc.Hash
+		}
+	}
+
+	var updates []searchUpdate
+	for _, episode := range manifest.Episodes {
+		hash, ok := hashes[episode.Number]
+		delete(hashes, episode.Number)
+		if ok && hash == episode.Hash {
+			continue
+		}
+		blocks, err := store.loadBlocks(episode.Number)
+		if err != nil {
+			// 本文のキャッシュがない話は検索できない
+			continue
+		}
+		updates = append(updates, searchUpdate{Number: episode.Number, Hash: episode.Hash, Blocks: blocks})
+	}
+	// マニフェストにない話は削除された話
+	removed := make([]string, 0, len(hashes))
+	for number := range hashes {
+		removed = append(removed, number)
+	}
+
+	if len(updates) > 0 || len(removed) > 0 {
+		index.apply(updates, removed)
+		if err := store.saveSearchIndex(index); err != nil {
+			return nil, err
+		}
+	}
+	return index, nil
+}
+
+// flushSearchIndex はダウンロード・修復で保存した話をまとめて検索用索引に反映します
+// 索引の更新に失敗しても、検索時に作り直せるため保存は成功とします
+func (a *App) flushSearchIndex(store *novelStore, manifest *Manifest) {
+	if _, err := a.syncSearchIndex(store, manifest); err != nil {
+		a.emit("log", fmt.Sprintf("検索用索引を更新できませんでした: %v", err))
+	}
+}
+
+// SearchLibrary は保存済みの小説の本文を検索します（フロントエンド・CLI用）
+// 検索語は全体を1つの語句として扱い、全角・半角の違いと大文字・小文字は区別しません
+func (a *App) SearchLibrary(request SearchRequest) (SearchResult, error) {
+	query := normalizeSearchRunes(strings.TrimSpace(request.Query))
+	if len(query) == 0 {
+		return SearchResult{}, fmt.Errorf("検索語を入力してください")
+	}
+	limit := request.Limit
+	if limit <= 0 {
+		limit = defaultSearchLimit
+	}
+
+	folder := request.Folder
+	if folder == "" {
+		root, err := defaultSaveRoot()
+		if err != nil {
+			return SearchResult{}, err
+		}
+		folder = root
+	}
+	stores, err := findNovelStores(folder)
+	if err != nil {
+		return SearchResult{}, err
+	}
+
+	result := SearchResult{Hits: []SearchHit{}}
+	for _, store := range stores {
+		if request.NCode != "" && !strings.EqualFold(store.ncode, request.NCode) {
+			continue
+		}
+		manifest, err := store.loadManifest()
+		if err != nil {
+			a.emit("log", fmt.Sprintf("%s を読み込めませんでした: %v", store.dir(), err))
+			continue
+		}
+		index, err := a.syncSearchIndex(store, manifest)
+		if err != nil {
+			a.emit("log", fmt.Sprintf("%s の検索用索引を更新できませんでした: %v", manifest.Title, err))
+			continue
+		}
+
+		candidates := index.candidates(query)
+		// 掲載順に並べる
+		slices.SortFunc(candidates, func(x, y string) int {
+			return manifest.episode(x).Index - manifest.episode(y).Index
+		})
+		for _, number := range candidates {
+			blocks, err := store.loadBlocks(number)
+			if err != nil {
+				continue
+			}
+			record := manifest.episode(number)
+			for _, hit := range findInBlocks(blocks, query) {
+				if len(result.Hits) >= limit {
+					result.Truncated = true
+					return result, nil
+				}
+				hit.Folder = store.root
+				hit.NCode = manifest.NCode
+				hit.Title = manifest.Title
+				hit.Number = number
+				hit.EpisodeTitle = record.Title
+				result.Hits = append(result.Hits, hit)
+			}
+		}
+	}
+	return result, nil
+}
+
+// findInBlocks は本文から検索語が現れる位置をすべて探し、前後の文字列とともに返します
+func findInBlocks(blocks []Block, query []rune) []SearchHit {
+	var hits []SearchHit
+	for i, text := range episodeSearchText(blocks) {
+		if len(text) < len(query) {
+			continue
+		}
+		original := []rune(blocks[i].PlainText())
+		for offset := 0; offset+len(query) <= len(text); offset++ {
+			if !slices.Equal(text[offset:offset+len(query)], query) {
+				continue
+			}
+			start := max(offset-snippetRunes, 0)
+			end := min(offset+len(query)+snippetRunes, len(original))
+			hits = append(hits, SearchHit{
+				Paragraph:  i,
+				Offset:     offset,
+				Snippet:    string(original[start:end]),
+				MatchStart: offset - start,
+				MatchEnd:   offset - start + len(query),
+			})
+			offset += len(query) - 1
+		}
+	}
+	return hits
+}
+
+// highlightSnippet は検索結果の一致した部分を【】で囲んだ文字列を返します
+func highlightSnippet(hit SearchHit) string {
+	runes := []rune(hit.Snippet)
+	return string(runes[:hit.MatchStart]) + "【" + string(runes[hit.MatchStart:hit.MatchEnd]) + "】" + string(runes[hit.MatchEnd:])
+}
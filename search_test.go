@@ -0,0 +1,149 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"slices"
+	"testing"
+)
+
+func TestSearchIndex_Candidates(t *testing.T) {
+	paragraph := func(text string) []Block {
+		return []Block{{Kind: BlockParagraph, Inlines: []Inline{{Kind: InlineText, Text: text}}}}
+	}
+	index := &searchIndex{Version: searchIndexVersion, Postings: make(map[string][]int32)}
+	index.update("1", "a", paragraph("勇者は魔王を倒した"))
+	index.update("2", "b", paragraph("魔法使いは王都へ向かった"))
+	index.update("3", "c", paragraph("ＡＢＣの話"))
+
+	tests := []struct {
+		name     string
+		query    string
+		expected []string
+	}{
+		{name: "バイグラム", query: "魔王", expected: []string{"1"}},
+		{name: "1文字", query: "魔", expected: []string{"1", "2"}},
+		{name: "一部の文字だけ一致", query: "魔王都", expected: []string{}},
+		{name: "全角・半角と大文字・小文字", query: "abc", expected: []string{"3"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := index.candidates(normalizeSearchRunes(tt.query))
+			if !slices.Equal(got, tt.expected) {
+				t.Errorf("candidates(%q) = %v, want %v", tt.query, got, tt.expected)
+			}
+		})
+	}
+
+	// 改稿と削除の反映
+	index.update("1", "d", paragraph("勇者は旅に出た"))
+	index.remove("2")
+	if got := index.candidates(normalizeSearchRunes("魔")); len(got) != 0 {
+		t.Errorf("更新後の candidates(魔) = %v, want []", got)
+	}
+	index.update("4", "e", paragraph("魔王の城"))
+	if got := index.candidates(normalizeSearchRunes("魔王")); !slices.Equal(got, []string{"4"}) {
+		t.Errorf("追加後の candidates(魔王) = %v, want [4]", got)
+	}
+}
+
+func TestSearchIndex_Apply(t *testing.T) {
+	paragraph := func(text string) []Block {
+		return []Block{{Kind: BlockParagraph, Inlines: []Inline{{Kind: InlineText, Text: text}}}}
+	}
+	var first []searchUpdate
+	for i := 1; i <= 50; i++ {
+		first = append(first, searchUpdate{Number: fmt.Sprint(i), Hash: "a", Blocks: paragraph(fmt.Sprintf("第%d話の本文", i))})
+	}
+	second := []searchUpdate{
+		{Number: "3", Hash: "b", Blocks: paragraph("改稿した本文")},
+		{Number: "51", Hash: "a", Blocks: paragraph("追加した話")},
+	}
+
+	// 1話ずつ反映した索引と、まとめて反映した索引は同じになる
+	oneByOne := &searchIndex{Version: searchIndexVersion, Postings: make(map[string][]int32)}
+	for _, update := range first {
+		oneByOne.update(update.Number, update.Hash, update.Blocks)
+	}
+	oneByOne.remove("10")
+	for _, update := range second {
+		oneByOne.update(update.Number, update.Hash, update.Blocks)
+	}
+
+	batch := &searchIndex{Version: searchIndexVersion, Postings: make(map[string][]int32)}
+	batch.apply(first, nil)
+	batch.apply(second, []string{"10"})
+
+	if !reflect.DeepEqual(batch, oneByOne) {
+		t.Errorf("まとめて反映した索引が1話ずつ反映した索引と異なります")
+	}
+	if got := batch.candidates(normalizeSearchRunes("追加")); !slices.Equal(got, []string{"51"}) {
+		t.Errorf("candidates(追加) = %v, want [51]", got)
+	}
+	if batch.Docs[9].Number != "51" {
+		t.Errorf("削除した10話の位置 = %q, want 51話で再利用", batch.Docs[9].Number)
+	}
+}
+
+func TestSearchLibrary(t *testing.T) {
+	app, store, novel := newVerifiedNovelStore(t)
+
+	result, err := app.SearchLibrary(SearchRequest{Folder: store.root, Query: "本文二"})
+	if err != nil {
+		t.Fatalf("SearchLibrary() error = %v", err)
+	}
+	if len(result.Hits) != 1 {
+		t.Fatalf("Hits = %+v, want 1件", result.Hits)
+	}
+	hit := result.Hits[0]
+	if hit.NCode != "N1234AB" || hit.Number != "2" || hit.EpisodeTitle != "二話" || hit.Paragraph != 0 || hit.Offset != 0 {
+		t.Errorf("Hits[0] = %+v", hit)
+	}
+	if got := highlightSnippet(hit); got != "【本文二】" {
+		t.Errorf("highlightSnippet() = %q, want %q", got, "【本文二】")
+	}
+
+	// 保存した話は次の検索の前に索引に反映される
+	manifest, _ := store.loadManifest()
+	episode := novel.Episodes()[2]
+	episode.Blocks = []Block{{Kind: BlockParagraph, Inlines: []Inline{{Kind: InlineText, Text: "本文三に本文を追記"}}}}
+	if err := app.saveEpisode(store, manifest, novel, episode, nil); err != nil {
+		t.Fatalf("saveEpisode() error = %v", err)
+	}
+	result, err = app.SearchLibrary(SearchRequest{Folder: store.root, Query: "本文"})
+	if err != nil {
+		t.Fatalf("SearchLibrary() error = %v", err)
+	}
+	var positions []string
+	for _, hit := range result.Hits {
+		positions = append(positions, fmt.Sprintf("%s:%d", hit.Number, hit.Offset))
+	}
+	if !slices.Equal(positions, []string{"1:0", "2:0", "3:0", "3:4"}) {
+		t.Errorf("Hits = %v, want [1:0 2:0 3:0 3:4]", positions)
+	}
+
+	// 上限を超えた場合は打ち切る
+	result, err = app.SearchLibrary(SearchRequest{Folder: store.root, Query: "本文", Limit: 2})
+	if err != nil {
+		t.Fatalf("SearchLibrary() error = %v", err)
+	}
+	if len(result.Hits) != 2 || !result.Truncated {
+		t.Errorf("len(Hits), Truncated = %d, %v, want 2, true", len(result.Hits), result.Truncated)
+	}
+
+	// 索引がなくても本文のキャッシュから作り直す
+	os.Remove(store.searchIndexPath())
+	result, err = app.SearchLibrary(SearchRequest{Folder: store.root, Query: "追記"})
+	if err != nil {
+		t.Fatalf("SearchLibrary() error = %v", err)
+	}
+	if len(result.Hits) != 1 || result.Hits[0].Number != "3" {
+		t.Errorf("Hits = %+v, want 3話", result.Hits)
+	}
+
+	if _, err := app.SearchLibrary(SearchRequest{Folder: store.root, Query: "  "}); err == nil {
+		t.Errorf("空の検索語で SearchLibrary() がエラーになりません")
+	}
+}
@@ -0,0 +1,346 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// settingsVersion は設定ファイルの現在の形式のバージョン
+// 0: バージョンを記録していなかった形式（出力形式は真偽値または一覧）、1: プロファイル・定期確認・送信先・フック・通知・キャッシュの追加
+const settingsVersion = 1
+
+// settingsFileName は設定ファイルの名前
+const settingsFileName = "settings.json"
+
+// configDirName はユーザーの設定ディレクトリ内に作るディレクトリの名前
+const configDirName = "narou_download"
+
+// portableMarkerName は実行ファイルと同じディレクトリに置くとポータブルモードになるファイルの名前
+const portableMarkerName = "portable"
+
+// portableEnv は値が設定されているとポータブルモードになる環境変数の名前
+const portableEnv = "NAROU_DOWNLOAD_PORTABLE"
+
+// Settings はアプリケーションの設定を表す構造体
+type Settings struct {
+	Version       int                  `json:"version"`
+	URL           string               `json:"url"`
+	SavePath      string               `json:"savePath"`
+	Formats       []FormatRequest      `json:"formats"`
+	ShowInFront   bool                 `json:"showInFront"`
+	Profiles      []Profile            `json:"profiles"`
+	ActiveProfile string               `json:"activeProfile"` // 最後に選択したプロファイルの名前
+	Schedule      ScheduleSettings     `json:"schedule"`
+	Deliveries    []DeliveryTarget     `json:"deliveries"` // ダウンロード後にファイルを送る電子書籍リーダーなど
+	Hooks         []Hook               `json:"hooks"`      // ダウンロード後に実行する外部のコマンド
+	Notifications NotificationSettings `json:"notifications"`
+	Cache         CacheSettings        `json:"cache"` // 取得したページのキャッシュとオフラインモード
+}
+
+// Profile は名前を付けて保存した出力形式の組み合わせを表す構造体
+// 「電子書籍リーダー用（Shift-JIS・CR+LF）」「保存用（UTF-8・LF）」などをダウンロードごとに切り替えられる
+type Profile struct {
+	Name      string          `json:"name"`
+	Formats   []FormatRequest `json:"formats"`
+	SavePath  string          `json:"savePath"`  // 空の場合は画面で指定した保存先を使う
+	SkipHooks bool            `json:"skipHooks"` // このプロファイルでダウンロードした場合はフックを実行しない
+}
+
+// legacySettings は出力形式を真偽値で保存していた旧形式の設定を表す構造体
+type legacySettings struct {
+	Encoding       string `json:"encoding"`
+	LineEnding     string `json:"lineEnding"`
+	CreateTxt      bool   `json:"createTxt"`
+	CreateCombined bool   `json:"createCombined"`
+}
+
+// settingsMigrations はバージョンごとに設定を1つ新しい形式に変換する処理の一覧
+// settingsMigrations[n] はバージョン n の設定をバージョン n+1 に変換する
+var settingsMigrations = []func(settings *Settings, data []byte) error{
+	// 0 → 1: 出力形式を一覧にしてプロファイルとして残し、追加した設定に既定値を入れる
+	func(settings *Settings, data []byte) error {
+		// 出力形式を真偽値で保存していた設定は出力形式の一覧に変換する
+		if settings.Formats == nil {
+			var legacy legacySettings
+			if err := json.Unmarshal(data, &legacy); err != nil {
+				return err
+			}
+			settings.Formats = legacyFormats(legacy.Encoding, legacy.LineEnding, legacy.CreateTxt, legacy.CreateCombined)
+		}
+		// それまでの出力形式を最初のプロファイルとして残す
+		if len(settings.Formats) > 0 {
+			settings.Profiles = []Profile{{Name: "既定", Formats: settings.Formats}}
+			settings.ActiveProfile = "既定"
+		}
+		// 定期確認は無効のまま既定の間隔を設定し、デスクトップ通知だけを有効にし、キャッシュは既定の有効期限で有効にする
+		settings.Schedule = defaultScheduleSettings()
+		settings.Deliveries = []DeliveryTarget{}
+		settings.Hooks = []Hook{}
+		settings.Notifications = defaultNotificationSettings()
+		settings.Cache = defaultCacheSettings()
+		return nil
+	},
+}
+
+// defaultSettings は設定ファイルがない場合の設定を返します
+func defaultSettings() Settings {
+	return Settings{Version: settingsVersion, Formats: defaultFormats(), Profiles: []Profile{}, Schedule: defaultScheduleSettings(), Deliveries: []DeliveryTarget{}, Hooks: []Hook{}, Notifications: defaultNotificationSettings(), Cache: defaultCacheSettings()}
+}
+
+// isPortable はポータブルモード（実行ファイルと同じディレクトリに設定を保存する）かどうかを返します
+func isPortable(exeDir string) bool {
+	if os.Getenv(portableEnv) != "" {
+		return true
+	}
+	_, err := os.Stat(filepath.Join(exeDir, portableMarkerName))
+	return err == nil
+}
+
+// configDir は設定ファイルを保存するディレクトリを返します
+// 通常はユーザーの設定ディレクトリ（Windowsでは %AppData%）を使い、
+// ポータブルモードの場合は実行ファイルと同じディレクトリを使います
+func configDir() (string, error) {
+	exePath, err := os.Executable()
+	if err != nil {
+		return "", fmt.Errorf("実行ファイルのパスを取得できませんでした: %w", err)
+	}
+	exeDir := filepath.Dir(exePath)
+	if isPortable(exeDir) {
+		return exeDir, nil
+	}
+
+	userDir, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("設定ディレクトリを取得できませんでした: %w", err)
+	}
+	return filepath.Join(userDir, configDirName), nil
+}
+
+// legacySettingsPath は以前のバージョンが設定を保存していたパス（実行ファイルと同じディレクトリ）を返します
+func legacySettingsPath() string {
+	exePath, err := os.Executable()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(filepath.Dir(exePath), settingsFileName)
+}
+
+// clone は一覧をコピーした設定を返します（返した設定を変更しても元の設定は変わらない）
+func (s Settings) clone() Settings {
+	s.Formats = slices.Clone(s.Formats)
+	s.Profiles = slices.Clone(s.Profiles)
+	s.Deliveries = slices.Clone(s.Deliveries)
+	s.Hooks = slices.Clone(s.Hooks)
+	s.Notifications.Events = slices.Clone(s.Notifications.Events)
+	s.Notifications.Webhooks = slices.Clone(s.Notifications.Webhooks)
+	return s
+}
+
+// currentSettings は現在の設定のコピーを返します
+// 定期確認やAPIサーバーのゴルーチンからも呼ばれるため、a.settings は直接読まずにこれを使います
+func (a *App) currentSettings() Settings {
+	a.settingsMu.RLock()
+	defer a.settingsMu.RUnlock()
+	return a.settings.clone()
+}
+
+// updateSettings は現在の設定を変更して保存し、変更後の設定を返します
+// 変更から保存までロックするため、同時に変更しても一方の変更が失われません
+func (a *App) updateSettings(update func(settings *Settings)) (Settings, error) {
+	a.settingsMu.Lock()
+	defer a.settingsMu.Unlock()
+	settings := a.settings.clone()
+	update(&settings)
+	return settings, a.saveSettingsLocked(settings)
+}
+
+// SaveSettings は設定をJSONファイルに保存します
+func (a *App) SaveSettings(settings Settings) error {
+	a.settingsMu.Lock()
+	defer a.settingsMu.Unlock()
+	return a.saveSettingsLocked(settings.clone())
+}
+
+// saveSettingsLocked は設定を反映して保存します（settingsMu をロックして呼び出します）
+func (a *App) saveSettingsLocked(settings Settings) error {
+	a.settings = settings
+	responseCache.configure(settings.Cache)
+	dir, err := configDir()
+	if err != nil {
+		return err
+	}
+	return saveSettingsFile(filepath.Join(dir, settingsFileName), settings)
+}
+
+// LoadSettings はJSONファイルから設定を読み込みます
+// 設定ディレクトリに設定がない場合は、以前のバージョンが実行ファイルの隣に保存した設定を引き継ぎます
+func (a *App) LoadSettings() (Settings, error) {
+	dir, err := configDir()
+	if err != nil {
+		return Settings{}, err
+	}
+
+	path := filepath.Join(dir, settingsFileName)
+	settings, err := loadSettingsFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		if legacy := legacySettingsPath(); legacy != "" && legacy != path {
+			settings, err = loadSettingsFile(legacy)
+			if err == nil {
+				a.emit("log", fmt.Sprintf("以前の設定を引き継ぎました: %s", legacy))
+				if err := saveSettingsFile(path, settings); err != nil {
+					a.emit("log", err.Error())
+				}
+			}
+		}
+	}
+	if errors.Is(err, os.ErrNotExist) {
+		// 設定ファイルが存在しない場合はデフォルト値を返す
+		settings, err = defaultSettings(), nil
+	}
+	if err != nil {
+		return Settings{}, err
+	}
+
+	a.settingsMu.Lock()
+	a.settings = settings.clone()
+	a.settingsMu.Unlock()
+	responseCache.configure(settings.Cache)
+	return settings, nil
+}
+
+// loadSettingsFile は設定ファイルを読み込み、現在の形式に変換します
+func loadSettingsFile(path string) (Settings, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return Settings{}, err
+		}
+		return Settings{}, fmt.Errorf("設定の読み込みに失敗しました: %w", err)
+	}
+	return migrateSettings(data)
+}
+
+// saveSettingsFile は設定を現在の形式で保存します
+func saveSettingsFile(path string, settings Settings) error {
+	settings.Version = settingsVersion
+	if settings.Profiles == nil {
+		settings.Profiles = []Profile{}
+	}
+	if settings.Deliveries == nil {
+		settings.Deliveries = []DeliveryTarget{}
+	}
+	if settings.Hooks == nil {
+		settings.Hooks = []Hook{}
+	}
+	if settings.Notifications.Events == nil {
+		settings.Notifications.Events = []string{}
+	}
+	if settings.Notifications.Webhooks == nil {
+		settings.Notifications.Webhooks = []Webhook{}
+	}
+	data, err := json.MarshalIndent(settings, "", "  ")
+	if err != nil {
+		return fmt.Errorf("設定のJSON変換に失敗しました: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("設定ディレクトリの作成に失敗しました: %w", err)
+	}
+	if err := writeFileAtomic(path, data, ""); err != nil {
+		return fmt.Errorf("設定の保存に失敗しました: %w", err)
+	}
+	return nil
+}
+
+// migrateSettings は設定ファイルの内容を解析し、古い形式の場合は順に変換します
+func migrateSettings(data []byte) (Settings, error) {
+	var settings Settings
+	if err := json.Unmarshal(data, &settings); err != nil {
+		return Settings{}, fmt.Errorf("設定のJSON解析に失敗しました: %w", err)
+	}
+	if settings.Version > settingsVersion {
+		return Settings{}, fmt.Errorf("設定ファイルは新しいバージョンのアプリで作成されています（形式 %d）", settings.Version)
+	}
+
+	for settings.Version < settingsVersion {
+		if err := settingsMigrations[settings.Version](&settings, data); err != nil {
+			return Settings{}, fmt.Errorf("設定の変換に失敗しました（形式 %d）: %w", settings.Version, err)
+		}
+		settings.Version++
+	}
+
+	if settings.Profiles == nil {
+		settings.Profiles = []Profile{}
+	}
+	if settings.Deliveries == nil {
+		settings.Deliveries = []DeliveryTarget{}
+	}
+	if settings.Hooks == nil {
+		settings.Hooks = []Hook{}
+	}
+	if settings.Notifications.Events == nil {
+		settings.Notifications.Events = []string{}
+	}
+	if settings.Notifications.Webhooks == nil {
+		settings.Notifications.Webhooks = []Webhook{}
+	}
+	return settings, nil
+}
+
+// findProfile は名前からプロファイルを探します
+func (s Settings) findProfile(name string) (Profile, bool) {
+	i := slices.IndexFunc(s.Profiles, func(p Profile) bool { return p.Name == name })
+	if i < 0 {
+		return Profile{}, false
+	}
+	return s.Profiles[i], true
+}
+
+// SaveProfile はプロファイルを追加または上書きし、設定を返します（フロントエンド用）
+func (a *App) SaveProfile(profile Profile) (Settings, error) {
+	profile.Name = strings.TrimSpace(profile.Name)
+	if profile.Name == "" {
+		return a.currentSettings(), fmt.Errorf("プロファイルの名前を入力してください")
+	}
+	if _, _, err := buildWriters(profile.Formats); err != nil {
+		return a.currentSettings(), fmt.Errorf("プロファイル %s の出力形式が正しくありません: %w", profile.Name, err)
+	}
+
+	return a.updateSettings(func(settings *Settings) {
+		if i := slices.IndexFunc(settings.Profiles, func(p Profile) bool { return p.Name == profile.Name }); i >= 0 {
+			settings.Profiles[i] = profile
+		} else {
+			settings.Profiles = append(settings.Profiles, profile)
+		}
+		settings.ActiveProfile = profile.Name
+	})
+}
+
+// DeleteProfile はプロファイルを削除し、設定を返します（フロントエンド用）
+func (a *App) DeleteProfile(name string) (Settings, error) {
+	return a.updateSettings(func(settings *Settings) {
+		settings.Profiles = slices.DeleteFunc(settings.Profiles, func(p Profile) bool { return p.Name == name })
+		if settings.ActiveProfile == name {
+			settings.ActiveProfile = ""
+		}
+	})
+}
+
+// DownloadNovelWithProfile はプロファイルの出力形式と保存先で小説をダウンロードします
+// プロファイルに保存先がない場合は savePath を使います
+func (a *App) DownloadNovelWithProfile(url, savePath, profileName string) error {
+	profile, ok := a.currentSettings().findProfile(profileName)
+	if !ok {
+		return fmt.Errorf("プロファイルが見つかりません: %s", profileName)
+	}
+	if profile.SavePath != "" {
+		savePath = profile.SavePath
+	}
+	return a.downloadNovel(context.Background(), url, savePath, profile.Formats, profile.Name)
+}
@@ -0,0 +1,227 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+// useTempConfigDir はユーザーの設定ディレクトリを一時ディレクトリに置き換えます
+func useTempConfigDir(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", dir)
+	t.Setenv("HOME", dir)
+	t.Setenv("AppData", dir)
+	t.Setenv("XDG_CACHE_HOME", dir)
+	t.Setenv("LocalAppData", dir)
+	t.Setenv(portableEnv, "")
+	// 設定の読み込みで有効になったキャッシュをほかのテストに残さない
+	t.Cleanup(func() { responseCache.configure(CacheSettings{}) })
+	return dir
+}
+
+func TestMigrateSettings(t *testing.T) {
+	tests := []struct {
+		name           string
+		data           string
+		wantFormats    []string
+		wantProfiles   int
+		wantInterval   string
+		wantDeliveries int
+		wantHooks      int
+		wantDesktop    bool
+		wantEpisodeTTL string
+		wantErr        bool
+	}{
+		{
+			name:           "真偽値で保存していた形式",
+			data:           `{"url":"https://ncode.syosetu.com/n1234ab/","encoding":"Shift-JIS","lineEnding":"CR+LF","createTxt":true,"createCombined":true}`,
+			wantFormats:    []string{"txt"},
+			wantProfiles:   1,
+			wantInterval:   "6h",
+			wantEpisodeTTL: "24h",
+			wantDesktop:    true,
+		},
+		{
+			name:           "出力形式の一覧（バージョンなし）",
+			data:           `{"formats":[{"name":"epub"}]}`,
+			wantFormats:    []string{"epub"},
+			wantProfiles:   1,
+			wantInterval:   "6h",
+			wantEpisodeTTL: "24h",
+			wantDesktop:    true,
+		},
+		{
+			name:           "出力形式が空の一覧（バージョンなし）",
+			data:           `{"formats":[]}`,
+			wantFormats:    []string{},
+			wantProfiles:   0,
+			wantInterval:   "6h",
+			wantEpisodeTTL: "24h",
+			wantDesktop:    true,
+		},
+		{
+			name:           "現在の形式",
+			data:           `{"version":1,"formats":[{"name":"epub"}],"profiles":[{"name":"確認用","skipHooks":true}],"schedule":{"interval":"1h"},"deliveries":[{"name":"Kindle","kind":"email"}],"hooks":[{"name":"AozoraEpub3","command":"echo"}],"notifications":{"desktop":false,"webhooks":[]},"cache":{"enabled":true,"offline":true,"tocTTL":"0s","episodeTTL":"1h"}}`,
+			wantFormats:    []string{"epub"},
+			wantProfiles:   1,
+			wantInterval:   "1h",
+			wantDeliveries: 1,
+			wantHooks:      1,
+			wantEpisodeTTL: "1h",
+		},
+		{
+			name:           "現在の形式（一覧を省略）",
+			data:           `{"version":1,"formats":[{"name":"epub"}],"schedule":{"interval":"1h"},"notifications":{"desktop":true},"cache":{"episodeTTL":"1h"}}`,
+			wantFormats:    []string{"epub"},
+			wantInterval:   "1h",
+			wantDesktop:    true,
+			wantEpisodeTTL: "1h",
+		},
+		{
+			name:    "新しいバージョンの形式",
+			data:    `{"version":99}`,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			settings, err := migrateSettings([]byte(tt.data))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("migrateSettings() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if settings.Version != settingsVersion {
+				t.Errorf("Version = %d, want %d", settings.Version, settingsVersion)
+			}
+			if len(settings.Formats) != len(tt.wantFormats) {
+				t.Fatalf("Formats = %+v, want %v", settings.Formats, tt.wantFormats)
+			}
+			for i, name := range tt.wantFormats {
+				if settings.Formats[i].Name != name {
+					t.Errorf("Formats[%d].Name = %s, want %s", i, settings.Formats[i].Name, name)
+				}
+			}
+			if len(settings.Profiles) != tt.wantProfiles {
+				t.Errorf("len(Profiles) = %d, want %d", len(settings.Profiles), tt.wantProfiles)
+			}
+			if settings.Schedule.Interval != tt.wantInterval {
+				t.Errorf("Schedule.Interval = %q, want %q", settings.Schedule.Interval, tt.wantInterval)
+			}
+			if settings.Deliveries == nil || len(settings.Deliveries) != tt.wantDeliveries {
+				t.Errorf("Deliveries = %+v, want %d件", settings.Deliveries, tt.wantDeliveries)
+			}
+			if settings.Hooks == nil || len(settings.Hooks) != tt.wantHooks {
+				t.Errorf("Hooks = %+v, want %d件", settings.Hooks, tt.wantHooks)
+			}
+			if settings.Notifications.Desktop != tt.wantDesktop || settings.Notifications.Webhooks == nil {
+				t.Errorf("Notifications = %+v, want Desktop %v", settings.Notifications, tt.wantDesktop)
+			}
+			if settings.Cache.EpisodeTTL != tt.wantEpisodeTTL {
+				t.Errorf("Cache.EpisodeTTL = %q, want %q", settings.Cache.EpisodeTTL, tt.wantEpisodeTTL)
+			}
+		})
+	}
+}
+
+func TestConfigDir(t *testing.T) {
+	dir := useTempConfigDir(t)
+	got, err := configDir()
+	if err != nil {
+		t.Fatalf("configDir() error = %v", err)
+	}
+	userDir, _ := os.UserConfigDir()
+	if got != filepath.Join(userDir, configDirName) || !filepath.IsAbs(got) {
+		t.Errorf("configDir() = %s, want %s 以下", got, dir)
+	}
+
+	// ポータブルモードでは実行ファイルと同じディレクトリを使う
+	t.Setenv(portableEnv, "1")
+	got, err = configDir()
+	if err != nil {
+		t.Fatalf("configDir() error = %v", err)
+	}
+	exePath, _ := os.Executable()
+	if got != filepath.Dir(exePath) {
+		t.Errorf("ポータブルモードの configDir() = %s, want %s", got, filepath.Dir(exePath))
+	}
+}
+
+func TestSettingsProfiles(t *testing.T) {
+	useTempConfigDir(t)
+	app := NewApp()
+
+	settings, err := app.LoadSettings()
+	if err != nil {
+		t.Fatalf("LoadSettings() error = %v", err)
+	}
+	if len(settings.Formats) == 0 || settings.Version != settingsVersion {
+		t.Errorf("既定の設定 = %+v", settings)
+	}
+
+	reader := Profile{Name: "電子書籍リーダー", Formats: []FormatRequest{{Name: "txt", Options: map[string]interface{}{"encoding": "Shift-JIS", "lineEnding": "CR+LF"}}}}
+	archive := Profile{Name: "保存用", Formats: []FormatRequest{{Name: "txt", Options: map[string]interface{}{"encoding": "UTF-8", "lineEnding": "LF"}}}}
+	for _, profile := range []Profile{reader, archive} {
+		if _, err := app.SaveProfile(profile); err != nil {
+			t.Fatalf("SaveProfile(%s) error = %v", profile.Name, err)
+		}
+	}
+	if _, err := app.SaveProfile(Profile{Name: "誤り", Formats: []FormatRequest{{Name: "docx"}}}); err == nil {
+		t.Errorf("不明な出力形式で SaveProfile() がエラーになりません")
+	}
+	if _, err := app.DeleteProfile("電子書籍リーダー"); err != nil {
+		t.Fatalf("DeleteProfile() error = %v", err)
+	}
+
+	// 保存した設定を読み直す
+	settings, err = NewApp().LoadSettings()
+	if err != nil {
+		t.Fatalf("LoadSettings() error = %v", err)
+	}
+	if len(settings.Profiles) != 1 || settings.Profiles[0].Name != "保存用" || settings.ActiveProfile != "保存用" {
+		t.Errorf("Profiles, ActiveProfile = %+v, %s, want 保存用", settings.Profiles, settings.ActiveProfile)
+	}
+	if _, ok := settings.findProfile("保存用"); !ok {
+		t.Errorf("findProfile(保存用) が見つかりません")
+	}
+
+	if err := app.DownloadNovelWithProfile("https://ncode.syosetu.com/n1234ab/", "", "電子書籍リーダー"); err == nil {
+		t.Errorf("削除したプロファイルで DownloadNovelWithProfile() がエラーになりません")
+	}
+}
+
+func TestSettingsConcurrentUpdates(t *testing.T) {
+	useTempConfigDir(t)
+	app := NewApp()
+	if _, err := app.LoadSettings(); err != nil {
+		t.Fatalf("LoadSettings() error = %v", err)
+	}
+
+	// 同時に変更しても、どの変更も失われない
+	var wg sync.WaitGroup
+	for i := range 10 {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			profile := Profile{Name: fmt.Sprintf("プロファイル%d", i), Formats: []FormatRequest{{Name: "txt"}}}
+			if _, err := app.SaveProfile(profile); err != nil {
+				t.Errorf("SaveProfile(%s) error = %v", profile.Name, err)
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			app.currentSettings().findProfile("既定")
+		}()
+	}
+	wg.Wait()
+
+	if got := len(app.currentSettings().Profiles); got != 10 {
+		t.Errorf("len(Profiles) = %d, want 10", got)
+	}
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"net/url"
+	"regexp"
+)
+
+// syosetuSite はなろうの各サイトのURLを表す構造体
+// テストでは httptest の偽のサイトのURLに置き換えて、実際のサーバーに接続せずに取得の処理を確認します
+type syosetuSite struct {
+	Novel   string // 小説家になろう
+	Novel18 string // ノクターンノベルズなどのR18の小説
+	API     string // なろう小説API・R18小説API
+}
+
+// defaultSite は実際のなろうのサイトのURL
+var defaultSite = syosetuSite{
+	Novel:   "https://ncode.syosetu.com",
+	Novel18: "https://novel18.syosetu.com",
+	API:     "https://api.syosetu.com",
+}
+
+// isR18 はURLがR18のサイトのページかどうかを返します
+func (s syosetuSite) isR18(rawURL string) bool {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return false
+	}
+	r18, err := url.Parse(s.Novel18)
+	return err == nil && u.Host == r18.Host
+}
+
+// baseURL はURLのページがあるサイトのURL（末尾の / なし）を返します
+func (s syosetuSite) baseURL(rawURL string) string {
+	if s.isR18(rawURL) {
+		return s.Novel18
+	}
+	return s.Novel
+}
+
+// isNovelURL はURLが小説家になろう（R18のサイトを含む）のページかどうかを返します
+// ホスト名で判定するため、ほかのサイトのURLのパスやクエリになろうのドメインが含まれていても一致しません
+func (s syosetuSite) isNovelURL(rawURL string) bool {
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return false
+	}
+	site, err := url.Parse(s.baseURL(rawURL))
+	return err == nil && u.Host == site.Host
+}
+
+// novelAPI は小説の情報を取得するAPIのURLを返します（r18 が true の場合はR18小説API）
+func (s syosetuSite) novelAPI(r18 bool) string {
+	if r18 {
+		return s.API + "/novel18api/api/"
+	}
+	return s.API + "/novelapi/api/"
+}
+
+// indexURL は各話のURLを小説の目次のURLに変換します（各話のURLでない場合はそのまま返す）
+func (s syosetuSite) indexURL(rawURL string) string {
+	for _, base := range []string{s.Novel, s.Novel18} {
+		pattern := regexp.MustCompile(`^(` + regexp.QuoteMeta(base) + `/n[0-9]+[a-z]+)/([0-9]+)/?$`)
+		if matches := pattern.FindStringSubmatch(rawURL); len(matches) >= 2 {
+			return matches[1] + "/"
+		}
+	}
+	return rawURL
+}
@@ -0,0 +1,34 @@
+package main
+
+import "testing"
+
+func TestSyosetuSite(t *testing.T) {
+	tests := []struct {
+		url       string
+		wantNovel bool
+		wantR18   bool
+		wantIndex string
+	}{
+		{url: "https://ncode.syosetu.com/n1234ab/", wantNovel: true, wantIndex: "https://ncode.syosetu.com/n1234ab/"},
+		{url: "https://ncode.syosetu.com/n1234ab/12/", wantNovel: true, wantIndex: "https://ncode.syosetu.com/n1234ab/"},
+		{url: "https://novel18.syosetu.com/n1234ab/3", wantNovel: true, wantR18: true, wantIndex: "https://novel18.syosetu.com/n1234ab/"},
+		{url: "https://example.com/n1234ab/3/", wantIndex: "https://example.com/n1234ab/3/"},
+		{url: "https://example.com/?next=ncode.syosetu.com", wantIndex: "https://example.com/?next=ncode.syosetu.com"},
+		{url: "https://ncode.syosetu.com.example.com/n1234ab/", wantIndex: "https://ncode.syosetu.com.example.com/n1234ab/"},
+		{url: "ftp://ncode.syosetu.com/n1234ab/", wantIndex: "ftp://ncode.syosetu.com/n1234ab/"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.url, func(t *testing.T) {
+			if got := defaultSite.isNovelURL(tt.url); got != tt.wantNovel {
+				t.Errorf("isNovelURL() = %v, want %v", got, tt.wantNovel)
+			}
+			if got := defaultSite.isR18(tt.url); got != tt.wantR18 {
+				t.Errorf("isR18() = %v, want %v", got, tt.wantR18)
+			}
+			if got := defaultSite.indexURL(tt.url); got != tt.wantIndex {
+				t.Errorf("indexURL() = %q, want %q", got, tt.wantIndex)
+			}
+		})
+	}
+}
@@ -0,0 +1,413 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	goruntime "runtime"
+)
+
+// workDirName は保存先フォルダ内に作成する作業用ディレクトリの名前
+const workDirName = ".narou"
+
+// manifestVersion はマニフェストの形式のバージョン
+const manifestVersion = 1
+
+// Manifest はダウンロード済みの小説の状態を記録する構造体（.narou/<NCODE>/manifest.json）
+type Manifest struct {
+	Version   int               `json:"version"`
+	NCode     string            `json:"ncode"`
+	Title     string            `json:"title"`
+	Author    string            `json:"author"`
+	URL       string            `json:"url"`
+	Short     bool              `json:"short,omitempty"`
+	Summary   string            `json:"summary,omitempty"`  // あらすじ（なろう小説APIから取得）
+	Keywords  []string          `json:"keywords,omitempty"` // キーワード（なろう小説APIから取得）
+	Genre     string            `json:"genre,omitempty"`    // ジャンル名（なろう小説APIから取得）
+	Formats   []FormatRequest   `json:"formats,omitempty"`  // 保存に使用した出力形式（修復時に使用）
+	Episodes  []ManifestEpisode `json:"episodes"`
+	Files     []ManifestFile    `json:"files"`              // 小説全体を1つにまとめたファイル
+	Archived  []ArchivedEpisode `json:"archived,omitempty"` // 目次から削除されたため archive フォルダに移した話
+	UpdatedAt time.Time         `json:"updatedAt"`
+}
+
+// ManifestEpisode は保存が完了したエピソードの記録を表す構造体
+type ManifestEpisode struct {
+	Index     int            `json:"index"`
+	Number    string         `json:"number"`
+	Chapter   string         `json:"chapter,omitempty"`
+	Title     string         `json:"title"`
+	URL       string         `json:"url"`
+	Hash      string         `json:"hash"`              // 本文のハッシュ（更新の検出に使用）
+	Revised   string         `json:"revised,omitempty"` // 保存した時点の目次の改稿日時（改稿の検出に使用）
+	FetchedAt time.Time      `json:"fetchedAt"`
+	Files     []ManifestFile `json:"files"`
+}
+
+// ManifestFile は保存したファイル1件分の記録を表す構造体
+type ManifestFile struct {
+	Path     string `json:"path"` // 保存先フォルダからの相対パス（区切りは /）
+	Format   string `json:"format"`
+	Encoding string `json:"encoding,omitempty"`
+	Size     int64  `json:"size"`
+	SHA256   string `json:"sha256"`
+}
+
+// episode は話数からエピソードの記録を探します
+func (m *Manifest) episode(number string) *ManifestEpisode {
+	for i := range m.Episodes {
+		if m.Episodes[i].Number == number {
+			return &m.Episodes[i]
+		}
+	}
+	return nil
+}
+
+// recordEpisode はエピソードの記録を追加または置き換えます（目次の順に並べる）
+func (m *Manifest) recordEpisode(episode ManifestEpisode) {
+	if existing := m.episode(episode.Number); existing != nil {
+		*existing = episode
+	} else {
+		m.Episodes = append(m.Episodes, episode)
+	}
+	sort.SliceStable(m.Episodes, func(i, j int) bool { return m.Episodes[i].Index < m.Episodes[j].Index })
+}
+
+// recordFile は小説全体のファイルの記録を追加または置き換えます
+func (m *Manifest) recordFile(file ManifestFile) {
+	for i := range m.Files {
+		if m.Files[i].Path == file.Path {
+			m.Files[i] = file
+			return
+		}
+	}
+	m.Files = append(m.Files, file)
+}
+
+// recordFormats は保存に使用した出力形式を記録します（以前の記録は今回の形式で置き換える）
+// 修復や更新の確認では最後に保存した形式だけを作成し直すため、以前に使用した形式は残さない
+func (m *Manifest) recordFormats(formats []FormatRequest) {
+	m.Formats = slices.Clone(formats)
+}
+
+// bookFiles は小説全体のファイルを返します
+// 短編は1話分のファイルが小説全体のファイルになります
+func (m *Manifest) bookFiles() []ManifestFile {
+	files := slices.Clone(m.Files)
+	if m.Short && len(m.Episodes) > 0 {
+		files = append(files, m.Episodes[0].Files...)
+	}
+	return files
+}
+
+// fetchedSince は since 以降に保存した話（新しい話と更新された話）の記録を返します
+func (m *Manifest) fetchedSince(since time.Time) []ManifestEpisode {
+	var episodes []ManifestEpisode
+	for _, episode := range m.Episodes {
+		if !episode.FetchedAt.Before(since) {
+			episodes = append(episodes, episode)
+		}
+	}
+	return episodes
+}
+
+// novel はマニフェストの記録から文書モデルを作成します（各話の本文は含まない）
+func (m *Manifest) novel() *Novel {
+	novel := &Novel{NCode: m.NCode, Title: m.Title, Author: m.Author, URL: m.URL, Short: m.Short}
+
+	var current *Chapter
+	for _, record := range m.Episodes {
+		if current == nil || current.Title != record.Chapter {
+			current = &Chapter{Title: record.Chapter}
+			novel.Chapters = append(novel.Chapters, current)
+		}
+		current.Episodes = append(current.Episodes, &Episode{
+			Index:  record.Index,
+			Number: record.Number,
+			Title:  record.Title,
+			URL:    record.URL,
+		})
+	}
+	return novel
+}
+
+// novelStore は保存先フォルダと小説ごとの作業用ディレクトリを扱う構造体
+type novelStore struct {
+	root  string // 保存先フォルダ
+	ncode string
+}
+
+// staleTempFileAge は前回中断した際に残ったとみなす一時ファイルの経過時間
+// 一時ファイルは書き込みの間だけ存在するため、同じ小説を同時にダウンロードしている場合の書き込み途中のファイルは削除しない
+const staleTempFileAge = time.Hour
+
+// newNovelStore は novelStore を作成し、前回中断した際の古い一時ファイルを削除します
+func newNovelStore(root, ncode string) (*novelStore, error) {
+	s := &novelStore{root: root, ncode: ncode}
+	if err := os.MkdirAll(s.tmpDir(), 0755); err != nil {
+		return nil, fmt.Errorf("作業用ディレクトリの作成に失敗しました: %w", err)
+	}
+	if err := removeStaleTempFiles(s.tmpDir(), time.Now().Add(-staleTempFileAge)); err != nil {
+		return nil, fmt.Errorf("一時ファイルの削除に失敗しました: %w", err)
+	}
+	return s, nil
+}
+
+// removeStaleTempFiles は dir の中の before より前に更新された一時ファイルを削除します
+func removeStaleTempFiles(dir string, before time.Time) error {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		info, err := entry.Info()
+		if err != nil {
+			// 確認している間に書き込みが終わって名前が変わった一時ファイル
+			continue
+		}
+		if info.ModTime().Before(before) {
+			if err := os.RemoveAll(filepath.Join(dir, entry.Name())); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// dir は小説ごとの作業用ディレクトリのパスを返します
+func (s *novelStore) dir() string {
+	return filepath.Join(s.root, workDirName, s.ncode)
+}
+
+// tmpDir は書き込み途中のファイルを置くディレクトリのパスを返します
+func (s *novelStore) tmpDir() string {
+	return filepath.Join(s.dir(), "tmp")
+}
+
+// manifestPath はマニフェストのパスを返します
+func (s *novelStore) manifestPath() string {
+	return filepath.Join(s.dir(), "manifest.json")
+}
+
+// blocksPath は本文のキャッシュのパスを返します
+func (s *novelStore) blocksPath(number string) string {
+	return filepath.Join(s.dir(), "episodes", sanitizeFileName(number)+".json")
+}
+
+// path は保存先フォルダからの相対パスを実際のパスに変換します
+func (s *novelStore) path(rel string) string {
+	return filepath.Join(s.root, filepath.FromSlash(rel))
+}
+
+// loadManifest はマニフェストを読み込みます（存在しない場合は空のマニフェストを返す）
+func (s *novelStore) loadManifest() (*Manifest, error) {
+	data, err := os.ReadFile(s.manifestPath())
+	if errors.Is(err, os.ErrNotExist) {
+		return &Manifest{Version: manifestVersion, NCode: s.ncode}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("マニフェストの読み込みに失敗しました: %w", err)
+	}
+
+	var manifest Manifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return nil, fmt.Errorf("マニフェストのJSON解析に失敗しました: %w", err)
+	}
+	if manifest.Version > manifestVersion {
+		return nil, fmt.Errorf("新しいバージョンのマニフェストには対応していません: %d", manifest.Version)
+	}
+	return &manifest, nil
+}
+
+// legacyEpisodeFilePattern はマニフェストを作成する前のバージョンが保存した各話のテキストファイルの名前（N1234AB-1.txt）にマッチします
+var legacyEpisodeFilePattern = regexp.MustCompile(`(?i)^(n[0-9]+[a-z]+)-([0-9]+)\.txt$`)
+
+// importLegacyEpisodes はマニフェストがない保存先フォルダにある以前のバージョンの各話のファイルを、
+// 最新の目次の同じ話数の話として記録します（取得し直さずに保存済みとして扱うため）
+// 目次にない話数のファイルは記録せず、記録した話の数を返します
+func (s *novelStore) importLegacyEpisodes(manifest *Manifest, novel *Novel) int {
+	entries, err := os.ReadDir(s.root)
+	if err != nil {
+		return 0
+	}
+	episodes := make(map[string]*Episode)
+	for _, episode := range novel.Episodes() {
+		episodes[episode.Number] = episode
+	}
+
+	imported := 0
+	for _, entry := range entries {
+		matches := legacyEpisodeFilePattern.FindStringSubmatch(entry.Name())
+		if matches == nil || !entry.Type().IsRegular() || !strings.EqualFold(matches[1], s.ncode) {
+			continue
+		}
+		episode, ok := episodes[matches[2]]
+		if !ok || manifest.episode(episode.Number) != nil {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(s.root, entry.Name()))
+		if err != nil {
+			continue
+		}
+
+		sum := sha256.Sum256(data)
+		record := ManifestEpisode{
+			Index:  episode.Index,
+			Number: episode.Number,
+			Title:  episode.Title,
+			URL:    episode.URL,
+			Files: []ManifestFile{{
+				Path:   entry.Name(),
+				Format: "txt",
+				Size:   int64(len(data)),
+				SHA256: hex.EncodeToString(sum[:]),
+			}},
+		}
+		if info, err := entry.Info(); err == nil {
+			record.FetchedAt = info.ModTime()
+		}
+		if chapter := novel.ChapterOf(episode); chapter != nil {
+			record.Chapter = chapter.Title
+		}
+		manifest.recordEpisode(record)
+		imported++
+	}
+	return imported
+}
+
+// saveManifest はマニフェストを保存します
+func (s *novelStore) saveManifest(manifest *Manifest) error {
+	manifest.Version = manifestVersion
+	manifest.UpdatedAt = time.Now()
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return fmt.Errorf("マニフェストのJSON変換に失敗しました: %w", err)
+	}
+	if err := writeFileAtomic(s.manifestPath(), data, s.tmpDir()); err != nil {
+		return fmt.Errorf("マニフェストの保存に失敗しました: %w", err)
+	}
+	return nil
+}
+
+// saveBlocks はエピソードの本文をキャッシュに保存します（小説全体のファイルの再作成に使用）
+func (s *novelStore) saveBlocks(number string, blocks []Block) error {
+	data, err := json.Marshal(blocks)
+	if err != nil {
+		return fmt.Errorf("本文のJSON変換に失敗しました: %w", err)
+	}
+	return writeFileAtomic(s.blocksPath(number), data, s.tmpDir())
+}
+
+// loadBlocks はキャッシュからエピソードの本文を読み込みます
+func (s *novelStore) loadBlocks(number string) ([]Block, error) {
+	data, err := os.ReadFile(s.blocksPath(number))
+	if err != nil {
+		return nil, err
+	}
+	var blocks []Block
+	if err := json.Unmarshal(data, &blocks); err != nil {
+		return nil, fmt.Errorf("本文のキャッシュが壊れています: %w", err)
+	}
+	return blocks, nil
+}
+
+// writeFile はファイルを保存先フォルダに書き込み、その記録を返します
+func (s *novelStore) writeFile(rel string, data []byte, format, encoding string) (ManifestFile, error) {
+	if err := writeFileAtomic(s.path(rel), data, s.tmpDir()); err != nil {
+		return ManifestFile{}, err
+	}
+	sum := sha256.Sum256(data)
+	return ManifestFile{
+		Path:     filepath.ToSlash(rel),
+		Format:   format,
+		Encoding: encoding,
+		Size:     int64(len(data)),
+		SHA256:   hex.EncodeToString(sum[:]),
+	}, nil
+}
+
+// isComplete は記録どおりのファイルが保存先に存在するかどうかを返します
+func (s *novelStore) isComplete(file ManifestFile) bool {
+	info, err := os.Stat(s.path(file.Path))
+	return err == nil && info.Mode().IsRegular() && info.Size() == file.Size
+}
+
+// writeFileAtomic はファイルを一時ファイルに書き込んでから名前を変更することで、
+// 書き込み途中で中断しても不完全なファイルが残らないように保存します（tmpDir が空の場合は保存先と同じディレクトリを使う）
+func writeFileAtomic(path string, data []byte, tmpDir string) error {
+	dir := filepath.Dir(path)
+	if tmpDir == "" {
+		tmpDir = dir
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(tmpDir, 0755); err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(tmpDir, "."+filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	tmpPath := tmp.Name()
+	// 名前の変更に成功した後は削除するファイルが存在しないため、エラーは無視してよい
+	defer os.Remove(tmpPath)
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmpPath, 0644); err != nil {
+		return err
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		return err
+	}
+
+	// 名前の変更をディスクに反映させる（Windowsではディレクトリを同期できない）
+	if goruntime.GOOS != "windows" {
+		if d, err := os.Open(dir); err == nil {
+			d.Sync()
+			d.Close()
+		}
+	}
+	return nil
+}
+
+// blocksHash は本文のハッシュを返します（作者による更新の検出に使用）
+func blocksHash(blocks []Block) string {
+	data, _ := json.Marshal(blocks)
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// writerEncoding は出力形式の文字コードを返します（テキスト以外は UTF-8）
+func writerEncoding(w Writer) string {
+	switch w := w.(type) {
+	case namedEpisodeWriter:
+		return writerEncoding(w.EpisodeWriter)
+	case namedNovelWriter:
+		return writerEncoding(w.NovelWriter)
+	case interface{ encodingName() string }:
+		return w.encodingName()
+	}
+	return ""
+}
@@ -0,0 +1,158 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestWriteFileAtomic(t *testing.T) {
+	dir := t.TempDir()
+	tmpDir := filepath.Join(dir, workDirName, "tmp")
+	path := filepath.Join(dir, "sub", "N1234AB-1.txt")
+
+	for _, content := range []string{"古い内容", "新しい内容"} {
+		if err := writeFileAtomic(path, []byte(content), tmpDir); err != nil {
+			t.Fatalf("writeFileAtomic() error = %v", err)
+		}
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("ReadFile() error = %v", err)
+	}
+	if string(data) != "新しい内容" {
+		t.Errorf("ファイルの内容 = %q, want %q", data, "新しい内容")
+	}
+
+	entries, err := os.ReadDir(tmpDir)
+	if err != nil {
+		t.Fatalf("ReadDir() error = %v", err)
+	}
+	if len(entries) != 0 {
+		t.Errorf("一時ファイルが残っています: %d件", len(entries))
+	}
+}
+
+func TestNewNovelStore_RemovesStaleTempFiles(t *testing.T) {
+	root := t.TempDir()
+	store := &novelStore{root: root, ncode: "N1234AB"}
+	if err := os.MkdirAll(store.tmpDir(), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	// 前回中断した際の一時ファイルと、同じ小説を同時にダウンロードしている書き込み途中の一時ファイル
+	stale := filepath.Join(store.tmpDir(), ".N1234AB-1.txt.1.tmp")
+	writing := filepath.Join(store.tmpDir(), ".N1234AB-2.txt.2.tmp")
+	for _, path := range []string{stale, writing} {
+		if err := os.WriteFile(path, []byte("途中"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	old := time.Now().Add(-2 * staleTempFileAge)
+	if err := os.Chtimes(stale, old, old); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := newNovelStore(root, "N1234AB"); err != nil {
+		t.Fatalf("newNovelStore() error = %v", err)
+	}
+	if _, err := os.Stat(stale); !os.IsNotExist(err) {
+		t.Error("古い一時ファイルが削除されていません")
+	}
+	if _, err := os.Stat(writing); err != nil {
+		t.Errorf("書き込み途中の一時ファイルを削除しました: %v", err)
+	}
+}
+
+func TestManifest_RecordEpisode(t *testing.T) {
+	manifest := &Manifest{}
+	manifest.recordEpisode(ManifestEpisode{Index: 2, Number: "2", Hash: "b"})
+	manifest.recordEpisode(ManifestEpisode{Index: 1, Number: "1", Hash: "a"})
+	manifest.recordEpisode(ManifestEpisode{Index: 2, Number: "2", Hash: "c"})
+
+	if len(manifest.Episodes) != 2 {
+		t.Fatalf("len(Episodes) = %d, want 2", len(manifest.Episodes))
+	}
+	if manifest.Episodes[0].Number != "1" || manifest.Episodes[1].Hash != "c" {
+		t.Errorf("Episodes = %+v, 目次の順に並び、同じ話は置き換える必要があります", manifest.Episodes)
+	}
+}
+
+func TestManifest_RecordFormats(t *testing.T) {
+	manifest := &Manifest{}
+	manifest.recordFormats([]FormatRequest{{Name: "txt"}, {Name: "epub"}})
+	manifest.recordFormats([]FormatRequest{{Name: "html"}})
+
+	if len(manifest.Formats) != 1 || manifest.Formats[0].Name != "html" {
+		t.Errorf("Formats = %+v, want 最後に保存した html だけ", manifest.Formats)
+	}
+}
+
+func TestShouldSkipEpisode(t *testing.T) {
+	novel := newTestNovel()
+	episode := novel.Chapters[0].Episodes[0]
+	writers := []EpisodeWriter{aozoraWriter{textEncoding{Encoding: "UTF-8", LineEnding: "LF"}}}
+
+	tests := []struct {
+		name       string
+		saved      bool // 事前に保存しておく
+		setup      func(store *novelStore, manifest *Manifest)
+		needBlocks bool
+		expected   bool
+	}{
+		{
+			name:     "マニフェストに記録がない",
+			setup:    func(store *novelStore, manifest *Manifest) {},
+			expected: false,
+		},
+		{
+			name:     "保存済み",
+			saved:    true,
+			setup:    func(store *novelStore, manifest *Manifest) {},
+			expected: true,
+		},
+		{
+			name:  "ファイルが途中で切れている",
+			saved: true,
+			setup: func(store *novelStore, manifest *Manifest) {
+				os.WriteFile(store.path("N1234AB-1.txt"), []byte("一"), 0644)
+			},
+			expected: false,
+		},
+		{
+			name:  "本文のキャッシュがない",
+			saved: true,
+			setup: func(store *novelStore, manifest *Manifest) {
+				os.Remove(store.blocksPath("1"))
+			},
+			needBlocks: true,
+			expected:   false,
+		},
+	}
+
+	app := NewApp()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store, err := newNovelStore(t.TempDir(), novel.NCode)
+			if err != nil {
+				t.Fatalf("newNovelStore() error = %v", err)
+			}
+			manifest, err := store.loadManifest()
+			if err != nil {
+				t.Fatalf("loadManifest() error = %v", err)
+			}
+			if tt.saved {
+				if err := app.saveEpisode(store, manifest, novel, episode, writers); err != nil {
+					t.Fatalf("saveEpisode() error = %v", err)
+				}
+			}
+			tt.setup(store, manifest)
+
+			if got := app.shouldSkipEpisode(store, manifest, novel, episode, writers, tt.needBlocks); got != tt.expected {
+				t.Errorf("shouldSkipEpisode() = %v, want %v", got, tt.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,433 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+// archiveDirName は削除された話を保管するフォルダの名前
+const archiveDirName = "archive"
+
+// 目次の変更の種類
+const (
+	tocAdded      = "added"      // 末尾に追加された話
+	tocInserted   = "inserted"   // 途中に挿入された話
+	tocRemoved    = "removed"    // 削除・非公開にされた話
+	tocRenumbered = "renumbered" // 前後の話の削除・挿入で話数が変わった話
+	tocRenamed    = "renamed"    // 話数はそのままで、話または章のタイトルが変わった話
+)
+
+// tocChange は目次の変更1件分を表す構造体
+type tocChange struct {
+	Kind string
+	Old  *ManifestEpisode // 保存済みの話（追加・挿入の場合は nil）
+	New  *Episode         // 最新の目次の話（削除の場合は nil）
+}
+
+// tocDiff は保存済みの話と最新の目次を比較した結果を表す構造体
+type tocDiff struct {
+	Changes []tocChange
+	Matched map[*Episode]*ManifestEpisode // 最新の目次の話と、それに対応する保存済みの話
+}
+
+// ArchivedEpisode は archive フォルダに移した話の記録を表す構造体
+type ArchivedEpisode struct {
+	ManifestEpisode
+	Reason     string    `json:"reason"`
+	ArchivedAt time.Time `json:"archivedAt"`
+}
+
+// newArchivedEpisode は保管する話の記録を作成します（ファイルの記録はパスを書き換えるため複製する）
+func newArchivedEpisode(record ManifestEpisode, reason string) ArchivedEpisode {
+	record.Files = slices.Clone(record.Files)
+	return ArchivedEpisode{ManifestEpisode: record, Reason: reason}
+}
+
+// diffTOC は保存済みの話（マニフェスト）と最新の目次を比較します
+// 話数（URL）とタイトルが同じ先頭の話、タイトルが同じ末尾の話をそのまま対応付け、残った中間の話だけを
+// タイトルの並びの最長共通部分列で対応付けます（なろうの話数は話の削除・挿入で前後にずれるため）
+// 中間で対応しない話のうち同じ話数のものはタイトルの変更とし、残りを削除・挿入・追加として扱います
+func diffTOC(manifest *Manifest, novel *Novel) tocDiff {
+	oldEpisodes := manifest.Episodes
+	var newEpisodes []*Episode
+	var newChapters []string
+	for _, chapter := range novel.Chapters {
+		for _, episode := range chapter.Episodes {
+			newEpisodes = append(newEpisodes, episode)
+			newChapters = append(newChapters, chapter.Title)
+		}
+	}
+
+	diff := tocDiff{Matched: make(map[*Episode]*ManifestEpisode)}
+	match := func(i, j int) {
+		old, episode := &oldEpisodes[i], newEpisodes[j]
+		diff.Matched[episode] = old
+		switch {
+		case old.Number != episode.Number:
+			diff.Changes = append(diff.Changes, tocChange{Kind: tocRenumbered, Old: old, New: episode})
+		case old.Title != episode.Title || old.Chapter != newChapters[j]:
+			diff.Changes = append(diff.Changes, tocChange{Kind: tocRenamed, Old: old, New: episode})
+		}
+	}
+
+	// 先頭から話数もタイトルも同じ話、末尾からタイトルが同じ話は比較の表を作らずに対応付ける
+	prefix := 0
+	for prefix < len(oldEpisodes) && prefix < len(newEpisodes) &&
+		oldEpisodes[prefix].Number == newEpisodes[prefix].Number && oldEpisodes[prefix].Title == newEpisodes[prefix].Title {
+		match(prefix, prefix)
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(oldEpisodes)-prefix && suffix < len(newEpisodes)-prefix &&
+		oldEpisodes[len(oldEpisodes)-1-suffix].Title == newEpisodes[len(newEpisodes)-1-suffix].Title {
+		suffix++
+	}
+
+	// 中間の話のタイトルの最長共通部分列の長さの表（後ろから計算する）
+	midOld, midNew := oldEpisodes[prefix:len(oldEpisodes)-suffix], newEpisodes[prefix:len(newEpisodes)-suffix]
+	lcs := make([][]int32, len(midOld)+1)
+	for i := range lcs {
+		lcs[i] = make([]int32, len(midNew)+1)
+	}
+	for i := len(midOld) - 1; i >= 0; i-- {
+		for j := len(midNew) - 1; j >= 0; j-- {
+			if midOld[i].Title == midNew[j].Title {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	// 対応しない話を並べ、同じ話数の保存済みの話と最新の目次の話はタイトルの変更として対応付ける
+	var ops []tokenOp
+	unmatched := make(map[string]int) // 話数ごとの対応しない最新の目次の話の位置
+	for i, j := 0, 0; i < len(midOld) || j < len(midNew); {
+		switch {
+		case i < len(midOld) && j < len(midNew) && midOld[i].Title == midNew[j].Title:
+			ops = append(ops, tokenOp{"equal", i})
+			i++
+			j++
+		case j >= len(midNew) || (i < len(midOld) && lcs[i+1][j] >= lcs[i][j+1]):
+			ops = append(ops, tokenOp{"delete", i})
+			i++
+		default:
+			ops = append(ops, tokenOp{"insert", j})
+			unmatched[midNew[j].Number] = j
+			j++
+		}
+	}
+	renamed := make(map[int]int) // 保存済みの話の位置から、タイトルが変わった最新の目次の話の位置
+	for _, op := range ops {
+		if op.kind != "delete" {
+			continue
+		}
+		if j, ok := unmatched[midOld[op.index].Number]; ok {
+			renamed[op.index] = j
+			delete(unmatched, midOld[op.index].Number)
+		}
+	}
+
+	var pending []*Episode // 対応する話が見つかっていない最新の目次の話
+	flushPending := func(kind string) {
+		for _, episode := range pending {
+			diff.Changes = append(diff.Changes, tocChange{Kind: kind, New: episode})
+		}
+		pending = nil
+	}
+	renamedNew := make(map[int]bool)
+	for _, j := range renamed {
+		renamedNew[j] = true
+	}
+	i, j := 0, 0
+	for _, op := range ops {
+		switch op.kind {
+		case "equal":
+			flushPending(tocInserted)
+			match(prefix+i, prefix+j)
+			i++
+			j++
+		case "delete":
+			if newIndex, ok := renamed[i]; ok {
+				flushPending(tocInserted)
+				match(prefix+i, prefix+newIndex)
+			} else {
+				diff.Changes = append(diff.Changes, tocChange{Kind: tocRemoved, Old: &oldEpisodes[prefix+i]})
+			}
+			i++
+		case "insert":
+			if !renamedNew[j] {
+				pending = append(pending, newEpisodes[prefix+j])
+			}
+			j++
+		}
+	}
+	// 保存済みの話より後ろにある話は末尾への追加
+	if suffix == 0 {
+		flushPending(tocAdded)
+	} else {
+		flushPending(tocInserted)
+	}
+
+	for k := 0; k < suffix; k++ {
+		match(len(oldEpisodes)-suffix+k, len(newEpisodes)-suffix+k)
+	}
+	return diff
+}
+
+// count は指定した種類の変更の件数を返します
+func (d tocDiff) count(kind string) int {
+	n := 0
+	for _, change := range d.Changes {
+		if change.Kind == kind {
+			n++
+		}
+	}
+	return n
+}
+
+// structural は保存済みのファイルに影響する変更（削除・話数の変更）があるかどうかを返します
+// 挿入だけの場合は前回取得に失敗した話と区別できず、保存済みのファイルにも影響しない
+func (d tocDiff) structural() bool {
+	return d.count(tocRemoved)+d.count(tocRenumbered) > 0
+}
+
+// describe は変更内容を1行で説明する文字列を返します
+func (c tocChange) describe() string {
+	switch c.Kind {
+	case tocAdded:
+		return fmt.Sprintf("追加: %s話「%s」", c.New.Number, c.New.Title)
+	case tocInserted:
+		return fmt.Sprintf("挿入: %s話「%s」", c.New.Number, c.New.Title)
+	case tocRemoved:
+		return fmt.Sprintf("削除: %s話「%s」", c.Old.Number, c.Old.Title)
+	case tocRenumbered:
+		return fmt.Sprintf("話数の変更: %s話 → %s話「%s」", c.Old.Number, c.New.Number, c.New.Title)
+	case tocRenamed:
+		if c.Old.Title == c.New.Title {
+			return fmt.Sprintf("章の変更: %s話「%s」", c.New.Number, c.New.Title)
+		}
+		return fmt.Sprintf("タイトルの変更: %s話「%s」→「%s」", c.New.Number, c.Old.Title, c.New.Title)
+	}
+	return c.Kind
+}
+
+// applyTOCChanges は目次の変更を保存先に反映します
+//   - 削除された話は archive フォルダに移し、マニフェストの記録を Archived に移す
+//   - タイトルが変わった話はマニフェストの記録だけを更新する
+//   - 話数が変わった話は本文のキャッシュから新しい話数で保存し直す（キャッシュがない場合は archive に移して取得し直す）
+//   - 削除・話数の変更があった場合、上書きされる前の小説全体のファイルを archive にコピーする
+func (a *App) applyTOCChanges(store *novelStore, manifest *Manifest, novel *Novel, diff tocDiff, episodeWriters []EpisodeWriter) error {
+	if !diff.structural() && diff.count(tocRenamed) == 0 {
+		return nil
+	}
+
+	a.emit("log", fmt.Sprintf("目次の変更を検出しました（削除 %d話、挿入 %d話、追加 %d話、話数の変更 %d話、タイトルの変更 %d話）",
+		diff.count(tocRemoved), diff.count(tocInserted), diff.count(tocAdded), diff.count(tocRenumbered), diff.count(tocRenamed)))
+	for _, change := range diff.Changes {
+		a.emit("log", "  "+change.describe())
+	}
+
+	// タイトルが変わった話は記録だけを更新する（保存済みのファイルはそのまま使う）
+	for _, change := range diff.Changes {
+		if change.Kind != tocRenamed {
+			continue
+		}
+		change.Old.Title = change.New.Title
+		change.Old.Chapter = ""
+		if chapter := novel.ChapterOf(change.New); chapter != nil {
+			change.Old.Chapter = chapter.Title
+		}
+	}
+	if !diff.structural() {
+		return store.saveManifest(manifest)
+	}
+
+	stamp := time.Now().Format("20060102-150405")
+
+	// 小説全体のファイルは作り直すと削除された話が失われるため、先に保管しておく
+	if diff.count(tocRemoved) > 0 {
+		for _, file := range manifest.Files {
+			if _, err := store.archiveFile(file.Path, stamp, false); err != nil {
+				return err
+			}
+		}
+	}
+
+	// 話数が変わる話の本文は、他の話に上書きされる前にすべて読み込んでおく
+	renumbered := make(map[*Episode][]Block)
+	var archived []ArchivedEpisode
+	for _, change := range diff.Changes {
+		switch change.Kind {
+		case tocRemoved:
+			archived = append(archived, newArchivedEpisode(*change.Old, tocRemoved))
+		case tocRenumbered:
+			blocks, err := store.loadBlocks(change.Old.Number)
+			if err != nil {
+				// 本文がない場合は保管して取得し直す
+				archived = append(archived, newArchivedEpisode(*change.Old, tocRenumbered))
+				continue
+			}
+			renumbered[change.New] = blocks
+		}
+	}
+
+	// 削除された話のファイルを archive に移す
+	for i := range archived {
+		record := &archived[i]
+		if len(record.Files) == 0 {
+			// 小説全体のファイルのみを出力している場合は、本文のキャッシュからテキストとして保管する
+			if err := store.archiveBlocks(record.ManifestEpisode, stamp); err != nil {
+				a.emit("log", fmt.Sprintf("%s話の保管に失敗しました: %v", record.Number, err))
+			}
+		}
+		files := record.Files[:0]
+		for _, file := range record.Files {
+			rel, err := store.archiveFile(file.Path, stamp, true)
+			if err != nil {
+				return err
+			}
+			if rel == "" {
+				continue
+			}
+			file.Path = rel
+			files = append(files, file)
+		}
+		record.Files = files
+		record.ArchivedAt = time.Now()
+		a.emit("log", fmt.Sprintf("%s話「%s」を%sフォルダに保管しました", record.Number, record.Title, archiveDirName))
+	}
+
+	// マニフェストから古い記録を除く（話数が変わる話は新しい話数で記録し直す）
+	drop := make(map[*ManifestEpisode]bool)
+	for _, change := range diff.Changes {
+		if change.Kind == tocRemoved || change.Kind == tocRenumbered {
+			drop[change.Old] = true
+		}
+	}
+	oldPaths := make(map[string]bool)
+	kept := manifest.Episodes[:0:0]
+	for i := range manifest.Episodes {
+		record := &manifest.Episodes[i]
+		if drop[record] {
+			for _, file := range record.Files {
+				oldPaths[file.Path] = true
+			}
+			continue
+		}
+		kept = append(kept, *record)
+	}
+	manifest.Episodes = kept
+	manifest.Archived = append(manifest.Archived, archived...)
+
+	// 残った話も目次上の位置を更新する
+	for episode, record := range diff.Matched {
+		if drop[record] {
+			continue
+		}
+		if current := manifest.episode(record.Number); current != nil {
+			current.Index = episode.Index
+		}
+	}
+
+	// 話数が変わった話を新しい話数で保存し直す
+	for _, episode := range novel.Episodes() {
+		blocks, ok := renumbered[episode]
+		if !ok {
+			continue
+		}
+		episode.Blocks = blocks
+		if err := a.saveEpisode(store, manifest, novel, episode, episodeWriters); err != nil {
+			return err
+		}
+		for _, w := range episodeWriters {
+			delete(oldPaths, episodeFileName(novel, episode, w))
+		}
+	}
+
+	// 保存し直した話の古いファイルのうち、他の話で上書きされなかったものを削除する
+	for _, record := range manifest.Episodes {
+		for _, file := range record.Files {
+			delete(oldPaths, file.Path)
+		}
+	}
+	for rel := range oldPaths {
+		if err := os.Remove(store.path(rel)); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("古いファイルの削除に失敗しました: %w", err)
+		}
+	}
+
+	// 既読の状態も新しい話数に移す（失敗しても保存済みのファイルには影響しない）
+	if err := store.renumberReadingState(diff); err != nil {
+		a.emit("log", fmt.Sprintf("既読の状態を新しい話数に移せませんでした: %v", err))
+	}
+
+	return store.saveManifest(manifest)
+}
+
+// archivePath は archive フォルダ内の保存先を返します（既に存在する場合は日時と連番を付ける）
+func (s *novelStore) archivePath(rel, stamp string) string {
+	target := path.Join(archiveDirName, rel)
+	ext := path.Ext(target)
+	base := strings.TrimSuffix(target, ext) + "_" + stamp
+	for i := 1; ; i++ {
+		if _, err := os.Stat(s.path(target)); err != nil {
+			return target
+		}
+		target = base + ext
+		if i > 1 {
+			target = fmt.Sprintf("%s-%d%s", base, i, ext)
+		}
+	}
+}
+
+// archiveFile はファイルを archive フォルダに移動し、保管先の相対パスを返します
+// （move が false の場合はコピー。ファイルがない場合は空文字列を返す）
+func (s *novelStore) archiveFile(rel, stamp string, move bool) (string, error) {
+	source := s.path(rel)
+	if _, err := os.Stat(source); errors.Is(err, os.ErrNotExist) {
+		return "", nil
+	}
+	archived := s.archivePath(rel, stamp)
+	target := s.path(archived)
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return "", fmt.Errorf("%sフォルダの作成に失敗しました: %w", archiveDirName, err)
+	}
+
+	if move {
+		if err := os.Rename(source, target); err != nil {
+			return "", fmt.Errorf("%sの保管に失敗しました: %w", rel, err)
+		}
+		return archived, nil
+	}
+	data, err := os.ReadFile(source)
+	if err != nil {
+		return "", fmt.Errorf("%sの保管に失敗しました: %w", rel, err)
+	}
+	if err := writeFileAtomic(target, data, s.tmpDir()); err != nil {
+		return "", fmt.Errorf("%sの保管に失敗しました: %w", rel, err)
+	}
+	return archived, nil
+}
+
+// archiveBlocks は本文のキャッシュからテキストファイルを作成して archive フォルダに保管します
+func (s *novelStore) archiveBlocks(record ManifestEpisode, stamp string) error {
+	blocks, err := s.loadBlocks(record.Number)
+	if err != nil {
+		return err
+	}
+	var buf bytes.Buffer
+	w := plainTextWriter{textEncoding{Encoding: "UTF-8", LineEnding: "LF"}}
+	if err := w.WriteEpisode(&buf, &Novel{NCode: s.ncode}, &Episode{Number: record.Number, Title: record.Title, Blocks: blocks}); err != nil {
+		return err
+	}
+	rel := s.archivePath(generateFileName(s.ncode, record.Number)+".txt", stamp)
+	return writeFileAtomic(s.path(rel), buf.Bytes(), s.tmpDir())
+}
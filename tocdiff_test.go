@@ -0,0 +1,235 @@
+package main
+
+import (
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// newTOCNovel は指定したタイトルの話を1話から順に並べた小説を作成します
+func newTOCNovel(titles ...string) *Novel {
+	chapter := &Chapter{}
+	for i, title := range titles {
+		number := strconv.Itoa(i + 1)
+		chapter.Episodes = append(chapter.Episodes, &Episode{
+			Index:  i + 1,
+			Number: number,
+			Title:  title,
+			URL:    "https://ncode.syosetu.com/n1234ab/" + number + "/",
+			Blocks: []Block{{Kind: BlockParagraph, Inlines: []Inline{{Kind: InlineText, Text: "本文" + title}}}},
+		})
+	}
+	return &Novel{NCode: "N1234AB", Title: "テスト小説", Chapters: []*Chapter{chapter}}
+}
+
+func TestDiffTOC(t *testing.T) {
+	tests := []struct {
+		name       string
+		old        []string
+		new        []string
+		newChapter string   // 最新の目次の章タイトル
+		expected   []string // 変更内容の説明
+	}{
+		{
+			name: "変更なし",
+			old:  []string{"一話", "二話"},
+			new:  []string{"一話", "二話"},
+		},
+		{
+			name:     "末尾への追加",
+			old:      []string{"一話", "二話"},
+			new:      []string{"一話", "二話", "三話"},
+			expected: []string{"追加: 3話「三話」"},
+		},
+		{
+			name:     "途中の話の削除",
+			old:      []string{"一話", "二話", "三話"},
+			new:      []string{"一話", "三話"},
+			expected: []string{"削除: 2話「二話」", "話数の変更: 3話 → 2話「三話」"},
+		},
+		{
+			name:     "途中への挿入",
+			old:      []string{"一話", "三話"},
+			new:      []string{"一話", "閑話", "三話"},
+			expected: []string{"挿入: 2話「閑話」", "話数の変更: 2話 → 3話「三話」"},
+		},
+		{
+			name:     "話のタイトルの変更",
+			old:      []string{"一話", "二話", "三話"},
+			new:      []string{"一話", "二話（改稿）", "三話"},
+			expected: []string{"タイトルの変更: 2話「二話」→「二話（改稿）」"},
+		},
+		{
+			name:     "末尾の話のタイトルの変更と追加",
+			old:      []string{"一話", "二話"},
+			new:      []string{"一話", "二話（前編）", "三話"},
+			expected: []string{"タイトルの変更: 2話「二話」→「二話（前編）」", "追加: 3話「三話」"},
+		},
+		{
+			name:       "章のタイトルの変更",
+			old:        []string{"一話", "二話"},
+			new:        []string{"一話", "二話"},
+			newChapter: "第一章",
+			expected:   []string{"章の変更: 1話「一話」", "章の変更: 2話「二話」"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			manifest := &Manifest{}
+			for _, episode := range newTOCNovel(tt.old...).Episodes() {
+				manifest.recordEpisode(ManifestEpisode{Index: episode.Index, Number: episode.Number, Title: episode.Title})
+			}
+
+			novel := newTOCNovel(tt.new...)
+			novel.Chapters[0].Title = tt.newChapter
+			diff := diffTOC(manifest, novel)
+			if len(diff.Changes) != len(tt.expected) {
+				t.Fatalf("len(Changes) = %d, want %d", len(diff.Changes), len(tt.expected))
+			}
+			for i, change := range diff.Changes {
+				if got := change.describe(); got != tt.expected[i] {
+					t.Errorf("Changes[%d] = %q, want %q", i, got, tt.expected[i])
+				}
+			}
+		})
+	}
+}
+
+func TestApplyTOCChanges(t *testing.T) {
+	app := NewApp()
+	writers := []EpisodeWriter{plainTextWriter{textEncoding{Encoding: "UTF-8", LineEnding: "LF"}}}
+
+	old := newTOCNovel("一話", "二話", "三話")
+	store, manifest, err := app.openNovelStore(t.TempDir(), old, nil)
+	if err != nil {
+		t.Fatalf("openNovelStore() error = %v", err)
+	}
+	for _, episode := range old.Episodes() {
+		if err := app.saveEpisode(store, manifest, old, episode, writers); err != nil {
+			t.Fatalf("saveEpisode() error = %v", err)
+		}
+	}
+
+	// 二話が削除され、三話が2話に繰り上がった
+	current := newTOCNovel("一話", "三話")
+	for _, episode := range current.Episodes() {
+		episode.Blocks = nil
+	}
+	if err := app.applyTOCChanges(store, manifest, current, diffTOC(manifest, current), writers); err != nil {
+		t.Fatalf("applyTOCChanges() error = %v", err)
+	}
+
+	files := map[string]string{
+		"archive/N1234AB-2.txt": "本文二話",
+		"N1234AB-2.txt":         "本文三話",
+	}
+	for rel, want := range files {
+		data, err := os.ReadFile(store.path(rel))
+		if err != nil {
+			t.Errorf("%s を読み込めません: %v", rel, err)
+			continue
+		}
+		if !strings.Contains(string(data), want) {
+			t.Errorf("%s の内容 = %q, want %q を含む", rel, data, want)
+		}
+	}
+	if _, err := os.Stat(store.path("N1234AB-3.txt")); !os.IsNotExist(err) {
+		t.Errorf("繰り上がる前の N1234AB-3.txt が残っています")
+	}
+
+	if len(manifest.Episodes) != 2 || manifest.Episodes[1].Title != "三話" {
+		t.Errorf("Episodes = %+v, want 一話・三話", manifest.Episodes)
+	}
+	if len(manifest.Archived) != 1 || manifest.Archived[0].Files[0].Path != "archive/N1234AB-2.txt" {
+		t.Errorf("Archived = %+v, want 二話", manifest.Archived)
+	}
+
+	// 反映後は保存済みとして扱われる
+	if !app.shouldSkipEpisode(store, manifest, current, current.Episodes()[1], writers, false) {
+		t.Errorf("繰り上がった話がスキップされません")
+	}
+}
+
+func TestApplyTOCChanges_RemovedTwice(t *testing.T) {
+	app := NewApp()
+	writers := []EpisodeWriter{plainTextWriter{textEncoding{Encoding: "UTF-8", LineEnding: "LF"}}}
+
+	old := newTOCNovel("一話", "二話", "三話")
+	store, manifest, err := app.openNovelStore(t.TempDir(), old, nil)
+	if err != nil {
+		t.Fatalf("openNovelStore() error = %v", err)
+	}
+	for _, episode := range old.Episodes() {
+		if err := app.saveEpisode(store, manifest, old, episode, writers); err != nil {
+			t.Fatalf("saveEpisode() error = %v", err)
+		}
+	}
+
+	// 2話が続けて削除される（二話の削除で繰り上がった三話が、次の更新で削除された）
+	for _, current := range []*Novel{newTOCNovel("一話", "三話"), newTOCNovel("一話")} {
+		for _, episode := range current.Episodes() {
+			episode.Blocks = nil
+		}
+		if err := app.applyTOCChanges(store, manifest, current, diffTOC(manifest, current), writers); err != nil {
+			t.Fatalf("applyTOCChanges() error = %v", err)
+		}
+	}
+
+	if len(manifest.Archived) != 2 {
+		t.Fatalf("Archived = %+v, want 二話・三話", manifest.Archived)
+	}
+	first, second := manifest.Archived[0].Files[0].Path, manifest.Archived[1].Files[0].Path
+	if first == second {
+		t.Fatalf("保管先が同じです: %s", first)
+	}
+	for i, want := range []string{"本文二話", "本文三話"} {
+		rel := manifest.Archived[i].Files[0].Path
+		data, err := os.ReadFile(store.path(rel))
+		if err != nil {
+			t.Errorf("%s を読み込めません: %v", rel, err)
+			continue
+		}
+		if !strings.Contains(string(data), want) {
+			t.Errorf("%s の内容 = %q, want %q を含む", rel, data, want)
+		}
+	}
+}
+
+func TestApplyTOCChanges_Renamed(t *testing.T) {
+	app := NewApp()
+	writers := []EpisodeWriter{plainTextWriter{textEncoding{Encoding: "UTF-8", LineEnding: "LF"}}}
+
+	old := newTOCNovel("一話", "二話")
+	store, manifest, err := app.openNovelStore(t.TempDir(), old, nil)
+	if err != nil {
+		t.Fatalf("openNovelStore() error = %v", err)
+	}
+	for _, episode := range old.Episodes() {
+		if err := app.saveEpisode(store, manifest, old, episode, writers); err != nil {
+			t.Fatalf("saveEpisode() error = %v", err)
+		}
+	}
+
+	// タイトルの変更は削除・挿入として扱わず、保存済みのファイルをそのまま使う
+	current := newTOCNovel("一話", "二話（改稿）")
+	current.Chapters[0].Title = "第一章"
+	if err := app.applyTOCChanges(store, manifest, current, diffTOC(manifest, current), writers); err != nil {
+		t.Fatalf("applyTOCChanges() error = %v", err)
+	}
+	if len(manifest.Archived) != 0 {
+		t.Errorf("Archived = %+v, want なし", manifest.Archived)
+	}
+	if record := manifest.episode("2"); record.Title != "二話（改稿）" || record.Chapter != "第一章" {
+		t.Errorf("2話の記録 = %+v", record)
+	}
+
+	saved, err := store.loadManifest()
+	if err != nil {
+		t.Fatalf("loadManifest() error = %v", err)
+	}
+	if saved.episode("2").Title != "二話（改稿）" {
+		t.Error("タイトルの変更がマニフェストに保存されていません")
+	}
+}
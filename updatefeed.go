@@ -0,0 +1,149 @@
+package main
+
+import (
+	"encoding/xml"
+	"fmt"
+	"net/url"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// updateFeedName は本棚のフォルダに書き出す新着のAtomフィードのファイル名
+const updateFeedName = "updates.atom"
+
+// updateFeedLimit は新着のAtomフィードに載せる話の最大数
+const updateFeedLimit = 100
+
+// updateFeedFolder はダウンロードした小説の保存先フォルダから、新着のAtomフィードを書き出す本棚のフォルダを返します
+// 既定の保存先（実行ファイルのディレクトリ）以下に保存した場合は既定の保存先、それ以外は保存先フォルダの親フォルダです
+func updateFeedFolder(savePath string) string {
+	if root, err := defaultSaveRoot(); err == nil {
+		rel, err := filepath.Rel(root, savePath)
+		if err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return root
+		}
+	}
+	return filepath.Dir(savePath)
+}
+
+// fileURL はローカルのファイルのパスを file: のURLに変換します
+func fileURL(path string) string {
+	if abs, err := filepath.Abs(path); err == nil {
+		path = abs
+	}
+	path = filepath.ToSlash(path)
+	// Windowsのドライブ文字から始まるパス（C:/...）
+	if !strings.HasPrefix(path, "/") {
+		path = "/" + path
+	}
+	return (&url.URL{Scheme: "file", Path: path}).String()
+}
+
+// updateFeedEntry は新着のAtomフィードに載せる1話分を表す構造体
+type updateFeedEntry struct {
+	store    *novelStore
+	manifest *Manifest
+	record   ManifestEpisode
+}
+
+// buildUpdateFeed は本棚の小説から新しく取得・更新した話を取得日時の新しい順に並べたAtomフィードを作成します
+func (a *App) buildUpdateFeed(folder string, limit int) (atomFeed, error) {
+	stores, err := findNovelStores(folder)
+	if err != nil {
+		return atomFeed{}, err
+	}
+
+	var entries []updateFeedEntry
+	for _, store := range stores {
+		manifest, err := store.loadManifest()
+		if err != nil {
+			a.emit("log", fmt.Sprintf("%s を読み込めませんでした: %v", store.dir(), err))
+			continue
+		}
+		for _, record := range manifest.Episodes {
+			entries = append(entries, updateFeedEntry{store: store, manifest: manifest, record: record})
+		}
+	}
+	sort.SliceStable(entries, func(i, j int) bool { return entries[i].record.FetchedAt.After(entries[j].record.FetchedAt) })
+	entries = entries[:min(len(entries), limit)]
+
+	feed := atomFeed{
+		Xmlns:   "http://www.w3.org/2005/Atom",
+		ID:      "urn:narou-download:updates:" + filepath.ToSlash(folder),
+		Title:   "小説家になろう 新着",
+		Links:   []atomLink{{Rel: "self", Href: fileURL(filepath.Join(folder, updateFeedName)), Type: "application/atom+xml"}},
+		Entries: []atomEntry{},
+	}
+	var updated time.Time
+	if len(entries) > 0 {
+		updated = entries[0].record.FetchedAt
+	}
+	feed.Updated = atomTime(updated)
+	for _, entry := range entries {
+		feed.Entries = append(feed.Entries, entry.atomEntry())
+	}
+	return feed, nil
+}
+
+// atomEntry は1話分をAtomの項目に変換します
+// 改稿された話は本文のハッシュごとに別の項目として扱い、フィードリーダーで新着として表示されるようにします
+func (e updateFeedEntry) atomEntry() atomEntry {
+	m, record := e.manifest, e.record
+	title := fmt.Sprintf("%s %s", m.Title, record.Title)
+	kind := "新着"
+	if versions, err := e.store.episodeVersions(record); err == nil && len(versions) > 1 {
+		kind = "改稿"
+		title += "（改稿）"
+	}
+
+	hash := record.Hash
+	if len(hash) > 12 {
+		hash = hash[:12]
+	}
+	entry := atomEntry{
+		ID:      fmt.Sprintf("urn:ncode:%s:%s:%s", strings.ToLower(m.NCode), record.Number, hash),
+		Title:   title,
+		Updated: atomTime(record.FetchedAt),
+		Authors: []atomPerson{{Name: m.Author}},
+		Summary: &atomText{Type: "text", Text: fmt.Sprintf("%s（%s）%s話 %s を%sに取得しました", m.Title, kind, record.Number, record.Title, record.FetchedAt.Format("2006-01-02 15:04"))},
+		Links:   []atomLink{{Rel: "alternate", Href: record.URL, Type: "text/html", Title: "小説家になろう"}},
+	}
+	if record.Chapter != "" {
+		entry.Categories = append(entry.Categories, atomCategory{Term: record.Chapter})
+	}
+
+	// 各話のファイルがない形式（EPUBなど）では小説全体のファイルにリンクする
+	files := record.Files
+	if len(files) == 0 {
+		files = m.Files
+	}
+	for _, file := range files {
+		entry.Links = append(entry.Links, atomLink{
+			Rel:   "enclosure",
+			Href:  fileURL(e.store.path(file.Path)),
+			Type:  opdsFileType(file),
+			Title: strings.ToUpper(file.Format),
+		})
+	}
+	return entry
+}
+
+// writeUpdateFeed は本棚のフォルダに新着のAtomフィードを書き出し、そのパスを返します
+func (a *App) writeUpdateFeed(folder string) (string, error) {
+	feed, err := a.buildUpdateFeed(folder, updateFeedLimit)
+	if err != nil {
+		return "", err
+	}
+	data, err := xml.MarshalIndent(feed, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("Atomフィードの生成に失敗しました: %w", err)
+	}
+
+	path := filepath.Join(folder, updateFeedName)
+	if err := writeFileAtomic(path, append([]byte(xml.Header), data...), ""); err != nil {
+		return "", fmt.Errorf("Atomフィードの保存に失敗しました: %w", err)
+	}
+	return path, nil
+}
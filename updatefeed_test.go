@@ -0,0 +1,146 @@
+package main
+
+import (
+	"encoding/xml"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestBuildUpdateFeed(t *testing.T) {
+	app, store, novel := newVerifiedNovelStore(t)
+
+	// 改稿された話は最新の取得として先頭に来る
+	manifest, _ := store.loadManifest()
+	episode := novel.Episodes()[0]
+	episode.Blocks = []Block{{Kind: BlockParagraph, Inlines: []Inline{{Kind: InlineText, Text: "本文一（改稿）"}}}}
+	episodeWriters, _, err := buildWriters(manifest.Formats)
+	if err != nil {
+		t.Fatalf("buildWriters() error = %v", err)
+	}
+	if err := app.saveEpisode(store, manifest, novel, episode, episodeWriters); err != nil {
+		t.Fatalf("saveEpisode() error = %v", err)
+	}
+
+	tests := []struct {
+		name       string
+		limit      int
+		wantTitles []string
+	}{
+		{name: "すべて", limit: updateFeedLimit, wantTitles: []string{"テスト小説 一話（改稿）", "テスト小説 三話", "テスト小説 二話"}},
+		{name: "件数の上限", limit: 1, wantTitles: []string{"テスト小説 一話（改稿）"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			feed, err := app.buildUpdateFeed(store.root, tt.limit)
+			if err != nil {
+				t.Fatalf("buildUpdateFeed() error = %v", err)
+			}
+			if len(feed.Entries) != len(tt.wantTitles) {
+				t.Fatalf("len(Entries) = %d, want %d", len(feed.Entries), len(tt.wantTitles))
+			}
+			for i, want := range tt.wantTitles {
+				if feed.Entries[i].Title != want {
+					t.Errorf("Entries[%d].Title = %q, want %q", i, feed.Entries[i].Title, want)
+				}
+			}
+		})
+	}
+
+	feed, _ := app.buildUpdateFeed(store.root, updateFeedLimit)
+	entry := feed.Entries[0]
+	if entry.Updated != feed.Updated {
+		t.Errorf("Updated = %s, want %s", feed.Updated, entry.Updated)
+	}
+	links := map[string]atomLink{}
+	for _, link := range entry.Links {
+		links[link.Rel] = link
+	}
+	if links["alternate"].Href != episode.URL {
+		t.Errorf("alternate = %+v, want %s", links["alternate"], episode.URL)
+	}
+	if enclosure := links["enclosure"]; !strings.HasPrefix(enclosure.Href, "file://") || enclosure.Type != "text/plain; charset=Shift_JIS" {
+		t.Errorf("enclosure = %+v", enclosure)
+	}
+}
+
+func TestWriteUpdateFeed(t *testing.T) {
+	app, store, _ := newVerifiedNovelStore(t)
+
+	path, err := app.writeUpdateFeed(store.root)
+	if err != nil {
+		t.Fatalf("writeUpdateFeed() error = %v", err)
+	}
+	if path != filepath.Join(store.root, updateFeedName) {
+		t.Errorf("path = %s", path)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("ReadFile() error = %v", err)
+	}
+
+	var feed struct {
+		Entries []struct {
+			Title string `xml:"title"`
+		} `xml:"entry"`
+	}
+	if err := xml.Unmarshal(data, &feed); err != nil {
+		t.Fatalf("xml.Unmarshal() error = %v", err)
+	}
+	if len(feed.Entries) != 3 {
+		t.Errorf("len(Entries) = %d, want 3", len(feed.Entries))
+	}
+	if strings.Contains(string(data), "xmlns:opds") {
+		t.Errorf("新着のフィードにOPDSの名前空間は不要です:\n%s", data)
+	}
+}
+
+func TestUpdateFeedFolder(t *testing.T) {
+	root, err := defaultSaveRoot()
+	if err != nil {
+		t.Fatalf("defaultSaveRoot() error = %v", err)
+	}
+	other := t.TempDir()
+
+	tests := []struct {
+		name     string
+		savePath string
+		want     string
+	}{
+		{name: "既定の保存先", savePath: filepath.Join(root, "テスト小説"), want: root},
+		{name: "既定の保存先の作者フォルダ", savePath: filepath.Join(root, "作者", "テスト小説"), want: root},
+		{name: "ほかのフォルダ", savePath: filepath.Join(other, "テスト小説"), want: other},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := updateFeedFolder(tt.savePath); got != tt.want {
+				t.Errorf("updateFeedFolder(%s) = %s, want %s", tt.savePath, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFileURL(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("Unix形式のパスで確認する")
+	}
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"/tmp/novel/1.txt", "file:///tmp/novel/1.txt"},
+		{"/tmp/a b/小説.txt", "file:///tmp/a%20b/%E5%B0%8F%E8%AA%AC.txt"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			if got := fileURL(tt.path); got != tt.want {
+				t.Errorf("fileURL(%q) = %q, want %q", tt.path, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,383 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+	"unicode/utf8"
+
+	"golang.org/x/text/encoding/japanese"
+	"golang.org/x/text/transform"
+)
+
+// 検証で見つかった問題の種類
+const (
+	issueMissingEpisode = "missing-episode" // 目次にあるが保存されていない話
+	issueMissingFile    = "missing-file"    // 記録されたファイルが存在しない
+	issueEmptyFile      = "empty"           // 0バイトのファイル
+	issueTruncated      = "truncated"       // 記録より小さい（書き込み途中で切れた）ファイル
+	issueModified       = "modified"        // 記録と内容が異なるファイル
+	issueEncoding       = "encoding"        // 記録と異なる文字コードのファイル
+	issueChanged        = "changed"         // 作者によって本文が更新された話
+	issueRemoved        = "removed"         // 目次から削除・非公開にされた話
+	issueRenumbered     = "renumbered"      // 前後の話の削除・挿入で話数が変わった話
+)
+
+// VerifyOptions は保存済みの小説を検証する際の設定を表す構造体
+type VerifyOptions struct {
+	URL    string `json:"url"`    // 目次のURL（空の場合はマニフェストに記録されたURL）
+	Online bool   `json:"online"` // 最新の目次と比較して未取得の話を探す
+	Deep   bool   `json:"deep"`   // 各話の本文を取得して更新されていないか確認する（時間がかかる）
+	Repair bool   `json:"repair"` // 問題のある話・ファイルだけを取得し直して修復する
+}
+
+// VerifyIssue は検証で見つかった問題1件分を表す構造体
+type VerifyIssue struct {
+	Kind   string `json:"kind"`
+	Number string `json:"number,omitempty"` // 話数（小説全体のファイルの場合は空）
+	Title  string `json:"title,omitempty"`
+	Path   string `json:"path,omitempty"`
+	Detail string `json:"detail"`
+}
+
+// VerifyReport は小説1件分の検証結果を表す構造体
+type VerifyReport struct {
+	Folder   string        `json:"folder"`
+	NCode    string        `json:"ncode"`
+	Title    string        `json:"title"`
+	Checked  int           `json:"checked"` // 確認したファイルの数
+	Issues   []VerifyIssue `json:"issues"`
+	Repaired int           `json:"repaired"` // 修復した話・ファイルの数
+}
+
+// VerifyNovel は保存先フォルダ以下の小説をマニフェストと比較して検証します（フロントエンド・CLI用）
+func (a *App) VerifyNovel(folder string, options VerifyOptions) ([]VerifyReport, error) {
+	stores, err := findNovelStores(folder)
+	if err != nil {
+		return nil, err
+	}
+	if len(stores) == 0 {
+		return nil, fmt.Errorf("マニフェストが見つかりません（%s/<小説番号>/manifest.json）: %s", workDirName, folder)
+	}
+	if options.URL != "" && len(stores) > 1 {
+		return nil, fmt.Errorf("複数の小説が見つかったため、URLを指定できません: %s", folder)
+	}
+
+	reports := make([]VerifyReport, 0, len(stores))
+	for _, store := range stores {
+		report, err := a.verifyNovel(store, options)
+		if err != nil {
+			return reports, fmt.Errorf("%sの検証に失敗しました: %w", store.ncode, err)
+		}
+		reports = append(reports, report)
+	}
+	return reports, nil
+}
+
+// findNovelStores はフォルダ以下のマニフェストを探し、小説ごとの novelStore を返します
+func findNovelStores(folder string) ([]*novelStore, error) {
+	if _, err := os.Stat(folder); err != nil {
+		return nil, fmt.Errorf("フォルダを開けませんでした: %w", err)
+	}
+
+	var stores []*novelStore
+	err := filepath.WalkDir(folder, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if d != nil && d.IsDir() {
+				return fs.SkipDir
+			}
+			return nil
+		}
+		if d.IsDir() || d.Name() != "manifest.json" {
+			return nil
+		}
+		workDir := filepath.Dir(filepath.Dir(path))
+		if filepath.Base(workDir) == workDirName {
+			stores = append(stores, &novelStore{root: filepath.Dir(workDir), ncode: filepath.Base(filepath.Dir(path))})
+		}
+		return nil
+	})
+	return stores, err
+}
+
+// verifyNovel は小説1件分を検証し、指定された場合は修復します
+func (a *App) verifyNovel(store *novelStore, options VerifyOptions) (VerifyReport, error) {
+	manifest, err := store.loadManifest()
+	if err != nil {
+		return VerifyReport{}, err
+	}
+	report := VerifyReport{Folder: store.root, NCode: manifest.NCode, Title: manifest.Title, Issues: []VerifyIssue{}}
+	a.emit("log", fmt.Sprintf("%s（%s）を検証しています...", manifest.Title, manifest.NCode))
+
+	novel := manifest.novel()
+	fetched := make(map[string][]Block)
+
+	// 最新の目次との比較
+	if options.Online || options.Deep {
+		url := options.URL
+		if url == "" {
+			url = manifest.URL
+		}
+		result := a.StartScraping(url)
+		if result.Error != "" {
+			return report, fmt.Errorf("目次の取得に失敗しました: %s", result.Error)
+		}
+		novel = newNovelFromResult(result, url)
+
+		// 目次の変更（削除・話数の変更）は修復せず、次回のダウンロード時に反映する
+		diff := diffTOC(manifest, novel)
+		for _, change := range diff.Changes {
+			switch change.Kind {
+			case tocRemoved:
+				report.Issues = append(report.Issues, VerifyIssue{
+					Kind: issueRemoved, Number: change.Old.Number, Title: change.Old.Title,
+					Detail: "目次から削除されています（次回のダウンロード時に archive フォルダに保管します）",
+				})
+			case tocRenumbered:
+				report.Issues = append(report.Issues, VerifyIssue{
+					Kind: issueRenumbered, Number: change.Old.Number, Title: change.Old.Title,
+					Detail: fmt.Sprintf("%s話に変わっています（次回のダウンロード時に反映します）", change.New.Number),
+				})
+			}
+		}
+
+		requests := 0
+		for _, episode := range novel.Episodes() {
+			record := diff.Matched[episode]
+			if record == nil || record.Number != episode.Number {
+				if manifest.episode(episode.Number) == nil {
+					report.Issues = append(report.Issues, VerifyIssue{
+						Kind: issueMissingEpisode, Number: episode.Number, Title: episode.Title,
+						Detail: "保存されていません",
+					})
+				}
+				continue
+			}
+			if !options.Deep {
+				continue
+			}
+
+			// 本文を取得してハッシュを比較する
+			if requests > 0 {
+				time.Sleep(episodeInterval)
+			}
+			requests++
+			blocks, err := a.fetchEpisodeBlocks(novel, episode)
+			if err != nil {
+				a.emit("log", fmt.Sprintf("%s話の取得に失敗しました: %v", episode.Number, err))
+				continue
+			}
+			fetched[episode.Number] = blocks
+			if blocksHash(blocks) != record.Hash {
+				report.Issues = append(report.Issues, VerifyIssue{
+					Kind: issueChanged, Number: episode.Number, Title: episode.Title,
+					Detail: "掲載されている本文が更新されています",
+				})
+			}
+		}
+	}
+
+	// 保存済みのファイルの確認
+	for _, record := range manifest.Episodes {
+		for _, file := range record.Files {
+			report.Checked++
+			if issue, ok := checkManifestFile(store, file); ok {
+				issue.Number = record.Number
+				issue.Title = record.Title
+				report.Issues = append(report.Issues, issue)
+			}
+		}
+	}
+	for _, file := range manifest.Files {
+		report.Checked++
+		if issue, ok := checkManifestFile(store, file); ok {
+			report.Issues = append(report.Issues, issue)
+		}
+	}
+
+	a.emit("log", fmt.Sprintf("%d件のファイルを確認し、%d件の問題が見つかりました", report.Checked, len(report.Issues)))
+
+	if options.Repair && len(report.Issues) > 0 {
+		repaired, err := a.repairNovel(store, manifest, novel, report.Issues, fetched)
+		report.Repaired = repaired
+		if err != nil {
+			return report, fmt.Errorf("修復に失敗しました: %w", err)
+		}
+		a.emit("log", fmt.Sprintf("%d件を修復しました", repaired))
+	}
+
+	return report, nil
+}
+
+// checkManifestFile はファイルが記録どおりに保存されているかを確認し、問題があればその内容を返します
+func checkManifestFile(store *novelStore, file ManifestFile) (VerifyIssue, bool) {
+	issue := VerifyIssue{Path: file.Path}
+
+	data, err := os.ReadFile(store.path(file.Path))
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+		issue.Kind, issue.Detail = issueMissingFile, "ファイルが存在しません"
+		return issue, true
+	case err != nil:
+		issue.Kind, issue.Detail = issueMissingFile, fmt.Sprintf("ファイルを読み込めません: %v", err)
+		return issue, true
+	case len(data) == 0:
+		issue.Kind, issue.Detail = issueEmptyFile, "ファイルが空です"
+		return issue, true
+	}
+
+	sum := sha256.Sum256(data)
+	if hex.EncodeToString(sum[:]) == file.SHA256 {
+		return VerifyIssue{}, false
+	}
+
+	if int64(len(data)) < file.Size {
+		issue.Kind, issue.Detail = issueTruncated, fmt.Sprintf("ファイルが途中で切れています（%dバイト、記録は%dバイト）", len(data), file.Size)
+		return issue, true
+	}
+	if err := checkEncoding(data, file.Encoding); err != nil {
+		issue.Kind, issue.Detail = issueEncoding, err.Error()
+		return issue, true
+	}
+	issue.Kind, issue.Detail = issueModified, "保存時と内容が異なります"
+	return issue, true
+}
+
+// checkEncoding はテキストが指定の文字コードとして正しいかどうかを確認します（空の場合は確認しない）
+func checkEncoding(data []byte, encoding string) error {
+	switch encoding {
+	case "UTF-8":
+		if !utf8.Valid(data) {
+			return fmt.Errorf("UTF-8として読み込めません")
+		}
+	case "UTF-16LE":
+		// 改行などのASCII文字は上位バイトが0になるため、0のバイトがない場合は別の文字コード
+		if len(data)%2 != 0 || !bytes.Contains(data, []byte{0}) {
+			return fmt.Errorf("UTF-16LEとして読み込めません")
+		}
+	case "Shift-JIS":
+		decoded, _, err := transform.Bytes(japanese.ShiftJIS.NewDecoder(), data)
+		if err != nil || bytes.ContainsRune(decoded, utf8.RuneError) {
+			return fmt.Errorf("Shift-JISとして読み込めません")
+		}
+	}
+	return nil
+}
+
+// fetchEpisodeBlocks は1話分の本文を取得します
+// 掲載されている本文と比べるため、キャッシュの有効期限内でもサーバーに変更を確認します
+func (a *App) fetchEpisodeBlocks(novel *Novel, episode *Episode) ([]Block, error) {
+	if novel.Short {
+		result := a.scrapeNovel(episode.URL, true)
+		if result.Error != "" {
+			return nil, fmt.Errorf("%s", result.Error)
+		}
+		return result.Blocks, nil
+	}
+	page, err := a.scrapeEpisode(episode.URL, true)
+	if err != nil {
+		return nil, err
+	}
+	return page.Blocks, nil
+}
+
+// repairNovel は問題のある話を保存し直し、必要に応じて小説全体のファイルを作り直します
+// 本文はキャッシュを優先し、キャッシュがない場合や本文が更新されている場合のみ取得し直します
+func (a *App) repairNovel(store *novelStore, manifest *Manifest, novel *Novel, issues []VerifyIssue, fetched map[string][]Block) (int, error) {
+	episodeWriters, novelWriters, err := buildWriters(manifest.Formats)
+	if err != nil {
+		return 0, fmt.Errorf("保存時の出力形式を復元できません: %w", err)
+	}
+	defer a.flushSearchIndex(store, manifest)
+
+	episodesByNumber := make(map[string]*Episode)
+	for _, episode := range novel.Episodes() {
+		episodesByNumber[episode.Number] = episode
+	}
+
+	// 修復する話（重複を除いて目次順）と、小説全体のファイルの問題の有無
+	refetch := make(map[string]bool)
+	var targets []string
+	novelFileBroken := false
+	for _, issue := range issues {
+		if issue.Kind == issueRemoved || issue.Kind == issueRenumbered {
+			continue
+		}
+		if issue.Number == "" {
+			novelFileBroken = true
+			continue
+		}
+		if issue.Kind == issueMissingEpisode || issue.Kind == issueChanged {
+			refetch[issue.Number] = true
+		}
+		if !slices.Contains(targets, issue.Number) {
+			targets = append(targets, issue.Number)
+		}
+	}
+
+	repaired := 0
+	requests := 0
+	for _, number := range targets {
+		episode := episodesByNumber[number]
+		if episode == nil {
+			a.emit("log", fmt.Sprintf("%s話は目次に見つからないため修復できません", number))
+			continue
+		}
+
+		blocks, ok := fetched[number]
+		if !ok && !refetch[number] {
+			if cached, err := store.loadBlocks(number); err == nil {
+				blocks, ok = cached, true
+			}
+		}
+		if !ok {
+			if novel.URL == "" {
+				return repaired, fmt.Errorf("%s話の本文のキャッシュがなく、URLも記録されていません", number)
+			}
+			if requests > 0 && !novel.Short {
+				time.Sleep(episodeInterval)
+			}
+			requests++
+			a.emit("log", fmt.Sprintf("%s話: %s を取得し直しています...", number, episode.Title))
+			fetchedBlocks, err := a.fetchEpisodeBlocks(novel, episode)
+			if err != nil {
+				a.emit("log", fmt.Sprintf("%s話の取得に失敗しました: %v", number, err))
+				continue
+			}
+			blocks = fetchedBlocks
+		}
+
+		episode.Blocks = blocks
+		if err := a.saveEpisode(store, manifest, novel, episode, episodeWriters); err != nil {
+			return repaired, err
+		}
+		repaired++
+	}
+
+	// 小説全体のファイルは話を修復した場合も作り直す
+	if len(novelWriters) > 0 && (novelFileBroken || repaired > 0) {
+		for _, episode := range novel.Episodes() {
+			if len(episode.Blocks) == 0 {
+				if blocks, err := store.loadBlocks(episode.Number); err == nil {
+					episode.Blocks = blocks
+				}
+			}
+		}
+		complete := novel.withEpisodes(func(e *Episode) bool { return len(e.Blocks) > 0 })
+		for _, w := range novelWriters {
+			if err := a.saveNovelFile(store, manifest, complete, w); err != nil {
+				return repaired, err
+			}
+			repaired++
+		}
+	}
+
+	return repaired, nil
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"os"
+	"testing"
+)
+
+// newVerifiedNovelStore は全話をTXT（Shift-JIS）とEPUBで保存した保存先を作成します
+func newVerifiedNovelStore(t *testing.T) (*App, *novelStore, *Novel) {
+	t.Helper()
+
+	app := NewApp()
+	novel := newTestNovel()
+	formats := []FormatRequest{
+		{Name: "txt", Options: map[string]interface{}{"encoding": "Shift-JIS"}},
+		{Name: "epub"},
+	}
+	episodeWriters, novelWriters, err := buildWriters(formats)
+	if err != nil {
+		t.Fatalf("buildWriters() error = %v", err)
+	}
+
+	store, manifest, err := app.openNovelStore(t.TempDir(), novel, formats)
+	if err != nil {
+		t.Fatalf("openNovelStore() error = %v", err)
+	}
+	for _, episode := range novel.Episodes() {
+		if err := app.saveEpisode(store, manifest, novel, episode, episodeWriters); err != nil {
+			t.Fatalf("saveEpisode() error = %v", err)
+		}
+	}
+	for _, w := range novelWriters {
+		if err := app.saveNovelFile(store, manifest, novel, w); err != nil {
+			t.Fatalf("saveNovelFile() error = %v", err)
+		}
+	}
+	return app, store, novel
+}
+
+func TestVerifyNovel(t *testing.T) {
+	tests := []struct {
+		name     string
+		damage   func(store *novelStore)
+		expected []string // 見つかる問題の種類
+	}{
+		{
+			name:   "問題なし",
+			damage: func(store *novelStore) {},
+		},
+		{
+			name: "ファイルの削除",
+			damage: func(store *novelStore) {
+				os.Remove(store.path("N1234AB-2.txt"))
+			},
+			expected: []string{issueMissingFile},
+		},
+		{
+			name: "空のファイルと途中で切れたファイル",
+			damage: func(store *novelStore) {
+				os.WriteFile(store.path("N1234AB-1.txt"), nil, 0644)
+				data, _ := os.ReadFile(store.path("テスト小説.epub"))
+				os.WriteFile(store.path("テスト小説.epub"), data[:len(data)/2], 0644)
+			},
+			expected: []string{issueEmptyFile, issueTruncated},
+		},
+		{
+			name: "文字コードの不一致",
+			damage: func(store *novelStore) {
+				os.WriteFile(store.path("N1234AB-3.txt"), []byte("三話\r\n\r\n本文三（UTF-8で上書き）\r\n"), 0644)
+			},
+			expected: []string{issueEncoding},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app, store, _ := newVerifiedNovelStore(t)
+			tt.damage(store)
+
+			reports, err := app.VerifyNovel(store.root, VerifyOptions{})
+			if err != nil {
+				t.Fatalf("VerifyNovel() error = %v", err)
+			}
+			if len(reports) != 1 {
+				t.Fatalf("len(reports) = %d, want 1", len(reports))
+			}
+			if reports[0].Checked != 4 {
+				t.Errorf("Checked = %d, want 4", reports[0].Checked)
+			}
+
+			var kinds []string
+			for _, issue := range reports[0].Issues {
+				kinds = append(kinds, issue.Kind)
+			}
+			if len(kinds) != len(tt.expected) {
+				t.Fatalf("Issues = %v, want %v", kinds, tt.expected)
+			}
+			for i := range kinds {
+				if kinds[i] != tt.expected[i] {
+					t.Errorf("Issues[%d].Kind = %s, want %s", i, kinds[i], tt.expected[i])
+				}
+			}
+		})
+	}
+}
+
+func TestVerifyNovel_Repair(t *testing.T) {
+	app, store, _ := newVerifiedNovelStore(t)
+	os.Remove(store.path("N1234AB-2.txt"))
+	os.WriteFile(store.path("テスト小説.epub"), []byte("PK"), 0644)
+
+	// 本文のキャッシュから修復するため、通信は発生しない
+	reports, err := app.VerifyNovel(store.root, VerifyOptions{Repair: true})
+	if err != nil {
+		t.Fatalf("VerifyNovel() error = %v", err)
+	}
+	if reports[0].Repaired != 2 {
+		t.Errorf("Repaired = %d, want 2", reports[0].Repaired)
+	}
+
+	reports, err = app.VerifyNovel(store.root, VerifyOptions{})
+	if err != nil {
+		t.Fatalf("VerifyNovel() error = %v", err)
+	}
+	if len(reports[0].Issues) != 0 {
+		t.Errorf("修復後も問題が残っています: %+v", reports[0].Issues)
+	}
+}
+
+func TestCheckEncoding(t *testing.T) {
+	tests := []struct {
+		name     string
+		data     []byte
+		encoding string
+		wantErr  bool
+	}{
+		{name: "UTF-8", data: []byte("本文\n"), encoding: "UTF-8"},
+		{name: "UTF-8ではない", data: []byte{0x96, 0x7b}, encoding: "UTF-8", wantErr: true},
+		{name: "UTF-16LE", data: []byte{0x2c, 0x67, 0x0a, 0x00}, encoding: "UTF-16LE"},
+		{name: "UTF-16LEではない", data: []byte("本文\n"), encoding: "UTF-16LE", wantErr: true},
+		{name: "Shift-JIS", data: []byte{0x96, 0x7b, 0x95, 0xb6}, encoding: "Shift-JIS"},
+		{name: "Shift-JISではない", data: []byte("本文"), encoding: "Shift-JIS", wantErr: true},
+		{name: "文字コードの記録なし", data: []byte{0xff}, encoding: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := checkEncoding(tt.data, tt.encoding); (err != nil) != tt.wantErr {
+				t.Errorf("checkEncoding() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
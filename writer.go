@@ -0,0 +1,220 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"golang.org/x/text/encoding/japanese"
+	"golang.org/x/text/transform"
+)
+
+// Writer は文書モデルを特定の形式で出力する出力形式が実装するインターフェース
+type Writer interface {
+	// Ext は出力ファイルの拡張子（ドットなし）を返します
+	Ext() string
+}
+
+// EpisodeWriter は1話ごとのファイルを出力できる形式が実装します
+type EpisodeWriter interface {
+	Writer
+	WriteEpisode(w io.Writer, novel *Novel, episode *Episode) error
+}
+
+// NovelWriter は小説全体を1つのファイルに出力できる形式が実装します
+type NovelWriter interface {
+	Writer
+	// NovelFileName は小説全体のファイル名（拡張子付き）を返します
+	NovelFileName(novel *Novel) string
+	WriteNovel(w io.Writer, novel *Novel) error
+}
+
+// episodeNamer は各話のファイル名を独自に決める出力形式が実装します
+type episodeNamer interface {
+	EpisodeFileName(novel *Novel, episode *Episode) string
+}
+
+// episodeFileName は各話のファイル名（既定は N1234AB-5.txt など）を返します
+func episodeFileName(novel *Novel, episode *Episode, w Writer) string {
+	if namer, ok := w.(episodeNamer); ok {
+		return namer.EpisodeFileName(novel, episode)
+	}
+	return generateFileName(novel.NCode, episode.Number) + "." + w.Ext()
+}
+
+// textEncoding はテキスト形式の文字コードと改行コードを表す構造体
+type textEncoding struct {
+	Encoding   string
+	LineEnding string
+}
+
+// encode はUTF-8・LFのテキストを指定の文字コードと改行コードに変換します
+func (e textEncoding) encode(content string) ([]byte, error) {
+	// 改行コードの変換
+	switch e.LineEnding {
+	case "CR+LF":
+		content = strings.ReplaceAll(content, "\n", "\r\n")
+	case "CR":
+		content = strings.ReplaceAll(content, "\n", "\r")
+	}
+
+	// エンコードの変換
+	switch e.Encoding {
+	case "UTF-16LE":
+		// UTF-16LEエンコード
+		utf16Data := make([]byte, 0, len(content)*2)
+		for _, r := range content {
+			utf16Data = append(utf16Data, byte(r), byte(r>>8))
+		}
+		return utf16Data, nil
+	case "Shift-JIS":
+		// Shift-JISエンコード
+		encoder := japanese.ShiftJIS.NewEncoder()
+		txtData, _, err := transform.Bytes(encoder, []byte(content))
+		if err != nil {
+			return nil, fmt.Errorf("Shift-JISエンコードエラー: %w", err)
+		}
+		return txtData, nil
+	default:
+		return []byte(content), nil
+	}
+}
+
+// encodingName は文字コードの名前を返します（マニフェストへの記録に使用）
+func (e textEncoding) encodingName() string { return e.Encoding }
+
+// write は変換したテキストを書き込みます
+func (e textEncoding) write(w io.Writer, content string) error {
+	data, err := e.encode(content)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(data)
+	return err
+}
+
+// aozoraWriter はルビ・傍点を青空文庫形式で表すテキスト形式
+type aozoraWriter struct {
+	textEncoding
+}
+
+func (aozoraWriter) Ext() string { return "txt" }
+
+// NovelFileName は連結ファイルのファイル名を返します
+func (aozoraWriter) NovelFileName(novel *Novel) string { return "all.txt" }
+
+// WriteEpisode は各話のタイトルと本文を出力します（短編の場合は小説タイトルを見出しにする）
+func (w aozoraWriter) WriteEpisode(out io.Writer, novel *Novel, episode *Episode) error {
+	var formatted strings.Builder
+
+	title := episode.Title
+	if title == "" {
+		title = novel.Title
+	}
+	formatted.WriteString(escapeAozora(title))
+	formatted.WriteString("\n\n")
+
+	// 本文（ルビ・傍点は青空文庫形式に変換）
+	formatted.WriteString(renderAozora(episode.Blocks))
+	formatted.WriteString("\n")
+
+	return w.write(out, formatted.String())
+}
+
+// WriteNovel は冒頭に小説タイトルと作者名を置き、各話を点線区切りで連結して出力します
+func (w aozoraWriter) WriteNovel(out io.Writer, novel *Novel) error {
+	var combined strings.Builder
+	combined.WriteString(escapeAozora(novel.Title))
+	combined.WriteString("\n")
+	combined.WriteString(escapeAozora(novel.Author))
+	combined.WriteString("\n\n\n")
+
+	first := true
+	for _, chapter := range novel.Chapters {
+		for i, episode := range chapter.Episodes {
+			if !first {
+				combined.WriteString("\n\n----------------\n\n\n")
+			}
+			first = false
+
+			// 章の最初の話の前に章タイトルを大見出しとして入れる
+			if i == 0 && chapter.Title != "" {
+				combined.WriteString("［＃大見出し］" + escapeAozora(chapter.Title) + "［＃大見出し終わり］\n\n")
+			}
+
+			combined.WriteString(escapeAozora(episode.Title))
+			combined.WriteString("\n\n")
+			combined.WriteString(renderAozora(episode.Blocks))
+			combined.WriteString("\n")
+		}
+	}
+
+	return w.write(out, combined.String())
+}
+
+// plainTextWriter はルビを括弧書きにした注記のないテキスト形式
+type plainTextWriter struct {
+	textEncoding
+}
+
+func (plainTextWriter) Ext() string { return "txt" }
+
+// NovelFileName は連結ファイルのファイル名を返します
+func (plainTextWriter) NovelFileName(novel *Novel) string { return "all.txt" }
+
+// renderPlainText はブロックの列を注記のないテキストに変換します
+func renderPlainText(blocks []Block) string {
+	lines := make([]string, 0, len(blocks))
+	for _, block := range blocks {
+		switch block.Kind {
+		case BlockParagraph:
+			var line strings.Builder
+			for _, inline := range block.Inlines {
+				line.WriteString(inline.Text)
+				if inline.Kind == InlineRuby {
+					line.WriteString("（" + inline.Ruby + "）")
+				}
+			}
+			lines = append(lines, line.String())
+		case BlockBlank:
+			lines = append(lines, "")
+		case BlockIllustration:
+			lines = append(lines, "［挿絵］")
+		case BlockSeparator:
+			lines = append(lines, "************************************************")
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// WriteEpisode は各話のタイトルと本文を出力します
+func (w plainTextWriter) WriteEpisode(out io.Writer, novel *Novel, episode *Episode) error {
+	title := episode.Title
+	if title == "" {
+		title = novel.Title
+	}
+	return w.write(out, title+"\n\n"+renderPlainText(episode.Blocks)+"\n")
+}
+
+// WriteNovel は小説全体を1つのテキストとして出力します
+func (w plainTextWriter) WriteNovel(out io.Writer, novel *Novel) error {
+	var combined strings.Builder
+	combined.WriteString(novel.Title + "\n" + novel.Author + "\n\n\n")
+
+	first := true
+	for _, chapter := range novel.Chapters {
+		for i, episode := range chapter.Episodes {
+			if !first {
+				combined.WriteString("\n\n----------------\n\n\n")
+			}
+			first = false
+
+			if i == 0 && chapter.Title != "" {
+				combined.WriteString("■ " + chapter.Title + "\n\n")
+			}
+			combined.WriteString(episode.Title + "\n\n" + renderPlainText(episode.Blocks) + "\n")
+		}
+	}
+
+	return w.write(out, combined.String())
+}
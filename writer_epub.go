@@ -0,0 +1,256 @@
+package main
+
+import (
+	"archive/zip"
+	"fmt"
+	"html"
+	"io"
+	"strings"
+	"time"
+)
+
+// epubWriter は小説全体を1つのEPUB3ファイルとして出力する形式
+type epubWriter struct {
+	Vertical bool // 縦書きで出力する
+}
+
+func (epubWriter) Ext() string { return "epub" }
+
+// NovelFileName はEPUBのファイル名を返します
+func (epubWriter) NovelFileName(novel *Novel) string {
+	return sanitizeFileName(novel.Title) + ".epub"
+}
+
+// epubItem はEPUBに含めるXHTML文書1件分の情報です
+type epubItem struct {
+	ID     string
+	Href   string
+	Title  string
+	Remote bool // 外部の挿絵を参照している
+}
+
+// WriteNovel はEPUBファイルを出力します
+func (w epubWriter) WriteNovel(out io.Writer, novel *Novel) error {
+	zw := zip.NewWriter(out)
+
+	// mimetype は先頭に無圧縮で格納する必要がある
+	mimetype, err := zw.CreateHeader(&zip.FileHeader{Name: "mimetype", Method: zip.Store})
+	if err != nil {
+		return err
+	}
+	if _, err := io.WriteString(mimetype, "application/epub+zip"); err != nil {
+		return err
+	}
+
+	files := map[string]string{
+		"META-INF/container.xml": epubContainerXML,
+		"OEBPS/style.css":        w.styleCSS(),
+	}
+	order := []string{"META-INF/container.xml", "OEBPS/style.css"}
+	add := func(name, content string) {
+		files[name] = content
+		order = append(order, name)
+	}
+
+	// 表紙（タイトルページ）
+	items := []epubItem{{ID: "title", Href: "title.xhtml", Title: novel.Title}}
+	add("OEBPS/title.xhtml", w.xhtml(novel.Title, "style.css", fmt.Sprintf(
+		"<div class=\"title-page\">\n<h1>%s</h1>\n<p class=\"author\">%s</p>\n</div>",
+		html.EscapeString(novel.Title), html.EscapeString(novel.Author))))
+
+	// 各話
+	episodeItems := make(map[*Episode]epubItem)
+	for _, chapter := range novel.Chapters {
+		for i, episode := range chapter.Episodes {
+			var body strings.Builder
+			if i == 0 && chapter.Title != "" {
+				fmt.Fprintf(&body, "<h2 class=\"chapter\">%s</h2>\n", html.EscapeString(chapter.Title))
+			}
+			title := episode.Title
+			if title == "" {
+				title = novel.Title
+			}
+			fmt.Fprintf(&body, "<h3>%s</h3>\n", html.EscapeString(title))
+			body.WriteString(renderHTML(episode.Blocks))
+
+			item := epubItem{
+				ID:    fmt.Sprintf("ep%04d", episode.Index),
+				Href:  fmt.Sprintf("text/ep%04d.xhtml", episode.Index),
+				Title: title,
+			}
+			for _, block := range episode.Blocks {
+				if block.Kind == BlockIllustration {
+					item.Remote = true
+				}
+			}
+			items = append(items, item)
+			episodeItems[episode] = item
+			add("OEBPS/"+item.Href, w.xhtml(title, "../style.css", toXHTML(body.String())))
+		}
+	}
+
+	add("OEBPS/nav.xhtml", w.navXHTML(novel, episodeItems))
+	add("OEBPS/toc.ncx", w.tocNCX(novel, items))
+	add("OEBPS/content.opf", w.contentOPF(novel, items))
+
+	for _, name := range order {
+		f, err := zw.Create(name)
+		if err != nil {
+			return err
+		}
+		if _, err := io.WriteString(f, files[name]); err != nil {
+			return err
+		}
+	}
+
+	return zw.Close()
+}
+
+const epubContainerXML = `<?xml version="1.0" encoding="UTF-8"?>
+<container version="1.0" xmlns="urn:oasis:names:tc:opendocument:xmlns:container">
+  <rootfiles>
+    <rootfile full-path="OEBPS/content.opf" media-type="application/oebps-package+xml"/>
+  </rootfiles>
+</container>
+`
+
+// toXHTML はHTML断片の空要素をXHTMLの形式に変換します
+func toXHTML(fragment string) string {
+	fragment = strings.ReplaceAll(fragment, "<br>", "<br/>")
+	fragment = strings.ReplaceAll(fragment, "<hr>", "<hr/>")
+	return strings.ReplaceAll(fragment, "alt=\"挿絵\">", "alt=\"挿絵\"/>")
+}
+
+// styleCSS はEPUB用のスタイルシートを返します
+func (w epubWriter) styleCSS() string {
+	writingMode := "horizontal-tb"
+	if w.Vertical {
+		writingMode = "vertical-rl"
+	}
+	return fmt.Sprintf(`html {
+  -epub-writing-mode: %[1]s;
+  -webkit-writing-mode: %[1]s;
+  writing-mode: %[1]s;
+}
+body { font-family: serif; line-height: 1.8; }
+p { margin: 0; }
+h2.chapter { font-size: 1.3em; margin: 2em 0; }
+h3 { font-size: 1.1em; margin: 1em 0 2em; }
+rt { font-size: 0.5em; }
+em.emphasis { font-style: normal; -epub-text-emphasis-style: sesame; -webkit-text-emphasis-style: sesame; text-emphasis-style: sesame; }
+.title-page { text-align: center; margin-top: 30%%; }
+.title-page .author { margin-top: 2em; }
+.illustration img { max-width: 100%%; max-height: 100%%; }
+`, writingMode)
+}
+
+// xhtml はXHTML文書全体を組み立てます
+func (w epubWriter) xhtml(title, cssHref, body string) string {
+	return fmt.Sprintf(`<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE html>
+<html xmlns="http://www.w3.org/1999/xhtml" xmlns:epub="http://www.idpf.org/2007/ops" xml:lang="ja" lang="ja">
+<head>
+<meta charset="UTF-8"/>
+<title>%s</title>
+<link rel="stylesheet" type="text/css" href="%s"/>
+</head>
+<body>
+%s
+</body>
+</html>
+`, html.EscapeString(title), cssHref, body)
+}
+
+// navXHTML はEPUB3の目次（ナビゲーション文書）を生成します
+func (w epubWriter) navXHTML(novel *Novel, episodeItems map[*Episode]epubItem) string {
+	var nav strings.Builder
+	nav.WriteString("<nav epub:type=\"toc\" id=\"toc\">\n<h1>目次</h1>\n<ol>\n")
+
+	for _, chapter := range novel.Chapters {
+		if chapter.Title != "" && len(chapter.Episodes) > 0 {
+			first := episodeItems[chapter.Episodes[0]]
+			fmt.Fprintf(&nav, "<li><a href=\"%s\">%s</a>\n<ol>\n", first.Href, html.EscapeString(chapter.Title))
+		}
+		for _, episode := range chapter.Episodes {
+			item := episodeItems[episode]
+			fmt.Fprintf(&nav, "<li><a href=\"%s\">%s</a></li>\n", item.Href, html.EscapeString(item.Title))
+		}
+		if chapter.Title != "" && len(chapter.Episodes) > 0 {
+			nav.WriteString("</ol>\n</li>\n")
+		}
+	}
+	nav.WriteString("</ol>\n</nav>")
+
+	return w.xhtml("目次", "style.css", nav.String())
+}
+
+// tocNCX はEPUB2互換の目次を生成します
+func (w epubWriter) tocNCX(novel *Novel, items []epubItem) string {
+	var points strings.Builder
+	for i, item := range items {
+		fmt.Fprintf(&points, `    <navPoint id="nav%d" playOrder="%d">
+      <navLabel><text>%s</text></navLabel>
+      <content src="%s"/>
+    </navPoint>
+`, i+1, i+1, html.EscapeString(item.Title), item.Href)
+	}
+
+	return fmt.Sprintf(`<?xml version="1.0" encoding="UTF-8"?>
+<ncx xmlns="http://www.daisy.org/z3986/2005/ncx/" version="2005-1">
+  <head>
+    <meta name="dtb:uid" content="%s"/>
+  </head>
+  <docTitle><text>%s</text></docTitle>
+  <navMap>
+%s  </navMap>
+</ncx>
+`, html.EscapeString(epubIdentifier(novel)), html.EscapeString(novel.Title), points.String())
+}
+
+// contentOPF はパッケージ文書を生成します
+func (w epubWriter) contentOPF(novel *Novel, items []epubItem) string {
+	var manifest, spine strings.Builder
+	for _, item := range items {
+		properties := ""
+		if item.Remote {
+			properties = ` properties="remote-resources"`
+		}
+		fmt.Fprintf(&manifest, "    <item id=\"%s\" href=\"%s\" media-type=\"application/xhtml+xml\"%s/>\n", item.ID, item.Href, properties)
+		fmt.Fprintf(&spine, "    <itemref idref=\"%s\"/>\n", item.ID)
+	}
+
+	direction := "ltr"
+	if w.Vertical {
+		direction = "rtl"
+	}
+
+	return fmt.Sprintf(`<?xml version="1.0" encoding="UTF-8"?>
+<package xmlns="http://www.idpf.org/2007/opf" version="3.0" unique-identifier="bookid" xml:lang="ja">
+  <metadata xmlns:dc="http://purl.org/dc/elements/1.1/">
+    <dc:identifier id="bookid">%s</dc:identifier>
+    <dc:title>%s</dc:title>
+    <dc:creator>%s</dc:creator>
+    <dc:language>ja</dc:language>
+    <dc:source>%s</dc:source>
+    <meta property="dcterms:modified">%s</meta>
+  </metadata>
+  <manifest>
+    <item id="nav" href="nav.xhtml" media-type="application/xhtml+xml" properties="nav"/>
+    <item id="ncx" href="toc.ncx" media-type="application/x-dtbncx+xml"/>
+    <item id="style" href="style.css" media-type="text/css"/>
+%s  </manifest>
+  <spine toc="ncx" page-progression-direction="%s">
+%s  </spine>
+</package>
+`, html.EscapeString(epubIdentifier(novel)), html.EscapeString(novel.Title), html.EscapeString(novel.Author),
+		html.EscapeString(novel.URL), time.Now().UTC().Format("2006-01-02T15:04:05Z"),
+		manifest.String(), direction, spine.String())
+}
+
+// epubIdentifier はEPUBの識別子を返します
+func epubIdentifier(novel *Novel) string {
+	if novel.NCode != "" {
+		return "urn:narou:" + strings.ToLower(novel.NCode)
+	}
+	return novel.URL
+}
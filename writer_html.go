@@ -0,0 +1,295 @@
+package main
+
+import (
+	"fmt"
+	"html"
+	"io"
+	"strings"
+)
+
+// htmlWriter は各話のHTMLとエピソード一覧のHTMLを出力する形式
+type htmlWriter struct {
+	Naming fileNaming // ファイル名のテンプレート（リンク先の決定にも使用）
+}
+
+func (htmlWriter) Ext() string { return "html" }
+
+// NovelFileName はエピソード一覧のファイル名を返します
+func (w htmlWriter) NovelFileName(novel *Novel) string {
+	if w.Naming.Novel != "" {
+		return w.Naming.Novel.render(novel, nil, w.Ext())
+	}
+	return "index.html"
+}
+
+// EpisodeFileName は各話のファイル名を返します
+func (w htmlWriter) EpisodeFileName(novel *Novel, episode *Episode) string {
+	if w.Naming.Episode != "" {
+		return w.Naming.Episode.render(novel, episode, w.Ext())
+	}
+	return generateFileName(novel.NCode, episode.Number) + "." + w.Ext()
+}
+
+// WriteEpisode はエピソード用HTMLを出力します
+func (w htmlWriter) WriteEpisode(out io.Writer, novel *Novel, episode *Episode) error {
+	if novel.Short {
+		_, err := io.WriteString(out, w.shortNovelHTML(novel.Title, episode.Blocks))
+		return err
+	}
+
+	// 本文ブロックをHTMLに変換
+	htmlContent := renderHTML(episode.Blocks)
+	episodeTitle := html.EscapeString(episode.Title)
+	novelTitle := html.EscapeString(novel.Title)
+
+	// ナビゲーションリンクの生成（テンプレートでフォルダが分かれる場合に備えて相対パスにする）
+	self := w.EpisodeFileName(novel, episode)
+	var prevLink, nextLink string
+	episodes := novel.Episodes()
+	for i, e := range episodes {
+		if e != episode {
+			continue
+		}
+		if i > 0 {
+			prevLink = fmt.Sprintf(`<a href="%s">← 前のエピソード</a>`, html.EscapeString(relativeLink(self, w.EpisodeFileName(novel, episodes[i-1]))))
+		}
+		if i < len(episodes)-1 {
+			nextLink = fmt.Sprintf(`<a href="%s">次のエピソード →</a>`, html.EscapeString(relativeLink(self, w.EpisodeFileName(novel, episodes[i+1]))))
+		}
+	}
+
+	page := fmt.Sprintf(`<!DOCTYPE html>
+<html lang="ja">
+<head>
+    <meta charset="UTF-8">
+    <meta name="viewport" content="width=device-width, initial-scale=1.0">
+    <title>%s - %s</title>
+    <style>
+        body { font-family: 'Hiragino Kaku Gothic Pro', 'ヒラギノ角ゴ Pro W3', Meiryo, メイリオ, Osaka, 'MS PGothic', arial, helvetica, sans-serif; line-height: 1.6; margin: 40px; max-width: 800px; margin: 0 auto; padding: 20px; }
+        h1 { color: #333; border-bottom: 2px solid #333; padding-bottom: 10px; }
+        .nav { margin: 20px 0; text-align: center; }
+        .nav a { display: inline-block; margin: 0 10px; padding: 8px 16px; background: #f0f0f0; text-decoration: none; color: #333; border-radius: 4px; }
+        .nav a:hover { background: #e0e0e0; }
+        .content { margin: 20px 0; }
+        .back-to-index { text-align: center; margin: 30px 0; }
+        .back-to-index a { padding: 10px 20px; background: #007bff; color: white; text-decoration: none; border-radius: 4px; }
+        .back-to-index a:hover { background: #0056b3; }
+    </style>
+</head>
+<body>
+    <h1>%s</h1>
+    
+    <div class="nav">
+        %s
+        %s
+    </div>
+    
+    <div class="content">
+        %s
+    </div>
+    
+    <div class="nav">
+        %s
+        %s
+    </div>
+    
+    <div class="back-to-index">
+        <a href="%s">← エピソード一覧に戻る</a>
+    </div>
+</body>
+</html>`, episodeTitle, novelTitle, episodeTitle, prevLink, nextLink, htmlContent, prevLink, nextLink, html.EscapeString(relativeLink(self, w.NovelFileName(novel))))
+
+	_, err := io.WriteString(out, page)
+	return err
+}
+
+// WriteNovel はエピソード一覧のHTMLを出力します
+func (w htmlWriter) WriteNovel(out io.Writer, novel *Novel) error {
+	novelTitle := html.EscapeString(novel.Title)
+
+	var episodeList strings.Builder
+	for _, chapter := range novel.Chapters {
+		if chapter.Title != "" {
+			episodeList.WriteString(fmt.Sprintf("    <h2>%s</h2>\n", html.EscapeString(chapter.Title)))
+		}
+		episodeList.WriteString("    <ul>\n")
+		for _, episode := range chapter.Episodes {
+			title := episode.Title
+			if title == "" {
+				title = novel.Title
+			}
+			episodeList.WriteString(fmt.Sprintf(`        <li><a href="%s">第%d話 %s</a></li>
+`, html.EscapeString(relativeLink(w.NovelFileName(novel), w.EpisodeFileName(novel, episode))), episode.Index, html.EscapeString(title)))
+		}
+		episodeList.WriteString("    </ul>\n")
+	}
+
+	page := fmt.Sprintf(`<!DOCTYPE html>
+<html lang="ja">
+<head>
+    <meta charset="UTF-8">
+    <meta name="viewport" content="width=device-width, initial-scale=1.0">
+    <title>%s - エピソード一覧</title>
+    <style>
+        body { font-family: 'Hiragino Kaku Gothic Pro', 'ヒラギノ角ゴ Pro W3', Meiryo, メイリオ, Osaka, 'MS PGothic', arial, helvetica, sans-serif; line-height: 1.6; margin: 40px; max-width: 800px; margin: 0 auto; padding: 20px; }
+        h1 { color: #333; border-bottom: 2px solid #333; padding-bottom: 10px; }
+        .author { text-align: center; margin: 20px 0; color: #666; }
+        h2 { color: #333; font-size: 1.1em; margin-top: 30px; }
+        ul { list-style-type: none; padding: 0; }
+        li { margin: 8px 0; padding: 8px; border: 1px solid #ddd; border-radius: 4px; }
+        li:hover { background-color: #f9f9f9; }
+        a { text-decoration: none; color: #007bff; }
+        a:hover { text-decoration: underline; }
+    </style>
+</head>
+<body>
+    <h1>%s</h1>
+    <div class="author">%s</div>
+%s</body>
+</html>`, novelTitle, novelTitle, html.EscapeString(novel.Author), episodeList.String())
+
+	_, err := io.WriteString(out, page)
+	return err
+}
+
+// shortNovelHTML は短編小説用のHTMLを生成します
+func (w htmlWriter) shortNovelHTML(title string, blocks []Block) string {
+	rawHTML := renderHTML(blocks)
+	title = html.EscapeString(title)
+	return fmt.Sprintf(`<!DOCTYPE html>
+<html lang="ja">
+<head>
+    <meta charset="UTF-8">
+    <meta name="viewport" content="width=device-width, initial-scale=1.0">
+    <title>%s</title>
+    <style>
+        /* 元サイトのスタイルを模擬 */
+        body { 
+            font-family: "Hiragino Kaku Gothic Pro", "ヒラギノ角ゴ Pro W3", Meiryo, メイリオ, Osaka, "MS PGothic", arial, helvetica, sans-serif; 
+            line-height: 1.7; 
+            color: #333; 
+            background-color: #fff;
+            max-width: 800px; 
+            margin: 0 auto; 
+            padding: 20px; 
+        }
+        
+        /* 小説本文エリア */
+        .p-novel__body {
+            margin: 30px 0;
+            line-height: 1.8;
+        }
+        
+        /* 本文テキスト */
+        .p-novel__text {
+            margin: 1.5em 0;
+            text-align: left;
+        }
+        
+        /* 改ページ */
+        .js-novel-text-br {
+            height: 1em;
+        }
+        
+        /* ルビ */
+        ruby {
+            ruby-align: center;
+        }
+        
+        rt {
+            font-size: 0.7em;
+        }
+        
+        /* 傍点 */
+        .emphasis {
+            text-emphasis: filled circle;
+            -webkit-text-emphasis: filled circle;
+        }
+        
+        h1 { 
+            color: #333; 
+            border-bottom: 2px solid #007bff; 
+            padding-bottom: 10px;
+            margin-bottom: 30px;
+        }
+    </style>
+</head>
+<body>
+    <h1>%s</h1>
+    
+    <div class="p-novel__body">
+        %s
+    </div>
+</body>
+</html>`, title, title, rawHTML)
+}
+
+// singleHTMLWriter は小説全体を目次付きの1つのHTMLファイルとして出力する形式
+type singleHTMLWriter struct{}
+
+func (singleHTMLWriter) Ext() string { return "html" }
+
+// NovelFileName はHTMLファイルのファイル名を返します
+func (singleHTMLWriter) NovelFileName(novel *Novel) string {
+	return sanitizeFileName(novel.Title) + ".html"
+}
+
+// WriteNovel は目次と全話の本文を含むHTMLを出力します
+func (w singleHTMLWriter) WriteNovel(out io.Writer, novel *Novel) error {
+	var toc, body strings.Builder
+	for c, chapter := range novel.Chapters {
+		if chapter.Title != "" {
+			anchor := fmt.Sprintf("chapter-%d", c+1)
+			fmt.Fprintf(&toc, "        <li class=\"chapter\"><a href=\"#%s\">%s</a></li>\n", anchor, html.EscapeString(chapter.Title))
+			fmt.Fprintf(&body, "    <h2 id=\"%s\" class=\"chapter\">%s</h2>\n", anchor, html.EscapeString(chapter.Title))
+		}
+		for _, episode := range chapter.Episodes {
+			title := episode.Title
+			if title == "" {
+				title = novel.Title
+			}
+			anchor := fmt.Sprintf("episode-%d", episode.Index)
+			fmt.Fprintf(&toc, "        <li><a href=\"#%s\">%s</a></li>\n", anchor, html.EscapeString(title))
+			fmt.Fprintf(&body, "    <section class=\"episode\">\n    <h3 id=\"%s\">%s</h3>\n", anchor, html.EscapeString(title))
+			body.WriteString(renderHTML(episode.Blocks))
+			body.WriteString("    <p class=\"to-toc\"><a href=\"#toc\">目次へ</a></p>\n    </section>\n")
+		}
+	}
+
+	page := fmt.Sprintf(`<!DOCTYPE html>
+<html lang="ja">
+<head>
+    <meta charset="UTF-8">
+    <meta name="viewport" content="width=device-width, initial-scale=1.0">
+    <title>%s</title>
+    <style>
+        body { font-family: 'Hiragino Mincho ProN', 'Yu Mincho', serif; line-height: 1.8; color: #333; max-width: 800px; margin: 0 auto; padding: 20px; }
+        h1 { border-bottom: 2px solid #333; padding-bottom: 10px; }
+        .author { text-align: right; color: #666; }
+        #toc ul { list-style-type: none; padding: 0; }
+        #toc li { margin: 4px 0 4px 1em; }
+        #toc li.chapter { margin-left: 0; font-weight: bold; }
+        h2.chapter { margin-top: 3em; }
+        .episode { margin: 3em 0; }
+        p { margin: 0; }
+        rt { font-size: 0.5em; }
+        .emphasis { font-style: normal; text-emphasis: sesame; -webkit-text-emphasis: sesame; }
+        .illustration img { max-width: 100%%; }
+        .to-toc { text-align: right; margin-top: 1em; font-size: 0.9em; }
+    </style>
+</head>
+<body>
+    <h1>%s</h1>
+    <p class="author">%s</p>
+    <nav id="toc">
+    <h2>目次</h2>
+    <ul>
+%s    </ul>
+    </nav>
+%s</body>
+</html>
+`, html.EscapeString(novel.Title), html.EscapeString(novel.Title), html.EscapeString(novel.Author), toc.String(), body.String())
+
+	_, err := io.WriteString(out, page)
+	return err
+}
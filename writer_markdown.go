@@ -0,0 +1,109 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+)
+
+// markdownWriter は小説全体を1つのMarkdownファイルとして出力する形式
+type markdownWriter struct {
+	Ruby string // ルビの表記（"html" は <ruby> タグ、"paren" は 漢字(かんじ)）
+}
+
+func (markdownWriter) Ext() string { return "md" }
+
+// NovelFileName はMarkdownファイルのファイル名を返します
+func (markdownWriter) NovelFileName(novel *Novel) string {
+	return sanitizeFileName(novel.Title) + ".md"
+}
+
+// markdownSpecialChars はMarkdownの記法として解釈される文字
+var markdownSpecialChars = strings.NewReplacer(
+	`\`, `\\`, "`", "\\`", `*`, `\*`, `_`, `\_`, `[`, `\[`, `]`, `\]`, `<`, `&lt;`, `>`, `&gt;`, `|`, `\|`,
+)
+
+// markdownLineStart は行頭にある場合にMarkdownの記法として解釈される文字列にマッチします
+var markdownLineStart = regexp.MustCompile(`^([#+\-=]|[0-9]+\.)`)
+
+// escapeMarkdown はMarkdownの記法として解釈される文字をエスケープします
+func escapeMarkdown(text string) string {
+	return markdownSpecialChars.Replace(text)
+}
+
+// renderMarkdownInlines は段落内の要素をMarkdownに変換します
+func (w markdownWriter) renderMarkdownInlines(inlines []Inline) string {
+	var text strings.Builder
+	for _, inline := range inlines {
+		switch inline.Kind {
+		case InlineRuby:
+			if w.Ruby == "paren" {
+				text.WriteString(escapeMarkdown(inline.Text) + "(" + escapeMarkdown(inline.Ruby) + ")")
+			} else {
+				text.WriteString("<ruby>" + escapeMarkdown(inline.Text) + "<rp>(</rp><rt>" + escapeMarkdown(inline.Ruby) + "</rt><rp>)</rp></ruby>")
+			}
+		case InlineEmphasis:
+			text.WriteString("*" + escapeMarkdown(inline.Text) + "*")
+		default:
+			text.WriteString(escapeMarkdown(inline.Text))
+		}
+	}
+	return text.String()
+}
+
+// renderMarkdown はブロックの列をMarkdownに変換します（1段落1行、段落の間は空行）
+func (w markdownWriter) renderMarkdown(blocks []Block) string {
+	var lines []string
+	for _, block := range blocks {
+		switch block.Kind {
+		case BlockParagraph:
+			line := w.renderMarkdownInlines(block.Inlines)
+			if markdownLineStart.MatchString(line) {
+				line = `\` + line
+			}
+			lines = append(lines, line)
+		case BlockBlank:
+			// 空行は段落の区切りとして表現されるので出力しない
+		case BlockIllustration:
+			lines = append(lines, fmt.Sprintf("![挿絵](%s)", block.Src))
+		case BlockSeparator:
+			lines = append(lines, "***")
+		}
+	}
+	return strings.Join(lines, "\n\n")
+}
+
+// WriteNovel は小説全体をMarkdownとして出力します
+// 章のある小説は章を ## 見出し、各話を ### 見出しとし、章のない小説は各話を ## 見出しとします
+func (w markdownWriter) WriteNovel(out io.Writer, novel *Novel) error {
+	var md strings.Builder
+	fmt.Fprintf(&md, "# %s\n\n%s\n", escapeMarkdown(novel.Title), escapeMarkdown(novel.Author))
+
+	hasChapters := false
+	for _, chapter := range novel.Chapters {
+		hasChapters = hasChapters || chapter.Title != ""
+	}
+
+	for _, chapter := range novel.Chapters {
+		episodeHeading := "##"
+		if hasChapters {
+			episodeHeading = "###"
+			if chapter.Title != "" {
+				fmt.Fprintf(&md, "\n## %s\n", escapeMarkdown(chapter.Title))
+			}
+		}
+		for _, episode := range chapter.Episodes {
+			title := episode.Title
+			if title == "" {
+				title = novel.Title
+			}
+			fmt.Fprintf(&md, "\n%s %s\n\n", episodeHeading, escapeMarkdown(title))
+			md.WriteString(w.renderMarkdown(episode.Blocks))
+			md.WriteString("\n")
+		}
+	}
+
+	_, err := io.WriteString(out, md.String())
+	return err
+}
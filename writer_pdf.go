@@ -0,0 +1,843 @@
+package main
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// pdfPageSizes は選択できる用紙サイズ（幅・高さ、ポイント単位）
+var pdfPageSizes = map[string][2]float64{
+	"A4": {595.28, 841.89},
+	"A5": {419.53, 595.28},
+	"A6": {297.64, 419.53},
+	"B6": {362.83, 515.91},
+}
+
+// pdfWriter は小説全体を縦書きのPDFとして出力する形式
+type pdfWriter struct {
+	FontPath string  // 埋め込むTrueTypeフォント（空の場合はシステムのフォントを探す）
+	PageSize string  // 用紙サイズ（pdfPageSizes のキー）
+	FontSize float64 // 本文の文字サイズ（ポイント）
+}
+
+func (pdfWriter) Ext() string { return "pdf" }
+
+// NovelFileName はPDFのファイル名を返します
+func (pdfWriter) NovelFileName(novel *Novel) string {
+	return sanitizeFileName(novel.Title) + ".pdf"
+}
+
+// defaultPDFFonts はフォントが指定されていない場合に探すTrueTypeフォントの一覧
+func defaultPDFFonts() []string {
+	windir := os.Getenv("WINDIR")
+	if windir == "" {
+		windir = `C:\Windows`
+	}
+	return []string{
+		filepath.Join(windir, "Fonts", "yumin.ttf"),
+		filepath.Join(windir, "Fonts", "msmincho.ttc"),
+		"/usr/share/fonts/opentype/ipafont-mincho/ipam.ttf",
+		"/usr/share/fonts/truetype/ipafont-mincho/ipam.ttf",
+		"/usr/share/fonts/ipa-mincho/ipam.ttf",
+		"/usr/share/fonts/truetype/takao-mincho/TakaoMincho.ttf",
+		"/System/Library/Fonts/Supplemental/Arial Unicode.ttf",
+		"/Library/Fonts/Arial Unicode.ttf",
+	}
+}
+
+// findPDFFont は埋め込むフォントのパスを返します
+func findPDFFont(fontPath string) (string, error) {
+	if fontPath != "" {
+		if _, err := os.Stat(fontPath); err != nil {
+			return "", fmt.Errorf("フォントファイルが見つかりません: %s", fontPath)
+		}
+		return fontPath, nil
+	}
+	for _, candidate := range defaultPDFFonts() {
+		if _, err := os.Stat(candidate); err == nil {
+			return candidate, nil
+		}
+	}
+	return "", fmt.Errorf("縦書きPDF用のフォントが見つかりません。設定でTrueType形式（.ttf/.ttc）のフォントを指定してください")
+}
+
+// WriteNovel は小説全体を縦書きのPDFとして出力します
+func (w pdfWriter) WriteNovel(out io.Writer, novel *Novel) error {
+	fontPath, err := findPDFFont(w.FontPath)
+	if err != nil {
+		return err
+	}
+	font, err := loadTrueTypeFont(fontPath)
+	if err != nil {
+		return err
+	}
+
+	size, ok := pdfPageSizes[w.PageSize]
+	if !ok {
+		return fmt.Errorf("不明な用紙サイズです: %s", w.PageSize)
+	}
+	fontSize := w.FontSize
+	if fontSize <= 0 {
+		fontSize = 10
+	}
+
+	l := newPDFLayout(font, size[0], size[1], fontSize)
+	pages, outline := l.layoutNovel(novel)
+
+	return writePDF(out, novel, font, l, pages, outline)
+}
+
+// 縦書きの組版
+
+// pdfUnitKind は組版の単位の種類を表します
+type pdfUnitKind int
+
+const (
+	unitUpright     pdfUnitKind = iota // 正立させる文字（1文字1マス）
+	unitRotated                        // 横倒しにする半角文字列
+	unitTateChuYoko                    // 縦中横（2桁までの数字など）
+)
+
+// pdfUnit は行分割で分けない組版の単位です（ルビの親文字列や連続する三点リーダーなど）
+type pdfUnit struct {
+	kind     pdfUnitKind
+	text     string
+	cells    int    // 占めるマス数
+	ruby     string // ルビ（正立の単位のみ）
+	emphasis bool   // 傍点
+}
+
+// pdfColumn は1行（縦書きの1列）を表す構造体
+type pdfColumn struct {
+	size   float64   // 文字サイズ
+	indent int       // 行頭の字下げ（マス数）
+	units  []pdfUnit // 行頭から並べる単位
+	tail   []pdfUnit // 行末に揃えて並べる単位（目次のページ番号など）
+}
+
+// pdfPage は1ページ分の行を表す構造体
+type pdfPage struct {
+	columns []pdfColumn
+	width   float64 // 使用済みの行の幅
+	number  bool    // ページ番号を表示する
+}
+
+// pdfOutlineItem はPDFのしおり（アウトライン）の項目を表す構造体
+type pdfOutlineItem struct {
+	title    string
+	page     int
+	children []*pdfOutlineItem
+}
+
+// pdfLayout は縦書きの組版の状態を表す構造体
+type pdfLayout struct {
+	font          *trueTypeFont
+	width, height float64
+	marginX       float64
+	marginTop     float64
+	marginBottom  float64
+	fontSize      float64
+	pages         []*pdfPage
+}
+
+// pdfLineAdvance は文字サイズに対する行送りの比率（右側のルビの領域を含む）
+const pdfLineAdvance = 1.8
+
+func newPDFLayout(font *trueTypeFont, width, height, fontSize float64) *pdfLayout {
+	return &pdfLayout{
+		font:         font,
+		width:        width,
+		height:       height,
+		marginX:      width * 0.09,
+		marginTop:    height * 0.08,
+		marginBottom: height * 0.08,
+		fontSize:     fontSize,
+	}
+}
+
+// capacity は指定の文字サイズで1行に入るマス数を返します
+func (l *pdfLayout) capacity(size float64) int {
+	return int((l.height - l.marginTop - l.marginBottom) / size)
+}
+
+// newPage は新しいページを始めます
+func (l *pdfLayout) newPage(number bool) {
+	l.pages = append(l.pages, &pdfPage{number: number})
+}
+
+// current は組版中のページを返します
+func (l *pdfLayout) current() *pdfPage {
+	if len(l.pages) == 0 {
+		l.newPage(true)
+	}
+	return l.pages[len(l.pages)-1]
+}
+
+// addColumn は行を追加します（ページに入らない場合は改ページする）
+func (l *pdfLayout) addColumn(column pdfColumn) {
+	page := l.current()
+	advance := column.size * pdfLineAdvance
+	if len(page.columns) > 0 && page.width+advance > l.width-l.marginX*2 {
+		l.newPage(page.number)
+		page = l.current()
+	}
+	page.columns = append(page.columns, column)
+	page.width += advance
+}
+
+// addText は単位の列を行に分割して追加します
+func (l *pdfLayout) addText(units []pdfUnit, size float64, indent int) {
+	if len(units) == 0 {
+		l.addColumn(pdfColumn{size: size})
+		return
+	}
+	for _, line := range breakLines(units, l.capacity(size)-indent) {
+		l.addColumn(pdfColumn{size: size, indent: indent, units: line})
+	}
+}
+
+// layoutNovel は表紙・目次・本文を組版し、ページとしおりを返します
+func (l *pdfLayout) layoutNovel(novel *Novel) ([]*pdfPage, []*pdfOutlineItem) {
+	// 本文を先に組版して各話の開始ページを求める
+	body := newPDFLayout(l.font, l.width, l.height, l.fontSize)
+	starts := make(map[*Episode]int)
+	chapterStarts := make(map[*Chapter]int)
+	body.layoutBody(novel, starts, chapterStarts)
+
+	// 表紙
+	l.layoutTitle(novel)
+	tocStart := len(l.pages)
+
+	// 目次のページ数は項目数で決まるので、仮の番号で組版してページ数を求める
+	toc := newPDFLayout(l.font, l.width, l.height, l.fontSize)
+	toc.layoutTOC(novel, func(*Episode) int { return 0 })
+	offset := tocStart + len(toc.pages) // 表紙と目次のページ数
+
+	// 目次
+	l.layoutTOC(novel, func(e *Episode) int { return offset + starts[e] + 1 })
+	l.pages = append(l.pages, body.pages...)
+
+	// しおり
+	outline := []*pdfOutlineItem{{title: "目次", page: tocStart}}
+	for _, chapter := range novel.Chapters {
+		var parent *pdfOutlineItem
+		if chapter.Title != "" {
+			parent = &pdfOutlineItem{title: chapter.Title, page: offset + chapterStarts[chapter]}
+			outline = append(outline, parent)
+		}
+		for _, episode := range chapter.Episodes {
+			item := &pdfOutlineItem{title: pdfEpisodeTitle(novel, episode), page: offset + starts[episode]}
+			if parent != nil {
+				parent.children = append(parent.children, item)
+			} else {
+				outline = append(outline, item)
+			}
+		}
+	}
+
+	return l.pages, outline
+}
+
+// pdfEpisodeTitle は各話の見出しを返します（短編の場合は小説タイトル）
+func pdfEpisodeTitle(novel *Novel, episode *Episode) string {
+	if episode.Title == "" {
+		return novel.Title
+	}
+	return episode.Title
+}
+
+// layoutTitle は表紙を組版します
+func (l *pdfLayout) layoutTitle(novel *Novel) {
+	l.newPage(false)
+	l.addColumn(pdfColumn{size: l.fontSize * 2})
+	l.addText(l.units([]Inline{{Kind: InlineText, Text: novel.Title}}, false), l.fontSize*2, 2)
+	l.addColumn(pdfColumn{size: l.fontSize * 2})
+	l.addColumn(pdfColumn{size: l.fontSize * 1.3, tail: l.units([]Inline{{Kind: InlineText, Text: novel.Author}}, false)})
+}
+
+// layoutTOC は目次を組版します（pageOf は各話の表示上のページ番号を返す）
+func (l *pdfLayout) layoutTOC(novel *Novel, pageOf func(*Episode) int) {
+	l.newPage(true)
+	l.addText(l.units([]Inline{{Kind: InlineText, Text: "目次"}}, false), l.fontSize*1.4, 2)
+	l.addColumn(pdfColumn{size: l.fontSize})
+
+	capacity := l.capacity(l.fontSize)
+	for _, chapter := range novel.Chapters {
+		if chapter.Title != "" {
+			l.addColumn(pdfColumn{size: l.fontSize, indent: 1, units: l.truncate(chapter.Title, capacity-1)})
+		}
+		for _, episode := range chapter.Episodes {
+			number := l.units([]Inline{{Kind: InlineText, Text: kanjiNumber(pageOf(episode))}}, false)
+			indent := 2
+			if chapter.Title == "" {
+				indent = 1
+			}
+			l.addColumn(pdfColumn{
+				size:   l.fontSize,
+				indent: indent,
+				units:  l.truncate(pdfEpisodeTitle(novel, episode), capacity-indent-len(number)-1),
+				tail:   number,
+			})
+		}
+	}
+}
+
+// truncate は文字列を指定のマス数に収まるように切り詰めた単位の列を返します
+func (l *pdfLayout) truncate(text string, cells int) []pdfUnit {
+	units := l.units([]Inline{{Kind: InlineText, Text: text}}, false)
+	used := 0
+	for i, u := range units {
+		if used+u.cells > cells {
+			return append(units[:i:i], pdfUnit{kind: unitUpright, text: "…", cells: 1})
+		}
+		used += u.cells
+	}
+	return units
+}
+
+// layoutBody は各話の本文を組版し、各話と章の開始ページ（0から始まる）を記録します
+func (l *pdfLayout) layoutBody(novel *Novel, starts map[*Episode]int, chapterStarts map[*Chapter]int) {
+	for _, chapter := range novel.Chapters {
+		for i, episode := range chapter.Episodes {
+			// 各話は新しいページから始める
+			l.newPage(true)
+			if i == 0 && chapter.Title != "" {
+				chapterStarts[chapter] = len(l.pages) - 1
+				l.addText(l.units([]Inline{{Kind: InlineText, Text: chapter.Title}}, false), l.fontSize*1.5, 2)
+				l.addColumn(pdfColumn{size: l.fontSize})
+			}
+			starts[episode] = len(l.pages) - 1
+
+			l.addText(l.units([]Inline{{Kind: InlineText, Text: pdfEpisodeTitle(novel, episode)}}, false), l.fontSize*1.25, 3)
+			l.addColumn(pdfColumn{size: l.fontSize})
+
+			for _, block := range episode.Blocks {
+				switch block.Kind {
+				case BlockParagraph:
+					l.addText(l.units(block.Inlines, false), l.fontSize, 0)
+				case BlockBlank:
+					l.addColumn(pdfColumn{size: l.fontSize})
+				case BlockIllustration:
+					l.addText(l.units([]Inline{{Kind: InlineText, Text: "［挿絵］"}}, false), l.fontSize, 2)
+				case BlockSeparator:
+					l.addColumn(pdfColumn{size: l.fontSize})
+					l.addText(l.units([]Inline{{Kind: InlineText, Text: "＊　＊　＊"}}, false), l.fontSize, 6)
+					l.addColumn(pdfColumn{size: l.fontSize})
+				}
+			}
+		}
+	}
+}
+
+// 禁則処理の文字種
+const (
+	pdfNoStartChars = "、。，．・：；？！゛゜ヽヾゝゞ々ー）」』】〕〉》〙〗〟’”｝］ぁぃぅぇぉっゃゅょゎゕゖァィゥェォッャュョヮヵヶ…‥!?),.:;"
+	pdfNoEndChars   = "（「『【〔〈《〘〖〝‘“｛［(["
+	pdfHangingChars = "、。，．"
+	pdfPairedChars  = "…‥―" // 2つ続く場合は分割しない
+)
+
+// isHalfWidthRune は横倒しにする半角文字かどうかを返します
+func isHalfWidthRune(r rune) bool {
+	return r < 0x1100 && r != '\u00a0'
+}
+
+// units は段落内の要素を組版の単位に変換します
+func (l *pdfLayout) units(inlines []Inline, emphasis bool) []pdfUnit {
+	var units []pdfUnit
+	for _, inline := range inlines {
+		emphasized := emphasis || inline.Kind == InlineEmphasis
+		if inline.Kind == InlineRuby {
+			units = append(units, pdfUnit{
+				kind:     unitUpright,
+				text:     inline.Text,
+				cells:    utf8.RuneCountInString(inline.Text),
+				ruby:     inline.Ruby,
+				emphasis: emphasized,
+			})
+			continue
+		}
+
+		runes := []rune(inline.Text)
+		for i := 0; i < len(runes); {
+			r := runes[i]
+			switch {
+			case isHalfWidthRune(r):
+				j := i
+				for j < len(runes) && isHalfWidthRune(runes[j]) {
+					j++
+				}
+				units = append(units, l.halfWidthUnits(string(runes[i:j]), emphasized)...)
+				i = j
+			case strings.ContainsRune(pdfPairedChars, r) && i+1 < len(runes) && runes[i+1] == r:
+				units = append(units, pdfUnit{kind: unitUpright, text: string(runes[i : i+2]), cells: 2, emphasis: emphasized})
+				i += 2
+			default:
+				units = append(units, pdfUnit{kind: unitUpright, text: string(r), cells: 1, emphasis: emphasized})
+				i++
+			}
+		}
+	}
+	return units
+}
+
+// halfWidthUnits は半角文字列を縦中横または横倒しの単位に変換します
+func (l *pdfLayout) halfWidthUnits(text string, emphasis bool) []pdfUnit {
+	if isTateChuYoko(text) {
+		return []pdfUnit{{kind: unitTateChuYoko, text: text, cells: 1, emphasis: emphasis}}
+	}
+
+	// 1行に入らない長さの場合は分割する
+	var units []pdfUnit
+	var run strings.Builder
+	width := 0.0
+	capacity := float64(l.capacity(l.fontSize))
+	flush := func() {
+		if run.Len() > 0 {
+			units = append(units, pdfUnit{kind: unitRotated, text: run.String(), cells: int(math.Max(1, math.Ceil(width))), emphasis: emphasis})
+			run.Reset()
+			width = 0
+		}
+	}
+	for _, r := range text {
+		advance := l.font.advance(r)
+		if width+advance > capacity {
+			flush()
+		}
+		run.WriteRune(r)
+		width += advance
+	}
+	flush()
+	return units
+}
+
+// isTateChuYoko は縦中横で組む文字列（2桁までの数字、!? など）かどうかを返します
+func isTateChuYoko(text string) bool {
+	if len(text) == 0 || len(text) > 2 {
+		return false
+	}
+	digits, marks := true, true
+	for _, r := range text {
+		digits = digits && r >= '0' && r <= '9'
+		marks = marks && (r == '!' || r == '?')
+	}
+	return digits || marks
+}
+
+// breakLines は単位の列を1行のマス数に収まるように分割します（行頭・行末の禁則処理を行う）
+func breakLines(units []pdfUnit, capacity int) [][]pdfUnit {
+	if capacity < 1 {
+		capacity = 1
+	}
+
+	var lines [][]pdfUnit
+	var line []pdfUnit
+	used := 0
+	for _, u := range splitLongUnits(units, capacity) {
+		if used+u.cells <= capacity {
+			line = append(line, u)
+			used += u.cells
+			continue
+		}
+
+		// 句読点は行末にぶら下げる
+		if used == capacity && isHangingUnit(u) {
+			lines = append(lines, append(line, u))
+			line, used = nil, 0
+			continue
+		}
+
+		var carry []pdfUnit
+		// 行頭禁則：直前の単位を次の行に追い出す
+		if isNoStartUnit(u) {
+			for len(line) > 1 && isNoStartUnit(line[len(line)-1]) {
+				carry = append([]pdfUnit{line[len(line)-1]}, carry...)
+				line = line[:len(line)-1]
+			}
+			if len(line) > 1 {
+				carry = append([]pdfUnit{line[len(line)-1]}, carry...)
+				line = line[:len(line)-1]
+			}
+		}
+		// 行末禁則：開き括弧は次の行に送る
+		for len(line) > 1 && isNoEndUnit(line[len(line)-1]) {
+			carry = append([]pdfUnit{line[len(line)-1]}, carry...)
+			line = line[:len(line)-1]
+		}
+
+		lines = append(lines, line)
+		line = append(carry, u)
+		used = 0
+		for _, c := range line {
+			used += c.cells
+		}
+	}
+	if len(line) > 0 {
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+// splitLongUnits は1行に入らない正立の単位（長いルビの親文字列など）を分割します
+func splitLongUnits(units []pdfUnit, capacity int) []pdfUnit {
+	result := make([]pdfUnit, 0, len(units))
+	for _, u := range units {
+		if u.cells <= capacity || u.kind != unitUpright {
+			result = append(result, u)
+			continue
+		}
+		base, ruby := []rune(u.text), []rune(u.ruby)
+		for start := 0; start < len(base); start += capacity {
+			end := min(start+capacity, len(base))
+			part := pdfUnit{kind: unitUpright, text: string(base[start:end]), cells: end - start, emphasis: u.emphasis}
+			// ルビは親文字の数に比例して割り振る
+			if len(ruby) > 0 {
+				part.ruby = string(ruby[start*len(ruby)/len(base) : end*len(ruby)/len(base)])
+			}
+			result = append(result, part)
+		}
+	}
+	return result
+}
+
+func firstRune(u pdfUnit) rune {
+	r, _ := utf8.DecodeRuneInString(u.text)
+	return r
+}
+
+func lastRune(u pdfUnit) rune {
+	r, _ := utf8.DecodeLastRuneInString(u.text)
+	return r
+}
+
+func isNoStartUnit(u pdfUnit) bool {
+	return u.ruby == "" && strings.ContainsRune(pdfNoStartChars, firstRune(u))
+}
+
+func isNoEndUnit(u pdfUnit) bool {
+	return u.ruby == "" && strings.ContainsRune(pdfNoEndChars, lastRune(u))
+}
+
+func isHangingUnit(u pdfUnit) bool {
+	return u.cells == 1 && u.kind == unitUpright && strings.ContainsRune(pdfHangingChars, firstRune(u))
+}
+
+// kanjiNumber は数値を縦書き用の漢数字（一二三…）に変換します
+func kanjiNumber(n int) string {
+	digits := []rune("〇一二三四五六七八九")
+	var result []rune
+	for _, r := range fmt.Sprintf("%d", n) {
+		result = append(result, digits[r-'0'])
+	}
+	return string(result)
+}
+
+// PDFの出力
+
+// pdfVerticalForms はフォントに縦書き用グリフの情報がない場合に使う縦書き用の文字
+var pdfVerticalForms = map[rune]rune{
+	'、': '︑', '。': '︒', '，': '︐', '：': '︓', '；': '︔', '！': '︕', '？': '︖',
+	'「': '﹁', '」': '﹂', '『': '﹃', '』': '﹄', '（': '︵', '）': '︶', '【': '︻', '】': '︼',
+	'〔': '︹', '〕': '︺', '〈': '︿', '〉': '﹀', '《': '︽', '》': '︾', '｛': '︷', '｝': '︸',
+	'…': '︙', '‥': '︰', '―': '︱', '—': '︱', 'ー': '丨',
+}
+
+// pdfRenderer はページの描画命令を組み立てます
+type pdfRenderer struct {
+	font *trueTypeFont
+	buf  bytes.Buffer
+}
+
+// hexGlyphs は文字列をグリフ番号の16進文字列に変換します
+func (r *pdfRenderer) hexGlyphs(text string, vertical bool) string {
+	var hex strings.Builder
+	for _, c := range text {
+		if vertical && len(r.font.vert) == 0 {
+			if v, ok := pdfVerticalForms[c]; ok && r.font.hasGlyph(v) {
+				c = v
+			}
+		}
+		fmt.Fprintf(&hex, "%04X", r.font.glyph(c, vertical))
+	}
+	return hex.String()
+}
+
+// text は縦書き（Identity-V）または横書き（Identity-H）の文字列を描画します
+func (r *pdfRenderer) text(text string, vertical bool, size float64, matrix [6]float64) {
+	font := "/FH"
+	if vertical {
+		font = "/FV"
+	}
+	fmt.Fprintf(&r.buf, "BT %s %.2f Tf %.3f %.3f %.3f %.3f %.2f %.2f Tm <%s> Tj ET\n",
+		font, size, matrix[0], matrix[1], matrix[2], matrix[3], matrix[4], matrix[5], r.hexGlyphs(text, vertical))
+}
+
+// renderPage はページの行を右から順に描画します
+func (l *pdfLayout) renderPage(r *pdfRenderer, page *pdfPage, number int) {
+	asc := float64(l.font.ascent) / float64(l.font.unitsPerEm)
+	desc := float64(l.font.descent) / float64(l.font.unitsPerEm)
+
+	right := l.width - l.marginX
+	top := l.height - l.marginTop
+	for _, column := range page.columns {
+		size := column.size
+		rubySize := size / 2
+		center := right - rubySize - size/2 // 右側にルビの領域を空ける
+		rubyX := center + size/2 + rubySize/2
+
+		renderUnits := func(units []pdfUnit, cell int) {
+			for _, u := range units {
+				y := top - float64(cell)*size
+				switch u.kind {
+				case unitUpright:
+					r.text(u.text, true, size, [6]float64{1, 0, 0, 1, center, y})
+					if u.ruby != "" {
+						rubyLen := float64(utf8.RuneCountInString(u.ruby)) * rubySize
+						rubyTop := math.Min(y-(float64(u.cells)*size-rubyLen)/2, top)
+						r.text(u.ruby, true, rubySize, [6]float64{1, 0, 0, 1, rubyX, rubyTop})
+					}
+				case unitRotated:
+					width := 0.0
+					for _, c := range u.text {
+						width += l.font.advance(c)
+					}
+					offset := (float64(u.cells) - width) * size / 2
+					r.text(u.text, false, size, [6]float64{0, -1, 1, 0, center - (asc+desc)/2*size, y - offset})
+				case unitTateChuYoko:
+					width := 0.0
+					for _, c := range u.text {
+						width += l.font.advance(c)
+					}
+					scale := math.Min(1, 1/width)
+					baseline := y - size/2 - (asc+desc)/2*size
+					r.text(u.text, false, size, [6]float64{scale, 0, 0, 1, center - width*scale*size/2, baseline})
+				}
+				if u.emphasis {
+					dot := "﹅"
+					if !l.font.hasGlyph('﹅') {
+						dot = "・"
+					}
+					for i := 0; i < u.cells; i++ {
+						r.text(dot, true, rubySize, [6]float64{1, 0, 0, 1, rubyX, y - float64(i)*size - (size-rubySize)/2})
+					}
+				}
+				cell += u.cells
+			}
+		}
+
+		renderUnits(column.units, column.indent)
+		if len(column.tail) > 0 {
+			tailCells := 0
+			for _, u := range column.tail {
+				tailCells += u.cells
+			}
+			renderUnits(column.tail, l.capacity(size)-tailCells)
+		}
+
+		right -= size * pdfLineAdvance
+	}
+
+	// ページ番号（下部中央に横書き）
+	if page.number {
+		label := fmt.Sprintf("%d", number)
+		numberSize := l.fontSize * 0.8
+		width := 0.0
+		for _, c := range label {
+			width += l.font.advance(c)
+		}
+		r.text(label, false, numberSize, [6]float64{1, 0, 0, 1, (l.width - width*numberSize) / 2, l.marginBottom / 2})
+	}
+}
+
+// pdfDocument はPDFのオブジェクトを組み立てます
+type pdfDocument struct {
+	objects [][]byte
+}
+
+// reserve はオブジェクト番号を予約します
+func (d *pdfDocument) reserve() int {
+	d.objects = append(d.objects, nil)
+	return len(d.objects)
+}
+
+// set は予約したオブジェクトの内容を設定します
+func (d *pdfDocument) set(id int, object string) {
+	d.objects[id-1] = []byte(object)
+}
+
+// add はオブジェクトを追加してオブジェクト番号を返します
+func (d *pdfDocument) add(object string) int {
+	id := d.reserve()
+	d.set(id, object)
+	return id
+}
+
+// addStream は圧縮したストリームを追加してオブジェクト番号を返します
+func (d *pdfDocument) addStream(dict string, data []byte) int {
+	var compressed bytes.Buffer
+	zw := zlib.NewWriter(&compressed)
+	zw.Write(data)
+	zw.Close()
+
+	id := d.reserve()
+	d.objects[id-1] = append([]byte(fmt.Sprintf("<< %s /Filter /FlateDecode /Length %d >>\nstream\n", dict, compressed.Len())),
+		append(compressed.Bytes(), "\nendstream"...)...)
+	return id
+}
+
+// writeTo はPDFファイルを出力します
+func (d *pdfDocument) writeTo(out io.Writer, root, info int) error {
+	var buf bytes.Buffer
+	buf.WriteString("%PDF-1.7\n%\xe2\xe3\xcf\xd3\n")
+	offsets := make([]int, len(d.objects))
+	for i, object := range d.objects {
+		offsets[i] = buf.Len()
+		fmt.Fprintf(&buf, "%d 0 obj\n", i+1)
+		buf.Write(object)
+		buf.WriteString("\nendobj\n")
+	}
+
+	xref := buf.Len()
+	fmt.Fprintf(&buf, "xref\n0 %d\n0000000000 65535 f \n", len(d.objects)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&buf, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&buf, "trailer\n<< /Size %d /Root %d 0 R /Info %d 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(d.objects)+1, root, info, xref)
+
+	_, err := out.Write(buf.Bytes())
+	return err
+}
+
+// pdfString は文字列をPDFのテキスト文字列（UTF-16BE）に変換します
+func pdfString(text string) string {
+	var hex strings.Builder
+	hex.WriteString("<FEFF")
+	for _, u := range utf16.Encode([]rune(text)) {
+		fmt.Fprintf(&hex, "%04X", u)
+	}
+	hex.WriteString(">")
+	return hex.String()
+}
+
+// writePDF は組版したページをPDFとして出力します
+func writePDF(out io.Writer, novel *Novel, font *trueTypeFont, l *pdfLayout, pages []*pdfPage, outline []*pdfOutlineItem) error {
+	doc := &pdfDocument{}
+	catalog := doc.reserve()
+	pagesID := doc.reserve()
+	fontH := doc.reserve()
+	fontV := doc.reserve()
+
+	// ページ（描画時に使用したグリフを記録するため、フォントより先に作成する）
+	pageIDs := make([]int, len(pages))
+	for i, page := range pages {
+		r := &pdfRenderer{font: font}
+		l.renderPage(r, page, i+1)
+		content := doc.addStream("", r.buf.Bytes())
+		pageIDs[i] = doc.add(fmt.Sprintf("<< /Type /Page /Parent %d 0 R /MediaBox [0 0 %.2f %.2f] /Resources << /Font << /FH %d 0 R /FV %d 0 R >> >> /Contents %d 0 R >>",
+			pagesID, l.width, l.height, fontH, fontV, content))
+	}
+	var kids strings.Builder
+	for _, id := range pageIDs {
+		fmt.Fprintf(&kids, "%d 0 R ", id)
+	}
+	doc.set(pagesID, fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", kids.String(), len(pageIDs)))
+
+	// フォント（使用したグリフのみを埋め込む）
+	subset, err := font.subset()
+	if err != nil {
+		return fmt.Errorf("フォントの埋め込みに失敗しました: %w", err)
+	}
+	fontFile := doc.addStream(fmt.Sprintf("/Length1 %d", len(subset)), subset)
+	const baseFont = "NAROUS+EmbeddedFont"
+	descriptor := doc.add(fmt.Sprintf("<< /Type /FontDescriptor /FontName /%s /Flags 4 /FontBBox [%d %d %d %d] /ItalicAngle 0 /Ascent %d /Descent %d /CapHeight %d /StemV 80 /FontFile2 %d 0 R >>",
+		baseFont, font.scale(font.bbox[0]), font.scale(font.bbox[1]), font.scale(font.bbox[2]), font.scale(font.bbox[3]),
+		font.scale(font.ascent), font.scale(font.descent), font.scale(font.capHeight), fontFile))
+
+	var widths strings.Builder
+	for _, gid := range font.usedGlyphs() {
+		fmt.Fprintf(&widths, "%d [%d] ", gid, font.scale(int(font.advances[gid])))
+	}
+	cidFont := doc.add(fmt.Sprintf("<< /Type /Font /Subtype /CIDFontType2 /BaseFont /%s /CIDSystemInfo << /Registry (Adobe) /Ordering (Identity) /Supplement 0 >> /FontDescriptor %d 0 R /DW 1000 /W [%s] /DW2 [880 -1000] /CIDToGIDMap /Identity >>",
+		baseFont, descriptor, widths.String()))
+	toUnicode := doc.addStream("", toUnicodeCMap(font))
+	doc.set(fontH, fmt.Sprintf("<< /Type /Font /Subtype /Type0 /BaseFont /%s-Identity-H /Encoding /Identity-H /DescendantFonts [%d 0 R] /ToUnicode %d 0 R >>", baseFont, cidFont, toUnicode))
+	doc.set(fontV, fmt.Sprintf("<< /Type /Font /Subtype /Type0 /BaseFont /%s-Identity-V /Encoding /Identity-V /DescendantFonts [%d 0 R] /ToUnicode %d 0 R >>", baseFont, cidFont, toUnicode))
+
+	// しおり
+	outlines := doc.reserve()
+	first, last, count := addOutlineItems(doc, outline, outlines, pageIDs)
+	if count > 0 {
+		doc.set(outlines, fmt.Sprintf("<< /Type /Outlines /First %d 0 R /Last %d 0 R /Count %d >>", first, last, count))
+	} else {
+		doc.set(outlines, "<< /Type /Outlines /Count 0 >>")
+	}
+
+	// 右綴じ（縦書き）として表示する
+	doc.set(catalog, fmt.Sprintf("<< /Type /Catalog /Pages %d 0 R /Outlines %d 0 R /PageMode /UseOutlines /ViewerPreferences << /Direction /R2L >> /Lang (ja) >>", pagesID, outlines))
+	info := doc.add(fmt.Sprintf("<< /Title %s /Author %s /Creator (narou_download) >>", pdfString(novel.Title), pdfString(novel.Author)))
+
+	return doc.writeTo(out, catalog, info)
+}
+
+// addOutlineItems はしおりの項目を追加し、最初と最後の項目の番号と項目数を返します
+func addOutlineItems(doc *pdfDocument, items []*pdfOutlineItem, parent int, pageIDs []int) (int, int, int) {
+	if len(items) == 0 {
+		return 0, 0, 0
+	}
+
+	ids := make([]int, len(items))
+	for i := range items {
+		ids[i] = doc.reserve()
+	}
+	count := len(items)
+	for i, item := range items {
+		page := min(max(item.page, 0), len(pageIDs)-1)
+		dict := fmt.Sprintf("/Title %s /Parent %d 0 R /Dest [%d 0 R /Fit]", pdfString(item.title), parent, pageIDs[page])
+		if i > 0 {
+			dict += fmt.Sprintf(" /Prev %d 0 R", ids[i-1])
+		}
+		if i < len(items)-1 {
+			dict += fmt.Sprintf(" /Next %d 0 R", ids[i+1])
+		}
+		if first, last, n := addOutlineItems(doc, item.children, ids[i], pageIDs); n > 0 {
+			dict += fmt.Sprintf(" /First %d 0 R /Last %d 0 R /Count %d", first, last, n)
+			count += n
+		}
+		doc.set(ids[i], "<< "+dict+" >>")
+	}
+	return ids[0], ids[len(ids)-1], count
+}
+
+// toUnicodeCMap はグリフ番号から文字への対応表（テキストの抽出・検索用）を作成します
+func toUnicodeCMap(font *trueTypeFont) []byte {
+	var cmap bytes.Buffer
+	cmap.WriteString("/CIDInit /ProcSet findresource begin\n12 dict begin\nbegincmap\n")
+	cmap.WriteString("/CIDSystemInfo << /Registry (Adobe) /Ordering (UCS) /Supplement 0 >> def\n")
+	cmap.WriteString("/CMapName /Adobe-Identity-UCS def\n/CMapType 2 def\n")
+	cmap.WriteString("1 begincodespacerange\n<0000> <FFFF>\nendcodespacerange\n")
+
+	glyphs := font.usedGlyphs()
+	for start := 0; start < len(glyphs); start += 100 {
+		end := min(start+100, len(glyphs))
+		fmt.Fprintf(&cmap, "%d beginbfchar\n", end-start)
+		for _, gid := range glyphs[start:end] {
+			var hex strings.Builder
+			for _, u := range utf16.Encode([]rune{font.used[gid]}) {
+				fmt.Fprintf(&hex, "%04X", u)
+			}
+			fmt.Fprintf(&cmap, "<%04X> <%s>\n", gid, hex.String())
+		}
+		cmap.WriteString("endbfchar\n")
+	}
+
+	cmap.WriteString("endcmap\nCMapName currentdict /CMap defineresource pop\nend\nend\n")
+	return cmap.Bytes()
+}
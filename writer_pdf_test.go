@@ -0,0 +1,106 @@
+package main
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+func uprightUnits(text string) []pdfUnit {
+	var units []pdfUnit
+	for _, r := range text {
+		units = append(units, pdfUnit{kind: unitUpright, text: string(r), cells: 1})
+	}
+	return units
+}
+
+func unitLines(lines [][]pdfUnit) []string {
+	result := make([]string, 0, len(lines))
+	for _, line := range lines {
+		var text string
+		for _, u := range line {
+			text += u.text
+		}
+		result = append(result, text)
+	}
+	return result
+}
+
+func TestBreakLines(t *testing.T) {
+	tests := []struct {
+		name     string
+		units    []pdfUnit
+		capacity int
+		expected []string
+	}{
+		{
+			name:     "禁則のない分割",
+			units:    uprightUnits("あいうえおかきく"),
+			capacity: 4,
+			expected: []string{"あいうえ", "おかきく"},
+		},
+		{
+			name:     "句読点のぶら下げ",
+			units:    uprightUnits("あいうえ。かき"),
+			capacity: 4,
+			expected: []string{"あいうえ。", "かき"},
+		},
+		{
+			name:     "閉じ括弧の追い出し",
+			units:    uprightUnits("あいうえ」かき"),
+			capacity: 4,
+			expected: []string{"あいう", "え」かき"},
+		},
+		{
+			name:     "開き括弧を行末に置かない",
+			units:    uprightUnits("あいう「えおか"),
+			capacity: 4,
+			expected: []string{"あいう", "「えおか"},
+		},
+		{
+			name: "ルビの親文字列を分割しない",
+			units: append(uprightUnits("あいう"),
+				pdfUnit{kind: unitUpright, text: "漢字", cells: 2, ruby: "かんじ"}),
+			capacity: 4,
+			expected: []string{"あいう", "漢字"},
+		},
+		{
+			name:     "1行に入らない親文字列の分割",
+			units:    []pdfUnit{{kind: unitUpright, text: "一二三四五六", cells: 6, ruby: "いちにさんしごろく"}},
+			capacity: 4,
+			expected: []string{"一二三四", "五六"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := unitLines(breakLines(tt.units, tt.capacity))
+			if !reflect.DeepEqual(result, tt.expected) {
+				t.Errorf("breakLines() = %q, want %q", result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestKanjiNumber(t *testing.T) {
+	if got := kanjiNumber(105); got != "一〇五" {
+		t.Errorf("kanjiNumber(105) = %q, want %q", got, "一〇五")
+	}
+}
+
+func TestPDFWriter_WriteNovel(t *testing.T) {
+	// システムのフォントがない環境でも、テスト用のフォントで出力を確認する
+	fontPath, err := findPDFFont("")
+	if err != nil {
+		fontPath = testFontPath
+	}
+
+	var buf bytes.Buffer
+	w := pdfWriter{FontPath: fontPath, PageSize: "A5", FontSize: 10}
+	if err := w.WriteNovel(&buf, newTestNovel()); err != nil {
+		t.Fatalf("WriteNovel() error = %v", err)
+	}
+	if !bytes.HasPrefix(buf.Bytes(), []byte("%PDF-")) || !bytes.Contains(buf.Bytes(), []byte("/Direction /R2L")) {
+		t.Error("WriteNovel() の出力が右綴じのPDFではありません")
+	}
+}
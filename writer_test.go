@@ -0,0 +1,169 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func newTestNovel() *Novel {
+	paragraph := func(text string) []Block {
+		return []Block{{Kind: BlockParagraph, Inlines: []Inline{{Kind: InlineText, Text: text}}}}
+	}
+	return &Novel{
+		NCode:  "N1234AB",
+		Title:  "テスト小説",
+		Author: "作者",
+		URL:    "https://ncode.syosetu.com/n1234ab/",
+		Chapters: []*Chapter{
+			{Title: "第一章", Episodes: []*Episode{
+				{Index: 1, Number: "1", Title: "一話", Blocks: paragraph("本文一")},
+				{Index: 2, Number: "2", Title: "二話", Blocks: paragraph("本文二")},
+			}},
+			{Title: "第二章", Episodes: []*Episode{
+				{Index: 3, Number: "3", Title: "三話", Blocks: paragraph("本文三")},
+			}},
+		},
+	}
+}
+
+func TestAozoraWriter_WriteNovel(t *testing.T) {
+	var buf bytes.Buffer
+	w := aozoraWriter{textEncoding{Encoding: "UTF-8", LineEnding: "LF"}}
+	if err := w.WriteNovel(&buf, newTestNovel()); err != nil {
+		t.Fatalf("WriteNovel() error = %v", err)
+	}
+
+	expected := "テスト小説\n作者\n\n\n" +
+		"［＃大見出し］第一章［＃大見出し終わり］\n\n一話\n\n本文一\n" +
+		"\n\n----------------\n\n\n二話\n\n本文二\n" +
+		"\n\n----------------\n\n\n［＃大見出し］第二章［＃大見出し終わり］\n\n三話\n\n本文三\n"
+	if buf.String() != expected {
+		t.Errorf("WriteNovel() = %q, want %q", buf.String(), expected)
+	}
+}
+
+func TestEpisodeFileName(t *testing.T) {
+	novel := newTestNovel()
+	episode := novel.Episodes()[2]
+
+	tests := []struct {
+		name     string
+		writer   Writer
+		expected string
+	}{
+		{name: "テキスト", writer: aozoraWriter{}, expected: "N1234AB-3.txt"},
+		{name: "HTML", writer: htmlWriter{}, expected: "N1234AB-3.html"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := episodeFileName(novel, episode, tt.writer); got != tt.expected {
+				t.Errorf("episodeFileName() = %q, want %q", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestEpubWriter_WriteNovel(t *testing.T) {
+	var buf bytes.Buffer
+	if err := (epubWriter{Vertical: true}).WriteNovel(&buf, newTestNovel()); err != nil {
+		t.Fatalf("WriteNovel() error = %v", err)
+	}
+
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatalf("EPUBの読み込みに失敗しました: %v", err)
+	}
+	if zr.File[0].Name != "mimetype" || zr.File[0].Method != zip.Store {
+		t.Errorf("先頭のファイル = %s (method %d), want 無圧縮の mimetype", zr.File[0].Name, zr.File[0].Method)
+	}
+
+	names := make(map[string]bool)
+	for _, f := range zr.File {
+		names[f.Name] = true
+	}
+	for _, name := range []string{"META-INF/container.xml", "OEBPS/content.opf", "OEBPS/nav.xhtml", "OEBPS/text/ep0003.xhtml"} {
+		if !names[name] {
+			t.Errorf("%s がEPUBに含まれていません", name)
+		}
+	}
+}
+
+func TestNovel_WithEpisodes(t *testing.T) {
+	novel := newTestNovel()
+	filtered := novel.withEpisodes(func(e *Episode) bool { return e.Index != 3 })
+
+	if len(filtered.Chapters) != 1 || len(filtered.Episodes()) != 2 {
+		t.Errorf("withEpisodes() chapters = %d, episodes = %d, want 1, 2", len(filtered.Chapters), len(filtered.Episodes()))
+	}
+	if len(novel.Chapters) != 2 || len(novel.Episodes()) != 3 {
+		t.Error("withEpisodes() が元の小説を変更しました")
+	}
+}
+
+func TestMarkdownWriter_WriteNovel(t *testing.T) {
+	novel := newTestNovel()
+	novel.Chapters = novel.Chapters[:1]
+	novel.Chapters[0].Episodes = novel.Chapters[0].Episodes[:1]
+	novel.Chapters[0].Episodes[0].Blocks = []Block{
+		{Kind: BlockParagraph, Inlines: []Inline{
+			{Kind: InlineRuby, Text: "漢字", Ruby: "かんじ"},
+			{Kind: InlineText, Text: "と"},
+			{Kind: InlineEmphasis, Text: "傍点"},
+		}},
+		{Kind: BlockBlank},
+		{Kind: BlockParagraph, Inlines: []Inline{{Kind: InlineText, Text: "#タグ*記号*"}}},
+		{Kind: BlockSeparator},
+	}
+
+	tests := []struct {
+		name     string
+		ruby     string
+		expected string
+	}{
+		{
+			name: "HTMLのルビ",
+			ruby: "html",
+			expected: "# テスト小説\n\n作者\n\n## 第一章\n\n### 一話\n\n" +
+				"<ruby>漢字<rp>(</rp><rt>かんじ</rt><rp>)</rp></ruby>と*傍点*\n\n\\#タグ\\*記号\\*\n\n***\n",
+		},
+		{
+			name: "括弧書きのルビ",
+			ruby: "paren",
+			expected: "# テスト小説\n\n作者\n\n## 第一章\n\n### 一話\n\n" +
+				"漢字(かんじ)と*傍点*\n\n\\#タグ\\*記号\\*\n\n***\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := (markdownWriter{Ruby: tt.ruby}).WriteNovel(&buf, novel); err != nil {
+				t.Fatalf("WriteNovel() error = %v", err)
+			}
+			if buf.String() != tt.expected {
+				t.Errorf("WriteNovel() = %q, want %q", buf.String(), tt.expected)
+			}
+		})
+	}
+}
+
+func TestSingleHTMLWriter_WriteNovel(t *testing.T) {
+	var buf bytes.Buffer
+	if err := (singleHTMLWriter{}).WriteNovel(&buf, newTestNovel()); err != nil {
+		t.Fatalf("WriteNovel() error = %v", err)
+	}
+
+	for _, want := range []string{
+		`<a href="#chapter-2">第二章</a>`,
+		`<a href="#episode-3">三話</a>`,
+		`<h3 id="episode-3">三話</h3>`,
+		`<p>本文三</p>`,
+	} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("WriteNovel() の出力に %q が含まれていません", want)
+		}
+	}
+}